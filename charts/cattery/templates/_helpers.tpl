@@ -49,7 +49,8 @@ app.kubernetes.io/instance: {{ .Release.Name }}
 {{- end }}
 
 {{/*
-Coordination (leader-election) backend: memory (default), mongo, or k8s.
+Coordination (leader-election) backend: memory (default), mongo, postgres,
+or k8s.
 Safe when config.coordination is unset.
 */}}
 {{- define "cattery.coordinationBackend" -}}
@@ -57,6 +58,16 @@ Safe when config.coordination is unset.
 {{- $coord.backend | default "memory" -}}
 {{- end }}
 
+{{/*
+"true" when any configured provider is of type kubernetes (trays run as pods
+in the cluster), otherwise empty.
+*/}}
+{{- define "cattery.kubernetesProvider" -}}
+{{- range .Values.config.providers | default list -}}
+{{- if eq (.type | default "") "kubernetes" -}}true{{- end -}}
+{{- end -}}
+{{- end }}
+
 {{/*
 Probe target port: status port if configured separately, otherwise http.
 */}}
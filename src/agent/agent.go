@@ -5,7 +5,9 @@ import (
 	"cattery/agent/githubListener"
 	"cattery/agent/tools"
 	"cattery/lib/agents"
+	"cattery/lib/logging"
 	"cattery/lib/messages"
+	"cattery/lib/tracing"
 	"context"
 	"errors"
 	"os"
@@ -16,6 +18,7 @@ import (
 
 	"github.com/fsnotify/fsnotify"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var RunnerFolder string
@@ -30,8 +33,12 @@ type shutdownCause struct {
 
 func (s *shutdownCause) Error() string { return s.message }
 
+// Start runs the agent. The tray's bootstrap token, if any, is read from the
+// environment or the GCE metadata rather than a flag, so it stays off the
+// process command line.
 func Start() {
-	var catteryAgent = NewCatteryAgent(RunnerFolder, CatteryServerUrl, Id)
+	token := bootstrapToken(logging.Logger("agent").WithField(logging.TrayIdKey, Id))
+	var catteryAgent = NewCatteryAgent(RunnerFolder, CatteryServerUrl, Id, token)
 	catteryAgent.Start()
 }
 
@@ -42,12 +49,17 @@ type CatteryAgent struct {
 	agentId       string
 
 	listenerExecPath string
+
+	// runSpan spans the agent's life from registration to unregistration,
+	// in the trace of its tray.
+	runSpan         trace.Span
+	shutdownTracing func(context.Context) error
 }
 
-func NewCatteryAgent(runnerFolder string, catteryServerUrl string, agentId string) *CatteryAgent {
+func NewCatteryAgent(runnerFolder string, catteryServerUrl string, agentId string, token string) *CatteryAgent {
 	return &CatteryAgent{
-		logger:           log.WithFields(log.Fields{"name": "agent", "agentId": agentId}),
-		catteryClient:    catteryClient.NewCatteryClient(catteryServerUrl, agentId),
+		logger:           logging.Logger("agent").WithField(logging.TrayIdKey, agentId),
+		catteryClient:    catteryClient.NewCatteryClient(catteryServerUrl, agentId, token),
 		listenerExecPath: path.Join(runnerFolder, "bin", "Runner.Listener"),
 		agentId:          agentId,
 	}
@@ -56,6 +68,13 @@ func NewCatteryAgent(runnerFolder string, catteryServerUrl string, agentId strin
 func (a *CatteryAgent) Start() {
 	a.logger.Info("Starting Cattery Agent")
 
+	shutdownTracing, err := tracing.SetupFromEnv(context.Background(), "cattery-agent")
+	if err != nil {
+		a.logger.Warnf("Failed to set up tracing; continuing without: %v", err)
+		shutdownTracing = func(context.Context) error { return nil }
+	}
+	a.shutdownTracing = shutdownTracing
+
 	agent, jitConfig, err := a.catteryClient.RegisterAgent(a.agentId)
 	if err != nil {
 		// The agent never managed to register. The VM is stranded — the
@@ -64,6 +83,7 @@ func (a *CatteryAgent) Start() {
 		// VM running indefinitely. Stale handler cleanup on the server side
 		// will reconcile the row state.
 		a.logger.Errorf("Failed to register agent; shutting down VM: %v", err)
+		a.flushTraces()
 		tools.Shutdown()
 		return
 	}
@@ -71,7 +91,14 @@ func (a *CatteryAgent) Start() {
 
 	a.logger.Info("Agent registered, starting Listener")
 
-	ctx, cancel := context.WithCancelCause(context.Background())
+	// Requests from here on carry the run span's context, so the server's
+	// spans for pings and the unregister nest under it.
+	runCtx, runSpan := tracing.Start(tracing.WithTraceParent(context.Background(), a.catteryClient.TraceParent()),
+		"agent.run", tracing.TrayIdKey.String(a.agentId))
+	a.runSpan = runSpan
+	a.catteryClient.SetTraceParent(tracing.TraceParent(runCtx))
+
+	ctx, cancel := context.WithCancelCause(runCtx)
 	defer cancel(nil)
 
 	a.watchSignal(ctx, cancel)
@@ -116,12 +143,14 @@ func (a *CatteryAgent) resolveShutdownCause(ctx context.Context) (messages.Unreg
 }
 
 func (a *CatteryAgent) unregisterAndShutdown(reason messages.UnregisterReason, msg string) {
-	log.Infof("Stopping Cattery Agent with reason: %d, message: `%s`", reason, msg)
+	a.logger.Infof("Stopping Cattery Agent with reason: %d, message: `%s`", reason, msg)
 
 	err := a.catteryClient.UnregisterAgent(a.agent, reason, msg)
 	if err != nil {
 		a.logger.Errorf("Failed to unregister agent: %v", err)
 	}
+	tracing.End(a.runSpan, err)
+	a.flushTraces()
 
 	if a.agent.Shutdown {
 		a.logger.Debugf("Shutdown now")
@@ -129,6 +158,15 @@ func (a *CatteryAgent) unregisterAndShutdown(reason messages.UnregisterReason, m
 	}
 }
 
+// flushTraces exports the spans still buffered before the VM goes away.
+func (a *CatteryAgent) flushTraces() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := a.shutdownTracing(ctx); err != nil {
+		a.logger.Warnf("Failed to flush traces: %v", err)
+	}
+}
+
 func (a *CatteryAgent) watchSignal(ctx context.Context, cancel context.CancelCauseFunc) {
 	go func() {
 		sigs := make(chan os.Signal, 1)
@@ -3,6 +3,7 @@ package catteryClient
 import (
 	"bytes"
 	"cattery/lib/agents"
+	"cattery/lib/logging"
 	"cattery/lib/messages"
 	"encoding/json"
 	"fmt"
@@ -25,6 +26,13 @@ type CatteryClient struct {
 	baseURL    string
 	logger     *logrus.Entry
 	agentId    string
+	// token is the tray's bootstrap token, sent as a Bearer token on every
+	// request. Empty for trays started without one.
+	token string
+	// traceParent is sent as the traceparent header on every request, so the
+	// server's spans for them join the tray's trace. Set by RegisterAgent to
+	// the trace context the server returns; empty until then.
+	traceParent string
 
 	// maxAttempts and retryDelay control the retry policy applied to every
 	// request. Transient failures (network errors, 5xx, 404) retry up to
@@ -34,12 +42,13 @@ type CatteryClient struct {
 	retryDelay  time.Duration
 }
 
-func NewCatteryClient(baseURL string, agentId string) *CatteryClient {
+func NewCatteryClient(baseURL string, agentId string, token string) *CatteryClient {
 	return &CatteryClient{
 		httpClient:  &http.Client{},
 		baseURL:     baseURL,
-		logger:      logrus.WithField("name", "catteryClient"),
+		logger:      logging.Logger("catteryClient").WithField(logging.TrayIdKey, agentId),
 		agentId:     agentId,
+		token:       token,
 		maxAttempts: defaultMaxAttempts,
 		retryDelay:  defaultRetryDelay,
 	}
@@ -49,6 +58,8 @@ func NewCatteryClient(baseURL string, agentId string) *CatteryClient {
 // server. 404 retries cover the race where the agent boots before the server
 // has finished persisting the tray row.
 //
+// The trace context in the response becomes the client's TraceParent.
+//
 // https://docs.github.com/en/rest/actions/self-hosted-runners?apiVersion=2022-11-28#create-configuration-for-a-just-in-time-runner-for-an-organization
 func (c *CatteryClient) RegisterAgent(id string) (*agents.Agent, *string, error) {
 	requestUrl, err := url.JoinPath(c.baseURL, "/agent", "register/", id)
@@ -60,9 +71,20 @@ func (c *CatteryClient) RegisterAgent(id string) (*agents.Agent, *string, error)
 	if err := c.doRequest("GET", requestUrl, nil, &resp); err != nil {
 		return nil, nil, err
 	}
+	c.traceParent = resp.TraceParent
 	return &resp.Agent, &resp.JitConfig, nil
 }
 
+// TraceParent returns the trace context sent with the client's requests.
+func (c *CatteryClient) TraceParent() string {
+	return c.traceParent
+}
+
+// SetTraceParent sets the trace context sent with the client's requests.
+func (c *CatteryClient) SetTraceParent(traceParent string) {
+	c.traceParent = traceParent
+}
+
 // UnregisterAgent tells the server the agent is shutting down.
 func (c *CatteryClient) UnregisterAgent(agent *agents.Agent, reason messages.UnregisterReason, message string) error {
 	requestJson, err := json.Marshal(messages.UnregisterRequest{
@@ -133,6 +155,12 @@ func (c *CatteryClient) try(method, requestUrl string, body []byte, dest any) (e
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err), false
 	}
+	if c.token != "" {
+		request.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.traceParent != "" {
+		request.Header.Set("traceparent", c.traceParent)
+	}
 	response, err := c.httpClient.Do(request)
 	if err != nil {
 		return err, true
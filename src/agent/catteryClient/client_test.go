@@ -20,7 +20,7 @@ import (
 // retry policy fast enough for unit tests.
 func newTestClient(t *testing.T, baseURL string) *CatteryClient {
 	t.Helper()
-	c := NewCatteryClient(baseURL, "test-agent")
+	c := NewCatteryClient(baseURL, "test-agent", "")
 	c.maxAttempts = 3
 	c.retryDelay = 1 * time.Millisecond
 	return c
@@ -49,6 +49,49 @@ func TestRegisterAgent_Success(t *testing.T) {
 	assert.Equal(t, jit, *jitConfig)
 }
 
+func TestRequests_SendToken(t *testing.T) {
+	var header atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header.Store(r.Header.Get("Authorization"))
+		_ = json.NewEncoder(w).Encode(messages.PingResponse{})
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, server.URL)
+	_, err := c.Ping()
+	require.NoError(t, err)
+	assert.Equal(t, "", header.Load(), "no token, no header")
+
+	c.token = "tray-token"
+	_, err = c.Ping()
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer tray-token", header.Load())
+}
+
+func TestRequests_SendTraceParent(t *testing.T) {
+	const traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	var header atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header.Store(r.Header.Get("traceparent"))
+		if r.Method == "GET" {
+			_ = json.NewEncoder(w).Encode(messages.RegisterResponse{TraceParent: traceParent})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(messages.PingResponse{})
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, server.URL)
+	_, _, err := c.RegisterAgent("test-agent")
+	require.NoError(t, err)
+	assert.Equal(t, "", header.Load(), "no trace context before registering")
+	assert.Equal(t, traceParent, c.TraceParent())
+
+	_, err = c.Ping()
+	require.NoError(t, err)
+	assert.Equal(t, traceParent, header.Load())
+}
+
 func TestRegisterAgent_RetriesOn404UntilSuccess(t *testing.T) {
 	// Simulates the race: server returns 404 until the tray row lands, then 200.
 	var calls atomic.Int32
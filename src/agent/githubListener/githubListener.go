@@ -1,14 +1,15 @@
 package githubListener
 
 import (
+	"cattery/lib/logging"
 	"context"
 	"os"
 	"os/exec"
 	"sync"
-
-	log "github.com/sirupsen/logrus"
 )
 
+var logger = logging.Logger("githubListener")
+
 type GithubListener struct {
 	listenerPath string
 	process      *os.Process
@@ -34,7 +35,7 @@ func (l *GithubListener) Start(ctx context.Context, cancel context.CancelCauseFu
 	go func() {
 		err := commandRun.Start()
 		if err != nil {
-			log.Errorf("Listener failed to start: %v", err)
+			logger.Errorf("Listener failed to start: %v", err)
 			close(l.started)
 			cancel(err)
 			return
@@ -62,7 +63,7 @@ func (l *GithubListener) Stop() {
 
 	err := l.kill()
 	if err != nil {
-		log.Error("Failed to kill process: ", err)
+		logger.Error("Failed to kill process: ", err)
 	}
 
 	l.process = nil
@@ -0,0 +1,45 @@
+package agent
+
+import (
+	"cattery/lib/agents"
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"cloud.google.com/go/compute/metadata"
+	log "github.com/sirupsen/logrus"
+)
+
+// gceTokenAttribute is the instance metadata key the google provider stores
+// the tray's bootstrap token under.
+const gceTokenAttribute = "cattery-agent-token"
+
+// gceTokenTimeout bounds the metadata server lookup.
+const gceTokenTimeout = 10 * time.Second
+
+// bootstrapToken returns the tray's bootstrap token. It is read from the
+// environment, where every provider but google puts it. Without it, on GCE,
+// the token is read from the instance metadata, so images whose startup
+// script does not export it keep registering.
+func bootstrapToken(logger *log.Entry) string {
+	if token := os.Getenv(agents.TokenEnv); token != "" {
+		return token
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), gceTokenTimeout)
+	defer cancel()
+	if !metadata.OnGCEWithContext(ctx) {
+		return ""
+	}
+
+	token, err := metadata.InstanceAttributeValueWithContext(ctx, gceTokenAttribute)
+	if err != nil {
+		var notDefined metadata.NotDefinedError
+		if !errors.As(err, &notDefined) {
+			logger.Warnf("Failed to read the bootstrap token from the GCE metadata server: %v", err)
+		}
+		return ""
+	}
+	return token
+}
@@ -0,0 +1,51 @@
+package agent
+
+import (
+	"cattery/lib/agents"
+	"cattery/lib/logging"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeGCEMetadata serves the instance attributes through GCE_METADATA_HOST,
+// which makes the metadata package treat the test as running on GCE.
+func fakeGCEMetadata(t *testing.T, attributes map[string]string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Google", r.Header.Get("Metadata-Flavor"))
+		value, ok := attributes[strings.TrimPrefix(r.URL.Path, "/computeMetadata/v1/instance/attributes/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Metadata-Flavor", "Google")
+		_, _ = w.Write([]byte(value))
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(server.URL, "http://"))
+}
+
+func TestBootstrapToken_GCEMetadataWithoutEnv(t *testing.T) {
+	fakeGCEMetadata(t, map[string]string{gceTokenAttribute: "gce-token"})
+	t.Setenv(agents.TokenEnv, "")
+
+	assert.Equal(t, "gce-token", bootstrapToken(logging.Logger("agent")))
+}
+
+func TestBootstrapToken_EnvWins(t *testing.T) {
+	fakeGCEMetadata(t, map[string]string{gceTokenAttribute: "gce-token"})
+	t.Setenv(agents.TokenEnv, "env-token")
+
+	assert.Equal(t, "env-token", bootstrapToken(logging.Logger("agent")))
+}
+
+func TestBootstrapToken_GCEMetadataWithoutToken(t *testing.T) {
+	fakeGCEMetadata(t, map[string]string{})
+	t.Setenv(agents.TokenEnv, "")
+
+	assert.Empty(t, bootstrapToken(logging.Logger("agent")))
+}
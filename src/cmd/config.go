@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"cattery/lib/config"
+	"cattery/lib/trayManager"
+	"encoding/json"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var configJson bool
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Work with the Cattery config file",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the config file and report every problem found",
+	Long: `Check the config file the way the server loads it, plus the checks the
+server skips: unknown provider types, unknown tray type config keys, duplicate
+names, unreadable GitHub App private keys and invalid stale thresholds.
+Exits with status 1 if any problem is found.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		file, cfg, problems := config.Check(configPath)
+		if cfg != nil {
+			problems = append(problems, trayManager.StaleThresholdProblems(cfg.Stale.Thresholds)...)
+		}
+
+		messages := make([]string, 0, len(problems))
+		for _, problem := range problems {
+			messages = append(messages, problem.Error())
+		}
+
+		if configJson {
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			_ = encoder.Encode(struct {
+				File     string   `json:"file"`
+				Valid    bool     `json:"valid"`
+				Problems []string `json:"problems"`
+			}{file, len(messages) == 0, messages})
+		} else if len(messages) == 0 {
+			cmd.Printf("%s: OK\n", file)
+		} else {
+			cmd.PrintErrf("%s: %d problem(s) found\n", file, len(messages))
+			for _, message := range messages {
+				cmd.PrintErrf("  - %s\n", message)
+			}
+		}
+
+		if len(messages) > 0 {
+			os.Exit(1)
+		}
+	},
+}
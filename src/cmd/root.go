@@ -20,8 +20,12 @@ func init() {
 
 	rootCmd.AddCommand(agentCmd)
 	rootCmd.AddCommand(serverCmd)
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configValidateCmd)
 
 	serverCmd.PersistentFlags().StringVarP(&configPath, "config-path", "c", "", "Path to the config file")
+	configCmd.PersistentFlags().StringVarP(&configPath, "config-path", "c", "", "Path to the config file")
+	configValidateCmd.Flags().BoolVar(&configJson, "json", false, "Print the result as JSON")
 
 	agentCmd.Flags().StringVarP(
 		&agent.RunnerFolder,
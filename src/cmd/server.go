@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"cattery/lib/config"
+	"cattery/lib/logging"
 	"cattery/server"
 	"github.com/spf13/cobra"
 	"os"
@@ -12,13 +13,19 @@ var serverCmd = &cobra.Command{
 	Short: "Start the Cattery server",
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 
-		_, err := config.LoadConfig(&configPath)
+		cfg, err := config.LoadConfig(&configPath)
 		if err != nil {
 			cmd.PrintErrln("Error loading config:", err)
 			os.Exit(1)
 			return
 		}
 
+		if err := logging.Configure(cfg.Logging); err != nil {
+			cmd.PrintErrln("Error configuring logging:", err)
+			os.Exit(1)
+			return
+		}
+
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		server.Start()
@@ -1,5 +1,9 @@
 package agents
 
+// TokenEnv is the environment variable through which providers hand a tray's
+// bootstrap token to its agent.
+const TokenEnv = "CATTERY_AGENT_TOKEN"
+
 type Agent struct {
 	AgentId  string `json:"agentId"`
 	RunnerId int64  `json:"runnerId"`
@@ -0,0 +1,53 @@
+package bolt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// Bucket names used by the bolt repositories. Open creates them, so the
+// repositories can assume they exist.
+var (
+	TraysBucket          = []byte("trays")
+	RestartersBucket     = []byte("restarters")
+	PausesBucket         = []byte("pauses")
+	TrayEventsBucket     = []byte("trayEvents")
+	PollerMessagesBucket = []byte("pollerMessages")
+)
+
+// openTimeout bounds the wait for the file lock. bbolt allows one process per
+// file; a second cattery pointed at the same path fails fast instead of
+// hanging on startup.
+const openTimeout = time.Second
+
+// Open opens (creating if needed) the bolt database at path and its buckets.
+func Open(path string) (*bbolt.DB, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			return nil, fmt.Errorf("failed to create directory for %s: %w", path, err)
+		}
+	}
+
+	db, err := bbolt.Open(path, 0o600, &bbolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bolt database %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range [][]byte{TraysBucket, RestartersBucket, PausesBucket, TrayEventsBucket, PollerMessagesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return fmt.Errorf("failed to create bucket %s: %w", name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
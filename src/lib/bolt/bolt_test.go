@@ -0,0 +1,43 @@
+package bolt
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
+)
+
+func TestOpen_CreatesDirectoryAndBuckets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "cattery.db")
+
+	db, err := Open(path)
+	require.NoError(t, err)
+
+	err = db.View(func(tx *bbolt.Tx) error {
+		assert.NotNil(t, tx.Bucket(TraysBucket))
+		assert.NotNil(t, tx.Bucket(RestartersBucket))
+		assert.NotNil(t, tx.Bucket(PausesBucket))
+		assert.NotNil(t, tx.Bucket(TrayEventsBucket))
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	// reopening an existing file keeps working
+	db, err = Open(path)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+}
+
+func TestOpen_SecondOpenerTimesOut(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cattery.db")
+
+	db, err := Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	_, err = Open(path)
+	assert.Error(t, err, "the file lock must keep a second process out")
+}
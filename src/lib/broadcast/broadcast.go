@@ -0,0 +1,56 @@
+// Package broadcast fans values out to subscribers without ever blocking
+// the publisher.
+package broadcast
+
+import "sync"
+
+// Broadcaster delivers every published value to each current subscriber. A
+// subscriber that falls a full buffer behind is dropped and its channel
+// closed, so it can start over from a fresh snapshot instead of silently
+// missing values. The zero value is ready to use.
+type Broadcaster[T any] struct {
+	mu          sync.Mutex
+	subscribers map[chan T]struct{}
+}
+
+// Subscribe returns a channel receiving values published from now on,
+// buffered to buffer values, and a func that unsubscribes it. The channel is
+// closed when the subscriber is dropped or unsubscribes.
+func (b *Broadcaster[T]) Subscribe(buffer int) (<-chan T, func()) {
+	ch := make(chan T, buffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subscribers == nil {
+		b.subscribers = make(map[chan T]struct{})
+	}
+	b.subscribers[ch] = struct{}{}
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.remove(ch)
+	}
+}
+
+// Publish sends v to every subscriber with room in its buffer and drops the
+// others.
+func (b *Broadcaster[T]) Publish(v T) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers {
+		select {
+		case ch <- v:
+		default:
+			b.remove(ch)
+		}
+	}
+}
+
+// remove closes ch once. b.mu must be held.
+func (b *Broadcaster[T]) remove(ch chan T) {
+	if _, ok := b.subscribers[ch]; ok {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
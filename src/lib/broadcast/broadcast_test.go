@@ -0,0 +1,53 @@
+package broadcast
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBroadcaster_DeliversToEverySubscriber(t *testing.T) {
+	var b Broadcaster[int]
+	a, stopA := b.Subscribe(4)
+	defer stopA()
+	c, stopC := b.Subscribe(4)
+	defer stopC()
+
+	b.Publish(1)
+	b.Publish(2)
+
+	assert.Equal(t, 1, <-a)
+	assert.Equal(t, 2, <-a)
+	assert.Equal(t, 1, <-c)
+	assert.Equal(t, 2, <-c)
+}
+
+func TestBroadcaster_DropsSlowSubscriber(t *testing.T) {
+	var b Broadcaster[int]
+	slow, stopSlow := b.Subscribe(1)
+	defer stopSlow()
+	fast, stopFast := b.Subscribe(4)
+	defer stopFast()
+
+	b.Publish(1)
+	b.Publish(2) // slow's buffer is full
+
+	assert.Equal(t, 1, <-slow)
+	_, ok := <-slow
+	assert.False(t, ok, "a subscriber that fell behind is closed")
+
+	assert.Equal(t, 1, <-fast)
+	assert.Equal(t, 2, <-fast)
+}
+
+func TestBroadcaster_Unsubscribe(t *testing.T) {
+	var b Broadcaster[int]
+	ch, stop := b.Subscribe(1)
+	stop()
+	stop() // unsubscribing twice is harmless
+
+	b.Publish(1)
+	_, ok := <-ch
+	require.False(t, ok)
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+// providerTypes are the provider types the tray provider factory can build.
+var providerTypes = []string{"docker", "google", "nomad", "kubernetes", ProviderTypeFallback}
+
+// Check reads the config file at configPath (or config.yaml in the default
+// locations if empty) the way LoadConfig does, without making it the current
+// config, and returns every problem found rather than the first. On top of
+// LoadConfig's validation it rejects unknown provider types, unknown keys in
+// tray type config, duplicate names and GitHub App private keys that are
+// missing or do not parse.
+//
+// It returns the path of the file read ("" if none was found) and the parsed
+// config, which is nil if the file could not be read or unmarshalled.
+func Check(configPath string) (string, *CatteryConfig, []error) {
+	v := viper.New()
+	configure(v, configPath)
+	cfg, problems := parseConfig(v, true)
+	return v.ConfigFileUsed(), cfg, problems
+}
+
+// checkStrict runs the checks LoadConfig skips.
+func checkStrict(cfg *CatteryConfig) []error {
+	var problems []error
+
+	orgNames := make(map[string]bool)
+	for _, org := range cfg.Github {
+		if org == nil {
+			continue
+		}
+		if orgNames[org.Name] {
+			problems = append(problems, fmt.Errorf("github org %s is defined more than once", org.Name))
+		}
+		orgNames[org.Name] = true
+
+		if err := checkPrivateKey(org); err != nil {
+			problems = append(problems, fmt.Errorf("github org %s: %w", org.Name, err))
+		}
+	}
+
+	providerNames := make(map[string]bool)
+	for _, provider := range cfg.Providers {
+		if provider == nil {
+			continue
+		}
+		name := provider.Get("name")
+		if name == "" {
+			problems = append(problems, fmt.Errorf("provider of type %q has no name", provider.Get("type")))
+			continue
+		}
+		if providerNames[name] {
+			problems = append(problems, fmt.Errorf("provider %s is defined more than once", name))
+		}
+		providerNames[name] = true
+
+		if !isProviderType(provider.Get("type")) {
+			problems = append(problems, fmt.Errorf("provider %s: unknown type %q", name, provider.Get("type")))
+		}
+	}
+
+	trayTypeNames := make(map[string]bool)
+	for _, trayType := range cfg.TrayTypes {
+		if trayType == nil {
+			continue
+		}
+		if trayTypeNames[trayType.Name] {
+			problems = append(problems, fmt.Errorf("trayType %s is defined more than once", trayType.Name))
+		}
+		trayTypeNames[trayType.Name] = true
+	}
+
+	quotaNames := make(map[string]bool)
+	for _, quota := range cfg.Quotas {
+		if quota == nil {
+			continue
+		}
+		if quotaNames[quota.Name] {
+			problems = append(problems, fmt.Errorf("quota %s is defined more than once", quota.Name))
+		}
+		quotaNames[quota.Name] = true
+	}
+
+	return problems
+}
+
+func isProviderType(providerType string) bool {
+	for _, t := range providerTypes {
+		if t == providerType {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPrivateKey checks that the org's private key is a PEM-encoded RSA
+// private key, as GitHub issues for apps.
+func checkPrivateKey(org *GitHubOrganization) error {
+	data, err := org.PrivateKeyPEM()
+	if err != nil {
+		return err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return fmt.Errorf("private key is not PEM encoded")
+	}
+	if _, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return nil
+	}
+	if _, err := x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
+		return fmt.Errorf("failed to parse private key: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+package config
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writePrivateKey(t *testing.T, dir string) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	path := filepath.Join(dir, "key.pem")
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	require.NoError(t, os.WriteFile(path, pemBytes, 0600))
+	return path
+}
+
+func TestCheck_Valid(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := writePrivateKey(t, dir)
+	configPath := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`
+server:
+  listenAddress: ":8080"
+  advertiseUrl: "http://localhost:8080"
+database:
+  uri: "mongodb://localhost:27017"
+  database: "cattery"
+github:
+  - name: "test-org"
+    appId: 12345
+    appClientId: "Iv1.test123"
+    installationId: 67890
+    privateKeyPath: "`+keyPath+`"
+providers:
+  - name: "docker-provider"
+    type: "docker"
+trayTypes:
+  - name: "docker-local"
+    provider: "docker-provider"
+    runnerGroupId: 1
+    githubOrg: "test-org"
+    config:
+      image: "test-image"
+`), 0600))
+
+	file, cfg, problems := Check(configPath)
+
+	assert.Empty(t, problems)
+	assert.Equal(t, configPath, file)
+	require.NotNil(t, cfg)
+	assert.Equal(t, DockerTrayConfig{Image: "test-image"}, cfg.GetTrayType("docker-local").Config)
+	assert.Nil(t, Get().GetTrayType("docker-local"), "Check must not replace the current config")
+}
+
+func TestCheck_ReportsAllProblems(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bad.pem"), []byte("not a key"), 0600))
+	require.NoError(t, os.WriteFile(configPath, []byte(`
+server:
+  listenAddress: ":8080"
+database:
+  uri: "mongodb://localhost:27017"
+  database: "cattery"
+github:
+  - name: "test-org"
+    appId: 12345
+    appClientId: "Iv1.test123"
+    installationId: 67890
+    privateKeyPath: "`+filepath.Join(dir, "missing.pem")+`"
+  - name: "test-org"
+    appId: 12345
+    appClientId: "Iv1.test123"
+    installationId: 67890
+    privateKeyPath: "`+filepath.Join(dir, "bad.pem")+`"
+providers:
+  - name: "docker-provider"
+    type: "docker"
+  - name: "lxd-provider"
+    type: "lxd"
+trayTypes:
+  - name: "docker-local"
+    provider: "docker-provider"
+    runnerGroupId: 1
+    githubOrg: "test-org"
+    config:
+      image: "test-image"
+      imagee: "typo"
+  - name: "docker-local"
+    provider: "docker-provider"
+    runnerGroupId: 1
+    githubOrg: "other-org"
+quotas:
+  - name: "docker"
+    provider: "docker-provider"
+    maxTrays: 10
+  - name: "docker"
+    maxTrays: 20
+`), 0600))
+
+	_, cfg, problems := Check(configPath)
+
+	require.NotNil(t, cfg)
+	var messages []string
+	for _, problem := range problems {
+		messages = append(messages, problem.Error())
+	}
+	assert.Len(t, messages, 9)
+	assert.Contains(t, messages[0], "imagee")
+	assert.Contains(t, messages[1], "github org other-org for trayType docker-local not found")
+	assert.Contains(t, messages[2], "Validation failed on field 'CatteryConfig.Server.AdvertiseUrl'")
+	assert.Contains(t, messages[3], "github org test-org: failed to read private key")
+	assert.Contains(t, messages[4], "github org test-org is defined more than once")
+	assert.Contains(t, messages[5], "github org test-org: private key is not PEM encoded")
+	assert.Contains(t, messages[6], `provider lxd-provider: unknown type "lxd"`)
+	assert.Contains(t, messages[7], "trayType docker-local is defined more than once")
+	assert.Contains(t, messages[8], "quota docker is defined more than once")
+}
+
+func TestCheck_FallbackUnknownMember(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := writePrivateKey(t, dir)
+	configPath := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`
+server:
+  listenAddress: ":8080"
+  advertiseUrl: "http://localhost:8080"
+database:
+  uri: "mongodb://localhost:27017"
+  database: "cattery"
+github:
+  - name: "test-org"
+    appId: 12345
+    appClientId: "Iv1.test123"
+    installationId: 67890
+    privateKeyPath: "`+keyPath+`"
+providers:
+  - name: "docker-provider"
+    type: "docker"
+  - name: "chain"
+    type: "fallback"
+    providers: "docker-provider"
+trayTypes:
+  - name: "linux"
+    provider: "chain"
+    runnerGroupId: 1
+    githubOrg: "test-org"
+    config:
+      docker-provider:
+        image: "test-image"
+      gce-provider:
+        project: "p"
+`), 0600))
+
+	_, _, problems := Check(configPath)
+
+	require.Len(t, problems, 1)
+	assert.Contains(t, problems[0].Error(), "gce-provider is not a member of fallback provider chain")
+}
+
+func TestCheck_FallbackMembers(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := writePrivateKey(t, dir)
+	writeConfig := func(t *testing.T, members string) string {
+		configPath := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(configPath, []byte(`
+server:
+  listenAddress: ":8080"
+  advertiseUrl: "http://localhost:8080"
+database:
+  uri: "mongodb://localhost:27017"
+  database: "cattery"
+github:
+  - name: "test-org"
+    appId: 12345
+    appClientId: "Iv1.test123"
+    installationId: 67890
+    privateKeyPath: "`+keyPath+`"
+providers:
+  - name: "docker-a"
+    type: "docker"
+  - name: "docker-b"
+    type: "docker"
+  - name: "other-chain"
+    type: "fallback"
+    providers: "docker-b"
+  - name: "chain"
+    type: "fallback"
+    providers: "`+members+`"
+trayTypes:
+  - name: "linux"
+    provider: "chain"
+    runnerGroupId: 1
+    githubOrg: "test-org"
+    config:
+      docker-a:
+        image: "test-image"
+`), 0600))
+		return configPath
+	}
+
+	t.Run("names are trimmed", func(t *testing.T) {
+		_, cfg, problems := Check(writeConfig(t, " docker-a , docker-b "))
+
+		assert.Empty(t, problems)
+		require.NotNil(t, cfg)
+		assert.Equal(t, []string{"docker-a", "docker-b"}, cfg.GetProvider("chain").Members())
+	})
+
+	tests := []struct {
+		name    string
+		members string
+		problem string
+	}{
+		{"empty name", "docker-a,,docker-b", `fallback provider chain: providers "docker-a,,docker-b" has an empty member name`},
+		{"trailing comma", "docker-a,", `fallback provider chain: providers "docker-a," has an empty member name`},
+		{"duplicate", "docker-a, docker-b, docker-a", "fallback provider chain: member docker-a is listed more than once"},
+		{"self-reference", "docker-a,chain", "fallback provider chain cannot list itself as a member"},
+		{"fallback in fallback", "docker-a,other-chain", "fallback member provider other-chain cannot itself be a fallback"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, problems := Check(writeConfig(t, tt.members))
+
+			require.Len(t, problems, 1)
+			assert.EqualError(t, problems[0], tt.problem)
+		})
+	}
+}
+
+func TestCheck_FileNotFound(t *testing.T) {
+	_, cfg, problems := Check(filepath.Join(t.TempDir(), "missing.yaml"))
+
+	assert.Nil(t, cfg)
+	require.Len(t, problems, 1)
+}
@@ -3,6 +3,9 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net/url"
+	"os"
+	"slices"
 	"strings"
 	"sync/atomic"
 	"testing"
@@ -37,15 +40,21 @@ func SetForTest(t *testing.T, cfg *CatteryConfig) {
 	t.Cleanup(func() { Set(old) })
 }
 
-
 type CatteryConfig struct {
-	Server       ServerConfig          `yaml:"server" validate:"required"`
-	Database     DatabaseConfig        `yaml:"database" validate:"required"`
-	Stale        StaleConfig           `yaml:"stale"`
-	Coordination CoordinationConfig    `yaml:"coordination"`
-	Github       []*GitHubOrganization `yaml:"github" validate:"required,dive,required"`
-	Providers    []*ProviderConfig     `yaml:"providers" validate:"required,dive,required"`
-	TrayTypes    []*TrayType           `yaml:"trayTypes" validate:"required,dive,required"`
+	Server         ServerConfig          `yaml:"server" validate:"required"`
+	Database       DatabaseConfig        `yaml:"database" validate:"required"`
+	Stale          StaleConfig           `yaml:"stale"`
+	Reconciler     ReconcilerConfig      `yaml:"reconciler"`
+	Breaker        BreakerConfig         `yaml:"circuitBreaker"`
+	TrayEvents     TrayEventsConfig      `yaml:"trayEvents"`
+	PollerMessages PollerMessagesConfig  `yaml:"pollerMessages"`
+	Tracing        TracingConfig         `yaml:"tracing"`
+	Logging        LoggingConfig         `yaml:"logging"`
+	Coordination   CoordinationConfig    `yaml:"coordination"`
+	Github         []*GitHubOrganization `yaml:"github" validate:"required,dive,required"`
+	Providers      []*ProviderConfig     `yaml:"providers" validate:"required,dive,required"`
+	TrayTypes      []*TrayType           `yaml:"trayTypes" validate:"required,dive,required"`
+	Quotas         []*Quota              `yaml:"quotas" validate:"dive,required"`
 
 	githubMap    map[string]*GitHubOrganization
 	providerMap  map[string]*ProviderConfig
@@ -69,75 +78,169 @@ func (c *CatteryConfig) InitMaps() {
 	}
 }
 
+// LoadConfig finds, parses and validates the config file and makes it the
+// current config.
 func LoadConfig(configPath *string) (*CatteryConfig, error) {
 
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
-	if *configPath == "" {
-		viper.AddConfigPath("/etc/cattery/")
-		viper.AddConfigPath("./")
+	configure(viper.GetViper(), *configPath)
+
+	cfg, err := readConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	Set(cfg)
+
+	return cfg, nil
+}
+
+// Reload re-reads the file LoadConfig found and, if it is valid, makes it the
+// current config. An invalid file is rejected with an error and the current
+// config stays in effect.
+func Reload() (*CatteryConfig, error) {
+	cfg, err := readConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	Set(cfg)
+
+	return cfg, nil
+}
+
+// FilePath returns the path of the config file LoadConfig found.
+func FilePath() string {
+	return viper.ConfigFileUsed()
+}
+
+// configure points v at the config file: configPath if set, otherwise
+// config.yaml in /etc/cattery or the working directory.
+func configure(v *viper.Viper, configPath string) {
+	v.SetConfigName("config")
+	v.SetConfigType("yaml")
+	if configPath == "" {
+		v.AddConfigPath("/etc/cattery/")
+		v.AddConfigPath("./")
 	} else {
-		viper.SetConfigFile(*configPath)
+		v.SetConfigFile(configPath)
 	}
+}
 
-	err := viper.ReadInConfig()
+// readConfig parses and validates the config file without touching the
+// current config.
+func readConfig() (*CatteryConfig, error) {
+	cfg, problems := parseConfig(viper.GetViper(), false)
+	if len(problems) > 0 {
+		return nil, problems[0]
+	}
+	return cfg, nil
+}
+
+// parseConfig reads the file v points at, resolves env and file references in
+// it (see references.go), decodes and validates it and returns every problem
+// found, in file order. The config is nil only if the file could not be read,
+// resolved or unmarshalled. With strict set, tray type config is
+// decoded rejecting unknown keys and the checks in checkStrict run too.
+func parseConfig(v *viper.Viper, strict bool) (*CatteryConfig, []error) {
+	err := v.ReadInConfig()
 	if err != nil {
 		var configFileNotFoundError viper.ConfigFileNotFoundError
 		if errors.As(err, &configFileNotFoundError) {
-			return nil, fmt.Errorf("config file not found")
+			return nil, []error{fmt.Errorf("config file not found")}
 		} else {
-			return nil, fmt.Errorf("fatal error reading config file: %w", err)
+			return nil, []error{fmt.Errorf("fatal error reading config file: %w", err)}
 		}
 	}
 
+	settings, problems := resolveReferences(v.AllSettings())
+	if len(problems) > 0 {
+		return nil, problems
+	}
+	resolved := viper.New()
+	if err := resolved.MergeConfigMap(settings); err != nil {
+		return nil, []error{fmt.Errorf("failed to unmarshal config file: %w", err)}
+	}
+
 	cfg := &CatteryConfig{}
 
-	err = viper.Unmarshal(cfg)
+	err = resolved.Unmarshal(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal config file: %w", err)
+		return nil, []error{fmt.Errorf("failed to unmarshal config file: %w", err)}
 	}
 
-	cfg.githubMap = make(map[string]*GitHubOrganization)
-	for _, organization := range cfg.Github {
-		cfg.githubMap[organization.Name] = organization
-	}
+	cfg.InitMaps()
 
-	cfg.providerMap = make(map[string]*ProviderConfig)
+	// Tray types on a fallback provider with a bad member list are not
+	// decoded, so the list is reported once rather than per tray type.
+	badFallbacks := make(map[string]bool)
 	for _, provider := range cfg.Providers {
-		cfg.providerMap[provider.Get("name")] = provider
+		if provider == nil || provider.Get("type") != ProviderTypeFallback {
+			continue
+		}
+		if memberProblems := checkFallbackMembers(cfg, *provider); len(memberProblems) > 0 {
+			problems = append(problems, memberProblems...)
+			badFallbacks[provider.Get("name")] = true
+		}
 	}
 
-	cfg.trayTypesMap = make(map[string]*TrayType)
 	for _, trayType := range cfg.TrayTypes {
-		cfg.trayTypesMap[trayType.Name] = trayType
+		if trayType == nil {
+			continue
+		}
 
 		providerConfig, ok := cfg.providerMap[trayType.Provider]
-
 		if !ok {
-			return nil, fmt.Errorf("provider %s for trayType %s not found", trayType.Provider, trayType.Name)
+			problems = append(problems, fmt.Errorf("provider %s for trayType %s not found", trayType.Provider, trayType.Name))
 		}
 
-		var decodeError error
-		switch providerConfig.Get("type") {
-		case "google":
-			var gc GoogleTrayConfig
-			decodeError = mapstructure.Decode(trayType.Config, &gc)
-			trayType.Config = gc
-		case "docker":
-			var dc DockerTrayConfig
-			decodeError = mapstructure.Decode(trayType.Config, &dc)
-			trayType.Config = dc
-		case "nomad":
-			var nc NomadTrayConfig
-			decodeError = mapstructure.Decode(trayType.Config, &nc)
-			trayType.Config = nc
-		//case "scaleway":
-		default:
+		if _, ok := cfg.githubMap[trayType.GitHubOrg]; !ok {
+			problems = append(problems, fmt.Errorf("github org %s for trayType %s not found", trayType.GitHubOrg, trayType.Name))
+		}
+
+		if trayType.Repository != "" {
+			if owner, repo, ok := strings.Cut(trayType.Repository, "/"); !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+				problems = append(problems, fmt.Errorf("trayType %s: repository %q must be in owner/repo form", trayType.Name, trayType.Repository))
+			}
+		}
 
+		if trayType.MaxIdle > 0 && trayType.MaxIdle < trayType.MinIdle {
+			problems = append(problems, fmt.Errorf("trayType %s: maxIdle (%d) must not be less than minIdle (%d)", trayType.Name, trayType.MaxIdle, trayType.MinIdle))
 		}
 
+		if trayType.Schedule != nil {
+			if err := trayType.Schedule.Init(); err != nil {
+				problems = append(problems, fmt.Errorf("trayType %s: invalid schedule: %w", trayType.Name, err))
+			}
+		}
+
+		if providerConfig == nil || badFallbacks[trayType.Provider] {
+			continue
+		}
+		decoded, decodeError := decodeTrayConfig(cfg, providerConfig, trayType.Config, strict)
 		if decodeError != nil {
-			return nil, fmt.Errorf("failed to decode '%s' %w", providerConfig.Get("type"), decodeError)
+			problems = append(problems, fmt.Errorf("failed to decode '%s' %w", providerConfig.Get("type"), decodeError))
+			continue
+		}
+		trayType.Config = decoded
+	}
+
+	for _, quota := range cfg.Quotas {
+		if quota == nil {
+			continue
+		}
+		if quota.GitHubOrg != "" && cfg.githubMap[quota.GitHubOrg] == nil {
+			problems = append(problems, fmt.Errorf("github org %s for quota %s not found", quota.GitHubOrg, quota.Name))
+		}
+		if quota.Provider != "" && cfg.providerMap[quota.Provider] == nil {
+			problems = append(problems, fmt.Errorf("provider %s for quota %s not found", quota.Provider, quota.Name))
+		}
+		if fallback := cfg.fallbackOf(quota.Provider); fallback != "" {
+			// Trays rerouted onto a member keep the fallback provider's name,
+			// so a quota on the member would never count them.
+			problems = append(problems, fmt.Errorf("quota %s: provider %s is a member of fallback provider %s; set the quota on %s instead", quota.Name, quota.Provider, fallback, fallback))
+		}
+		if quota.MaxTrays == 0 && quota.MaxWeight == 0 {
+			problems = append(problems, fmt.Errorf("quota %s sets neither maxTrays nor maxWeight", quota.Name))
 		}
 	}
 
@@ -146,13 +249,98 @@ func LoadConfig(configPath *string) (*CatteryConfig, error) {
 	if err != nil {
 		// err is of type validator.ValidationErrors
 		for _, fieldErr := range err.(validator.ValidationErrors) {
-			return nil, fmt.Errorf("Validation failed on field '%s' for tag '%s'\n", fieldErr.Namespace(), fieldErr.Tag())
+			problems = append(problems, fmt.Errorf("Validation failed on field '%s' for tag '%s'", fieldErr.Namespace(), fieldErr.Tag()))
 		}
 	}
 
-	Set(cfg)
+	if err := cfg.Database.WithDefaults().validate(); err != nil {
+		problems = append(problems, err)
+	}
 
-	return cfg, nil
+	if err := checkCoordinationDatabase(cfg.Coordination.WithDefaults(), cfg.Database.WithDefaults()); err != nil {
+		problems = append(problems, err)
+	}
+
+	if strict {
+		problems = append(problems, checkStrict(cfg)...)
+	}
+
+	return cfg, problems
+}
+
+// decodeTrayConfig converts the raw trayType.config map into the typed config
+// for the provider's type. For a fallback provider the raw map is keyed by
+// member provider name, and each entry is decoded for that member's type.
+// With strict set, keys that match no config field are an error.
+func decodeTrayConfig(cfg *CatteryConfig, providerConfig *ProviderConfig, raw TrayConfig, strict bool) (TrayConfig, error) {
+	var err error
+	switch providerConfig.Get("type") {
+	case "google":
+		var gc GoogleTrayConfig
+		err = decode(raw, &gc, strict)
+		return gc, err
+	case "docker":
+		var dc DockerTrayConfig
+		err = decode(raw, &dc, strict)
+		return dc, err
+	case "nomad":
+		var nc NomadTrayConfig
+		err = decode(raw, &nc, strict)
+		return nc, err
+	case "kubernetes":
+		var kc KubernetesTrayConfig
+		err = decode(raw, &kc, strict)
+		return kc, err
+	case ProviderTypeFallback:
+		var rawMembers map[string]any
+		if err = mapstructure.Decode(raw, &rawMembers); err != nil {
+			return nil, err
+		}
+		if strict {
+			for key := range rawMembers {
+				if !slices.ContainsFunc(providerConfig.Members(), func(member string) bool { return strings.EqualFold(member, key) }) {
+					return nil, fmt.Errorf("%s is not a member of fallback provider %s", key, providerConfig.Get("name"))
+				}
+			}
+		}
+		fc := make(FallbackTrayConfig)
+		for _, member := range providerConfig.Members() {
+			memberConfig, ok := cfg.providerMap[member]
+			if !ok {
+				return nil, fmt.Errorf("fallback member provider %s not found", member)
+			}
+			if memberConfig.Get("type") == ProviderTypeFallback {
+				return nil, fmt.Errorf("fallback member provider %s cannot itself be a fallback", member)
+			}
+			// viper lowercases map keys, so match member names case-insensitively.
+			rawMember, ok := rawMembers[member]
+			if !ok {
+				rawMember = rawMembers[strings.ToLower(member)]
+			}
+			decoded, err := decodeTrayConfig(cfg, memberConfig, rawMember, strict)
+			if err != nil {
+				return nil, fmt.Errorf("member %s: %w", member, err)
+			}
+			fc[member] = decoded
+		}
+		return fc, nil
+	//case "scaleway":
+	default:
+		return raw, nil
+	}
+}
+
+// decode decodes raw into out, rejecting unknown keys if strict is set.
+func decode(raw any, out any, strict bool) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		ErrorUnused: strict,
+		DecodeHook:  mapstructure.StringToTimeDurationHookFunc(),
+		Result:      out,
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(raw)
 }
 
 // GetGitHubOrg returns the GitHub organization by name
@@ -173,6 +361,55 @@ func (c *CatteryConfig) GetProvider(name string) *ProviderConfig {
 	return provider
 }
 
+// checkFallbackMembers checks the "providers" list of a fallback provider,
+// whether or not a tray type uses it: every name must be non-empty, listed
+// once, and must not be the fallback provider itself or another fallback.
+func checkFallbackMembers(cfg *CatteryConfig, provider ProviderConfig) []error {
+	var problems []error
+	name := provider.Get("name")
+	seen := make(map[string]bool)
+	for _, member := range strings.Split(provider.Get("providers"), ",") {
+		member = strings.TrimSpace(member)
+		switch {
+		case member == "":
+			problems = append(problems, fmt.Errorf("fallback provider %s: providers %q has an empty member name", name, provider.Get("providers")))
+		case seen[member]:
+			problems = append(problems, fmt.Errorf("fallback provider %s: member %s is listed more than once", name, member))
+		case member == name:
+			problems = append(problems, fmt.Errorf("fallback provider %s cannot list itself as a member", name))
+		case cfg.providerMap[member] != nil && cfg.providerMap[member].Get("type") == ProviderTypeFallback:
+			problems = append(problems, fmt.Errorf("fallback member provider %s cannot itself be a fallback", member))
+		}
+		seen[member] = true
+	}
+	return problems
+}
+
+// fallbackOf returns the name of the first fallback provider listing
+// providerName as a member, "" if none does.
+func (c *CatteryConfig) fallbackOf(providerName string) string {
+	if providerName == "" {
+		return ""
+	}
+	for _, provider := range c.Providers {
+		if provider != nil && provider.Get("type") == ProviderTypeFallback && slices.Contains(provider.Members(), providerName) {
+			return provider.Get("name")
+		}
+	}
+	return ""
+}
+
+// QuotasFor returns the quotas that count trays of trayType.
+func (c *CatteryConfig) QuotasFor(trayType *TrayType) []*Quota {
+	var quotas []*Quota
+	for _, quota := range c.Quotas {
+		if quota.Matches(trayType.GitHubOrg, trayType.Provider) {
+			quotas = append(quotas, quota)
+		}
+	}
+	return quotas
+}
+
 // GetTrayType returns the tray type by name
 func (c *CatteryConfig) GetTrayType(name string) *TrayType {
 	trayType, ok := c.trayTypesMap[name]
@@ -189,11 +426,60 @@ type ServerConfig struct {
 	StatusListenAddress string `yaml:"statusListenAddress"`
 	AdvertiseUrl        string `yaml:"advertiseUrl" validate:"required"`
 	AgentSecret         string `yaml:"agentSecret"`
+	// AdminToken is the Bearer token for the /api/v1 admin API, served next to
+	// /status. If empty, the admin API is disabled.
+	AdminToken string `yaml:"adminToken"`
 }
 
+// DatabaseConfig selects the storage backend for trays, restart requests and
+// mongo/postgres coordination leases.
+//
+// Driver "mongodb" (the default) stores them in the Database of the MongoDB at
+// Uri. Driver "postgres" treats Uri as a libpq connection string (the database
+// name is part of it, so Database is ignored) and migrates the schema on
+// startup. Driver "bolt" keeps everything in a local bbolt file at Path, for a
+// single replica with no external services; it has no lease store, so pair it
+// with the "memory" (or "k8s") coordination backend.
 type DatabaseConfig struct {
-	Uri      string `yaml:"uri" validate:"required"`
-	Database string `yaml:"database" validate:"required"`
+	Driver   string `yaml:"driver" validate:"omitempty,oneof=mongodb postgres bolt"`
+	Uri      string `yaml:"uri"`
+	Database string `yaml:"database"`
+	Path     string `yaml:"path"`
+}
+
+const (
+	DatabaseDriverMongo    = "mongodb"
+	DatabaseDriverPostgres = "postgres"
+	DatabaseDriverBolt     = "bolt"
+)
+
+// WithDefaults returns a copy with an empty Driver set to "mongodb".
+func (d DatabaseConfig) WithDefaults() DatabaseConfig {
+	out := d
+	if out.Driver == "" {
+		out.Driver = DatabaseDriverMongo
+	}
+	return out
+}
+
+// validate checks that the fields the driver needs are set. d is expected to
+// have defaults applied.
+func (d DatabaseConfig) validate() error {
+	switch d.Driver {
+	case DatabaseDriverMongo:
+		if d.Uri == "" || d.Database == "" {
+			return fmt.Errorf("database driver %q requires uri and database", d.Driver)
+		}
+	case DatabaseDriverPostgres:
+		if d.Uri == "" {
+			return fmt.Errorf("database driver %q requires uri", d.Driver)
+		}
+	case DatabaseDriverBolt:
+		if d.Path == "" {
+			return fmt.Errorf("database driver %q requires path", d.Driver)
+		}
+	}
+	return nil
 }
 
 // StaleConfig configures the stale-tray cleanup loop.
@@ -236,6 +522,213 @@ func (s StaleConfig) WithDefaults() StaleConfig {
 	return out
 }
 
+// ReconcilerConfig configures the orphaned resource reconciler, which deletes
+// upstream resources (VMs, containers, Nomad jobs, pods) that look like trays
+// but have no tray row, e.g. after a crash between deleting the row and the
+// resource. It is off unless Enabled is set.
+//
+// Interval is how often provider inventories are listed. A resource is only
+// deleted once it has been seen without a row for at least GracePeriod. With
+// DryRun, orphans are logged and counted but not deleted.
+//
+// Defaults are applied in ReconcilerConfig.WithDefaults when fields are zero.
+type ReconcilerConfig struct {
+	Enabled     bool          `yaml:"enabled"`
+	Interval    time.Duration `yaml:"interval"`
+	GracePeriod time.Duration `yaml:"gracePeriod"`
+	DryRun      bool          `yaml:"dryRun"`
+}
+
+// DefaultReconcilerInterval is used when ReconcilerConfig.Interval is zero.
+const DefaultReconcilerInterval = 10 * time.Minute
+
+// DefaultReconcilerGracePeriod is used when ReconcilerConfig.GracePeriod is zero.
+const DefaultReconcilerGracePeriod = 30 * time.Minute
+
+// WithDefaults returns a copy with zero fields populated from defaults.
+func (r ReconcilerConfig) WithDefaults() ReconcilerConfig {
+	out := r
+	if out.Interval <= 0 {
+		out.Interval = DefaultReconcilerInterval
+	}
+	if out.GracePeriod <= 0 {
+		out.GracePeriod = DefaultReconcilerGracePeriod
+	}
+	return out
+}
+
+// BreakerConfig tunes the circuit breaker that stops creating trays of a tray
+// type while its provider keeps failing. After FailureThreshold consecutive
+// failed creations the circuit opens for InitialBackoff; then one probe tray
+// is created, and each failed probe doubles the backoff up to MaxBackoff. A
+// successful creation closes the circuit. Disabled turns the breaker off.
+//
+// Defaults are applied in BreakerConfig.WithDefaults when fields are zero.
+type BreakerConfig struct {
+	Disabled         bool          `yaml:"disabled"`
+	FailureThreshold int           `yaml:"failureThreshold" validate:"gte=0"`
+	InitialBackoff   time.Duration `yaml:"initialBackoff"`
+	MaxBackoff       time.Duration `yaml:"maxBackoff"`
+}
+
+// DefaultBreakerFailureThreshold is used when BreakerConfig.FailureThreshold is zero.
+const DefaultBreakerFailureThreshold = 5
+
+// DefaultBreakerInitialBackoff is used when BreakerConfig.InitialBackoff is zero.
+const DefaultBreakerInitialBackoff = 30 * time.Second
+
+// DefaultBreakerMaxBackoff is used when BreakerConfig.MaxBackoff is zero.
+const DefaultBreakerMaxBackoff = 15 * time.Minute
+
+// WithDefaults returns a copy with zero fields populated from defaults.
+func (b BreakerConfig) WithDefaults() BreakerConfig {
+	out := b
+	if out.FailureThreshold <= 0 {
+		out.FailureThreshold = DefaultBreakerFailureThreshold
+	}
+	if out.InitialBackoff <= 0 {
+		out.InitialBackoff = DefaultBreakerInitialBackoff
+	}
+	if out.MaxBackoff <= 0 {
+		out.MaxBackoff = DefaultBreakerMaxBackoff
+	}
+	out.MaxBackoff = max(out.MaxBackoff, out.InitialBackoff)
+	return out
+}
+
+// TrayEventsConfig configures the tray lifecycle event log. Events older
+// than Retention are deleted every PruneInterval.
+//
+// Defaults are applied in TrayEventsConfig.WithDefaults when fields are zero.
+type TrayEventsConfig struct {
+	Retention     time.Duration `yaml:"retention"`
+	PruneInterval time.Duration `yaml:"pruneInterval"`
+}
+
+// DefaultTrayEventsRetention is used when TrayEventsConfig.Retention is zero.
+const DefaultTrayEventsRetention = 7 * 24 * time.Hour
+
+// DefaultTrayEventsPruneInterval is used when TrayEventsConfig.PruneInterval is zero.
+const DefaultTrayEventsPruneInterval = time.Hour
+
+// WithDefaults returns a copy with zero fields populated from defaults.
+func (e TrayEventsConfig) WithDefaults() TrayEventsConfig {
+	out := e
+	if out.Retention <= 0 {
+		out.Retention = DefaultTrayEventsRetention
+	}
+	if out.PruneInterval <= 0 {
+		out.PruneInterval = DefaultTrayEventsPruneInterval
+	}
+	return out
+}
+
+// PollerMessagesConfig configures the stored history of scale set messages
+// shown on the status page. Messages older than Retention are deleted every
+// PruneInterval.
+//
+// Defaults are applied in PollerMessagesConfig.WithDefaults when fields are zero.
+type PollerMessagesConfig struct {
+	Retention     time.Duration `yaml:"retention"`
+	PruneInterval time.Duration `yaml:"pruneInterval"`
+}
+
+// DefaultPollerMessagesRetention is used when PollerMessagesConfig.Retention is zero.
+const DefaultPollerMessagesRetention = 24 * time.Hour
+
+// DefaultPollerMessagesPruneInterval is used when PollerMessagesConfig.PruneInterval is zero.
+const DefaultPollerMessagesPruneInterval = 10 * time.Minute
+
+// WithDefaults returns a copy with zero fields populated from defaults.
+func (m PollerMessagesConfig) WithDefaults() PollerMessagesConfig {
+	out := m
+	if out.Retention <= 0 {
+		out.Retention = DefaultPollerMessagesRetention
+	}
+	if out.PruneInterval <= 0 {
+		out.PruneInterval = DefaultPollerMessagesPruneInterval
+	}
+	return out
+}
+
+// TracingConfig configures OpenTelemetry tracing, exported over OTLP. It is
+// off unless Enabled is set.
+//
+// Protocol is "grpc" or "http" (OTLP over HTTP with protobuf). Endpoint is
+// the collector's host:port; empty uses the exporter's default (localhost on
+// the protocol's standard port) or the OTEL_EXPORTER_OTLP_ENDPOINT variable.
+// Insecure turns off TLS. SampleRatio is the fraction of new traces that are
+// recorded; traces continued from a caller follow the caller's decision.
+//
+// Defaults are applied in TracingConfig.WithDefaults when fields are zero.
+type TracingConfig struct {
+	Enabled     bool              `yaml:"enabled"`
+	Protocol    string            `yaml:"protocol" validate:"omitempty,oneof=grpc http"`
+	Endpoint    string            `yaml:"endpoint"`
+	Insecure    bool              `yaml:"insecure"`
+	Headers     map[string]string `yaml:"headers"`
+	SampleRatio float64           `yaml:"sampleRatio" validate:"gte=0,lte=1"`
+	ServiceName string            `yaml:"serviceName"`
+}
+
+const (
+	TracingProtocolGrpc = "grpc"
+	TracingProtocolHttp = "http"
+)
+
+// DefaultTracingServiceName is used when TracingConfig.ServiceName is empty.
+const DefaultTracingServiceName = "cattery"
+
+// WithDefaults returns a copy with zero fields populated from defaults: the
+// grpc protocol, every trace sampled and the "cattery" service name.
+func (t TracingConfig) WithDefaults() TracingConfig {
+	out := t
+	if out.Protocol == "" {
+		out.Protocol = TracingProtocolGrpc
+	}
+	if out.SampleRatio <= 0 {
+		out.SampleRatio = 1
+	}
+	if out.ServiceName == "" {
+		out.ServiceName = DefaultTracingServiceName
+	}
+	return out
+}
+
+// LoggingConfig configures the server's log output. Format is "text" or
+// "json" (one object per line). Level is the minimum level logged; Components
+// overrides it for single components, keyed by the "component" field of their
+// log lines, e.g. {"scaleSetClient": "warn", "trayManager": "trace"}. The
+// keys match component names case-insensitively, as viper lowercases them.
+//
+// Defaults are applied in LoggingConfig.WithDefaults when fields are zero.
+type LoggingConfig struct {
+	Format     string            `yaml:"format" validate:"omitempty,oneof=text json"`
+	Level      string            `yaml:"level" validate:"omitempty,oneof=panic fatal error warn warning info debug trace"`
+	Components map[string]string `yaml:"components" validate:"dive,oneof=panic fatal error warn warning info debug trace"`
+}
+
+const (
+	LogFormatText = "text"
+	LogFormatJson = "json"
+)
+
+// DefaultLogLevel is used when LoggingConfig.Level is empty.
+const DefaultLogLevel = "debug"
+
+// WithDefaults returns a copy with zero fields populated from defaults: text
+// format at debug level.
+func (l LoggingConfig) WithDefaults() LoggingConfig {
+	out := l
+	if out.Format == "" {
+		out.Format = LogFormatText
+	}
+	if out.Level == "" {
+		out.Level = DefaultLogLevel
+	}
+	return out
+}
+
 // CoordinationConfig selects the leader-election backend and tunes the lease
 // cadence. Leader election decides which replica runs each tray type's scale
 // set poller; every replica serves the tray HTTP plane regardless.
@@ -245,10 +738,13 @@ func (s StaleConfig) WithDefaults() StaleConfig {
 // on "memory" and every replica will try to hold the GitHub session for every
 // tray type, which conflicts. To run multiple replicas, pick a shared backend
 // (e.g. "mongo"), which leases each tray type to one replica at a time.
+// The "mongo" and "postgres" backends keep their leases in the configured
+// database, so they must match database.driver.
 type CoordinationConfig struct {
-	// Backend is the election backend: "memory" (single replica), "mongo", or
-	// "k8s" (native coordination.k8s.io Leases; requires running in-cluster).
-	Backend    string                `yaml:"backend" validate:"omitempty,oneof=memory mongo k8s"`
+	// Backend is the election backend: "memory" (single replica), "mongo",
+	// "postgres", or "k8s" (native coordination.k8s.io Leases; requires
+	// running in-cluster).
+	Backend    string                `yaml:"backend" validate:"omitempty,oneof=memory mongo postgres k8s"`
 	Lease      LeaseConfig           `yaml:"lease"`
 	Kubernetes KubernetesCoordConfig `yaml:"kubernetes"`
 }
@@ -275,9 +771,10 @@ type LeaseConfig struct {
 }
 
 const (
-	CoordinationBackendMemory = "memory"
-	CoordinationBackendMongo  = "mongo"
-	CoordinationBackendK8s    = "k8s"
+	CoordinationBackendMemory   = "memory"
+	CoordinationBackendMongo    = "mongo"
+	CoordinationBackendPostgres = "postgres"
+	CoordinationBackendK8s      = "k8s"
 
 	DefaultLeaseTTL           = 30 * time.Second
 	DefaultLeaseRetryInterval = 5 * time.Second
@@ -302,33 +799,238 @@ func (c CoordinationConfig) WithDefaults() CoordinationConfig {
 	return out
 }
 
+// checkCoordinationDatabase rejects a lease backend that lives in a database
+// other than the configured one.
+func checkCoordinationDatabase(coordination CoordinationConfig, database DatabaseConfig) error {
+	switch coordination.Backend {
+	case CoordinationBackendMongo:
+		if database.Driver != DatabaseDriverMongo {
+			return fmt.Errorf("coordination backend %q requires database driver %q, got %q", coordination.Backend, DatabaseDriverMongo, database.Driver)
+		}
+	case CoordinationBackendPostgres:
+		if database.Driver != DatabaseDriverPostgres {
+			return fmt.Errorf("coordination backend %q requires database driver %q, got %q", coordination.Backend, DatabaseDriverPostgres, database.Driver)
+		}
+	}
+	return nil
+}
+
+// GitHubOrganization is a GitHub App installation in an organization. The
+// App's private key is either given inline (PEM) in PrivateKey, typically as
+// an env or file reference, or read from the file at PrivateKeyPath.
+//
+// BaseUrl is the GitHub web URL, https://github.com if empty; set it for
+// GitHub Enterprise Server or GHE.com. ApiUrl overrides the REST API URL
+// derived from it (see APIURL).
 type GitHubOrganization struct {
 	Name           string `yaml:"name" validate:"required"`
 	AppId          int64  `yaml:"appId" validate:"required"`
 	AppClientId    string `yaml:"appClientId" validate:"required"`
 	InstallationId int64  `yaml:"installationId" validate:"required"`
+	PrivateKey     string `yaml:"privateKey"`
 	PrivateKeyPath string `yaml:"privateKeyPath"`
+	BaseUrl        string `yaml:"baseUrl" validate:"omitempty,url"`
+	ApiUrl         string `yaml:"apiUrl" validate:"omitempty,url"`
+}
+
+const (
+	DefaultGitHubURL    = "https://github.com"
+	DefaultGitHubAPIURL = "https://api.github.com"
+)
+
+// WebURL returns the GitHub web URL of the organization's instance, without a
+// trailing slash.
+func (o *GitHubOrganization) WebURL() string {
+	if o.BaseUrl == "" {
+		return DefaultGitHubURL
+	}
+	return strings.TrimRight(o.BaseUrl, "/")
+}
+
+// APIURL returns the GitHub REST API URL, without a trailing slash: ApiUrl if
+// set, otherwise api.<host> for github.com and GHE.com, and <BaseUrl>/api/v3
+// for GitHub Enterprise Server.
+func (o *GitHubOrganization) APIURL() string {
+	if o.ApiUrl != "" {
+		return strings.TrimRight(o.ApiUrl, "/")
+	}
+	webURL, err := url.Parse(o.WebURL())
+	if err != nil {
+		return DefaultGitHubAPIURL
+	}
+	host := strings.ToLower(webURL.Host)
+	switch {
+	case host == "github.com" || host == "www.github.com":
+		return DefaultGitHubAPIURL
+	case strings.HasSuffix(host, ".ghe.com"):
+		return webURL.Scheme + "://api." + webURL.Host
+	default:
+		return o.WebURL() + "/api/v3"
+	}
+}
+
+// PrivateKeyPEM returns the GitHub App private key: PrivateKey if set,
+// otherwise the contents of PrivateKeyPath.
+func (o *GitHubOrganization) PrivateKeyPEM() ([]byte, error) {
+	if o.PrivateKey != "" {
+		return []byte(o.PrivateKey), nil
+	}
+	if o.PrivateKeyPath == "" {
+		return nil, errors.New("neither privateKey nor privateKeyPath is set")
+	}
+	privateKey, err := os.ReadFile(o.PrivateKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key: %w", err)
+	}
+	return privateKey, nil
 }
 
 const DefaultMaxParallelCreation = 10
 
+// TrayType describes one tray profile.
+//
+// MinIdle keeps that many spare trays (creating, registering or registered,
+// i.e. able to take a job) on top of current demand, so jobs do not wait for
+// a VM to boot. MaxIdle, if set, trims registered trays beyond demand plus
+// MaxIdle. Both are bounded by MaxTrays.
+//
+// Schedule, if set, overrides MaxTrays, MaxParallelCreation and MinIdle during
+// recurring time windows; use Scheduled to get the values in effect.
+//
+// Paused stops tray creation while keeping the scale set, the same as a pause
+// set through the admin API; DeleteIdleWhenPaused also deletes registered
+// trays still waiting for a job.
+//
+// The scale set is registered on the GitHubOrg organization unless Repository
+// ("owner/repo") or Enterprise (an enterprise slug) narrows or widens it; the
+// GitHubOrg App must be installed there. Its runner group is given by id
+// (RunnerGroupId) or by name (RunnerGroup); repository scale sets have no
+// runner groups, so neither is required for them. Labels are added to the
+// scale set next to the tray type name.
 type TrayType struct {
-	Name                string     `yaml:"name" validate:"required"`
-	Description         string     `yaml:"description"`
-	Provider            string     `yaml:"provider" validate:"required"`
-	RunnerGroupId       int64      `yaml:"runnerGroupId" validate:"required"`
-	Shutdown            bool       `yaml:"shutdown"`
-	GitHubOrg           string     `yaml:"githubOrg" validate:"required"`
-	MaxTrays            int        `yaml:"maxTrays"`
-	MaxParallelCreation int        `yaml:"maxParallelCreation"`
-	Config              TrayConfig `yaml:"config"`
-	ExtraMetadata       TrayExtraMetadata
+	Name                 string            `yaml:"name" validate:"required"`
+	Description          string            `yaml:"description"`
+	Provider             string            `yaml:"provider" validate:"required"`
+	RunnerGroupId        int64             `yaml:"runnerGroupId" validate:"required_without_all=Repository RunnerGroup,excluded_with=RunnerGroup"`
+	RunnerGroup          string            `yaml:"runnerGroup"`
+	Labels               []string          `yaml:"labels" validate:"dive,required"`
+	Shutdown             bool              `yaml:"shutdown"`
+	GitHubOrg            string            `yaml:"githubOrg" validate:"required"`
+	Repository           string            `yaml:"repository" validate:"excluded_with=Enterprise"`
+	Enterprise           string            `yaml:"enterprise"`
+	MaxTrays             int               `yaml:"maxTrays"`
+	MaxParallelCreation  int               `yaml:"maxParallelCreation"`
+	MinIdle              int               `yaml:"minIdle" validate:"gte=0"`
+	MaxIdle              int               `yaml:"maxIdle" validate:"gte=0"`
+	Weight               int               `yaml:"weight" validate:"gte=0"`
+	Paused               bool              `yaml:"paused"`
+	DeleteIdleWhenPaused bool              `yaml:"deleteIdleWhenPaused"`
+	Schedule             *TrayTypeSchedule `yaml:"schedule"`
+	Config               TrayConfig        `yaml:"config"`
+	ExtraMetadata        TrayExtraMetadata
+}
+
+// HasWarmPool reports whether MinIdle or MaxIdle is configured.
+func (t *TrayType) HasWarmPool() bool {
+	return t.MinIdle > 0 || t.MaxIdle > 0
+}
+
+// QuotaWeight returns how much a tray of this type counts against a quota's
+// MaxWeight: Weight, or 1 if unset.
+func (t *TrayType) QuotaWeight() int {
+	return max(t.Weight, 1)
+}
+
+// DefaultRunnerGroupId is the id of the "Default" runner group, used for
+// scale sets of tray types without a RunnerGroupId.
+const DefaultRunnerGroupId = 1
+
+// ScaleSetURL returns the GitHub URL the tray type's scale set is registered
+// at: the repository, the enterprise or, by default, the organization.
+func (t *TrayType) ScaleSetURL(org *GitHubOrganization) string {
+	switch {
+	case t.Repository != "":
+		return fmt.Sprintf("%s/%s", org.WebURL(), t.Repository)
+	case t.Enterprise != "":
+		return fmt.Sprintf("%s/enterprises/%s", org.WebURL(), t.Enterprise)
+	default:
+		return fmt.Sprintf("%s/%s", org.WebURL(), org.Name)
+	}
+}
+
+// ScaleSetLabels returns the labels of the tray type's scale set: the tray
+// type name followed by Labels, without duplicates.
+func (t *TrayType) ScaleSetLabels() []string {
+	labels := []string{t.Name}
+	for _, label := range t.Labels {
+		if !slices.Contains(labels, label) {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+// ScaleSetRunnerGroupId returns the id of the runner group the scale set is
+// created in when it is not given by name.
+func (t *TrayType) ScaleSetRunnerGroupId() int64 {
+	if t.RunnerGroupId == 0 {
+		return DefaultRunnerGroupId
+	}
+	return t.RunnerGroupId
 }
 
 type TrayExtraMetadata map[string]string
 
+// Quota caps the trays of all tray types matching GitHubOrg and Provider, an
+// empty field matching any; a quota with neither is global. MaxTrays bounds
+// the number of trays that are not being deleted and MaxWeight the sum of
+// their weights (see TrayType.Weight). Zero means no limit.
+//
+// Quotas are checked in the database when a tray row is reserved, so replicas
+// creating trays at the same time cannot overshoot them together.
+//
+// Trays of a tray type on a fallback provider count under the fallback
+// provider, whichever member they run on, so Provider cannot name a member.
+type Quota struct {
+	Name      string `yaml:"name" validate:"required"`
+	GitHubOrg string `yaml:"githubOrg"`
+	Provider  string `yaml:"provider"`
+	MaxTrays  int    `yaml:"maxTrays" validate:"gte=0"`
+	MaxWeight int    `yaml:"maxWeight" validate:"gte=0"`
+}
+
+// Matches reports whether the quota counts trays of the given org and
+// provider.
+func (q *Quota) Matches(orgName string, providerName string) bool {
+	return (q.GitHubOrg == "" || q.GitHubOrg == orgName) &&
+		(q.Provider == "" || q.Provider == providerName)
+}
+
+// Exceeded reports whether a total of trays trays weighing weight would break
+// the quota.
+func (q *Quota) Exceeded(trays int, weight int) bool {
+	return (q.MaxTrays > 0 && trays > q.MaxTrays) ||
+		(q.MaxWeight > 0 && weight > q.MaxWeight)
+}
+
 type ProviderConfig map[string]string
 
+// ProviderTypeFallback is the provider type that chains other providers,
+// rerouting a tray to the next member when one is out of capacity.
+const ProviderTypeFallback = "fallback"
+
 func (p ProviderConfig) Get(key string) string {
 	return p[strings.ToLower(key)]
 }
+
+// Members returns the ordered member provider names of a fallback provider,
+// parsed from its comma-separated "providers" key.
+func (p ProviderConfig) Members() []string {
+	var members []string
+	for _, name := range strings.Split(p.Get("providers"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			members = append(members, name)
+		}
+	}
+	return members
+}
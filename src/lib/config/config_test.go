@@ -1,11 +1,14 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -360,6 +363,54 @@ trayTypes:
 	assert.Equal(t, "projects/my-project/global/instanceTemplates/runner", gc.InstanceTemplate)
 }
 
+func TestLoadConfig_KubernetesTrayType(t *testing.T) {
+	tempFile, err := os.CreateTemp("", "config_kubernetes*.yaml")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	kubernetesConfig := `
+server:
+  listenAddress: ":8080"
+  advertiseUrl: "http://localhost:8080"
+database:
+  uri: "mongodb://localhost:27017"
+  database: "cattery"
+github:
+  - name: "test-org"
+    appId: 12345
+    appClientId: "Iv1.test123"
+    installationId: 67890
+    privateKeyPath: "path/to/key.pem"
+providers:
+  - name: "k8s"
+    type: "kubernetes"
+trayTypes:
+  - name: "k8s-runner"
+    provider: "k8s"
+    runnerGroupId: 1
+    githubOrg: "test-org"
+    config:
+      namespace: "runners"
+      unschedulableTimeout: "5m"
+`
+	_, err = tempFile.Write([]byte(kubernetesConfig))
+	assert.NoError(t, err)
+	tempFile.Close()
+
+	configPath := tempFile.Name()
+	config, err := LoadConfig(&configPath)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, config)
+
+	kc, ok := config.GetTrayType("k8s-runner").Config.(KubernetesTrayConfig)
+	assert.True(t, ok)
+	assert.Equal(t, "runners", kc.Namespace)
+	assert.Equal(t, 5*time.Minute, kc.UnschedulableTimeout)
+}
+
 func TestLoadConfig_ProviderNotFound(t *testing.T) {
 	tempFile, err := os.CreateTemp("", "config_bad_provider*.yaml")
 	if err != nil {
@@ -401,6 +452,290 @@ trayTypes:
 	assert.Contains(t, err.Error(), "provider nonexistent-provider for trayType broken not found")
 }
 
+func TestLoadConfig_FallbackTrayType(t *testing.T) {
+	tempFile, err := os.CreateTemp("", "config_fallback*.yaml")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	fallbackConfig := `
+server:
+  listenAddress: ":8080"
+  advertiseUrl: "http://localhost:8080"
+database:
+  uri: "mongodb://localhost:27017"
+  database: "cattery"
+github:
+  - name: "test-org"
+    appId: 12345
+    appClientId: "Iv1.test123"
+    installationId: 67890
+    privateKeyPath: "path/to/key.pem"
+providers:
+  - name: "nomad-scw"
+    type: "nomad"
+    address: "http://nomad:4646"
+  - name: "gce-prod"
+    type: "google"
+    project: "my-project"
+  - name: "burst"
+    type: "fallback"
+    providers: "nomad-scw, gce-prod"
+trayTypes:
+  - name: "burst-runner"
+    provider: "burst"
+    runnerGroupId: 1
+    githubOrg: "test-org"
+    config:
+      nomad-scw:
+        jobId: "cattery-runner"
+      gce-prod:
+        zones:
+          - "us-central1-a"
+        machineType: "n1-standard-4"
+`
+	_, err = tempFile.Write([]byte(fallbackConfig))
+	assert.NoError(t, err)
+	tempFile.Close()
+
+	configPath := tempFile.Name()
+	config, err := LoadConfig(&configPath)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, config)
+
+	assert.Equal(t, []string{"nomad-scw", "gce-prod"}, config.GetProvider("burst").Members())
+
+	tt := config.GetTrayType("burst-runner")
+	assert.NotNil(t, tt)
+
+	fc, ok := tt.Config.(FallbackTrayConfig)
+	assert.True(t, ok)
+	assert.Len(t, fc, 2)
+
+	nc, ok := fc["nomad-scw"].(NomadTrayConfig)
+	assert.True(t, ok)
+	assert.Equal(t, "cattery-runner", nc.JobId)
+
+	gc, ok := fc["gce-prod"].(GoogleTrayConfig)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"us-central1-a"}, gc.Zones)
+	assert.Equal(t, "n1-standard-4", gc.MachineType)
+}
+
+func TestLoadConfig_FallbackUnknownMember(t *testing.T) {
+	tempFile, err := os.CreateTemp("", "config_fallback_bad*.yaml")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	badConfig := `
+server:
+  listenAddress: ":8080"
+  advertiseUrl: "http://localhost:8080"
+database:
+  uri: "mongodb://localhost:27017"
+  database: "cattery"
+github:
+  - name: "test-org"
+    appId: 12345
+    appClientId: "Iv1.test123"
+    installationId: 67890
+    privateKeyPath: "path/to/key.pem"
+providers:
+  - name: "burst"
+    type: "fallback"
+    providers: "missing"
+trayTypes:
+  - name: "burst-runner"
+    provider: "burst"
+    runnerGroupId: 1
+    githubOrg: "test-org"
+`
+	_, err = tempFile.Write([]byte(badConfig))
+	assert.NoError(t, err)
+	tempFile.Close()
+
+	configPath := tempFile.Name()
+	config, err := LoadConfig(&configPath)
+
+	assert.Error(t, err)
+	assert.Nil(t, config)
+	assert.Contains(t, err.Error(), "fallback member provider missing not found")
+}
+
+func TestLoadConfig_MaxIdleBelowMinIdle(t *testing.T) {
+	tempFile, err := os.CreateTemp("", "config_idle*.yaml")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	badConfig := `
+server:
+  listenAddress: ":8080"
+  advertiseUrl: "http://localhost:8080"
+database:
+  uri: "mongodb://localhost:27017"
+  database: "cattery"
+github:
+  - name: "test-org"
+    appId: 12345
+    appClientId: "Iv1.test123"
+    installationId: 67890
+    privateKeyPath: "path/to/key.pem"
+providers:
+  - name: "docker-provider"
+    type: "docker"
+trayTypes:
+  - name: "warm"
+    provider: "docker-provider"
+    runnerGroupId: 1
+    githubOrg: "test-org"
+    maxTrays: 10
+    minIdle: 3
+    maxIdle: 2
+`
+	_, err = tempFile.Write([]byte(badConfig))
+	assert.NoError(t, err)
+	tempFile.Close()
+
+	configPath := tempFile.Name()
+	config, err := LoadConfig(&configPath)
+
+	assert.Error(t, err)
+	assert.Nil(t, config)
+	assert.Contains(t, err.Error(), "maxIdle (2) must not be less than minIdle (3)")
+}
+
+func TestReload(t *testing.T) {
+	old := Get()
+	t.Cleanup(func() { Set(old) })
+
+	configYAML := func(maxTrays string, githubOrg string) string {
+		return `
+server:
+  listenAddress: ":8080"
+  advertiseUrl: "http://localhost:8080"
+database:
+  uri: "mongodb://localhost:27017"
+  database: "cattery"
+github:
+  - name: "test-org"
+    appId: 12345
+    appClientId: "Iv1.test123"
+    installationId: 67890
+    privateKeyPath: "path/to/key.pem"
+providers:
+  - name: "docker-provider"
+    type: "docker"
+trayTypes:
+  - name: "linux"
+    provider: "docker-provider"
+    runnerGroupId: 1
+    githubOrg: "` + githubOrg + `"
+    maxTrays: ` + maxTrays + `
+`
+	}
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(configYAML("5", "test-org")), 0o644))
+	_, err := LoadConfig(&configPath)
+	require.NoError(t, err)
+	assert.Equal(t, configPath, FilePath())
+
+	require.NoError(t, os.WriteFile(configPath, []byte(configYAML("8", "test-org")), 0o644))
+	reloaded, err := Reload()
+	require.NoError(t, err)
+	assert.Equal(t, 8, reloaded.GetTrayType("linux").MaxTrays)
+	assert.Same(t, reloaded, Get())
+
+	require.NoError(t, os.WriteFile(configPath, []byte(configYAML("9", "other-org")), 0o644))
+	_, err = Reload()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "github org other-org for trayType linux not found")
+	assert.Equal(t, 8, Get().GetTrayType("linux").MaxTrays, "an invalid reload keeps the current config")
+}
+
+func TestLoadConfig_Schedule(t *testing.T) {
+	base := `
+server:
+  listenAddress: ":8080"
+  advertiseUrl: "http://localhost:8080"
+database:
+  uri: "mongodb://localhost:27017"
+  database: "cattery"
+github:
+  - name: "test-org"
+    appId: 12345
+    appClientId: "Iv1.test123"
+    installationId: 67890
+    privateKeyPath: "path/to/key.pem"
+providers:
+  - name: "docker-provider"
+    type: "docker"
+trayTypes:
+  - name: "scheduled"
+    provider: "docker-provider"
+    runnerGroupId: 1
+    githubOrg: "test-org"
+    maxTrays: 2
+    schedule:
+      timezone: "Europe/Berlin"
+      windows:
+        - name: "office-hours"
+          days: ["mon", "tue", "wed", "thu", "fri"]
+          start: "08:00"
+          end: "%s"
+          maxTrays: 20
+          minIdle: 0
+`
+
+	load := func(t *testing.T, end string) (*CatteryConfig, error) {
+		t.Helper()
+		tempFile, err := os.CreateTemp("", "config_schedule*.yaml")
+		if err != nil {
+			t.Fatalf("Failed to create temp file: %v", err)
+		}
+		defer os.Remove(tempFile.Name())
+
+		_, err = tempFile.Write([]byte(fmt.Sprintf(base, end)))
+		assert.NoError(t, err)
+		tempFile.Close()
+
+		configPath := tempFile.Name()
+		return LoadConfig(&configPath)
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		config, err := load(t, "18:00")
+		require.NoError(t, err)
+
+		trayType := config.GetTrayType("scheduled")
+		require.NotNil(t, trayType.Schedule)
+		require.Len(t, trayType.Schedule.Windows, 1)
+		window := trayType.Schedule.Windows[0]
+		require.NotNil(t, window.MaxTrays)
+		assert.Equal(t, 20, *window.MaxTrays)
+		require.NotNil(t, window.MinIdle, "explicit zero is kept")
+		assert.Equal(t, 0, *window.MinIdle)
+		assert.Nil(t, window.MaxParallelCreation)
+
+		// 2026-10-12 is a Monday; 10:00 in Berlin is 08:00 UTC.
+		scheduled, profile := trayType.Scheduled(time.Date(2026, 10, 12, 8, 0, 0, 0, time.UTC))
+		assert.Equal(t, "office-hours", profile)
+		assert.Equal(t, 20, scheduled.MaxTrays)
+	})
+
+	t.Run("invalid window", func(t *testing.T) {
+		config, err := load(t, "6pm")
+		assert.Error(t, err)
+		assert.Nil(t, config)
+	})
+}
+
 func TestLoadConfig_AgentSecret(t *testing.T) {
 	tempFile, err := os.CreateTemp("", "config_secret*.yaml")
 	if err != nil {
@@ -540,6 +875,175 @@ trayTypes:
 	})
 }
 
+func TestLoadConfig_DatabaseDriver(t *testing.T) {
+	base := `
+server:
+  listenAddress: ":8080"
+  advertiseUrl: "http://localhost:8080"
+database:
+%s
+coordination:
+  backend: "%s"
+github:
+  - name: "test-org"
+    appId: 12345
+    appClientId: "Iv1.test123"
+    installationId: 67890
+    privateKeyPath: "path/to/key.pem"
+providers:
+  - name: "docker-provider"
+    type: "docker"
+trayTypes:
+  - name: "docker-tray"
+    provider: "docker-provider"
+    runnerGroupId: 1
+    githubOrg: "test-org"
+`
+	const mongoDatabase = `  uri: "mongodb://localhost:27017"
+  database: "cattery"`
+	const postgresDatabase = `  driver: "postgres"
+  uri: "postgres://cattery@localhost:5432/cattery"`
+
+	load := func(t *testing.T, database string, backend string) (*CatteryConfig, error) {
+		t.Helper()
+		tempFile, err := os.CreateTemp("", "config_database*.yaml")
+		if err != nil {
+			t.Fatalf("Failed to create temp file: %v", err)
+		}
+		defer os.Remove(tempFile.Name())
+
+		_, err = tempFile.Write([]byte(fmt.Sprintf(base, database, backend)))
+		assert.NoError(t, err)
+		tempFile.Close()
+
+		configPath := tempFile.Name()
+		return LoadConfig(&configPath)
+	}
+
+	t.Run("mongodb is the default", func(t *testing.T) {
+		config, err := load(t, mongoDatabase, "mongo")
+		require.NoError(t, err)
+		assert.Equal(t, "", config.Database.Driver)
+		assert.Equal(t, DatabaseDriverMongo, config.Database.WithDefaults().Driver)
+	})
+
+	t.Run("postgres without database name", func(t *testing.T) {
+		config, err := load(t, postgresDatabase, "postgres")
+		require.NoError(t, err)
+		assert.Equal(t, DatabaseDriverPostgres, config.Database.Driver)
+	})
+
+	t.Run("mongodb requires database name", func(t *testing.T) {
+		_, err := load(t, `  uri: "mongodb://localhost:27017"`, "memory")
+		assert.Error(t, err)
+	})
+
+	t.Run("unknown driver", func(t *testing.T) {
+		_, err := load(t, `  driver: "mysql"
+  uri: "mysql://localhost"
+  database: "cattery"`, "memory")
+		assert.Error(t, err)
+	})
+
+	t.Run("lease backend must match driver", func(t *testing.T) {
+		_, err := load(t, postgresDatabase, "mongo")
+		assert.Error(t, err)
+
+		_, err = load(t, mongoDatabase, "postgres")
+		assert.Error(t, err)
+	})
+
+	t.Run("k8s backend with postgres", func(t *testing.T) {
+		_, err := load(t, postgresDatabase, "k8s")
+		assert.NoError(t, err)
+	})
+
+	t.Run("bolt", func(t *testing.T) {
+		config, err := load(t, `  driver: "bolt"
+  path: "/var/lib/cattery/cattery.db"`, "memory")
+		require.NoError(t, err)
+		assert.Equal(t, "/var/lib/cattery/cattery.db", config.Database.Path)
+	})
+
+	t.Run("bolt requires path", func(t *testing.T) {
+		_, err := load(t, `  driver: "bolt"`, "memory")
+		assert.Error(t, err)
+	})
+
+	t.Run("bolt has no lease store", func(t *testing.T) {
+		_, err := load(t, `  driver: "bolt"
+  path: "/var/lib/cattery/cattery.db"`, "mongo")
+		assert.Error(t, err)
+	})
+}
+
+func TestReconcilerConfigWithDefaults(t *testing.T) {
+	got := ReconcilerConfig{Enabled: true}.WithDefaults()
+	assert.True(t, got.Enabled)
+	assert.Equal(t, DefaultReconcilerInterval, got.Interval)
+	assert.Equal(t, DefaultReconcilerGracePeriod, got.GracePeriod)
+
+	got = ReconcilerConfig{Interval: time.Minute, GracePeriod: time.Hour, DryRun: true}.WithDefaults()
+	assert.Equal(t, time.Minute, got.Interval)
+	assert.Equal(t, time.Hour, got.GracePeriod)
+	assert.True(t, got.DryRun)
+}
+
+func TestBreakerConfigWithDefaults(t *testing.T) {
+	got := BreakerConfig{}.WithDefaults()
+	assert.Equal(t, DefaultBreakerFailureThreshold, got.FailureThreshold)
+	assert.Equal(t, DefaultBreakerInitialBackoff, got.InitialBackoff)
+	assert.Equal(t, DefaultBreakerMaxBackoff, got.MaxBackoff)
+
+	got = BreakerConfig{FailureThreshold: 2, InitialBackoff: time.Hour}.WithDefaults()
+	assert.Equal(t, 2, got.FailureThreshold)
+	assert.Equal(t, time.Hour, got.InitialBackoff)
+	assert.Equal(t, time.Hour, got.MaxBackoff, "never below the initial backoff")
+}
+
+func TestTrayEventsConfigWithDefaults(t *testing.T) {
+	got := TrayEventsConfig{}.WithDefaults()
+	assert.Equal(t, DefaultTrayEventsRetention, got.Retention)
+	assert.Equal(t, DefaultTrayEventsPruneInterval, got.PruneInterval)
+
+	got = TrayEventsConfig{Retention: 48 * time.Hour, PruneInterval: 10 * time.Minute}.WithDefaults()
+	assert.Equal(t, 48*time.Hour, got.Retention)
+	assert.Equal(t, 10*time.Minute, got.PruneInterval)
+}
+
+func TestPollerMessagesConfigWithDefaults(t *testing.T) {
+	got := PollerMessagesConfig{}.WithDefaults()
+	assert.Equal(t, DefaultPollerMessagesRetention, got.Retention)
+	assert.Equal(t, DefaultPollerMessagesPruneInterval, got.PruneInterval)
+
+	got = PollerMessagesConfig{Retention: 72 * time.Hour, PruneInterval: time.Hour}.WithDefaults()
+	assert.Equal(t, 72*time.Hour, got.Retention)
+	assert.Equal(t, time.Hour, got.PruneInterval)
+}
+
+func TestTracingConfigWithDefaults(t *testing.T) {
+	got := TracingConfig{Enabled: true}.WithDefaults()
+	assert.Equal(t, TracingProtocolGrpc, got.Protocol)
+	assert.Equal(t, 1.0, got.SampleRatio)
+	assert.Equal(t, DefaultTracingServiceName, got.ServiceName)
+
+	got = TracingConfig{Protocol: TracingProtocolHttp, SampleRatio: 0.1, ServiceName: "cattery-eu"}.WithDefaults()
+	assert.Equal(t, TracingProtocolHttp, got.Protocol)
+	assert.Equal(t, 0.1, got.SampleRatio)
+	assert.Equal(t, "cattery-eu", got.ServiceName)
+}
+
+func TestLoggingConfigWithDefaults(t *testing.T) {
+	got := LoggingConfig{}.WithDefaults()
+	assert.Equal(t, LogFormatText, got.Format)
+	assert.Equal(t, DefaultLogLevel, got.Level)
+
+	got = LoggingConfig{Format: LogFormatJson, Level: "warn", Components: map[string]string{"trayManager": "trace"}}.WithDefaults()
+	assert.Equal(t, LogFormatJson, got.Format)
+	assert.Equal(t, "warn", got.Level)
+	assert.Equal(t, map[string]string{"trayManager": "trace"}, got.Components)
+}
+
 func TestProviderConfigGet(t *testing.T) {
 	// Setup test provider config
 	providerConfig := ProviderConfig{
@@ -565,3 +1069,303 @@ func TestProviderConfigGet(t *testing.T) {
 		assert.Equal(t, "", value)
 	})
 }
+
+func TestGitHubOrganizationURLs(t *testing.T) {
+	tests := []struct {
+		name    string
+		org     GitHubOrganization
+		wantWeb string
+		wantAPI string
+	}{
+		{name: "github.com by default", org: GitHubOrganization{}, wantWeb: "https://github.com", wantAPI: "https://api.github.com"},
+		{name: "explicit github.com", org: GitHubOrganization{BaseUrl: "https://github.com/"}, wantWeb: "https://github.com", wantAPI: "https://api.github.com"},
+		{name: "GitHub Enterprise Server", org: GitHubOrganization{BaseUrl: "https://ghes.example.com"}, wantWeb: "https://ghes.example.com", wantAPI: "https://ghes.example.com/api/v3"},
+		{name: "GHE.com", org: GitHubOrganization{BaseUrl: "https://acme.ghe.com"}, wantWeb: "https://acme.ghe.com", wantAPI: "https://api.acme.ghe.com"},
+		{name: "explicit API URL", org: GitHubOrganization{BaseUrl: "https://ghes.example.com", ApiUrl: "https://ghes-api.example.com/"}, wantWeb: "https://ghes.example.com", wantAPI: "https://ghes-api.example.com"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.wantWeb, tc.org.WebURL())
+			assert.Equal(t, tc.wantAPI, tc.org.APIURL())
+		})
+	}
+}
+
+func TestTrayTypeScaleSetURL(t *testing.T) {
+	org := &GitHubOrganization{Name: "test-org"}
+	ghes := &GitHubOrganization{Name: "test-org", BaseUrl: "https://ghes.example.com"}
+
+	assert.Equal(t, "https://github.com/test-org", (&TrayType{}).ScaleSetURL(org))
+	assert.Equal(t, "https://github.com/test-org/monorepo", (&TrayType{Repository: "test-org/monorepo"}).ScaleSetURL(org))
+	assert.Equal(t, "https://github.com/enterprises/acme", (&TrayType{Enterprise: "acme"}).ScaleSetURL(org))
+	assert.Equal(t, "https://ghes.example.com/enterprises/acme", (&TrayType{Enterprise: "acme"}).ScaleSetURL(ghes))
+
+	assert.Equal(t, int64(DefaultRunnerGroupId), (&TrayType{Repository: "test-org/monorepo"}).ScaleSetRunnerGroupId())
+	assert.Equal(t, int64(7), (&TrayType{RunnerGroupId: 7}).ScaleSetRunnerGroupId())
+}
+
+func TestLoadConfig_TrayTypeScope(t *testing.T) {
+	write := func(t *testing.T, trayType string) string {
+		configPath := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(configPath, []byte(`
+server:
+  listenAddress: ":8080"
+  advertiseUrl: "http://localhost:8080"
+database:
+  uri: "mongodb://localhost:27017"
+  database: "cattery"
+github:
+  - name: "test-org"
+    appId: 12345
+    appClientId: "Iv1.test123"
+    installationId: 67890
+providers:
+  - name: "docker-provider"
+    type: "docker"
+trayTypes:
+  - name: "scoped"
+    provider: "docker-provider"
+    githubOrg: "test-org"
+`+trayType), 0600))
+		return configPath
+	}
+
+	t.Run("repository without runner group", func(t *testing.T) {
+		configPath := write(t, "    repository: test-org/monorepo\n")
+		cfg, err := LoadConfig(&configPath)
+		require.NoError(t, err)
+		t.Cleanup(func() { Set(&CatteryConfig{}) })
+		assert.Equal(t, "test-org/monorepo", cfg.GetTrayType("scoped").Repository)
+	})
+
+	t.Run("enterprise needs a runner group", func(t *testing.T) {
+		configPath := write(t, "    enterprise: acme\n")
+		_, err := LoadConfig(&configPath)
+		assert.ErrorContains(t, err, "RunnerGroupId")
+	})
+
+	t.Run("repository and enterprise are exclusive", func(t *testing.T) {
+		configPath := write(t, "    runnerGroupId: 1\n    repository: test-org/monorepo\n    enterprise: acme\n")
+		_, err := LoadConfig(&configPath)
+		assert.ErrorContains(t, err, "excluded_with")
+	})
+
+	t.Run("malformed repository", func(t *testing.T) {
+		configPath := write(t, "    repository: monorepo\n")
+		_, err := LoadConfig(&configPath)
+		assert.ErrorContains(t, err, `repository "monorepo" must be in owner/repo form`)
+	})
+}
+
+func TestTrayTypeScaleSetLabels(t *testing.T) {
+	assert.Equal(t, []string{"linux"}, (&TrayType{Name: "linux"}).ScaleSetLabels())
+	assert.Equal(t, []string{"linux-16cpu", "linux", "x64"},
+		(&TrayType{Name: "linux-16cpu", Labels: []string{"linux", "linux-16cpu", "x64", "linux"}}).ScaleSetLabels())
+}
+
+func TestLoadConfig_RunnerGroupByName(t *testing.T) {
+	write := func(t *testing.T, trayType string) string {
+		configPath := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(configPath, []byte(`
+server:
+  listenAddress: ":8080"
+  advertiseUrl: "http://localhost:8080"
+database:
+  uri: "mongodb://localhost:27017"
+  database: "cattery"
+github:
+  - name: "test-org"
+    appId: 12345
+    appClientId: "Iv1.test123"
+    installationId: 67890
+providers:
+  - name: "docker-provider"
+    type: "docker"
+trayTypes:
+  - name: "linux-16cpu"
+    provider: "docker-provider"
+    githubOrg: "test-org"
+`+trayType), 0600))
+		return configPath
+	}
+
+	t.Run("by name with labels", func(t *testing.T) {
+		configPath := write(t, "    runnerGroup: big-runners\n    labels: [linux, x64, 16cpu]\n")
+		cfg, err := LoadConfig(&configPath)
+		require.NoError(t, err)
+		t.Cleanup(func() { Set(&CatteryConfig{}) })
+		trayType := cfg.GetTrayType("linux-16cpu")
+		assert.Equal(t, "big-runners", trayType.RunnerGroup)
+		assert.Equal(t, []string{"linux-16cpu", "linux", "x64", "16cpu"}, trayType.ScaleSetLabels())
+	})
+
+	t.Run("id and name are exclusive", func(t *testing.T) {
+		configPath := write(t, "    runnerGroup: big-runners\n    runnerGroupId: 3\n")
+		_, err := LoadConfig(&configPath)
+		assert.ErrorContains(t, err, "excluded_with")
+	})
+
+	t.Run("one of them is required", func(t *testing.T) {
+		configPath := write(t, "")
+		_, err := LoadConfig(&configPath)
+		assert.ErrorContains(t, err, "required_without_all")
+	})
+
+	t.Run("empty label", func(t *testing.T) {
+		configPath := write(t, "    runnerGroupId: 3\n    labels: [linux, \"\"]\n")
+		_, err := LoadConfig(&configPath)
+		assert.ErrorContains(t, err, "Labels[1]")
+	})
+}
+
+func TestLoadConfig_Quotas(t *testing.T) {
+	write := func(t *testing.T, quotas string) string {
+		configPath := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(configPath, []byte(`
+server:
+  listenAddress: ":8080"
+  advertiseUrl: "http://localhost:8080"
+database:
+  uri: "mongodb://localhost:27017"
+  database: "cattery"
+github:
+  - name: "test-org"
+    appId: 12345
+    appClientId: "Iv1.test123"
+    installationId: 67890
+providers:
+  - name: "gce"
+    type: "google"
+  - name: "docker-provider"
+    type: "docker"
+trayTypes:
+  - name: "linux-16cpu"
+    provider: "gce"
+    githubOrg: "test-org"
+    runnerGroupId: 1
+    weight: 16
+  - name: "linux-small"
+    provider: "docker-provider"
+    githubOrg: "test-org"
+    runnerGroupId: 1
+quotas:
+`+quotas), 0600))
+		return configPath
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		configPath := write(t, `
+  - name: gce-cpus
+    provider: gce
+    maxWeight: 64
+  - name: org
+    githubOrg: test-org
+    maxTrays: 20
+  - name: global
+    maxTrays: 100
+`)
+		cfg, err := LoadConfig(&configPath)
+		require.NoError(t, err)
+		t.Cleanup(func() { Set(&CatteryConfig{}) })
+
+		big := cfg.GetTrayType("linux-16cpu")
+		small := cfg.GetTrayType("linux-small")
+		assert.Equal(t, 16, big.QuotaWeight())
+		assert.Equal(t, 1, small.QuotaWeight())
+
+		names := func(quotas []*Quota) []string {
+			var result []string
+			for _, quota := range quotas {
+				result = append(result, quota.Name)
+			}
+			return result
+		}
+		assert.Equal(t, []string{"gce-cpus", "org", "global"}, names(cfg.QuotasFor(big)))
+		assert.Equal(t, []string{"org", "global"}, names(cfg.QuotasFor(small)))
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		configPath := write(t, "  - name: q\n    provider: nope\n    maxTrays: 1\n")
+		_, err := LoadConfig(&configPath)
+		assert.ErrorContains(t, err, "provider nope for quota q not found")
+	})
+
+	t.Run("unknown org", func(t *testing.T) {
+		configPath := write(t, "  - name: q\n    githubOrg: nope\n    maxTrays: 1\n")
+		_, err := LoadConfig(&configPath)
+		assert.ErrorContains(t, err, "github org nope for quota q not found")
+	})
+
+	t.Run("no limit", func(t *testing.T) {
+		configPath := write(t, "  - name: q\n    provider: gce\n")
+		_, err := LoadConfig(&configPath)
+		assert.ErrorContains(t, err, "quota q sets neither maxTrays nor maxWeight")
+	})
+}
+
+func TestQuotaExceeded(t *testing.T) {
+	quota := &Quota{Name: "q", MaxTrays: 3, MaxWeight: 10}
+	assert.False(t, quota.Exceeded(3, 10))
+	assert.True(t, quota.Exceeded(4, 4))
+	assert.True(t, quota.Exceeded(2, 11))
+
+	unlimitedWeight := &Quota{Name: "q", MaxTrays: 3}
+	assert.False(t, unlimitedWeight.Exceeded(3, 1000))
+}
+
+func TestLoadConfig_QuotasWithFallback(t *testing.T) {
+	write := func(t *testing.T, quotas string) string {
+		configPath := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(configPath, []byte(`
+server:
+  listenAddress: ":8080"
+  advertiseUrl: "http://localhost:8080"
+database:
+  uri: "mongodb://localhost:27017"
+  database: "cattery"
+github:
+  - name: "test-org"
+    appId: 12345
+    appClientId: "Iv1.test123"
+    installationId: 67890
+providers:
+  - name: "gce-prod"
+    type: "google"
+  - name: "docker-provider"
+    type: "docker"
+  - name: "burst"
+    type: "fallback"
+    providers: "docker-provider,gce-prod"
+trayTypes:
+  - name: "burst-runner"
+    provider: "burst"
+    githubOrg: "test-org"
+    runnerGroupId: 1
+  - name: "linux"
+    provider: "gce-prod"
+    githubOrg: "test-org"
+    runnerGroupId: 1
+quotas:
+`+quotas), 0600))
+		return configPath
+	}
+
+	t.Run("quota on the fallback provider counts its tray types", func(t *testing.T) {
+		configPath := write(t, "  - name: burst\n    provider: burst\n    maxTrays: 5\n")
+		cfg, err := LoadConfig(&configPath)
+		require.NoError(t, err)
+		t.Cleanup(func() { Set(&CatteryConfig{}) })
+
+		quotas := cfg.QuotasFor(cfg.GetTrayType("burst-runner"))
+		require.Len(t, quotas, 1)
+		assert.Equal(t, "burst", quotas[0].Name)
+		assert.Empty(t, cfg.QuotasFor(cfg.GetTrayType("linux")))
+	})
+
+	t.Run("quota on a member is rejected", func(t *testing.T) {
+		configPath := write(t, "  - name: gce\n    provider: gce-prod\n    maxTrays: 5\n")
+		_, err := LoadConfig(&configPath)
+		assert.ErrorContains(t, err, "quota gce: provider gce-prod is a member of fallback provider burst")
+	})
+}
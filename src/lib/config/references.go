@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Config values can reference secrets instead of holding them:
+//
+//   - "env:NAME" is replaced by the value of the environment variable NAME;
+//   - "file:///path" is replaced by the contents of the file at /path, minus
+//     a trailing newline;
+//   - "${NAME}" anywhere in a value is replaced by the value of NAME, and
+//     "$${NAME}" stands for a literal "${NAME}".
+//
+// A referenced variable that is not set, or a file that cannot be read, is an
+// error. Tray type config and extraMetadata are left as is: scripts, pod
+// templates and startup scripts in instance metadata are run on the tray,
+// where "${NAME}" means the tray's environment.
+const (
+	envReferencePrefix  = "env:"
+	fileReferencePrefix = "file://"
+)
+
+var (
+	envVarPattern      = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+	trayTypeConfigPath = regexp.MustCompile(`^traytypes\[\d+\]\.(config|extrametadata)$`)
+)
+
+// resolveReferences returns a copy of settings (as returned by
+// viper.AllSettings) with env and file references resolved, and an error for
+// each reference that could not be resolved.
+func resolveReferences(settings map[string]any) (map[string]any, []error) {
+	resolved, problems := resolveValue("", settings)
+	return resolved.(map[string]any), problems
+}
+
+func resolveValue(path string, value any) (any, []error) {
+	if trayTypeConfigPath.MatchString(path) {
+		return value, nil
+	}
+
+	switch v := value.(type) {
+	case string:
+		s, err := resolveString(v)
+		if err != nil {
+			return v, []error{fmt.Errorf("%s: %w", path, err)}
+		}
+		return s, nil
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		var problems []error
+		out := make(map[string]any, len(v))
+		for _, key := range keys {
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			var errs []error
+			out[key], errs = resolveValue(childPath, v[key])
+			problems = append(problems, errs...)
+		}
+		return out, problems
+	case []any:
+		var problems []error
+		out := make([]any, len(v))
+		for i, item := range v {
+			var errs []error
+			out[i], errs = resolveValue(fmt.Sprintf("%s[%d]", path, i), item)
+			problems = append(problems, errs...)
+		}
+		return out, problems
+	default:
+		return value, nil
+	}
+}
+
+func resolveString(s string) (string, error) {
+	if name, ok := strings.CutPrefix(s, envReferencePrefix); ok {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value, nil
+	}
+
+	if path, ok := strings.CutPrefix(s, fileReferencePrefix); ok {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", s, err)
+		}
+		return strings.TrimSuffix(string(content), "\n"), nil
+	}
+
+	var err error
+	out := envVarPattern.ReplaceAllStringFunc(s, func(match string) string {
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+		name := match[2 : len(match)-1]
+		value, ok := os.LookupEnv(name)
+		if !ok && err == nil {
+			err = fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value
+	})
+	if err != nil {
+		return "", err
+	}
+	return out, nil
+}
@@ -0,0 +1,167 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TrayTypeSchedule overrides a tray type's capacity during recurring time
+// windows, e.g. more trays and a warm pool during office hours.
+//
+// Timezone is an IANA name (e.g. "Europe/Berlin"); defaults to UTC. Windows
+// are evaluated in order and the first one that contains the current time
+// wins. Outside every window the tray type's own settings apply.
+type TrayTypeSchedule struct {
+	Timezone string           `yaml:"timezone"`
+	Windows  []ScheduleWindow `yaml:"windows" validate:"dive"`
+
+	location *time.Location
+}
+
+// ScheduleWindow is one recurring window, cron-like: a set of weekdays and a
+// start/end time of day. Days are three-letter names ("mon".."sun"); empty
+// means every day. Start and End are "HH:MM". A window whose End is not
+// after Start wraps past midnight and belongs to the day it starts on
+// (a Friday 22:00–06:00 window covers Saturday until 06:00); Start == End
+// covers the whole day.
+//
+// MaxTrays, MaxParallelCreation and MinIdle override the tray type's values
+// while the window is active. Unset fields keep the tray type's value; set
+// them to 0 explicitly to e.g. drop the warm pool at night.
+type ScheduleWindow struct {
+	Name                string   `yaml:"name" validate:"required"`
+	Days                []string `yaml:"days"`
+	Start               string   `yaml:"start" validate:"required"`
+	End                 string   `yaml:"end" validate:"required"`
+	MaxTrays            *int     `yaml:"maxTrays"`
+	MaxParallelCreation *int     `yaml:"maxParallelCreation"`
+	MinIdle             *int     `yaml:"minIdle"`
+
+	days       map[time.Weekday]bool
+	start, end time.Duration
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Init parses the timezone and windows. Called by LoadConfig; call manually
+// when constructing a schedule in tests.
+func (s *TrayTypeSchedule) Init() error {
+	loc := time.UTC
+	if s.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(s.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone %q: %w", s.Timezone, err)
+		}
+	}
+	s.location = loc
+
+	for i := range s.Windows {
+		if err := s.Windows[i].init(); err != nil {
+			return fmt.Errorf("window %q: %w", s.Windows[i].Name, err)
+		}
+	}
+	return nil
+}
+
+func (w *ScheduleWindow) init() error {
+	var err error
+	if w.start, err = parseTimeOfDay(w.Start); err != nil {
+		return fmt.Errorf("invalid start: %w", err)
+	}
+	if w.end, err = parseTimeOfDay(w.End); err != nil {
+		return fmt.Errorf("invalid end: %w", err)
+	}
+
+	w.days = make(map[time.Weekday]bool, len(w.Days))
+	for _, d := range w.Days {
+		day, ok := weekdays[strings.ToLower(strings.TrimSpace(d))]
+		if !ok {
+			return fmt.Errorf("invalid day %q", d)
+		}
+		w.days[day] = true
+	}
+	return nil
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Active returns the first window containing now, or nil.
+func (s *TrayTypeSchedule) Active(now time.Time) *ScheduleWindow {
+	loc := s.location
+	if loc == nil {
+		loc = time.UTC
+	}
+	local := now.In(loc)
+	for i := range s.Windows {
+		if s.Windows[i].contains(local) {
+			return &s.Windows[i]
+		}
+	}
+	return nil
+}
+
+func (w *ScheduleWindow) contains(local time.Time) bool {
+	// Wall-clock time of day, so DST transitions don't shift the windows.
+	sinceMidnight := time.Duration(local.Hour())*time.Hour +
+		time.Duration(local.Minute())*time.Minute +
+		time.Duration(local.Second())*time.Second
+	today := local.Weekday()
+	yesterday := (today + 6) % 7
+
+	switch {
+	case w.start == w.end:
+		return w.onDay(today)
+	case w.start < w.end:
+		return w.onDay(today) && sinceMidnight >= w.start && sinceMidnight < w.end
+	default: // wraps past midnight
+		return (w.onDay(today) && sinceMidnight >= w.start) ||
+			(w.onDay(yesterday) && sinceMidnight < w.end)
+	}
+}
+
+func (w *ScheduleWindow) onDay(day time.Weekday) bool {
+	return len(w.days) == 0 || w.days[day]
+}
+
+// Scheduled returns the tray type as it applies at now: a copy with the
+// active schedule window's overrides, and that window's name ("" when no
+// window is active or the tray type has no schedule).
+func (t *TrayType) Scheduled(now time.Time) (*TrayType, string) {
+	if t.Schedule == nil {
+		return t, ""
+	}
+	window := t.Schedule.Active(now)
+	if window == nil {
+		return t, ""
+	}
+
+	out := *t
+	if window.MaxTrays != nil {
+		out.MaxTrays = *window.MaxTrays
+	}
+	if window.MaxParallelCreation != nil {
+		out.MaxParallelCreation = *window.MaxParallelCreation
+	}
+	if window.MinIdle != nil {
+		out.MinIdle = *window.MinIdle
+		if out.MaxIdle > 0 && out.MaxIdle < out.MinIdle {
+			out.MaxIdle = out.MinIdle
+		}
+	}
+	return &out, window.Name
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func intPtr(i int) *int { return &i }
+
+func TestTrayTypeSchedule_Active(t *testing.T) {
+	schedule := &TrayTypeSchedule{
+		Timezone: "Europe/Berlin",
+		Windows: []ScheduleWindow{
+			{Name: "office", Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "08:00", End: "18:00"},
+			{Name: "night", Days: []string{"Fri"}, Start: "22:00", End: "06:00"},
+			{Name: "sunday", Days: []string{"sun"}, Start: "00:00", End: "00:00"},
+		},
+	}
+	require.NoError(t, schedule.Init())
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want string
+	}{
+		// 2026-10-12 is a Monday.
+		{"weekday office hours", time.Date(2026, 10, 12, 9, 30, 0, 0, berlin), "office"},
+		{"start is inclusive", time.Date(2026, 10, 12, 8, 0, 0, 0, berlin), "office"},
+		{"end is exclusive", time.Date(2026, 10, 12, 18, 0, 0, 0, berlin), ""},
+		{"evaluated in schedule timezone", time.Date(2026, 10, 12, 7, 30, 0, 0, time.UTC), "office"},
+		{"saturday daytime", time.Date(2026, 10, 17, 12, 0, 0, 0, berlin), ""},
+		{"overnight on start day", time.Date(2026, 10, 16, 23, 0, 0, 0, berlin), "night"},
+		{"overnight wraps into next day", time.Date(2026, 10, 17, 5, 59, 0, 0, berlin), "night"},
+		{"overnight only from listed day", time.Date(2026, 10, 13, 5, 0, 0, 0, berlin), ""},
+		{"whole day", time.Date(2026, 10, 18, 15, 0, 0, 0, berlin), "sunday"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := schedule.Active(tt.at)
+			if tt.want == "" {
+				assert.Nil(t, got)
+				return
+			}
+			require.NotNil(t, got)
+			assert.Equal(t, tt.want, got.Name)
+		})
+	}
+}
+
+func TestTrayTypeSchedule_FirstWindowWins(t *testing.T) {
+	schedule := &TrayTypeSchedule{
+		Windows: []ScheduleWindow{
+			{Name: "first", Start: "00:00", End: "12:00"},
+			{Name: "second", Start: "06:00", End: "18:00"},
+		},
+	}
+	require.NoError(t, schedule.Init())
+
+	assert.Equal(t, "first", schedule.Active(time.Date(2026, 10, 12, 7, 0, 0, 0, time.UTC)).Name)
+	assert.Equal(t, "second", schedule.Active(time.Date(2026, 10, 12, 13, 0, 0, 0, time.UTC)).Name)
+}
+
+func TestTrayTypeSchedule_InitErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule TrayTypeSchedule
+	}{
+		{"bad timezone", TrayTypeSchedule{Timezone: "Mars/Olympus"}},
+		{"bad start", TrayTypeSchedule{Windows: []ScheduleWindow{{Name: "w", Start: "8am", End: "18:00"}}}},
+		{"bad end", TrayTypeSchedule{Windows: []ScheduleWindow{{Name: "w", Start: "08:00", End: "25:00"}}}},
+		{"bad day", TrayTypeSchedule{Windows: []ScheduleWindow{{Name: "w", Days: []string{"monday"}, Start: "08:00", End: "18:00"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, tt.schedule.Init())
+		})
+	}
+}
+
+func TestTrayType_Scheduled(t *testing.T) {
+	trayType := &TrayType{
+		Name:                "scheduled",
+		MaxTrays:            2,
+		MaxParallelCreation: 1,
+		MinIdle:             0,
+		MaxIdle:             1,
+		Schedule: &TrayTypeSchedule{
+			Windows: []ScheduleWindow{
+				{Name: "busy", Days: []string{"mon"}, Start: "08:00", End: "18:00",
+					MaxTrays: intPtr(20), MaxParallelCreation: intPtr(5), MinIdle: intPtr(3)},
+			},
+		},
+	}
+	require.NoError(t, trayType.Schedule.Init())
+
+	t.Run("inside window", func(t *testing.T) {
+		got, profile := trayType.Scheduled(time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC))
+		assert.Equal(t, "busy", profile)
+		assert.Equal(t, 20, got.MaxTrays)
+		assert.Equal(t, 5, got.MaxParallelCreation)
+		assert.Equal(t, 3, got.MinIdle)
+		assert.Equal(t, 3, got.MaxIdle, "maxIdle is raised to the window's minIdle")
+		assert.Equal(t, 2, trayType.MaxTrays, "the configured tray type is not modified")
+	})
+
+	t.Run("outside window", func(t *testing.T) {
+		got, profile := trayType.Scheduled(time.Date(2026, 10, 13, 9, 0, 0, 0, time.UTC))
+		assert.Equal(t, "", profile)
+		assert.Same(t, trayType, got)
+	})
+
+	t.Run("no schedule", func(t *testing.T) {
+		plain := &TrayType{Name: "plain", MaxTrays: 4}
+		got, profile := plain.Scheduled(time.Now())
+		assert.Equal(t, "", profile)
+		assert.Same(t, plain, got)
+	})
+}
@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 type TrayConfig interface {
 }
 
@@ -43,3 +45,44 @@ type NomadTrayConfig struct {
 	Script       string `yaml:"script"`
 	RunnerFolder string `yaml:"runnerFolder"`
 }
+
+// KubernetesTrayConfig configures a tray that runs as a single Pod.
+//
+// PodTemplate is a pod template (`metadata` + `spec`, the same shape as a
+// Deployment's `spec.template`) that every tray pod is created from. The
+// provider overrides the agent container's command, the pod name and the
+// restart policy; everything else (image, resources, nodeSelector,
+// tolerations, volumes, ...) comes from the template. Note that viper
+// lowercases map keys, so label and annotation keys in the template end up
+// lowercase.
+//
+// Container names the template container that runs the agent. Defaults to
+// the first container. Image, if set, overrides that container's image.
+//
+// Namespace overrides the provider-level namespace for this tray type.
+//
+// RunnerFolder is passed as `--runner-folder` to `cattery agent`; defaults to
+// /cattery like the nomad provider. AgentPath is the agent binary inside the
+// image; defaults to `cattery` (resolved from PATH).
+//
+// UnschedulableTimeout is how long the pod may stay Unschedulable before the
+// tray counts as out of capacity (and a fallback provider moves on). The
+// scheduler reports Unschedulable while the cluster autoscaler is still
+// adding a node, so the first report alone is not enough. Defaults to 2m.
+type KubernetesTrayConfig struct {
+	TrayConfig
+	Namespace            string         `yaml:"namespace"`
+	Image                string         `yaml:"image"`
+	Container            string         `yaml:"container"`
+	AgentPath            string         `yaml:"agentPath"`
+	RunnerFolder         string         `yaml:"runnerFolder"`
+	PodTemplate          map[string]any `yaml:"podTemplate"`
+	UnschedulableTimeout time.Duration  `yaml:"unschedulableTimeout"`
+}
+
+// FallbackTrayConfig holds the per-member tray config for a tray type whose
+// provider is a fallback chain, keyed by member provider name. Each value is
+// the typed config for that member's provider type (DockerTrayConfig,
+// GoogleTrayConfig, etc.), so a member provider sees exactly the config it
+// would see if the tray type referenced it directly.
+type FallbackTrayConfig map[string]TrayConfig
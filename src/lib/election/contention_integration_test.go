@@ -1,4 +1,4 @@
-//go:build integration_mongo || integration_k8s
+//go:build integration_mongo || integration_k8s || integration_postgres
 
 package election
 
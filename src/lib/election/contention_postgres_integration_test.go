@@ -0,0 +1,27 @@
+//go:build integration_postgres
+
+package election
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPostgresElector_SingleLeaderAndFailover(t *testing.T) {
+	pool := setupLeasePool(t)
+	cfg := LeaseConfig{
+		TTL:           1 * time.Second,
+		RenewInterval: 300 * time.Millisecond,
+		RetryInterval: 200 * time.Millisecond,
+	}
+
+	var instances []electorInstance
+	for i := 0; i < 3; i++ {
+		store := NewPostgresLeaseStore()
+		store.Connect(pool) // all share one table → real contention
+		id := HolderID()
+		instances = append(instances, electorInstance{id, NewLeaseElector(store, id, cfg)})
+	}
+
+	assertSingleLeaderAndFailover(t, instances, "contention")
+}
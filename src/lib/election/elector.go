@@ -35,6 +35,16 @@ type Elector interface {
 	// retrying for the lifetime of ctx, so a replica that loses or never wins
 	// leadership will take over whenever the key next becomes free.
 	Run(ctx context.Context, key string, onElected OnElected) error
+
+	// Leadership returns this replica's view of every key it is running an
+	// election for, sorted by key.
+	Leadership() []Leadership
+
+	// StepDown ends this replica's current leadership term for key and hands
+	// the lease back, so another replica can take over. The replica does not
+	// contend for key again until one lease TTL has passed. It returns
+	// ErrNotLeader if this replica does not lead key.
+	StepDown(key string) error
 }
 
 // HolderID returns a stable-per-process, unique-across-replicas identity used
@@ -3,8 +3,6 @@ package election
 import (
 	"cattery/lib/config"
 	"fmt"
-
-	"go.mongodb.org/mongo-driver/v2/mongo"
 )
 
 // NewFromConfig builds the Elector selected by cfg. A single Elector instance
@@ -12,9 +10,11 @@ import (
 // call concurrently with distinct keys, and the holder identity is fixed once
 // here so every key is leased under the same replica id.
 //
-// For the "mongo" backend leaseCollection must be non-nil. cfg is expected to
-// have defaults already applied via config.CoordinationConfig.WithDefaults.
-func NewFromConfig(cfg config.CoordinationConfig, leaseCollection *mongo.Collection) (Elector, error) {
+// For the "mongo" and "postgres" backends leaseStore must be non-nil; the
+// caller builds it on the configured database (a MongoLeaseStore or a
+// PostgresLeaseStore respectively). cfg is expected to have defaults already
+// applied via config.CoordinationConfig.WithDefaults.
+func NewFromConfig(cfg config.CoordinationConfig, leaseStore LeaseStore) (Elector, error) {
 	lease := LeaseConfig{
 		TTL:           cfg.Lease.TTL,
 		RenewInterval: cfg.Lease.RenewInterval,
@@ -23,15 +23,13 @@ func NewFromConfig(cfg config.CoordinationConfig, leaseCollection *mongo.Collect
 
 	switch cfg.Backend {
 	case config.CoordinationBackendMemory:
-		return NewMemoryElector(), nil
+		return NewMemoryElector(HolderID()), nil
 
-	case config.CoordinationBackendMongo:
-		if leaseCollection == nil {
-			return nil, fmt.Errorf("mongo coordination backend requires a lease collection")
+	case config.CoordinationBackendMongo, config.CoordinationBackendPostgres:
+		if leaseStore == nil {
+			return nil, fmt.Errorf("%s coordination backend requires a lease store", cfg.Backend)
 		}
-		store := NewMongoLeaseStore()
-		store.Connect(leaseCollection)
-		return NewLeaseElector(store, HolderID(), lease), nil
+		return NewLeaseElector(leaseStore, HolderID(), lease), nil
 
 	case config.CoordinationBackendK8s:
 		return NewK8sElector(cfg.Kubernetes.Namespace, cfg.Kubernetes.LeaseNamePrefix, HolderID(), lease)
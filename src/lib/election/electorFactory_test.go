@@ -6,6 +6,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/v2/mongo"
@@ -15,12 +16,15 @@ import (
 func TestNewFromConfig_Memory(t *testing.T) {
 	e, err := NewFromConfig(config.CoordinationConfig{Backend: config.CoordinationBackendMemory}, nil)
 	require.NoError(t, err)
-	assert.IsType(t, MemoryElector{}, e)
+	assert.IsType(t, &MemoryElector{}, e)
 }
 
-func TestNewFromConfig_MongoRequiresCollection(t *testing.T) {
+func TestNewFromConfig_LeaseBackendsRequireStore(t *testing.T) {
 	_, err := NewFromConfig(config.CoordinationConfig{Backend: config.CoordinationBackendMongo}, nil)
-	assert.Error(t, err, "mongo backend without a collection must error")
+	assert.Error(t, err, "mongo backend without a lease store must error")
+
+	_, err = NewFromConfig(config.CoordinationConfig{Backend: config.CoordinationBackendPostgres}, nil)
+	assert.Error(t, err, "postgres backend without a lease store must error")
 }
 
 // With a collection the mongo backend yields a lease-loop elector. mongo.Connect
@@ -31,8 +35,23 @@ func TestNewFromConfig_MongoReturnsLeaseElector(t *testing.T) {
 	require.NoError(t, err)
 	t.Cleanup(func() { _ = client.Disconnect(context.Background()) })
 
-	coll := client.Database("test").Collection("leases")
-	e, err := NewFromConfig(config.CoordinationConfig{Backend: config.CoordinationBackendMongo}, coll)
+	store := NewMongoLeaseStore()
+	store.Connect(client.Database("test").Collection("leases"))
+	e, err := NewFromConfig(config.CoordinationConfig{Backend: config.CoordinationBackendMongo}, store)
+	require.NoError(t, err)
+	assert.IsType(t, &LeaseElector{}, e)
+}
+
+// pgxpool.New does not dial until first use, so the postgres backend can be
+// wired without a live server too.
+func TestNewFromConfig_PostgresReturnsLeaseElector(t *testing.T) {
+	pool, err := pgxpool.New(context.Background(), "postgres://localhost/test")
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	store := NewPostgresLeaseStore()
+	store.Connect(pool)
+	e, err := NewFromConfig(config.CoordinationConfig{Backend: config.CoordinationBackendPostgres}, store)
 	require.NoError(t, err)
 	assert.IsType(t, &LeaseElector{}, e)
 }
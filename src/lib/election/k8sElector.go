@@ -1,6 +1,7 @@
 package election
 
 import (
+	"cattery/lib/logging"
 	"context"
 	"fmt"
 	"os"
@@ -34,7 +35,8 @@ type K8sElector struct {
 	renewDeadline time.Duration
 	retryPeriod   time.Duration
 
-	logger *log.Entry
+	leaderships leaderships
+	logger      *log.Entry
 }
 
 // NewK8sElector builds a k8s-backed Elector using in-cluster credentials.
@@ -82,39 +84,64 @@ func newK8sElector(client coordinationv1.CoordinationV1Interface, namespace, pre
 		leaseDuration: leaseDuration,
 		renewDeadline: renewDeadline,
 		retryPeriod:   retryPeriod,
-		logger:        log.WithField("component", "election"),
+		logger:        logging.Logger("election"),
 	}
 }
 
 func (e *K8sElector) Run(ctx context.Context, key string, onElected OnElected) error {
 	logger := e.logger.WithField("key", key)
+	e.leaderships.track(key)
+	defer e.leaderships.untrack(key)
+
 	for ctx.Err() == nil {
 		if err := e.runOnce(ctx, key, logger, onElected); err != nil {
 			return err // config error — deterministic, no point retrying
 		}
 		// le.Run blocks on its own acquire loop, so this only spins after a
 		// leadership loss; a small jittered pause avoids a hot loop in edge cases.
-		if !sleep(ctx, jitter(e.retryPeriod)) {
+		// After a step-down, wait out a lease duration so another replica
+		// takes over.
+		pause := jitter(e.retryPeriod)
+		if e.leaderships.lost(key) {
+			logger.Info("Stepped down from leadership")
+			pause = e.leaseDuration
+		}
+		if !sleep(ctx, pause) {
 			break
 		}
 	}
 	return ctx.Err()
 }
 
+func (e *K8sElector) Leadership() []Leadership {
+	return e.leaderships.list()
+}
+
+func (e *K8sElector) StepDown(key string) error {
+	return e.leaderships.stepDown(key)
+}
+
 // runOnce contends for the Lease once: it blocks in le.Run until leadership is
 // lost or ctx ends, then — if leadership was actually held — waits for
 // onElected to fully return before allowing Run to re-contend, so the same
-// replica never runs two leadership terms for a key concurrently.
+// replica never runs two leadership terms for a key concurrently. StepDown
+// cancels termCtx, which makes le.Run release the Lease and return.
 func (e *K8sElector) runOnce(ctx context.Context, key string, logger *log.Entry, onElected OnElected) error {
-	lock := &resourcelock.LeaseLock{
-		LeaseMeta: metav1.ObjectMeta{
-			Name:      e.leaseName(key),
-			Namespace: e.namespace,
+	lock := &renewRecordingLock{
+		LeaseLock: &resourcelock.LeaseLock{
+			LeaseMeta: metav1.ObjectMeta{
+				Name:      e.leaseName(key),
+				Namespace: e.namespace,
+			},
+			Client:     e.client,
+			LockConfig: resourcelock.ResourceLockConfig{Identity: e.identity},
 		},
-		Client:     e.client,
-		LockConfig: resourcelock.ResourceLockConfig{Identity: e.identity},
+		renewed: func() { e.leaderships.renewed(key) },
 	}
 
+	termCtx, endTerm := context.WithCancel(ctx)
+	defer endTerm()
+
 	started := make(chan struct{})
 	finished := make(chan struct{})
 
@@ -128,6 +155,7 @@ func (e *K8sElector) runOnce(ctx context.Context, key string, logger *log.Entry,
 		Callbacks: leaderelection.LeaderCallbacks{
 			OnStartedLeading: func(leaderCtx context.Context) {
 				logger.Info("Acquired leadership")
+				e.leaderships.elected(key, e.identity, endTerm)
 				close(started)
 				onElected(leaderCtx)
 				close(finished)
@@ -135,13 +163,16 @@ func (e *K8sElector) runOnce(ctx context.Context, key string, logger *log.Entry,
 			OnStoppedLeading: func() {
 				logger.Info("Lost leadership")
 			},
+			OnNewLeader: func(identity string) {
+				e.leaderships.observe(key, identity)
+			},
 		},
 	})
 	if err != nil {
 		return fmt.Errorf("invalid leader election config: %w", err)
 	}
 
-	le.Run(ctx)
+	le.Run(termCtx)
 
 	// If we ever started leading, OnStartedLeading is running onElected in a
 	// goroutine; wait for it to unwind before re-contending.
@@ -153,6 +184,21 @@ func (e *K8sElector) runOnce(ctx context.Context, key string, logger *log.Entry,
 	return nil
 }
 
+// renewRecordingLock is a LeaseLock that reports each successful write of
+// the Lease record, which while leading is a renewal.
+type renewRecordingLock struct {
+	*resourcelock.LeaseLock
+	renewed func()
+}
+
+func (l *renewRecordingLock) Update(ctx context.Context, ler resourcelock.LeaderElectionRecord) error {
+	if err := l.LeaseLock.Update(ctx, ler); err != nil {
+		return err
+	}
+	l.renewed()
+	return nil
+}
+
 // leaseName builds a DNS-subdomain-safe Lease object name from the key.
 func (e *K8sElector) leaseName(key string) string {
 	return e.prefix + sanitizeName(key)
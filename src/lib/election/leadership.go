@@ -0,0 +1,147 @@
+package election
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNotLeader is returned by StepDown when this replica does not hold the
+// lease for the key.
+var ErrNotLeader = errors.New("this replica is not the leader")
+
+// ErrStepDownUnsupported is returned by StepDown of an elector with no other
+// replica to hand leadership to.
+var ErrStepDownUnsupported = errors.New("the elector does not support stepping down")
+
+// Leadership is this replica's view of the election for one key.
+type Leadership struct {
+	Key string `json:"key"`
+	// Holder is the id of the replica holding the key's lease, as last seen
+	// by this replica; "" if the key looked free or has not been seen yet.
+	Holder string `json:"holder"`
+	// Leader is true while this replica holds the lease.
+	Leader bool `json:"leader"`
+	// AcquiredAt is when this replica's current term started and LastRenew
+	// when it last renewed the lease. Both are zero unless Leader is set.
+	AcquiredAt time.Time `json:"acquiredAt,omitzero"`
+	LastRenew  time.Time `json:"lastRenew,omitzero"`
+	// Terms counts the terms this replica has led the key since it started.
+	Terms int `json:"terms"`
+}
+
+// leaderships tracks the Leadership of every key an elector runs, and lets
+// StepDown end the current term of a key. The zero value is ready to use.
+type leaderships struct {
+	mu   sync.Mutex
+	keys map[string]*keyState
+}
+
+type keyState struct {
+	Leadership
+	// endTerm cancels the current term; nil unless leading.
+	endTerm context.CancelFunc
+	// steppedDown is set by StepDown until the term's end is handled.
+	steppedDown bool
+}
+
+// track starts tracking key, for the duration of a Run.
+func (l *leaderships) track(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.keys == nil {
+		l.keys = make(map[string]*keyState)
+	}
+	l.keys[key] = &keyState{Leadership: Leadership{Key: key}}
+}
+
+// untrack stops tracking key once its Run returns.
+func (l *leaderships) untrack(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.keys, key)
+}
+
+// update applies fn to key's state, if key is tracked.
+func (l *leaderships) update(key string, fn func(s *keyState)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if s, ok := l.keys[key]; ok {
+		fn(s)
+	}
+}
+
+// observe records the holder of key's lease seen while not leading it.
+func (l *leaderships) observe(key string, holder string) {
+	l.update(key, func(s *keyState) {
+		if !s.Leader {
+			s.Holder = holder
+		}
+	})
+}
+
+// elected starts a term of holder; endTerm must end it.
+func (l *leaderships) elected(key string, holder string, endTerm context.CancelFunc) {
+	now := time.Now().UTC()
+	l.update(key, func(s *keyState) {
+		s.Holder = holder
+		s.Leader = true
+		s.AcquiredAt = now
+		s.LastRenew = now
+		s.Terms++
+		s.endTerm = endTerm
+	})
+}
+
+// renewed records a renewal of the lease during a term.
+func (l *leaderships) renewed(key string) {
+	now := time.Now().UTC()
+	l.update(key, func(s *keyState) {
+		if s.Leader {
+			s.LastRenew = now
+		}
+	})
+}
+
+// lost ends the current term. The holder is unknown until observed again.
+// It reports whether the term ended because of StepDown.
+func (l *leaderships) lost(key string) bool {
+	steppedDown := false
+	l.update(key, func(s *keyState) {
+		steppedDown = s.steppedDown
+		s.Holder = ""
+		s.Leader = false
+		s.AcquiredAt = time.Time{}
+		s.LastRenew = time.Time{}
+		s.endTerm = nil
+		s.steppedDown = false
+	})
+	return steppedDown
+}
+
+// stepDown ends the current term of key.
+func (l *leaderships) stepDown(key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s, ok := l.keys[key]
+	if !ok || !s.Leader || s.endTerm == nil {
+		return ErrNotLeader
+	}
+	s.steppedDown = true
+	s.endTerm()
+	return nil
+}
+
+// list returns the Leadership of every tracked key, sorted by key.
+func (l *leaderships) list() []Leadership {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	result := make([]Leadership, 0, len(l.keys))
+	for _, s := range l.keys {
+		result = append(result, s.Leadership)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result
+}
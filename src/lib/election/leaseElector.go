@@ -1,6 +1,7 @@
 package election
 
 import (
+	"cattery/lib/logging"
 	"context"
 	"math/rand"
 	"time"
@@ -11,9 +12,9 @@ import (
 // LeaseStore is the backend primitive the generic lease loop drives. A backend
 // only has to implement an atomic acquire/renew and a best-effort release; the
 // renew cadence, leadership-loss detection, and leaderCtx lifecycle all live in
-// LeaseElector. Mongo and the SQL lease-table backend both implement this; the
-// k8s and Postgres-advisory-lock electors implement Elector directly instead,
-// because they bring their own loop.
+// LeaseElector. The Mongo and Postgres lease-table backends both implement
+// this; the k8s and Postgres-advisory-lock electors implement Elector directly
+// instead, because they bring their own loop.
 type LeaseStore interface {
 	// Acquire atomically acquires or renews the lease for key on behalf of
 	// holder, valid for ttl from now. It returns acquired=true iff holder owns
@@ -26,6 +27,9 @@ type LeaseStore interface {
 	// Release relinquishes the lease for key if still held by holder.
 	// Best-effort: a failure here only delays takeover until the lease expires.
 	Release(ctx context.Context, key, holder string) error
+
+	// Holder returns the holder of key's live lease, or "" if key is free.
+	Holder(ctx context.Context, key string) (string, error)
 }
 
 // LeaseConfig tunes the renew/retry cadence. Zero fields fall back to defaults.
@@ -60,10 +64,11 @@ func (c LeaseConfig) withDefaults() LeaseConfig {
 
 // LeaseElector turns a LeaseStore into an Elector.
 type LeaseElector struct {
-	store  LeaseStore
-	holder string
-	cfg    LeaseConfig
-	logger *log.Entry
+	store       LeaseStore
+	holder      string
+	cfg         LeaseConfig
+	leaderships leaderships
+	logger      *log.Entry
 }
 
 // NewLeaseElector builds an Elector backed by store. holder must be unique per
@@ -73,18 +78,24 @@ func NewLeaseElector(store LeaseStore, holder string, cfg LeaseConfig) Elector {
 		store:  store,
 		holder: holder,
 		cfg:    cfg.withDefaults(),
-		logger: log.WithField("component", "election"),
+		logger: logging.Logger("election"),
 	}
 }
 
 func (e *LeaseElector) Run(ctx context.Context, key string, onElected OnElected) error {
 	logger := e.logger.WithField("key", key)
+	e.leaderships.track(key)
+	defer e.leaderships.untrack(key)
+
 	for ctx.Err() == nil {
 		acquired, err := e.store.Acquire(ctx, key, e.holder, e.cfg.TTL)
 		if err != nil {
 			logger.Warnf("Lease acquire failed: %v", err)
 		}
 		if !acquired {
+			if err == nil {
+				e.observe(ctx, key, logger)
+			}
 			if !sleep(ctx, jitter(e.cfg.RetryInterval)) {
 				break
 			}
@@ -93,18 +104,47 @@ func (e *LeaseElector) Run(ctx context.Context, key string, onElected OnElected)
 
 		logger.Info("Acquired leadership")
 		e.lead(ctx, key, logger, onElected)
+		if e.leaderships.lost(key) {
+			// Give the other replicas a lease TTL to take over before
+			// contending again.
+			logger.Info("Stepped down from leadership")
+			if !sleep(ctx, e.cfg.TTL) {
+				break
+			}
+			continue
+		}
 		logger.Info("Lost leadership")
 	}
 	return ctx.Err()
 }
 
+func (e *LeaseElector) Leadership() []Leadership {
+	return e.leaderships.list()
+}
+
+func (e *LeaseElector) StepDown(key string) error {
+	return e.leaderships.stepDown(key)
+}
+
+// observe records which replica holds key after losing the race for it.
+func (e *LeaseElector) observe(ctx context.Context, key string, logger *log.Entry) {
+	holder, err := e.store.Holder(ctx, key)
+	if err != nil {
+		logger.Warnf("Lease holder lookup failed: %v", err)
+		return
+	}
+	e.leaderships.observe(key, holder)
+}
+
 // lead runs onElected for one leadership term: it renews on a ticker, cancels
 // leaderCtx the instant a renew fails (or ctx ends), waits for onElected to
 // return, and best-effort releases the lease. It returns when the term ends;
-// Run then loops to attempt reacquisition.
+// Run then loops to attempt reacquisition. StepDown cancels leaderCtx, which
+// ends the term like onElected returning on its own.
 func (e *LeaseElector) lead(ctx context.Context, key string, logger *log.Entry, onElected OnElected) {
 	leaderCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
+	e.leaderships.elected(key, e.holder, cancel)
 
 	done := make(chan struct{})
 	go func() {
@@ -125,8 +165,9 @@ func (e *LeaseElector) lead(ctx context.Context, key string, logger *log.Entry,
 			e.release(key, logger)
 			return
 		case <-done:
-			// onElected returned on its own (the poller errored out). Drop the
-			// lease so another replica can pick the key up immediately.
+			// onElected returned on its own (the poller errored out) or the
+			// replica stepped down. Drop the lease so another replica can
+			// pick the key up immediately.
 			e.release(key, logger)
 			return
 		case <-ticker.C:
@@ -141,6 +182,7 @@ func (e *LeaseElector) lead(ctx context.Context, key string, logger *log.Entry,
 				<-done
 				return
 			}
+			e.leaderships.renewed(key)
 		}
 	}
 }
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // fakeStore is a controllable LeaseStore. acquireFn decides each Acquire result
@@ -19,6 +20,7 @@ type fakeStore struct {
 	lastRelKey   string
 	lastRelHold  string
 	acquireFn    func(call int) (bool, error)
+	holder       string
 }
 
 func (f *fakeStore) Acquire(_ context.Context, _, _ string, _ time.Duration) (bool, error) {
@@ -42,6 +44,12 @@ func (f *fakeStore) Release(_ context.Context, key, holder string) error {
 	return nil
 }
 
+func (f *fakeStore) Holder(_ context.Context, _ string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.holder, nil
+}
+
 func (f *fakeStore) counts() (acquire, release int) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -220,3 +228,83 @@ func TestLeaseElector_NeverRunsOverlappingTerms(t *testing.T) {
 	assert.Equal(t, int32(1), atomic.LoadInt32(&maxConcurrent), "terms must not overlap")
 	assert.Greater(t, atomic.LoadInt32(&terms), int32(1), "should have flapped through several terms")
 }
+
+// Leadership reports the holder seen while waiting, then this replica's term
+// with its renewals.
+func TestLeaseElector_Leadership(t *testing.T) {
+	store := &fakeStore{
+		holder: "holder-2",
+		acquireFn: func(call int) (bool, error) {
+			return call >= 3, nil // holder-2 leads for the first two attempts
+		},
+	}
+	elector := newTestElector(store)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	started := make(chan struct{})
+
+	go elector.Run(ctx, "type-a", func(lctx context.Context) {
+		close(started)
+		<-lctx.Done()
+	})
+
+	assert.Eventually(t, func() bool {
+		l := elector.Leadership()
+		return len(l) == 1 && l[0].Holder == "holder-2" && !l[0].Leader
+	}, time.Second, time.Millisecond, "the other replica is seen as holder")
+
+	waitClosed(t, started, "leadership start")
+	l := elector.Leadership()
+	require.Len(t, l, 1)
+	assert.Equal(t, "type-a", l[0].Key)
+	assert.Equal(t, "holder-1", l[0].Holder)
+	assert.True(t, l[0].Leader)
+	assert.Equal(t, 1, l[0].Terms)
+	assert.False(t, l[0].AcquiredAt.IsZero())
+
+	acquiredAt := l[0].AcquiredAt
+	assert.Eventually(t, func() bool {
+		return elector.Leadership()[0].LastRenew.After(acquiredAt)
+	}, time.Second, time.Millisecond, "renewals are recorded")
+
+	cancel()
+	assert.Eventually(t, func() bool {
+		return len(elector.Leadership()) == 0
+	}, time.Second, time.Millisecond, "a key is dropped once its Run returns")
+}
+
+// StepDown ends the term, releases the lease and keeps the replica from
+// contending for a lease TTL.
+func TestLeaseElector_StepDown(t *testing.T) {
+	store := &fakeStore{} // always acquired
+	elector := newTestElector(store)
+	assert.ErrorIs(t, elector.StepDown("type-a"), ErrNotLeader, "unknown key")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var terms int32
+	go elector.Run(ctx, "type-a", func(lctx context.Context) {
+		atomic.AddInt32(&terms, 1)
+		<-lctx.Done()
+	})
+
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&terms) == 1
+	}, time.Second, time.Millisecond, "leadership start")
+	require.NoError(t, elector.StepDown("type-a"))
+	assert.Eventually(t, func() bool {
+		_, releases := store.counts()
+		return releases == 1
+	}, time.Second, time.Millisecond, "stepping down releases the lease")
+	assert.False(t, elector.Leadership()[0].Leader)
+	assert.ErrorIs(t, elector.StepDown("type-a"), ErrNotLeader)
+
+	time.Sleep(50 * time.Millisecond) // half the TTL
+	assert.Equal(t, int32(1), atomic.LoadInt32(&terms), "no new term within the TTL")
+
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&terms) == 2
+	}, time.Second, time.Millisecond, "contends again after the TTL")
+	assert.Equal(t, 2, elector.Leadership()[0].Terms)
+}
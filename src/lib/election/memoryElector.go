@@ -8,12 +8,30 @@ import "context"
 // state, so every process believes it leads. Running more than one replica on
 // this elector means each replica tries to hold every tray type's GitHub
 // session, which conflicts — use a shared backend (mongo, k8s) for HA.
-type MemoryElector struct{}
+type MemoryElector struct {
+	holder      string
+	leaderships leaderships
+}
 
 // NewMemoryElector returns an Elector that always leads. Single-replica only.
-func NewMemoryElector() Elector { return MemoryElector{} }
+// holder is reported as the holder of every key.
+func NewMemoryElector(holder string) Elector { return &MemoryElector{holder: holder} }
+
+func (e *MemoryElector) Run(ctx context.Context, key string, onElected OnElected) error {
+	e.leaderships.track(key)
+	defer e.leaderships.untrack(key)
 
-func (MemoryElector) Run(ctx context.Context, _ string, onElected OnElected) error {
+	e.leaderships.elected(key, e.holder, nil)
 	onElected(ctx) // leaderCtx == ctx: leader until shutdown
 	return ctx.Err()
 }
+
+func (e *MemoryElector) Leadership() []Leadership {
+	return e.leaderships.list()
+}
+
+// StepDown always fails: with no other replica, the key would be taken right
+// back.
+func (e *MemoryElector) StepDown(string) error {
+	return ErrStepDownUnsupported
+}
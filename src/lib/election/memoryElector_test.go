@@ -10,7 +10,7 @@ import (
 // MemoryElector leads immediately, hands onElected a leaderCtx tied to the
 // parent ctx, and returns when the parent is cancelled.
 func TestMemoryElector_AlwaysLeads(t *testing.T) {
-	e := NewMemoryElector()
+	e := NewMemoryElector("holder-1")
 
 	ctx, cancel := context.WithCancel(context.Background())
 	started := make(chan struct{})
@@ -28,6 +28,13 @@ func TestMemoryElector_AlwaysLeads(t *testing.T) {
 
 	waitClosed(t, started, "memory elector leads immediately")
 	assert.NoError(t, leaderCtx.Err())
+	leadership := e.Leadership()
+	if assert.Len(t, leadership, 1) {
+		assert.Equal(t, "holder-1", leadership[0].Holder)
+		assert.True(t, leadership[0].Leader)
+		assert.Equal(t, 1, leadership[0].Terms)
+	}
+	assert.ErrorIs(t, e.StepDown("type-a"), ErrStepDownUnsupported)
 
 	cancel()
 	waitClosed(t, runDone, "memory elector Run returns on ctx cancel")
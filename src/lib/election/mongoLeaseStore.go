@@ -2,6 +2,7 @@ package election
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
@@ -64,3 +65,17 @@ func (s *MongoLeaseStore) Release(ctx context.Context, key, holder string) error
 	_, err := s.collection.DeleteOne(ctx, bson.M{"_id": key, "holder": holder})
 	return err
 }
+
+func (s *MongoLeaseStore) Holder(ctx context.Context, key string) (string, error) {
+	var lease struct {
+		Holder string `bson:"holder"`
+	}
+	err := s.collection.FindOne(ctx, bson.M{"_id": key, "expiresAt": bson.M{"$gte": time.Now().UTC()}}).Decode(&lease)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return "", nil
+		}
+		return "", err
+	}
+	return lease.Holder, nil
+}
@@ -44,6 +44,10 @@ func TestMongoLeaseStore_Lifecycle(t *testing.T) {
 	require.NoError(t, err)
 	assert.True(t, ok, "acquire free key")
 
+	holder, err := store.Holder(ctx, "k")
+	require.NoError(t, err)
+	assert.Equal(t, "A", holder, "holder of a live lease")
+
 	// same holder renews
 	ok, err = store.Acquire(ctx, "k", "A", ttl)
 	require.NoError(t, err)
@@ -73,6 +77,9 @@ func TestMongoLeaseStore_Lifecycle(t *testing.T) {
 
 	// release by the holder frees it
 	require.NoError(t, store.Release(ctx, "k", "B"))
+	holder, err = store.Holder(ctx, "k")
+	require.NoError(t, err)
+	assert.Empty(t, holder, "a released key has no holder")
 	ok, err = store.Acquire(ctx, "k", "C", ttl)
 	require.NoError(t, err)
 	assert.True(t, ok, "holder release frees the lease")
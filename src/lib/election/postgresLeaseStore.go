@@ -0,0 +1,69 @@
+package election
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresLeaseStore implements LeaseStore on the leases table created by the
+// postgres package migrations. One row per key; expiry is computed on the
+// database clock, so replica clock skew does not affect takeover timing.
+type PostgresLeaseStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresLeaseStore() *PostgresLeaseStore {
+	return &PostgresLeaseStore{}
+}
+
+func (s *PostgresLeaseStore) Connect(pool *pgxpool.Pool) {
+	s.pool = pool
+}
+
+// Acquire inserts the lease row, or on conflict overwrites it only when it is
+// ours (renew) or expired (takeover). A live lease held by someone else makes
+// the conditional DO UPDATE a no-op and RETURNING yields no row.
+//
+// Concurrent takeovers are safe: the row lock taken by ON CONFLICT serializes
+// them, and the loser re-evaluates the WHERE against the winner's fresh
+// expires_at, which no longer matches.
+func (s *PostgresLeaseStore) Acquire(ctx context.Context, key, holder string, ttl time.Duration) (bool, error) {
+	var owner string
+	err := s.pool.QueryRow(ctx, `INSERT INTO leases (key, holder, expires_at)
+		VALUES ($1, $2, now() + $3 * interval '1 millisecond')
+		ON CONFLICT (key) DO UPDATE SET
+			holder     = excluded.holder,
+			expires_at = excluded.expires_at
+		WHERE leases.holder = excluded.holder OR leases.expires_at < now()
+		RETURNING holder`,
+		key, holder, ttl.Milliseconds(),
+	).Scan(&owner)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil // live lease held by another replica
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *PostgresLeaseStore) Release(ctx context.Context, key, holder string) error {
+	_, err := s.pool.Exec(ctx, `DELETE FROM leases WHERE key = $1 AND holder = $2`, key, holder)
+	return err
+}
+
+func (s *PostgresLeaseStore) Holder(ctx context.Context, key string) (string, error) {
+	var holder string
+	err := s.pool.QueryRow(ctx, `SELECT holder FROM leases WHERE key = $1 AND expires_at >= now()`, key).Scan(&holder)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return "", err
+	}
+	return holder, nil
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -57,7 +58,8 @@ func (gc *GithubClient) RestartFailedJobs(repoName string, workflowId int64) err
 	ctx, cancel := context.WithTimeout(context.Background(), githubAPITimeout)
 	defer cancel()
 
-	_, err := gc.client.Actions.RerunFailedJobsByID(ctx, gc.Org.Name, repoName, workflowId)
+	owner, repo := gc.splitRepo(repoName)
+	_, err := gc.client.Actions.RerunFailedJobsByID(ctx, owner, repo, workflowId)
 	return err
 }
 
@@ -73,7 +75,8 @@ func (gc *GithubClient) GetWorkflowRunInfo(repoName string, workflowRunId int64)
 	ctx, cancel := context.WithTimeout(context.Background(), githubAPITimeout)
 	defer cancel()
 
-	wr, _, err := gc.client.Actions.GetWorkflowRunByID(ctx, gc.Org.Name, repoName, workflowRunId)
+	owner, repo := gc.splitRepo(repoName)
+	wr, _, err := gc.client.Actions.GetWorkflowRunByID(ctx, owner, repo, workflowRunId)
 	if err != nil {
 		return WorkflowRunInfo{}, err
 	}
@@ -95,9 +98,10 @@ func (gc *GithubClient) HasClosedPullRequestForBranch(repoName string, headBranc
 	ctx, cancel := context.WithTimeout(context.Background(), githubAPITimeout)
 	defer cancel()
 
-	prs, _, err := gc.client.PullRequests.List(ctx, gc.Org.Name, repoName, &github.PullRequestListOptions{
+	owner, repo := gc.splitRepo(repoName)
+	prs, _, err := gc.client.PullRequests.List(ctx, owner, repo, &github.PullRequestListOptions{
 		State: "all",
-		Head:  gc.Org.Name + ":" + headBranch,
+		Head:  owner + ":" + headBranch,
 	})
 	if err != nil {
 		return false, err
@@ -118,6 +122,16 @@ func (gc *GithubClient) HasClosedPullRequestForBranch(repoName string, headBranc
 	return false, nil
 }
 
+// splitRepo splits an "owner/repo" name, as recorded on trays, into owner and
+// repository. A bare repository name belongs to the client's org. Trays of
+// enterprise-scoped tray types run jobs for repositories outside that org.
+func (gc *GithubClient) splitRepo(repoName string) (string, string) {
+	if owner, repo, ok := strings.Cut(repoName, "/"); ok {
+		return owner, repo
+	}
+	return gc.Org.Name, repoName
+}
+
 // IsForbidden reports whether err is a GitHub API 403 response, which for an
 // installation token means the App lacks the required permission.
 func IsForbidden(err error) bool {
@@ -136,19 +150,32 @@ func createClient(org *config.GitHubOrganization) (*github.Client, error) {
 
 	tr := http.DefaultTransport
 
-	itr, err := ghinstallation.NewKeyFromFile(
+	privateKey, err := org.PrivateKeyPEM()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load GitHub App private key for org %s: %w", org.Name, err)
+	}
+
+	itr, err := ghinstallation.New(
 		tr,
 		org.AppId,
 		org.InstallationId,
-		org.PrivateKeyPath,
+		privateKey,
 	)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to load GitHub App private key for org %s: %w", org.Name, err)
 	}
 
+	itr.BaseURL = org.APIURL()
+
 	// Use installation transport with github.com/google/go-github
 	client := github.NewClient(&http.Client{Transport: itr})
+	if org.APIURL() != config.DefaultGitHubAPIURL {
+		client, err = client.WithEnterpriseURLs(org.APIURL(), org.WebURL())
+		if err != nil {
+			return nil, fmt.Errorf("invalid GitHub URL for org %s: %w", org.Name, err)
+		}
+	}
 
 	githubClients[org.Name] = client
 
@@ -0,0 +1,42 @@
+package logging
+
+import (
+	"cattery/lib/trays"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Field keys used on cattery's log lines. Every line concerning a tray
+// carries TrayIdKey, TrayTypeKey, OrgKey and ProviderKey, and the job's
+// WorkflowRunIdKey and RepositoryKey once the tray runs one.
+const (
+	ComponentKey     = "component"
+	TrayIdKey        = "trayId"
+	TrayTypeKey      = "trayType"
+	OrgKey           = "org"
+	ProviderKey      = "provider"
+	WorkflowRunIdKey = "workflowRunId"
+	RepositoryKey    = "repository"
+)
+
+// TrayFields returns the fields describing tray on a log line.
+func TrayFields(tray *trays.Tray) log.Fields {
+	fields := log.Fields{
+		TrayIdKey:   tray.Id,
+		TrayTypeKey: tray.TrayTypeName,
+		OrgKey:      tray.GitHubOrgName,
+		ProviderKey: tray.ProviderName,
+	}
+	if tray.WorkflowRunId != 0 {
+		fields[WorkflowRunIdKey] = tray.WorkflowRunId
+	}
+	if tray.Repository != "" {
+		fields[RepositoryKey] = tray.Repository
+	}
+	return fields
+}
+
+// WithTray returns entry with tray's fields added.
+func WithTray(entry *log.Entry, tray *trays.Tray) *log.Entry {
+	return entry.WithFields(TrayFields(tray))
+}
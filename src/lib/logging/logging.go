@@ -0,0 +1,93 @@
+// Package logging configures cattery's logrus output and hands out the
+// component loggers whose levels the logging config can override.
+package logging
+
+import (
+	"cattery/lib/config"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	mu sync.Mutex
+	// components holds a logger per component, so each can log at its own
+	// level while sharing the standard logger's output and format.
+	components = make(map[string]*log.Logger)
+	// overrides are the per-component levels of the applied config, keyed
+	// by lowercased component name since viper lowercases map keys.
+	overrides = make(map[string]log.Level)
+)
+
+// Logger returns the logger of component. Its lines carry a "component"
+// field, and it logs at the level the logging config sets for the component,
+// or else at the global level. Loggers handed out before Configure follow
+// every later Configure.
+func Logger(component string) *log.Entry {
+	mu.Lock()
+	defer mu.Unlock()
+
+	logger, ok := components[component]
+	if !ok {
+		logger = log.New()
+		configure(logger, component)
+		components[component] = logger
+	}
+	return logger.WithField(ComponentKey, component)
+}
+
+// Configure applies cfg to the standard logger and every component logger.
+// It is called at startup and again on each config reload, so level and
+// format changes take effect without a restart.
+func Configure(cfg config.LoggingConfig) error {
+	cfg = cfg.WithDefaults()
+
+	level, err := log.ParseLevel(cfg.Level)
+	if err != nil {
+		return fmt.Errorf("invalid log level: %w", err)
+	}
+	componentLevels := make(map[string]log.Level, len(cfg.Components))
+	for component, name := range cfg.Components {
+		componentLevel, err := log.ParseLevel(name)
+		if err != nil {
+			return fmt.Errorf("invalid log level for component %s: %w", component, err)
+		}
+		componentLevels[strings.ToLower(component)] = componentLevel
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	std := log.StandardLogger()
+	std.SetFormatter(newFormatter(cfg.Format))
+	std.SetLevel(level)
+
+	overrides = componentLevels
+	for component, logger := range components {
+		configure(logger, component)
+	}
+	return nil
+}
+
+// configure copies the standard logger's output and format to a component
+// logger and sets its level. mu must be held.
+func configure(logger *log.Logger, component string) {
+	std := log.StandardLogger()
+	logger.SetOutput(std.Out)
+	logger.SetFormatter(std.Formatter)
+	if level, ok := overrides[strings.ToLower(component)]; ok {
+		logger.SetLevel(level)
+	} else {
+		logger.SetLevel(std.GetLevel())
+	}
+}
+
+func newFormatter(format string) log.Formatter {
+	if format == config.LogFormatJson {
+		return &log.JSONFormatter{TimestampFormat: time.RFC3339Nano}
+	}
+	return &log.TextFormatter{}
+}
@@ -0,0 +1,77 @@
+package logging
+
+import (
+	"bytes"
+	"cattery/lib/config"
+	"cattery/lib/trays"
+	"encoding/json"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureOutput sends the standard logger's output to a buffer and restores
+// the logging config afterwards.
+func captureOutput(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	std := log.StandardLogger()
+	out, formatter, level := std.Out, std.Formatter, std.GetLevel()
+	var buf bytes.Buffer
+	std.SetOutput(&buf)
+	t.Cleanup(func() {
+		std.SetOutput(out)
+		std.SetFormatter(formatter)
+		std.SetLevel(level)
+		require.NoError(t, Configure(config.LoggingConfig{Level: level.String()}))
+	})
+	return &buf
+}
+
+func TestConfigure_ComponentOverrides(t *testing.T) {
+	buf := captureOutput(t)
+	quiet := Logger("logging-test-quiet")
+	chatty := Logger("logging-test-Chatty")
+
+	require.NoError(t, Configure(config.LoggingConfig{
+		Format:     config.LogFormatJson,
+		Level:      "info",
+		Components: map[string]string{"logging-test-quiet": "warn", "logging-test-chatty": "debug"},
+	}))
+
+	quiet.Info("dropped")
+	quiet.Warn("kept")
+	chatty.Debug("kept")
+	log.Debug("dropped")
+	Logger("logging-test-new").Info("kept")
+
+	var lines []map[string]any
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal(line, &entry), "every line is JSON")
+		lines = append(lines, entry)
+	}
+	require.Len(t, lines, 3)
+	assert.Equal(t, "logging-test-quiet", lines[0][ComponentKey])
+	assert.Equal(t, "warning", lines[0]["level"])
+	assert.Equal(t, "logging-test-Chatty", lines[1][ComponentKey], "overrides match components case-insensitively")
+	assert.Equal(t, "logging-test-new", lines[2][ComponentKey], "loggers made after Configure follow it")
+}
+
+func TestConfigure_RejectsInvalidLevel(t *testing.T) {
+	captureOutput(t)
+	assert.Error(t, Configure(config.LoggingConfig{Level: "loud"}))
+	assert.Error(t, Configure(config.LoggingConfig{Components: map[string]string{"trayManager": "loud"}}))
+}
+
+func TestTrayFields(t *testing.T) {
+	tray := &trays.Tray{Id: "t1", TrayTypeName: "runner", GitHubOrgName: "org", ProviderName: "docker"}
+	assert.Equal(t, log.Fields{TrayIdKey: "t1", TrayTypeKey: "runner", OrgKey: "org", ProviderKey: "docker"}, TrayFields(tray))
+
+	tray.WorkflowRunId = 42
+	tray.Repository = "org/repo"
+	fields := TrayFields(tray)
+	assert.Equal(t, int64(42), fields[WorkflowRunIdKey])
+	assert.Equal(t, "org/repo", fields[RepositoryKey])
+}
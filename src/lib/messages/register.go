@@ -7,6 +7,9 @@ import (
 type RegisterResponse struct {
 	Agent     agents.Agent `json:"agent"`
 	JitConfig string       `json:"jit_config"`
+	// TraceParent is the trace context the agent parents its spans on, so
+	// they join the trace of its tray. Empty when the server does not trace.
+	TraceParent string `json:"trace_parent,omitempty"`
 }
 
 type UnregisterRequest struct {
@@ -25,6 +28,21 @@ const (
 	UnregisterReasonControllerKill
 )
 
+func (r UnregisterReason) String() string {
+	switch r {
+	case UnregisterReasonDone:
+		return "done"
+	case UnregisterReasonPreempted:
+		return "preempted"
+	case UnregisterReasonSigTerm:
+		return "sigterm"
+	case UnregisterReasonControllerKill:
+		return "controller-kill"
+	default:
+		return "unknown"
+	}
+}
+
 type PingResponse struct {
 	Terminate bool   `json:"terminate"`
 	Message   string `json:"message"`
@@ -1,6 +1,7 @@
 package metrics
 
 import (
+	"cattery/lib/election"
 	"cattery/lib/trays"
 	"context"
 	"time"
@@ -10,11 +11,24 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// provisioningBuckets span one second to about half an hour; lifetimeBuckets
+// span ten seconds to about six hours.
+var (
+	provisioningBuckets = prometheus.ExponentialBuckets(1, 2, 12)
+	lifetimeBuckets     = prometheus.ExponentialBuckets(10, 2, 12)
+)
+
 // TrayLister is the subset of TrayManager needed by the metrics collector.
 type TrayLister interface {
 	ListTrays(ctx context.Context) ([]*trays.Tray, error)
 }
 
+// LeadershipLister is the subset of an election.Elector needed by the leader
+// collector.
+type LeadershipLister interface {
+	Leadership() []election.Leadership
+}
+
 var (
 	// Counters
 
@@ -33,13 +47,76 @@ var (
 		Help: "Number of provider errors during tray operations",
 	}, []string{"org", "provider", "tray_type", "operation_type"})
 
+	trayProviderFallbacks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cattery_tray_provider_fallbacks",
+		Help: "Number of trays moved off a fallback member provider because it ran out of capacity",
+	}, []string{"org", "provider", "tray_type"})
+
+	orphanedTraysDeleted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cattery_orphaned_trays_deleted",
+		Help: "Number of upstream resources deleted by the reconciler because they had no tray row",
+	}, []string{"org", "provider", "tray_type"})
+
+	trayQuotaLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cattery_tray_quota_limited",
+		Help: "Number of tray creations skipped because a quota was reached",
+	}, []string{"quota", "tray_type"})
+
 	scaleSetPollErrors = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "cattery_scaleset_poll_errors",
 		Help: "Number of scale set polling errors",
 	}, []string{"org", "tray_type"})
 
+	// Histograms
+
+	trayStartDeployDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cattery_tray_start_deploy_seconds",
+		Help:    "Time the provider took to accept a tray's create request (StartDeploy)",
+		Buckets: provisioningBuckets,
+	}, []string{"org", "provider", "tray_type"})
+
+	trayWaitDeployDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cattery_tray_wait_deploy_seconds",
+		Help:    "Time the provider took to make a tray's resource ready (WaitDeploy)",
+		Buckets: provisioningBuckets,
+	}, []string{"org", "provider", "tray_type"})
+
+	trayTimeToRegistered = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cattery_tray_time_to_registered_seconds",
+		Help:    "Time from creating a tray until its runner registered with GitHub",
+		Buckets: provisioningBuckets,
+	}, []string{"org", "provider", "tray_type"})
+
+	trayIdleDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cattery_tray_idle_seconds",
+		Help:    "Time a registered tray waited for a job",
+		Buckets: lifetimeBuckets,
+	}, []string{"org", "provider", "tray_type"})
+
+	trayRunningDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cattery_tray_running_seconds",
+		Help:    "Time from a tray taking a job until it started deleting",
+		Buckets: lifetimeBuckets,
+	}, []string{"org", "provider", "tray_type"})
+
+	trayDeleteDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cattery_tray_delete_seconds",
+		Help:    "Time from a tray starting to delete until its resource was cleaned up, including retries",
+		Buckets: provisioningBuckets,
+	}, []string{"org", "provider", "tray_type"})
+
 	// Gauges
 
+	breakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cattery_circuit_breaker_state",
+		Help: "State of the tray creation circuit breaker: 0 closed, 1 half-open, 2 open",
+	}, []string{"provider", "tray_type"})
+
+	breakerFailures = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cattery_circuit_breaker_consecutive_failures",
+		Help: "Number of consecutive failed tray creations counted by the circuit breaker",
+	}, []string{"provider", "tray_type"})
+
 	scaleSetPendingJobs = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "cattery_scaleset_pending_jobs",
 		Help: "Number of available (queued) jobs reported by scale set statistics",
@@ -70,11 +147,22 @@ var (
 		Help: "Number of registered runners reported by scale set statistics",
 	}, []string{"org", "tray_type"})
 
+	orphanedTrays = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cattery_orphaned_trays",
+		Help: "Number of upstream resources without a tray row past the reconciler's grace period, as of its last run",
+	}, []string{"org", "provider", "tray_type"})
+
 	registeredTraysDesc = prometheus.NewDesc(
 		"cattery_registered_trays",
 		"Number of currently registered trays",
 		[]string{"org", "tray_type"}, nil,
 	)
+
+	leaderDesc = prometheus.NewDesc(
+		"cattery_leader",
+		"1 if this replica holds the tray type's lease and runs its scale set poller, else 0",
+		[]string{"tray_type"}, nil,
+	)
 )
 
 // StaleTrays
@@ -103,6 +191,64 @@ func TrayProviderErrors(org string, provider, trayType string, operationType str
 	trayProviderErrors.WithLabelValues(org, provider, trayType, operationType).Inc()
 }
 
+// TrayProviderFallbacks
+
+func TrayProviderFallbacksInc(org string, provider string, trayType string) {
+	trayProviderFallbacks.WithLabelValues(org, provider, trayType).Inc()
+}
+
+// TrayQuotaLimited
+
+func TrayQuotaLimitedInc(quota string, trayType string) {
+	trayQuotaLimited.WithLabelValues(quota, trayType).Inc()
+}
+
+// CircuitBreaker
+
+func BreakerStateSet(provider string, trayType string, state int) {
+	breakerState.WithLabelValues(provider, trayType).Set(float64(state))
+}
+
+func BreakerFailuresSet(provider string, trayType string, count int) {
+	breakerFailures.WithLabelValues(provider, trayType).Set(float64(count))
+}
+
+// Tray lifecycle durations
+
+func TrayStartDeployObserve(org string, provider string, trayType string, d time.Duration) {
+	trayStartDeployDuration.WithLabelValues(org, provider, trayType).Observe(d.Seconds())
+}
+
+func TrayWaitDeployObserve(org string, provider string, trayType string, d time.Duration) {
+	trayWaitDeployDuration.WithLabelValues(org, provider, trayType).Observe(d.Seconds())
+}
+
+func TrayTimeToRegisteredObserve(org string, provider string, trayType string, d time.Duration) {
+	trayTimeToRegistered.WithLabelValues(org, provider, trayType).Observe(d.Seconds())
+}
+
+func TrayIdleObserve(org string, provider string, trayType string, d time.Duration) {
+	trayIdleDuration.WithLabelValues(org, provider, trayType).Observe(d.Seconds())
+}
+
+func TrayRunningObserve(org string, provider string, trayType string, d time.Duration) {
+	trayRunningDuration.WithLabelValues(org, provider, trayType).Observe(d.Seconds())
+}
+
+func TrayDeleteObserve(org string, provider string, trayType string, d time.Duration) {
+	trayDeleteDuration.WithLabelValues(org, provider, trayType).Observe(d.Seconds())
+}
+
+// OrphanedTrays
+
+func OrphanedTraysSet(org string, provider string, trayType string, count int) {
+	orphanedTrays.WithLabelValues(org, provider, trayType).Set(float64(count))
+}
+
+func OrphanedTraysDeletedInc(org string, provider string, trayType string) {
+	orphanedTraysDeleted.WithLabelValues(org, provider, trayType).Inc()
+}
+
 // ScaleSet metrics
 
 func ScaleSetPollErrorsInc(org string, trayType string) {
@@ -168,3 +314,28 @@ func (c *trayCollector) Collect(ch chan<- prometheus.Metric) {
 func RegisterTrayCollector(lister TrayLister) {
 	prometheus.MustRegister(&trayCollector{lister: lister})
 }
+
+// leaderCollector reports the elector's leadership on each Prometheus scrape,
+// so tray types removed from the config drop out.
+type leaderCollector struct {
+	lister LeadershipLister
+}
+
+func (c *leaderCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- leaderDesc
+}
+
+func (c *leaderCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, l := range c.lister.Leadership() {
+		value := 0.0
+		if l.Leader {
+			value = 1
+		}
+		ch <- prometheus.MustNewConstMetric(leaderDesc, prometheus.GaugeValue, value, l.Key)
+	}
+}
+
+// RegisterLeaderCollector registers the per tray type leadership collector.
+func RegisterLeaderCollector(lister LeadershipLister) {
+	prometheus.MustRegister(&leaderCollector{lister: lister})
+}
@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations_Embedded(t *testing.T) {
+	migrations, err := loadMigrations(migrationFiles)
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	assert.Equal(t, 1, migrations[0].version)
+	assert.Contains(t, migrations[0].sql, "CREATE TABLE trays")
+}
+
+func TestLoadMigrations_SortsByVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0010_later.sql":  {Data: []byte("SELECT 10")},
+		"migrations/0002_second.sql": {Data: []byte("SELECT 2")},
+		"migrations/0001_first.sql":  {Data: []byte("SELECT 1")},
+		"migrations/README.md":       {Data: []byte("ignored")},
+	}
+
+	migrations, err := loadMigrations(fsys)
+	require.NoError(t, err)
+
+	var versions []int
+	for _, m := range migrations {
+		versions = append(versions, m.version)
+	}
+	assert.Equal(t, []int{1, 2, 10}, versions)
+}
+
+func TestLoadMigrations_RejectsBadNames(t *testing.T) {
+	_, err := loadMigrations(fstest.MapFS{
+		"migrations/init.sql": {Data: []byte("SELECT 1")},
+	})
+	assert.Error(t, err)
+
+	_, err = loadMigrations(fstest.MapFS{
+		"migrations/0001_a.sql": {Data: []byte("SELECT 1")},
+		"migrations/1_b.sql":    {Data: []byte("SELECT 1")},
+	})
+	assert.Error(t, err, "duplicate versions must be rejected")
+}
+
+func TestPendingMigrations(t *testing.T) {
+	migrations := []migration{{version: 1}, {version: 2}, {version: 3}}
+
+	pending := pendingMigrations(migrations, []int{1, 3})
+	require.Len(t, pending, 1)
+	assert.Equal(t, 2, pending[0].version)
+
+	assert.Empty(t, pendingMigrations(migrations, []int{1, 2, 3}))
+	assert.Len(t, pendingMigrations(migrations, nil), 3)
+}
@@ -0,0 +1,31 @@
+CREATE TABLE trays (
+    id               text        PRIMARY KEY,
+    tray_type_name   text        NOT NULL,
+    provider_name    text        NOT NULL DEFAULT '',
+    github_org_name  text        NOT NULL DEFAULT '',
+    github_runner_id bigint      NOT NULL DEFAULT 0,
+    job_run_id       bigint      NOT NULL DEFAULT 0,
+    job_name         text        NOT NULL DEFAULT '',
+    workflow_run_id  bigint      NOT NULL DEFAULT 0,
+    workflow_name    text        NOT NULL DEFAULT '',
+    repository       text        NOT NULL DEFAULT '',
+    status           smallint    NOT NULL,
+    status_changed   timestamptz NOT NULL,
+    provider_data    jsonb       NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX trays_tray_type_status_idx ON trays (tray_type_name, status, status_changed);
+CREATE INDEX trays_status_changed_idx ON trays (status, status_changed);
+
+CREATE TABLE restart_requests (
+    workflow_run_id bigint      PRIMARY KEY,
+    org_name        text        NOT NULL,
+    repo_name       text        NOT NULL,
+    created_at      timestamptz NOT NULL
+);
+
+CREATE TABLE leases (
+    key        text        PRIMARY KEY,
+    holder     text        NOT NULL,
+    expires_at timestamptz NOT NULL
+);
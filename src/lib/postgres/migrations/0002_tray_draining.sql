@@ -0,0 +1 @@
+ALTER TABLE trays ADD COLUMN draining boolean NOT NULL DEFAULT false;
@@ -0,0 +1,5 @@
+CREATE TABLE tray_type_pauses (
+    tray_type_name text        PRIMARY KEY,
+    delete_idle    boolean     NOT NULL DEFAULT false,
+    paused_at      timestamptz NOT NULL
+);
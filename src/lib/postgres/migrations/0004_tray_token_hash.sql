@@ -0,0 +1 @@
+ALTER TABLE trays ADD COLUMN token_hash text NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE trays ADD COLUMN weight integer NOT NULL DEFAULT 0;
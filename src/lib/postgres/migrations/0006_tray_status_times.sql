@@ -0,0 +1,6 @@
+ALTER TABLE trays
+    ADD COLUMN created_at     timestamptz,
+    ADD COLUMN registering_at timestamptz,
+    ADD COLUMN registered_at  timestamptz,
+    ADD COLUMN running_at     timestamptz,
+    ADD COLUMN deleting_at    timestamptz;
@@ -0,0 +1,11 @@
+CREATE TABLE tray_events (
+    id             bigserial   PRIMARY KEY,
+    tray_id        text        NOT NULL,
+    tray_type_name text        NOT NULL,
+    kind           text        NOT NULL,
+    message        text        NOT NULL DEFAULT '',
+    time           timestamptz NOT NULL
+);
+
+CREATE INDEX tray_events_tray_id_idx ON tray_events (tray_id, id);
+CREATE INDEX tray_events_time_idx ON tray_events (time);
@@ -0,0 +1 @@
+ALTER TABLE trays ADD COLUMN trace_parent text NOT NULL DEFAULT '';
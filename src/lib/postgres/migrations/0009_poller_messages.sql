@@ -0,0 +1,17 @@
+CREATE TABLE poller_messages (
+    id               bigserial   PRIMARY KEY,
+    time             timestamptz NOT NULL,
+    kind             text        NOT NULL,
+    tray_type_name   text        NOT NULL,
+    repository       text        NOT NULL DEFAULT '',
+    workflow_run_id  bigint      NOT NULL DEFAULT 0,
+    job_id           bigint      NOT NULL DEFAULT 0,
+    job_display_name text        NOT NULL DEFAULT '',
+    runner_name      text        NOT NULL DEFAULT '',
+    result           text        NOT NULL DEFAULT '',
+    desired_count    integer     NOT NULL DEFAULT 0,
+    stats            jsonb
+);
+
+CREATE INDEX poller_messages_time_idx ON poller_messages (time DESC, id DESC);
+CREATE INDEX poller_messages_tray_type_idx ON poller_messages (tray_type_name, time DESC);
@@ -0,0 +1,27 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Connect opens a connection pool for uri (a libpq URL or key/value DSN) and
+// pings it, so a bad URI or unreachable server fails at startup rather than on
+// the first query.
+func Connect(ctx context.Context, uri string) (*pgxpool.Pool, error) {
+	pool, err := pgxpool.New(ctx, uri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse postgres uri: %w", err)
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	if err := pool.Ping(pingCtx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+	return pool, nil
+}
@@ -0,0 +1,71 @@
+package repositories
+
+import (
+	"cattery/lib/bolt"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// BoltRestarterRepository stores restart requests in the restarters bucket of
+// a local bbolt file, keyed by the big-endian workflow run id.
+type BoltRestarterRepository struct {
+	db *bbolt.DB
+}
+
+func NewBoltRestarterRepository() *BoltRestarterRepository {
+	return &BoltRestarterRepository{}
+}
+
+func (b *BoltRestarterRepository) Connect(db *bbolt.DB) {
+	b.db = db
+}
+
+func restartRequestKey(workflowRunId int64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(workflowRunId))
+	return key
+}
+
+func (b *BoltRestarterRepository) SaveRestartRequest(_ context.Context, workflowRunId int64, orgName string, repoName string) error {
+	data, err := json.Marshal(RestartRequest{
+		WorkflowRunId: workflowRunId,
+		OrgName:       orgName,
+		RepoName:      repoName,
+		CreatedAt:     time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bolt.RestartersBucket).Put(restartRequestKey(workflowRunId), data)
+	})
+}
+
+func (b *BoltRestarterRepository) DeleteRestartRequest(_ context.Context, workflowRunId int64) error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bolt.RestartersBucket).Delete(restartRequestKey(workflowRunId))
+	})
+}
+
+func (b *BoltRestarterRepository) GetAllPendingRestartRequests(_ context.Context) ([]RestartRequest, error) {
+	var requests []RestartRequest
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bolt.RestartersBucket).ForEach(func(_, data []byte) error {
+			var request RestartRequest
+			if err := json.Unmarshal(data, &request); err != nil {
+				return err
+			}
+			requests = append(requests, request)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
@@ -0,0 +1,45 @@
+package repositories
+
+import (
+	"cattery/lib/bolt"
+	"context"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoltRestarterRepository(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "cattery.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	repo := NewBoltRestarterRepository()
+	repo.Connect(db)
+	ctx := context.Background()
+
+	requests, err := repo.GetAllPendingRestartRequests(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, requests)
+
+	require.NoError(t, repo.SaveRestartRequest(ctx, 1, "org", "repo-a"))
+	require.NoError(t, repo.SaveRestartRequest(ctx, 2, "org", "repo-b"))
+	// saving the same run again overwrites it
+	require.NoError(t, repo.SaveRestartRequest(ctx, 1, "org", "repo-c"))
+
+	requests, err = repo.GetAllPendingRestartRequests(ctx)
+	require.NoError(t, err)
+	require.Len(t, requests, 2)
+	sort.Slice(requests, func(i, j int) bool { return requests[i].WorkflowRunId < requests[j].WorkflowRunId })
+	assert.Equal(t, "repo-c", requests[0].RepoName)
+	assert.Equal(t, "repo-b", requests[1].RepoName)
+	assert.False(t, requests[0].CreatedAt.IsZero())
+
+	require.NoError(t, repo.DeleteRestartRequest(ctx, 1))
+	requests, err = repo.GetAllPendingRestartRequests(ctx)
+	require.NoError(t, err)
+	require.Len(t, requests, 1)
+	assert.Equal(t, int64(2), requests[0].WorkflowRunId)
+}
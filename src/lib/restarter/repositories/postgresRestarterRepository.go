@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PostgresRestarterRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresRestarterRepository() *PostgresRestarterRepository {
+	return &PostgresRestarterRepository{}
+}
+
+func (p *PostgresRestarterRepository) Connect(pool *pgxpool.Pool) {
+	p.pool = pool
+}
+
+func (p *PostgresRestarterRepository) SaveRestartRequest(ctx context.Context, workflowRunId int64, orgName string, repoName string) error {
+	_, err := p.pool.Exec(ctx, `INSERT INTO restart_requests (workflow_run_id, org_name, repo_name, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (workflow_run_id) DO UPDATE SET
+			org_name   = excluded.org_name,
+			repo_name  = excluded.repo_name,
+			created_at = excluded.created_at`,
+		workflowRunId, orgName, repoName, time.Now().UTC())
+	return err
+}
+
+func (p *PostgresRestarterRepository) DeleteRestartRequest(ctx context.Context, workflowRunId int64) error {
+	_, err := p.pool.Exec(ctx, `DELETE FROM restart_requests WHERE workflow_run_id = $1`, workflowRunId)
+	return err
+}
+
+func (p *PostgresRestarterRepository) GetAllPendingRestartRequests(ctx context.Context) ([]RestartRequest, error) {
+	rows, err := p.pool.Query(ctx, `SELECT workflow_run_id, org_name, repo_name, created_at FROM restart_requests`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (RestartRequest, error) {
+		var r RestartRequest
+		err := row.Scan(&r.WorkflowRunId, &r.OrgName, &r.RepoName, &r.CreatedAt)
+		r.CreatedAt = r.CreatedAt.UTC()
+		return r, err
+	})
+}
@@ -2,6 +2,7 @@ package restarter
 
 import (
 	"cattery/lib/githubClient"
+	"cattery/lib/logging"
 	"cattery/lib/restarter/repositories"
 	"context"
 	"time"
@@ -9,6 +10,8 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+var logger = logging.Logger("restarter")
+
 type WorkflowRestarter struct {
 	repository repositories.RestarterRepository
 	// newGithubClient is overridable so tests can point the client at a fake
@@ -24,7 +27,11 @@ func NewWorkflowRestarter(repository repositories.RestarterRepository) *Workflow
 }
 
 func (wr *WorkflowRestarter) RequestRestart(ctx context.Context, workflowRunId int64, orgName string, repoName string) error {
-	log.Debugf("Requesting restart for workflow run id %d (%s/%s)", workflowRunId, orgName, repoName)
+	logger.WithFields(log.Fields{
+		logging.OrgKey:           orgName,
+		logging.RepositoryKey:    repoName,
+		logging.WorkflowRunIdKey: workflowRunId,
+	}).Debugf("Requesting restart for workflow run id %d (%s/%s)", workflowRunId, orgName, repoName)
 	return wr.repository.SaveRestartRequest(ctx, workflowRunId, orgName, repoName)
 }
 
@@ -34,8 +41,6 @@ func (wr *WorkflowRestarter) StartPoller(ctx context.Context) {
 	const pollInterval = 30 * time.Second
 	const requestTTL = 1 * time.Hour
 
-	logger := log.WithField("component", "restarterPoller")
-
 	go func() {
 		for {
 			select {
@@ -68,7 +73,11 @@ func (wr *WorkflowRestarter) pollPendingRestarts(ctx context.Context, logger *lo
 			continue
 		}
 
-		wr.handleRestartRequest(ctx, logger, req)
+		wr.handleRestartRequest(ctx, logger.WithFields(log.Fields{
+			logging.OrgKey:           req.OrgName,
+			logging.RepositoryKey:    req.RepoName,
+			logging.WorkflowRunIdKey: req.WorkflowRunId,
+		}), req)
 	}
 }
 
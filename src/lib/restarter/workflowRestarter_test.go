@@ -70,10 +70,12 @@ type fakeGithubAPI struct {
 
 	prCalls  int
 	restarts int
+	paths    []string
 }
 
 func (f *fakeGithubAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
+	f.paths = append(f.paths, r.URL.Path+"?"+r.URL.RawQuery)
 	switch {
 	case strings.HasSuffix(r.URL.Path, "/rerun-failed-jobs"):
 		f.restarts++
@@ -361,3 +363,24 @@ func TestNewWorkflowRestarter(t *testing.T) {
 	wr := NewWorkflowRestarter(repo)
 	assert.NotNil(t, wr)
 }
+
+func TestHandleRestartRequest_FullRepoName(t *testing.T) {
+	repo := &mockRestarterRepository{}
+	api := &fakeGithubAPI{
+		runJSON: runJSON("completed", "failure", "feature", "pull_request"),
+		prsJSON: prListJSON(),
+	}
+	wr := newTestRestarter(t, repo, api)
+
+	req := testRequest()
+	req.RepoName = "other-org/repo"
+	wr.handleRestartRequest(context.Background(), log.WithField("test", true), req)
+
+	assert.Equal(t, 1, api.restarts)
+	if assert.Len(t, api.paths, 3) {
+		assert.Equal(t, "/repos/other-org/repo/actions/runs/42?", api.paths[0])
+		assert.Contains(t, api.paths[1], "/repos/other-org/repo/pulls?")
+		assert.Contains(t, api.paths[1], "head=other-org%3Afeature")
+		assert.Equal(t, "/repos/other-org/repo/actions/runs/42/rerun-failed-jobs?", api.paths[2])
+	}
+}
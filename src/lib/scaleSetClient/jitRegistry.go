@@ -3,8 +3,8 @@ package scaleSetClient
 import "sync"
 
 // JitRegistry maps a tray type name to its JitConfigGenerator. It is populated
-// once at startup (one entry per tray type) and read by the agent HTTP handlers
-// on every replica.
+// at startup (one entry per tray type), updated on config reloads and read by
+// the agent HTTP handlers on every replica.
 //
 // It decouples tray registration from leadership: generating a JIT runner
 // config is a sessionless GitHub call, so any replica can serve it regardless
@@ -32,3 +32,10 @@ func (r *JitRegistry) Get(trayTypeName string) JitConfigGenerator {
 	defer r.mu.RUnlock()
 	return r.gens[trayTypeName]
 }
+
+// Unregister removes the generator of a tray type.
+func (r *JitRegistry) Unregister(trayTypeName string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.gens, trayTypeName)
+}
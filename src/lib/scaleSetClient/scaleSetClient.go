@@ -2,9 +2,11 @@ package scaleSetClient
 
 import (
 	"cattery/lib/config"
+	"cattery/lib/logging"
 	"context"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -27,26 +29,25 @@ type ScaleSetClient struct {
 }
 
 func NewScaleSetClient(org *config.GitHubOrganization, trayType *config.TrayType) (*ScaleSetClient, error) {
-	privateKey, err := os.ReadFile(org.PrivateKeyPath)
+	privateKey, err := org.PrivateKeyPEM()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read private key: %w", err)
+		return nil, err
 	}
 
-	logger := log.WithFields(log.Fields{
-		"component": "scaleSetClient",
-		"trayType":  trayType.Name,
-		"org":       org.Name,
+	logger := logging.Logger("scaleSetClient").WithFields(log.Fields{
+		logging.TrayTypeKey: trayType.Name,
+		logging.OrgKey:      org.Name,
 	})
 
 	client, err := scaleset.NewClientWithGitHubApp(scaleset.ClientWithGitHubAppConfig{
-		GitHubConfigURL: fmt.Sprintf("https://github.com/%s", org.Name),
+		GitHubConfigURL: trayType.ScaleSetURL(org),
 		GitHubAppAuth: scaleset.GitHubAppAuth{
 			ClientID:       org.AppClientId,
 			InstallationID: org.InstallationId,
 			PrivateKey:     string(privateKey),
 		},
 	},
-		scaleset.WithLogger(newSlogLogger(logger)),
+		scaleset.WithLogger(NewSlogLogger(logger)),
 		scaleset.WithRetryableHTTPClint(newRetryableClient(logger)),
 	)
 	if err != nil {
@@ -65,6 +66,9 @@ func NewScaleSetClient(org *config.GitHubOrganization, trayType *config.TrayType
 // is idempotent and safe for concurrent callers: the first caller resolves the
 // scale set, the rest return immediately. This lets the sessionless JIT path
 // and the leader's poller share one client without racing on scaleSet.
+//
+// An existing scale set whose labels differ from the tray type's is updated,
+// so label changes in the config reach GitHub.
 func (sc *ScaleSetClient) EnsureScaleSet(ctx context.Context) error {
 	sc.mu.Lock()
 	defer sc.mu.Unlock()
@@ -73,24 +77,36 @@ func (sc *ScaleSetClient) EnsureScaleSet(ctx context.Context) error {
 		return nil
 	}
 
-	existing, err := sc.client.GetRunnerScaleSet(ctx, int(sc.trayType.RunnerGroupId), sc.trayType.Name)
+	runnerGroupID, err := sc.runnerGroupID(ctx)
+	if err != nil {
+		return err
+	}
+	desired := &scaleset.RunnerScaleSet{
+		Name:          sc.trayType.Name,
+		RunnerGroupID: runnerGroupID,
+		Labels:        scaleSetLabels(sc.trayType),
+	}
+
+	existing, err := sc.client.GetRunnerScaleSet(ctx, runnerGroupID, sc.trayType.Name)
 	if err != nil {
 		return fmt.Errorf("failed to get scale set: %w", err)
 	}
 	if existing != nil {
-		sc.scaleSet = existing
 		sc.logger.Infof("Found existing scale set: %s (ID: %d)", existing.Name, existing.ID)
+		if !labelsMatch(existing.Labels, desired.Labels) {
+			sc.logger.Infof("Updating labels of scale set %s to %v", existing.Name, sc.trayType.ScaleSetLabels())
+			updated, err := sc.client.UpdateRunnerScaleSet(ctx, existing.ID, desired)
+			if err != nil {
+				return fmt.Errorf("failed to update scale set labels: %w", err)
+			}
+			existing = updated
+		}
+		sc.scaleSet = existing
 		return nil
 	}
 
 	sc.logger.Infof("Creating new scale set: %s", sc.trayType.Name)
-	created, err := sc.client.CreateRunnerScaleSet(ctx, &scaleset.RunnerScaleSet{
-		Name:          sc.trayType.Name,
-		RunnerGroupID: int(sc.trayType.RunnerGroupId),
-		Labels: []scaleset.Label{
-			{Name: sc.trayType.Name, Type: "User"},
-		},
-	})
+	created, err := sc.client.CreateRunnerScaleSet(ctx, desired)
 	if err != nil {
 		return fmt.Errorf("failed to create scale set: %w", err)
 	}
@@ -100,6 +116,41 @@ func (sc *ScaleSetClient) EnsureScaleSet(ctx context.Context) error {
 	return nil
 }
 
+// runnerGroupID returns the id of the tray type's runner group, looking it up
+// by name if it is configured by name.
+func (sc *ScaleSetClient) runnerGroupID(ctx context.Context) (int, error) {
+	if sc.trayType.RunnerGroup == "" {
+		return int(sc.trayType.ScaleSetRunnerGroupId()), nil
+	}
+	group, err := sc.client.GetRunnerGroupByName(ctx, sc.trayType.RunnerGroup)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get runner group %s: %w", sc.trayType.RunnerGroup, err)
+	}
+	return group.ID, nil
+}
+
+func scaleSetLabels(trayType *config.TrayType) []scaleset.Label {
+	var labels []scaleset.Label
+	for _, name := range trayType.ScaleSetLabels() {
+		labels = append(labels, scaleset.Label{Name: name, Type: "User"})
+	}
+	return labels
+}
+
+// labelsMatch reports whether two label lists have the same names, ignoring
+// order and case as GitHub does when matching runs-on.
+func labelsMatch(a, b []scaleset.Label) bool {
+	names := func(labels []scaleset.Label) []string {
+		out := make([]string, 0, len(labels))
+		for _, label := range labels {
+			out = append(out, strings.ToLower(label.Name))
+		}
+		slices.Sort(out)
+		return slices.Compact(out)
+	}
+	return slices.Equal(names(a), names(b))
+}
+
 func (sc *ScaleSetClient) CreateSession(ctx context.Context) error {
 	hostname, _ := os.Hostname()
 
@@ -108,7 +159,7 @@ func (sc *ScaleSetClient) CreateSession(ctx context.Context) error {
 
 	for attempt := range maxRetries {
 		session, err := sc.client.MessageSessionClient(ctx, sc.scaleSet.ID, hostname,
-			scaleset.WithLogger(newSlogLogger(sc.logger)),
+			scaleset.WithLogger(NewSlogLogger(sc.logger)),
 			scaleset.WithRetryableHTTPClint(newRetryableClient(sc.logger)),
 		)
 		if err == nil {
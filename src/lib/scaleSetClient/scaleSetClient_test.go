@@ -0,0 +1,34 @@
+package scaleSetClient
+
+import (
+	"cattery/lib/config"
+	"testing"
+
+	"github.com/actions/scaleset"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScaleSetLabels(t *testing.T) {
+	trayType := &config.TrayType{Name: "linux-16cpu", Labels: []string{"linux", "x64", "linux-16cpu"}}
+
+	assert.Equal(t, []scaleset.Label{
+		{Name: "linux-16cpu", Type: "User"},
+		{Name: "linux", Type: "User"},
+		{Name: "x64", Type: "User"},
+	}, scaleSetLabels(trayType))
+}
+
+func TestLabelsMatch(t *testing.T) {
+	labels := func(names ...string) []scaleset.Label {
+		var out []scaleset.Label
+		for _, name := range names {
+			out = append(out, scaleset.Label{Name: name, Type: "User"})
+		}
+		return out
+	}
+
+	assert.True(t, labelsMatch(labels("linux", "x64"), labels("x64", "Linux")))
+	assert.True(t, labelsMatch(nil, labels()))
+	assert.False(t, labelsMatch(labels("linux"), labels("linux", "x64")))
+	assert.False(t, labelsMatch(labels("linux", "arm64"), labels("linux", "x64")))
+}
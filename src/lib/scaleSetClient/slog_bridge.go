@@ -23,18 +23,21 @@ import (
 // stays in place. *slog.Logger satisfies retryablehttp.LeveledLogger.
 func newRetryableClient(entry *log.Entry) *retryablehttp.Client {
 	rc := retryablehttp.NewClient()
-	rc.Logger = newSlogLogger(entry)
+	rc.Logger = NewSlogLogger(entry)
 	return rc
 }
 
 // logrusHandler bridges slog into logrus so that third-party libraries using
-// slog (e.g. actions/scaleset / go-retryablehttp) respect cattery's log level
-// and emit records in the same format as the rest of the application.
+// slog (e.g. actions/scaleset / go-retryablehttp) respect cattery's logging
+// config — the level of entry's component and the text or JSON format — and
+// carry entry's fields like the rest of the application.
 type logrusHandler struct {
 	entry *log.Entry
 }
 
-func newSlogLogger(entry *log.Entry) *slog.Logger {
+// NewSlogLogger returns a slog.Logger writing through entry, for libraries
+// that take one.
+func NewSlogLogger(entry *log.Entry) *slog.Logger {
 	return slog.New(&logrusHandler{entry: entry})
 }
 
@@ -47,7 +50,7 @@ func (h *logrusHandler) Handle(_ context.Context, r slog.Record) error {
 	if r.NumAttrs() > 0 {
 		fields := make(log.Fields, r.NumAttrs())
 		r.Attrs(func(a slog.Attr) bool {
-			fields[a.Key] = a.Value.Any()
+			fields[a.Key] = a.Value.Resolve().Any()
 			return true
 		})
 		entry = entry.WithFields(fields)
@@ -59,7 +62,7 @@ func (h *logrusHandler) Handle(_ context.Context, r slog.Record) error {
 func (h *logrusHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	fields := make(log.Fields, len(attrs))
 	for _, a := range attrs {
-		fields[a.Key] = a.Value.Any()
+		fields[a.Key] = a.Value.Resolve().Any()
 	}
 	return &logrusHandler{entry: h.entry.WithFields(fields)}
 }
@@ -78,7 +81,9 @@ func slogToLogrus(level slog.Level) log.Level {
 		return log.WarnLevel
 	case level >= slog.LevelInfo:
 		return log.InfoLevel
-	default:
+	case level >= slog.LevelDebug:
 		return log.DebugLevel
+	default:
+		return log.TraceLevel
 	}
 }
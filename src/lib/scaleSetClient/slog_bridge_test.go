@@ -0,0 +1,45 @@
+package scaleSetClient
+
+import (
+	"bytes"
+	"cattery/lib/config"
+	"cattery/lib/logging"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlogLogger_FollowsLoggingConfig(t *testing.T) {
+	std := log.StandardLogger()
+	out, level := std.Out, std.GetLevel()
+	var buf bytes.Buffer
+	std.SetOutput(&buf)
+	t.Cleanup(func() {
+		std.SetOutput(out)
+		require.NoError(t, logging.Configure(config.LoggingConfig{Level: level.String()}))
+	})
+
+	require.NoError(t, logging.Configure(config.LoggingConfig{
+		Format:     config.LogFormatJson,
+		Level:      "debug",
+		Components: map[string]string{"slog-bridge-test": "info"},
+	}))
+	logger := NewSlogLogger(logging.Logger("slog-bridge-test").WithField(logging.TrayTypeKey, "runner"))
+
+	assert.False(t, logger.Enabled(context.Background(), slog.LevelDebug), "the component's level applies")
+	logger.Debug("dropped")
+	logger.Info("performing request", "method", "GET", "attempt", 2)
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &entry), "one JSON line")
+	assert.Equal(t, "performing request", entry["msg"])
+	assert.Equal(t, "slog-bridge-test", entry[logging.ComponentKey])
+	assert.Equal(t, "runner", entry[logging.TrayTypeKey])
+	assert.Equal(t, "GET", entry["method"])
+	assert.Equal(t, float64(2), entry["attempt"])
+}
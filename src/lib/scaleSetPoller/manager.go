@@ -1,7 +1,8 @@
 package scaleSetPoller
 
 import (
-	"sort"
+	"cattery/lib/broadcast"
+	"cattery/lib/scaleSetPoller/repositories"
 	"sync"
 )
 
@@ -9,45 +10,41 @@ type Manager struct {
 	mu      sync.RWMutex
 	pollers map[string]*Poller
 	wg      sync.WaitGroup
+
+	// messageRepository stores the messages of every poller, on any replica.
+	messageRepository repositories.MessageRepository
+	// messages receives the messages of the pollers registered here.
+	messages broadcast.Broadcaster[*repositories.Message]
 }
 
-func NewManager() *Manager {
+func NewManager(messageRepository repositories.MessageRepository) *Manager {
 	return &Manager{
-		pollers: make(map[string]*Poller),
+		pollers:           make(map[string]*Poller),
+		messageRepository: messageRepository,
 	}
 }
 
 func (m *Manager) Register(trayTypeName string, poller *Poller) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	poller.manager = m
 	m.pollers[trayTypeName] = poller
 }
 
+// Unregister removes the poller of a tray type that was removed from the
+// config. Its stored messages are kept until pruned.
+func (m *Manager) Unregister(trayTypeName string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.pollers, trayTypeName)
+}
+
 func (m *Manager) GetPoller(trayTypeName string) *Poller {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 	return m.pollers[trayTypeName]
 }
 
-// MessageHistory returns all recent messages across all pollers, newest first.
-func (m *Manager) MessageHistory() []*Message {
-	m.mu.RLock()
-	pollers := make([]*Poller, 0, len(m.pollers))
-	for _, p := range m.pollers {
-		pollers = append(pollers, p)
-	}
-	m.mu.RUnlock()
-
-	var all []*Message
-	for _, p := range pollers {
-		all = append(all, p.History().Recent()...)
-	}
-	sort.Slice(all, func(i, j int) bool {
-		return all[i].Time.After(all[j].Time)
-	})
-	return all
-}
-
 func (m *Manager) Add(delta int) {
 	m.wg.Add(delta)
 }
@@ -1,18 +1,19 @@
 package scaleSetPoller
 
 import (
+	"cattery/lib/testutil"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestNewManager(t *testing.T) {
-	m := NewManager()
+	m := NewManager(testutil.NewMockMessageRepository())
 	assert.NotNil(t, m)
 }
 
 func TestManagerRegisterAndGet(t *testing.T) {
-	m := NewManager()
+	m := NewManager(testutil.NewMockMessageRepository())
 
 	// A nil poller is fine for testing the registry itself
 	m.Register("type-a", &Poller{})
@@ -23,12 +24,12 @@ func TestManagerRegisterAndGet(t *testing.T) {
 }
 
 func TestManagerGetNonExistent(t *testing.T) {
-	m := NewManager()
+	m := NewManager(testutil.NewMockMessageRepository())
 	assert.Nil(t, m.GetPoller("does-not-exist"))
 }
 
 func TestManagerRegisterOverwrite(t *testing.T) {
-	m := NewManager()
+	m := NewManager(testutil.NewMockMessageRepository())
 
 	poller1 := &Poller{}
 	poller2 := &Poller{}
@@ -39,3 +40,13 @@ func TestManagerRegisterOverwrite(t *testing.T) {
 	result := m.GetPoller("type-a")
 	assert.Same(t, poller2, result)
 }
+
+func TestManagerUnregister(t *testing.T) {
+	m := NewManager(testutil.NewMockMessageRepository())
+	m.Register("type-a", &Poller{})
+
+	m.Unregister("type-a")
+	m.Unregister("type-a")
+
+	assert.Nil(t, m.GetPoller("type-a"))
+}
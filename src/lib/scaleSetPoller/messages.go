@@ -0,0 +1,69 @@
+package scaleSetPoller
+
+import (
+	"cattery/lib/config"
+	"cattery/lib/logging"
+	"cattery/lib/scaleSetPoller/repositories"
+	"context"
+	"time"
+)
+
+var logger = logging.Logger("scaleSetPoller")
+
+// RecordMessage stores a poller's message in the shared message store and
+// streams it to this replica's status page subscribers. The history is for
+// display, so failing to store a message is logged and otherwise ignored.
+func (m *Manager) RecordMessage(ctx context.Context, message *repositories.Message) {
+	if err := m.messageRepository.AddMessage(ctx, message); err != nil {
+		logger.WithField(logging.TrayTypeKey, message.TrayType).Warnf("Failed to record %s message: %v", message.Kind, err)
+	}
+	m.messages.Publish(message)
+}
+
+// MessageHistory returns the stored messages of all pollers matching filter,
+// newest first. Every replica reads the same store, so it includes messages
+// of pollers running on other replicas.
+func (m *Manager) MessageHistory(ctx context.Context, filter repositories.MessageFilter) ([]*repositories.Message, error) {
+	return m.messageRepository.ListMessages(ctx, filter)
+}
+
+// SubscribeMessages returns a channel receiving the messages of the pollers
+// registered on this replica from now on, and a func that unsubscribes it.
+// The channel is closed if the subscriber falls buffer messages behind.
+func (m *Manager) SubscribeMessages(buffer int) (<-chan *repositories.Message, func()) {
+	return m.messages.Subscribe(buffer)
+}
+
+// HandleMessages periodically deletes stored messages older than the
+// configured retention. Every replica prunes; deleting already deleted
+// messages is harmless.
+func (m *Manager) HandleMessages(ctx context.Context) {
+	cfg := config.Get().PollerMessages.WithDefaults()
+
+	logger.Infof("Poller message pruning starting: retention=%s pruneInterval=%s", cfg.Retention, cfg.PruneInterval)
+
+	go func() {
+		ticker := time.NewTicker(cfg.PruneInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				m.pruneMessages(ctx, now.Add(-cfg.Retention))
+			}
+		}
+	}()
+}
+
+func (m *Manager) pruneMessages(ctx context.Context, before time.Time) {
+	pruned, err := m.messageRepository.PruneMessages(ctx, before)
+	if err != nil {
+		logger.Errorf("Failed to prune poller messages: %v", err)
+		return
+	}
+	if pruned > 0 {
+		logger.Debugf("Pruned %d poller messages older than %s", pruned, before.UTC().Format(time.RFC3339))
+	}
+}
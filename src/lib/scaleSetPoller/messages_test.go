@@ -0,0 +1,92 @@
+package scaleSetPoller
+
+import (
+	"cattery/lib/config"
+	"cattery/lib/scaleSetPoller/repositories"
+	"cattery/lib/testutil"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_RecordMessage(t *testing.T) {
+	ctx := context.Background()
+	store := testutil.NewMockMessageRepository()
+	m := NewManager(store)
+
+	base := time.Now().UTC()
+	m.RecordMessage(ctx, &repositories.Message{Time: base.Add(1 * time.Second), Kind: repositories.MessageKindScale, TrayType: "a"})
+	m.RecordMessage(ctx, &repositories.Message{Time: base.Add(2 * time.Second), Kind: repositories.MessageKindJobStarted, TrayType: "b", Repository: "org/repo"})
+	m.RecordMessage(ctx, &repositories.Message{Time: base.Add(3 * time.Second), Kind: repositories.MessageKindScale, TrayType: "a"})
+	require.Len(t, store.Messages, 3)
+
+	got, err := m.MessageHistory(ctx, repositories.MessageFilter{})
+	require.NoError(t, err)
+	require.Len(t, got, 3)
+	for i := 1; i < len(got); i++ {
+		assert.False(t, got[i-1].Time.Before(got[i].Time), "not newest-first at %d", i)
+	}
+
+	got, err = m.MessageHistory(ctx, repositories.MessageFilter{TrayType: "a", Offset: 1, Limit: 5})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, base.Add(1*time.Second), got[0].Time)
+
+	got, err = m.MessageHistory(ctx, repositories.MessageFilter{Repository: "org/repo"})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "b", got[0].TrayType)
+}
+
+func TestManager_MessageHistory_Empty(t *testing.T) {
+	m := NewManager(testutil.NewMockMessageRepository())
+	got, err := m.MessageHistory(context.Background(), repositories.MessageFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestManager_RecordMessageErrorIsIgnored(t *testing.T) {
+	store := testutil.NewMockMessageRepository()
+	store.Err = errors.New("db down")
+	m := NewManager(store)
+
+	messages, stop := m.SubscribeMessages(10)
+	defer stop()
+
+	msg := &repositories.Message{Time: time.Now(), Kind: repositories.MessageKindScale, TrayType: "a"}
+	m.RecordMessage(context.Background(), msg)
+
+	assert.Same(t, msg, <-messages, "the live stream does not depend on the store")
+}
+
+func TestManager_SubscribeMessages(t *testing.T) {
+	m := NewManager(testutil.NewMockMessageRepository())
+	p := &Poller{}
+	m.Register("a", p)
+
+	messages, stop := m.SubscribeMessages(10)
+	defer stop()
+
+	msg := &repositories.Message{Time: time.Now(), Kind: repositories.MessageKindScale, TrayType: "a", DesiredCount: 3}
+	p.recordMessage(context.Background(), msg)
+
+	assert.Same(t, msg, <-messages)
+}
+
+func TestManager_PruneMessages(t *testing.T) {
+	store := testutil.NewMockMessageRepository()
+	m := NewManager(store)
+	now := time.Now().UTC()
+	store.Messages = []*repositories.Message{
+		{Time: now.Add(-2 * config.DefaultPollerMessagesRetention), TrayType: "a"},
+		{Time: now.Add(-time.Minute), TrayType: "a"},
+	}
+
+	m.pruneMessages(context.Background(), now.Add(-config.DefaultPollerMessagesRetention))
+	require.Len(t, store.Messages, 1)
+	assert.Equal(t, now.Add(-time.Minute), store.Messages[0].Time)
+}
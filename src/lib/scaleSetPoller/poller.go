@@ -1,9 +1,13 @@
 package scaleSetPoller
 
 import (
+	"cattery/lib/logging"
 	"cattery/lib/metrics"
 	"cattery/lib/scaleSetClient"
+	"cattery/lib/scaleSetPoller/repositories"
+	"cattery/lib/tracing"
 	"cattery/lib/trayManager"
+	"cattery/lib/trays"
 	"context"
 	"fmt"
 	"path"
@@ -17,14 +21,18 @@ import (
 	"github.com/actions/scaleset"
 	"github.com/actions/scaleset/listener"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Poller struct {
 	client      *scaleSetClient.ScaleSetClient
 	trayType    *config.TrayType
 	trayManager *trayManager.TrayManager
-	history     *History
 	logger      *log.Entry
+
+	// manager records the poller's messages; Manager.Register sets it.
+	manager *Manager
 }
 
 func NewPoller(
@@ -36,16 +44,31 @@ func NewPoller(
 		client:      client,
 		trayType:    trayType,
 		trayManager: tm,
-		history:     &History{},
-		logger: log.WithFields(log.Fields{
-			"component": "scaleSetPoller",
-			"trayType":  trayType.Name,
+		logger: logging.Logger("scaleSetPoller").WithFields(log.Fields{
+			logging.TrayTypeKey: trayType.Name,
+			logging.OrgKey:      trayType.GitHubOrg,
+			logging.ProviderKey: trayType.Provider,
 		}),
 	}
 }
 
-func (p *Poller) History() *History {
-	return p.history
+// TrayType returns the poller's tray type from the current config, so a config
+// reload applies to a running poller without restarting its session. Once the
+// tray type is removed from the config, the one the poller was started with is
+// returned until the poller is stopped.
+func (p *Poller) TrayType() *config.TrayType {
+	if trayType := config.Get().GetTrayType(p.trayType.Name); trayType != nil {
+		return trayType
+	}
+	return p.trayType
+}
+
+// recordMessage records a message of the poller, unless it was never
+// registered with a Manager.
+func (p *Poller) recordMessage(ctx context.Context, message *repositories.Message) {
+	if p.manager != nil {
+		p.manager.RecordMessage(ctx, message)
+	}
 }
 
 func (p *Poller) Run(ctx context.Context) error {
@@ -69,12 +92,14 @@ func (p *Poller) Run(ctx context.Context) error {
 	scaleSetID := p.client.GetScaleSetID()
 
 	scaler := &catteryScaler{poller: p}
+	trayType, _ := p.TrayType().Scheduled(time.Now())
 
 	l, err := listener.New(
 		&sessionAdapter{client: p.client},
 		listener.Config{
 			ScaleSetID: scaleSetID,
-			MaxRunners: p.trayType.MaxTrays,
+			MaxRunners: trayType.MaxTrays,
+			Logger:     scaleSetClient.NewSlogLogger(p.logger),
 		},
 		listener.WithMetricsRecorder(scaler),
 	)
@@ -82,6 +107,8 @@ func (p *Poller) Run(ctx context.Context) error {
 		return fmt.Errorf("failed to create listener: %w", err)
 	}
 
+	scaler.listener = l
+
 	p.logger.Info("Entering listener loop")
 	return l.Run(ctx, scaler)
 }
@@ -110,6 +137,7 @@ func (s *sessionAdapter) Session() scaleset.RunnerScaleSetSession {
 // catteryScaler implements the listener.Scaler and listener.MetricsRecorder interfaces.
 type catteryScaler struct {
 	poller     *Poller
+	listener   *listener.Listener
 	latestStat atomic.Pointer[scaleset.RunnerScaleSetStatistic]
 }
 
@@ -131,10 +159,21 @@ func (cs *catteryScaler) RecordJobStarted(msg *scaleset.JobStarted)     {}
 func (cs *catteryScaler) RecordJobCompleted(msg *scaleset.JobCompleted) {}
 func (cs *catteryScaler) RecordDesiredRunners(count int)                {}
 
-func (cs *catteryScaler) HandleDesiredRunnerCount(ctx context.Context, count int) (int, error) {
-	cs.recordScaleMessage(count)
+func (cs *catteryScaler) HandleDesiredRunnerCount(ctx context.Context, count int) (_ int, err error) {
+	ctx, span := cs.startSpan(ctx, "poller.DesiredRunnerCount", attribute.Int("cattery.desired_count", count))
+	defer func() { tracing.End(span, err) }()
+
+	cs.recordScaleMessage(ctx, count)
 
-	err := cs.poller.trayManager.ScaleForDemand(ctx, cs.poller.trayType, count)
+	// Keep the capacity advertised to GitHub in line with the current config
+	// and the active schedule window.
+	current := cs.poller.TrayType()
+	if cs.listener != nil {
+		trayType, _ := current.Scheduled(time.Now())
+		cs.listener.SetMaxRunners(trayType.MaxTrays)
+	}
+
+	err = cs.poller.trayManager.ScaleForDemand(ctx, current, count)
 	if err != nil {
 		cs.poller.logger.Errorf("Failed to scale for demand (%d): %v", count, err)
 		return 0, err
@@ -143,15 +182,47 @@ func (cs *catteryScaler) HandleDesiredRunnerCount(ctx context.Context, count int
 	return cs.poller.trayManager.CountTrays(ctx, cs.poller.trayType.Name)
 }
 
-func (cs *catteryScaler) recordScaleMessage(count int) {
-	msg := &Message{
-		Time:         time.Now(),
-		Kind:         MessageKindScale,
+// startSpan starts the root span of handling a listener message. Each message
+// starts a trace of its own; the tray operations it leads to link to the
+// trays' traces.
+func (cs *catteryScaler) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs,
+		tracing.TrayTypeKey.String(cs.poller.trayType.Name),
+		tracing.OrgKey.String(cs.poller.trayType.GitHubOrg))
+	return tracing.Start(ctx, name, attrs...)
+}
+
+// jobLogger returns the logger for lines about a job message. The runner
+// name is the id of the tray running the job.
+func (cs *catteryScaler) jobLogger(runnerName string, workflowRunId int64, repository string) *log.Entry {
+	fields := log.Fields{
+		logging.WorkflowRunIdKey: workflowRunId,
+		logging.RepositoryKey:    repository,
+	}
+	if runnerName != "" {
+		fields[logging.TrayIdKey] = runnerName
+	}
+	return cs.poller.logger.WithFields(fields)
+}
+
+func jobAttributes(runnerName string, repository string, workflowRunId int64, jobName string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		tracing.TrayIdKey.String(runnerName),
+		attribute.String("cattery.repository", repository),
+		attribute.Int64("cattery.workflow_run_id", workflowRunId),
+		attribute.String("cattery.job", jobName),
+	}
+}
+
+func (cs *catteryScaler) recordScaleMessage(ctx context.Context, count int) {
+	msg := &repositories.Message{
+		Time:         time.Now().UTC(),
+		Kind:         repositories.MessageKindScale,
 		TrayType:     cs.poller.trayType.Name,
 		DesiredCount: count,
 	}
 	if stats := cs.latestStat.Load(); stats != nil {
-		msg.Stats = &ScaleStats{
+		msg.Stats = &repositories.ScaleStats{
 			Available:  stats.TotalAvailableJobs,
 			Assigned:   stats.TotalAssignedJobs,
 			Running:    stats.TotalRunningJobs,
@@ -160,31 +231,36 @@ func (cs *catteryScaler) recordScaleMessage(count int) {
 			Registered: stats.TotalRegisteredRunners,
 		}
 	}
-	cs.poller.history.Add(msg)
+	cs.poller.recordMessage(ctx, msg)
 }
 
-func (cs *catteryScaler) HandleJobStarted(ctx context.Context, jobInfo *scaleset.JobStarted) error {
-	cs.poller.logger.Infof("Job started: %s on runner %s (workflow run %d)",
-		jobInfo.JobDisplayName, jobInfo.RunnerName, jobInfo.WorkflowRunID)
-
+func (cs *catteryScaler) HandleJobStarted(ctx context.Context, jobInfo *scaleset.JobStarted) (err error) {
 	jobID, _ := strconv.ParseInt(jobInfo.JobID, 10, 64)
 	workflowName := parseWorkflowName(jobInfo.JobWorkflowRef)
 	repo := fullRepoName(jobInfo.OwnerName, jobInfo.RepositoryName)
 
+	logger := cs.jobLogger(jobInfo.RunnerName, jobInfo.WorkflowRunID, repo)
+	logger.Infof("Job started: %s on runner %s (workflow run %d)",
+		jobInfo.JobDisplayName, jobInfo.RunnerName, jobInfo.WorkflowRunID)
+
+	ctx, span := cs.startSpan(ctx, "poller.JobStarted",
+		jobAttributes(jobInfo.RunnerName, repo, jobInfo.WorkflowRunID, jobInfo.JobDisplayName)...)
+	defer func() { tracing.End(span, err) }()
+
 	tray, err := cs.poller.trayManager.SetJob(ctx, jobInfo.RunnerName, jobID, jobInfo.WorkflowRunID, repo, jobInfo.JobDisplayName, workflowName)
 	if err != nil {
-		cs.poller.logger.Errorf("Failed to set job on tray %s: %v", jobInfo.RunnerName, err)
+		logger.Errorf("Failed to set job on tray %s: %v", jobInfo.RunnerName, err)
 		return err
 	}
 
 	if tray == nil {
-		cs.poller.logger.Warnf("Tray %s not found for job %s (workflow run %d) — tray already removed",
+		logger.Warnf("Tray %s not found for job %s (workflow run %d) — tray already removed",
 			jobInfo.RunnerName, jobInfo.JobDisplayName, jobInfo.WorkflowRunID)
 	}
 
-	cs.poller.history.Add(&Message{
-		Time:           time.Now(),
-		Kind:           MessageKindJobStarted,
+	cs.poller.recordMessage(ctx, &repositories.Message{
+		Time:           time.Now().UTC(),
+		Kind:           repositories.MessageKindJobStarted,
 		TrayType:       cs.poller.trayType.Name,
 		Repository:     repo,
 		WorkflowRunID:  jobInfo.WorkflowRunID,
@@ -196,26 +272,35 @@ func (cs *catteryScaler) HandleJobStarted(ctx context.Context, jobInfo *scaleset
 	return nil
 }
 
-func (cs *catteryScaler) HandleJobCompleted(ctx context.Context, jobInfo *scaleset.JobCompleted) error {
-	cs.poller.logger.Infof("Job completed: %s on runner %s (result: %s)",
+func (cs *catteryScaler) HandleJobCompleted(ctx context.Context, jobInfo *scaleset.JobCompleted) (err error) {
+	logger := cs.jobLogger(jobInfo.RunnerName, jobInfo.WorkflowRunID, fullRepoName(jobInfo.OwnerName, jobInfo.RepositoryName))
+	logger.Infof("Job completed: %s on runner %s (result: %s)",
 		jobInfo.JobDisplayName, jobInfo.RunnerName, jobInfo.Result)
 
+	ctx, span := cs.startSpan(ctx, "poller.JobCompleted",
+		append(jobAttributes(jobInfo.RunnerName, fullRepoName(jobInfo.OwnerName, jobInfo.RepositoryName), jobInfo.WorkflowRunID, jobInfo.JobDisplayName),
+			attribute.String("cattery.job_result", jobInfo.Result))...)
+	defer func() { tracing.End(span, err) }()
+
 	if jobInfo.RunnerName == "" {
-		cs.poller.logger.Warnf("Job completed with empty runner name (result: %s, job: %s) — skipping tray deletion",
+		logger.Warnf("Job completed with empty runner name (result: %s, job: %s) — skipping tray deletion",
 			jobInfo.Result, jobInfo.JobDisplayName)
 		return nil
 	}
 
-	_, err := cs.poller.trayManager.DeleteTray(ctx, jobInfo.RunnerName)
+	cs.poller.trayManager.RecordEvent(ctx, jobInfo.RunnerName, cs.poller.trayType.Name, trays.TrayEventJobCompleted,
+		fmt.Sprintf("%s (result: %s)", jobInfo.JobDisplayName, jobInfo.Result))
+
+	_, err = cs.poller.trayManager.DeleteTray(ctx, jobInfo.RunnerName)
 	if err != nil {
-		cs.poller.logger.Errorf("Failed to delete tray %s: %v", jobInfo.RunnerName, err)
+		logger.Errorf("Failed to delete tray %s: %v", jobInfo.RunnerName, err)
 		return err
 	}
 
 	jobID, _ := strconv.ParseInt(jobInfo.JobID, 10, 64)
-	cs.poller.history.Add(&Message{
-		Time:           time.Now(),
-		Kind:           MessageKindJobCompleted,
+	cs.poller.recordMessage(ctx, &repositories.Message{
+		Time:           time.Now().UTC(),
+		Kind:           repositories.MessageKindJobCompleted,
 		TrayType:       cs.poller.trayType.Name,
 		Repository:     fullRepoName(jobInfo.OwnerName, jobInfo.RepositoryName),
 		WorkflowRunID:  jobInfo.WorkflowRunID,
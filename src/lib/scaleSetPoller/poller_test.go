@@ -2,6 +2,7 @@ package scaleSetPoller
 
 import (
 	"cattery/lib/config"
+	"cattery/lib/scaleSetPoller/repositories"
 	"cattery/lib/testutil"
 	"cattery/lib/trayManager"
 	"cattery/lib/trays"
@@ -69,13 +70,15 @@ func TestHandleDesiredRunnerCount_RecordsScaleBeforeProviderWaitCompletes(t *tes
 	}
 	tm := trayManager.NewTrayManager(
 		testutil.NewMockTrayRepository(),
+		testutil.NewMockPauseRepository(),
+		testutil.NewMockTrayEventRepository(),
 		&pollerTestProviderFactory{provider: provider},
 	)
 	poller := &Poller{
 		trayType:    trayType,
 		trayManager: tm,
-		history:     &History{},
 	}
+	NewManager(testutil.NewMockMessageRepository()).Register("test-type", poller)
 	scaler := &catteryScaler{poller: poller}
 	scaler.latestStat.Store(&scaleset.RunnerScaleSetStatistic{
 		TotalAvailableJobs:     1,
@@ -98,9 +101,10 @@ func TestHandleDesiredRunnerCount_RecordsScaleBeforeProviderWaitCompletes(t *tes
 		t.Fatal("provider wait did not start")
 	}
 
-	got := poller.History().Recent()
+	got, err := poller.manager.MessageHistory(context.Background(), repositories.MessageFilter{})
+	require.NoError(t, err)
 	require.Len(t, got, 1)
-	assert.Equal(t, MessageKindScale, got[0].Kind)
+	assert.Equal(t, repositories.MessageKindScale, got[0].Kind)
 	assert.Equal(t, "test-type", got[0].TrayType)
 	assert.Equal(t, 1, got[0].DesiredCount)
 	require.NotNil(t, got[0].Stats)
@@ -116,7 +120,15 @@ func TestHandleDesiredRunnerCount_RecordsScaleBeforeProviderWaitCompletes(t *tes
 	}
 }
 
-func TestNewPollerInitializesHistory(t *testing.T) {
-	poller := NewPoller(nil, &config.TrayType{Name: "test-type"}, nil)
-	require.NotNil(t, poller.History())
+func TestPollerTrayType_FollowsConfig(t *testing.T) {
+	started := &config.TrayType{Name: "test-type", MaxTrays: 1}
+	poller := NewPoller(nil, started, nil)
+
+	config.SetForTest(t, &config.CatteryConfig{
+		TrayTypes: []*config.TrayType{{Name: "test-type", MaxTrays: 5}},
+	})
+	assert.Equal(t, 5, poller.TrayType().MaxTrays, "reloaded settings apply to the running poller")
+
+	config.SetForTest(t, &config.CatteryConfig{})
+	assert.Same(t, started, poller.TrayType(), "a removed tray type keeps its last settings")
 }
@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"bytes"
+	"cattery/lib/bolt"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// BoltMessageRepository stores poller messages in the pollerMessages bucket
+// of a local bbolt file, keyed by the bucket's next sequence number, so the
+// keys are in insertion order.
+type BoltMessageRepository struct {
+	db *bbolt.DB
+}
+
+func NewBoltMessageRepository() *BoltMessageRepository {
+	return &BoltMessageRepository{}
+}
+
+func (b *BoltMessageRepository) Connect(db *bbolt.DB) {
+	b.db = db
+}
+
+func (b *BoltMessageRepository) AddMessage(_ context.Context, message *Message) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bolt.PollerMessagesBucket)
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		return bucket.Put(binary.BigEndian.AppendUint64(nil, seq), data)
+	})
+}
+
+// ListMessages walks the bucket backwards; a single process writes it, so
+// insertion order is time order.
+func (b *BoltMessageRepository) ListMessages(_ context.Context, filter MessageFilter) ([]*Message, error) {
+	var messages []*Message
+	skipped := 0
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(bolt.PollerMessagesBucket).Cursor()
+		for k, data := c.Last(); k != nil; k, data = c.Prev() {
+			if filter.Limit > 0 && len(messages) == filter.Limit {
+				return nil
+			}
+			message := &Message{}
+			if err := json.Unmarshal(data, message); err != nil {
+				return err
+			}
+			if !filter.Matches(message) {
+				continue
+			}
+			if skipped < filter.Offset {
+				skipped++
+				continue
+			}
+			messages = append(messages, message)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// PruneMessages scans the whole bucket; it runs rarely.
+func (b *BoltMessageRepository) PruneMessages(_ context.Context, before time.Time) (int64, error) {
+	var pruned int64
+	err := b.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bolt.PollerMessagesBucket)
+		var expired [][]byte
+		err := bucket.ForEach(func(k, data []byte) error {
+			var message Message
+			if err := json.Unmarshal(data, &message); err != nil {
+				return err
+			}
+			if message.Time.Before(before) {
+				expired = append(expired, bytes.Clone(k))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		// Keys are deleted after the scan; bolt cursors do not support
+		// deleting while iterating with ForEach.
+		for _, k := range expired {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		pruned = int64(len(expired))
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return pruned, nil
+}
@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"cattery/lib/bolt"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoltMessageRepository(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "cattery.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	repo := NewBoltMessageRepository()
+	repo.Connect(db)
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	add := func(trayType string, kind MessageKind, repository string, at time.Time) {
+		require.NoError(t, repo.AddMessage(ctx, &Message{TrayType: trayType, Kind: kind, Repository: repository, Time: at}))
+	}
+	add("linux", MessageKindScale, "", now.Add(-2*time.Hour))
+	add("linux", MessageKindJobStarted, "org/a", now.Add(-time.Hour))
+	add("windows", MessageKindJobStarted, "org/b", now.Add(-time.Hour))
+	add("linux", MessageKindJobCompleted, "org/a", now)
+	require.NoError(t, repo.AddMessage(ctx, &Message{TrayType: "linux", Kind: MessageKindScale, DesiredCount: 2,
+		Stats: &ScaleStats{Available: 1, Busy: 1}, Time: now}))
+
+	messages, err := repo.ListMessages(ctx, MessageFilter{})
+	require.NoError(t, err)
+	require.Len(t, messages, 5)
+	assert.Equal(t, MessageKindScale, messages[0].Kind, "newest first")
+	assert.Equal(t, 2, messages[0].DesiredCount)
+	require.NotNil(t, messages[0].Stats)
+	assert.Equal(t, 1, messages[0].Stats.Busy)
+	assert.Equal(t, now.Add(-2*time.Hour), messages[4].Time)
+
+	messages, err = repo.ListMessages(ctx, MessageFilter{TrayType: "linux", Kind: MessageKindJobStarted})
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "org/a", messages[0].Repository)
+
+	messages, err = repo.ListMessages(ctx, MessageFilter{Repository: "org/a", Offset: 1, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, MessageKindJobStarted, messages[0].Kind)
+
+	pruned, err := repo.PruneMessages(ctx, now.Add(-30*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), pruned)
+
+	messages, err = repo.ListMessages(ctx, MessageFilter{})
+	require.NoError(t, err)
+	assert.Len(t, messages, 2)
+}
@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"context"
+	"time"
+)
+
+type MessageKind string
+
+const (
+	MessageKindScale        MessageKind = "scale"
+	MessageKindJobStarted   MessageKind = "job_started"
+	MessageKindJobCompleted MessageKind = "job_completed"
+)
+
+// ScaleStats is a display-only snapshot of scale-set statistics, decoupled from
+// the scaleset library types so the store has no upstream dependency.
+type ScaleStats struct {
+	Available  int `bson:"available" json:"available"`
+	Assigned   int `bson:"assigned" json:"assigned"`
+	Running    int `bson:"running" json:"running"`
+	Busy       int `bson:"busy" json:"busy"`
+	Idle       int `bson:"idle" json:"idle"`
+	Registered int `bson:"registered" json:"registered"`
+}
+
+// Message is one scale set message handled by a poller. Messages are stored
+// in the database, so every replica shows the messages of the replica
+// holding a tray type's lease, and they outlive a failover until pruned.
+type Message struct {
+	Time     time.Time   `bson:"time" json:"time"`
+	Kind     MessageKind `bson:"kind" json:"kind"`
+	TrayType string      `bson:"trayType" json:"trayType"`
+
+	// Job event fields (set on job_started / job_completed).
+	Repository     string `bson:"repository,omitempty" json:"repository,omitempty"`
+	WorkflowRunID  int64  `bson:"workflowRunId,omitempty" json:"workflowRunId,omitempty"`
+	JobID          int64  `bson:"jobId,omitempty" json:"jobId,omitempty"`
+	JobDisplayName string `bson:"jobDisplayName,omitempty" json:"jobDisplayName,omitempty"`
+	RunnerName     string `bson:"runnerName,omitempty" json:"runnerName,omitempty"`
+	Result         string `bson:"result,omitempty" json:"result,omitempty"`
+
+	// Scale event fields (set on scale).
+	DesiredCount int         `bson:"desiredCount,omitempty" json:"desiredCount,omitempty"`
+	Stats        *ScaleStats `bson:"stats,omitempty" json:"stats,omitempty"`
+}
+
+func (m *Message) IsScale() bool { return m.Kind == MessageKindScale }
+
+// MessageFilter selects and pages messages. Zero fields match everything;
+// a zero Limit returns all matching messages.
+type MessageFilter struct {
+	TrayType   string
+	Kind       MessageKind
+	Repository string
+
+	Offset int
+	Limit  int
+}
+
+// Matches reports whether m passes the filter's field conditions.
+func (f MessageFilter) Matches(m *Message) bool {
+	return (f.TrayType == "" || m.TrayType == f.TrayType) &&
+		(f.Kind == "" || m.Kind == f.Kind) &&
+		(f.Repository == "" || m.Repository == f.Repository)
+}
+
+// MessageRepository stores the scale set messages of all pollers, shared by
+// every replica. Messages are kept until PruneMessages removes them.
+type MessageRepository interface {
+	AddMessage(ctx context.Context, message *Message) error
+	// ListMessages returns the messages matching filter, newest first,
+	// skipping filter.Offset and returning at most filter.Limit.
+	ListMessages(ctx context.Context, filter MessageFilter) ([]*Message, error)
+	// PruneMessages deletes messages older than before and returns how many
+	// were deleted.
+	PruneMessages(ctx context.Context, before time.Time) (int64, error)
+}
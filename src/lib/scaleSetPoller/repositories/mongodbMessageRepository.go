@@ -0,0 +1,68 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type MongodbMessageRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongodbMessageRepository() *MongodbMessageRepository {
+	return &MongodbMessageRepository{}
+}
+
+func (m *MongodbMessageRepository) Connect(collection *mongo.Collection) {
+	m.collection = collection
+}
+
+func (m *MongodbMessageRepository) AddMessage(ctx context.Context, message *Message) error {
+	_, err := m.collection.InsertOne(ctx, message)
+	return err
+}
+
+// ListMessages sorts by time, then by _id, whose ObjectIds increase with
+// insertion order on each client.
+func (m *MongodbMessageRepository) ListMessages(ctx context.Context, filter MessageFilter) ([]*Message, error) {
+	query := bson.M{}
+	if filter.TrayType != "" {
+		query["trayType"] = filter.TrayType
+	}
+	if filter.Kind != "" {
+		query["kind"] = filter.Kind
+	}
+	if filter.Repository != "" {
+		query["repository"] = filter.Repository
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "time", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64(filter.Offset))
+	if filter.Limit > 0 {
+		opts.SetLimit(int64(filter.Limit))
+	}
+
+	cursor, err := m.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []*Message
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func (m *MongodbMessageRepository) PruneMessages(ctx context.Context, before time.Time) (int64, error) {
+	result, err := m.collection.DeleteMany(ctx, bson.M{"time": bson.M{"$lt": before}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresMessageRepository implements MessageRepository on the
+// poller_messages table.
+type PostgresMessageRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresMessageRepository() *PostgresMessageRepository {
+	return &PostgresMessageRepository{}
+}
+
+func (p *PostgresMessageRepository) Connect(pool *pgxpool.Pool) {
+	p.pool = pool
+}
+
+const messageColumns = `time, kind, tray_type_name, repository, workflow_run_id, job_id,
+	job_display_name, runner_name, result, desired_count, stats`
+
+func scanMessage(row pgx.CollectableRow) (*Message, error) {
+	message := &Message{}
+	err := row.Scan(&message.Time, &message.Kind, &message.TrayType, &message.Repository,
+		&message.WorkflowRunID, &message.JobID, &message.JobDisplayName, &message.RunnerName,
+		&message.Result, &message.DesiredCount, &message.Stats)
+	message.Time = message.Time.UTC()
+	return message, err
+}
+
+func (p *PostgresMessageRepository) AddMessage(ctx context.Context, message *Message) error {
+	_, err := p.pool.Exec(ctx, `INSERT INTO poller_messages (`+messageColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		message.Time, string(message.Kind), message.TrayType, message.Repository,
+		message.WorkflowRunID, message.JobID, message.JobDisplayName, message.RunnerName,
+		message.Result, message.DesiredCount, message.Stats)
+	return err
+}
+
+func (p *PostgresMessageRepository) ListMessages(ctx context.Context, filter MessageFilter) ([]*Message, error) {
+	var conditions []string
+	var args []any
+	where := func(column string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	if filter.TrayType != "" {
+		where("tray_type_name", filter.TrayType)
+	}
+	if filter.Kind != "" {
+		where("kind", string(filter.Kind))
+	}
+	if filter.Repository != "" {
+		where("repository", filter.Repository)
+	}
+
+	query := `SELECT ` + messageColumns + ` FROM poller_messages`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	query += ` ORDER BY time DESC, id DESC`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := p.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, scanMessage)
+}
+
+func (p *PostgresMessageRepository) PruneMessages(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := p.pool.Exec(ctx, `DELETE FROM poller_messages WHERE time < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"cattery/lib/tracing"
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// dbSystemKey is the OpenTelemetry semantic convention attribute naming the
+// database behind a repository span.
+const dbSystemKey = attribute.Key("db.system")
+
+// TracedMessageRepository wraps a MessageRepository with a span around each
+// call.
+type TracedMessageRepository struct {
+	repository MessageRepository
+	driver     string
+}
+
+// NewTracedMessageRepository wraps repository, which stores poller messages
+// in driver.
+func NewTracedMessageRepository(repository MessageRepository, driver string) *TracedMessageRepository {
+	return &TracedMessageRepository{repository: repository, driver: driver}
+}
+
+func (r *TracedMessageRepository) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "messageRepository."+name, append(attrs, dbSystemKey.String(r.driver))...)
+}
+
+func (r *TracedMessageRepository) AddMessage(ctx context.Context, message *Message) (err error) {
+	ctx, span := r.start(ctx, "AddMessage", tracing.TrayTypeKey.String(message.TrayType))
+	defer func() { tracing.End(span, err) }()
+	return r.repository.AddMessage(ctx, message)
+}
+
+func (r *TracedMessageRepository) ListMessages(ctx context.Context, filter MessageFilter) (_ []*Message, err error) {
+	ctx, span := r.start(ctx, "ListMessages")
+	defer func() { tracing.End(span, err) }()
+	return r.repository.ListMessages(ctx, filter)
+}
+
+func (r *TracedMessageRepository) PruneMessages(ctx context.Context, before time.Time) (_ int64, err error) {
+	ctx, span := r.start(ctx, "PruneMessages")
+	defer func() { tracing.End(span, err) }()
+	return r.repository.PruneMessages(ctx, before)
+}
@@ -0,0 +1,47 @@
+package testutil
+
+import (
+	"cattery/lib/election"
+	"context"
+	"sync"
+)
+
+// Compile-time interface check.
+var _ election.Elector = (*MockElector)(nil)
+
+// MockElector is a test double for election.Elector. Leaderships is what
+// Leadership returns; StepDown clears Leader of the key's entry, and returns
+// election.ErrNotLeader if it is not set.
+type MockElector struct {
+	mu          sync.Mutex
+	Leaderships []election.Leadership
+}
+
+func NewMockElector() *MockElector {
+	return &MockElector{}
+}
+
+// Run invokes onElected once with ctx and returns when ctx is done.
+func (m *MockElector) Run(ctx context.Context, _ string, onElected election.OnElected) error {
+	onElected(ctx)
+	<-ctx.Done()
+	return nil
+}
+
+func (m *MockElector) Leadership() []election.Leadership {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]election.Leadership(nil), m.Leaderships...)
+}
+
+func (m *MockElector) StepDown(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.Leaderships {
+		if m.Leaderships[i].Key == key && m.Leaderships[i].Leader {
+			m.Leaderships[i].Leader = false
+			return nil
+		}
+	}
+	return election.ErrNotLeader
+}
@@ -0,0 +1,77 @@
+package testutil
+
+import (
+	"cattery/lib/scaleSetPoller/repositories"
+	"context"
+	"sync"
+	"time"
+)
+
+// Compile-time interface check.
+var _ repositories.MessageRepository = (*MockMessageRepository)(nil)
+
+// MockMessageRepository is a test double for repositories.MessageRepository.
+// Messages holds every added message in insertion order.
+type MockMessageRepository struct {
+	mu       sync.Mutex
+	Messages []*repositories.Message
+	Err      error
+}
+
+func NewMockMessageRepository() *MockMessageRepository {
+	return &MockMessageRepository{}
+}
+
+func (m *MockMessageRepository) AddMessage(_ context.Context, message *repositories.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	m.Messages = append(m.Messages, message)
+	return nil
+}
+
+// ListMessages returns matching messages in reverse insertion order, which
+// tests keep in time order.
+func (m *MockMessageRepository) ListMessages(_ context.Context, filter repositories.MessageFilter) ([]*repositories.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	var messages []*repositories.Message
+	skipped := 0
+	for i := len(m.Messages) - 1; i >= 0; i-- {
+		if filter.Limit > 0 && len(messages) == filter.Limit {
+			break
+		}
+		message := m.Messages[i]
+		if !filter.Matches(message) {
+			continue
+		}
+		if skipped < filter.Offset {
+			skipped++
+			continue
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+func (m *MockMessageRepository) PruneMessages(_ context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return 0, m.Err
+	}
+	kept := m.Messages[:0]
+	for _, message := range m.Messages {
+		if !message.Time.Before(before) {
+			kept = append(kept, message)
+		}
+	}
+	pruned := int64(len(m.Messages) - len(kept))
+	m.Messages = kept
+	return pruned, nil
+}
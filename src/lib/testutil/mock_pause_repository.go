@@ -0,0 +1,74 @@
+package testutil
+
+import (
+	"cattery/lib/trays/repositories"
+	"context"
+	"sync"
+	"time"
+)
+
+// Compile-time interface check.
+var _ repositories.PauseRepository = (*MockPauseRepository)(nil)
+
+// MockPauseRepository is a test double for repositories.PauseRepository.
+type MockPauseRepository struct {
+	mu     sync.Mutex
+	Pauses map[string]repositories.TrayTypePause
+	Err    error
+}
+
+func NewMockPauseRepository() *MockPauseRepository {
+	return &MockPauseRepository{
+		Pauses: make(map[string]repositories.TrayTypePause),
+	}
+}
+
+func (m *MockPauseRepository) Pause(_ context.Context, trayTypeName string, deleteIdle bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	m.Pauses[trayTypeName] = repositories.TrayTypePause{
+		TrayTypeName: trayTypeName,
+		DeleteIdle:   deleteIdle,
+		PausedAt:     time.Now().UTC(),
+	}
+	return nil
+}
+
+func (m *MockPauseRepository) Resume(_ context.Context, trayTypeName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	delete(m.Pauses, trayTypeName)
+	return nil
+}
+
+func (m *MockPauseRepository) GetPause(_ context.Context, trayTypeName string) (*repositories.TrayTypePause, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	pause, ok := m.Pauses[trayTypeName]
+	if !ok {
+		return nil, nil
+	}
+	return &pause, nil
+}
+
+func (m *MockPauseRepository) ListPauses(_ context.Context) ([]repositories.TrayTypePause, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	pauses := make([]repositories.TrayTypePause, 0, len(m.Pauses))
+	for _, pause := range m.Pauses {
+		pauses = append(pauses, pause)
+	}
+	return pauses, nil
+}
@@ -0,0 +1,79 @@
+package testutil
+
+import (
+	"cattery/lib/trays"
+	"cattery/lib/trays/repositories"
+	"context"
+	"sync"
+	"time"
+)
+
+// Compile-time interface check.
+var _ repositories.TrayEventRepository = (*MockTrayEventRepository)(nil)
+
+// MockTrayEventRepository is a test double for
+// repositories.TrayEventRepository. Events holds every added event in order.
+type MockTrayEventRepository struct {
+	mu     sync.Mutex
+	Events []trays.TrayEvent
+	Err    error
+}
+
+func NewMockTrayEventRepository() *MockTrayEventRepository {
+	return &MockTrayEventRepository{}
+}
+
+func (m *MockTrayEventRepository) AddEvent(_ context.Context, event *trays.TrayEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	m.Events = append(m.Events, *event)
+	return nil
+}
+
+func (m *MockTrayEventRepository) ListEvents(_ context.Context, trayId string) ([]trays.TrayEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	var events []trays.TrayEvent
+	for _, event := range m.Events {
+		if event.TrayId == trayId {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (m *MockTrayEventRepository) PruneEvents(_ context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return 0, m.Err
+	}
+	kept := m.Events[:0]
+	for _, event := range m.Events {
+		if !event.Time.Before(before) {
+			kept = append(kept, event)
+		}
+	}
+	pruned := int64(len(m.Events) - len(kept))
+	m.Events = kept
+	return pruned, nil
+}
+
+// Kinds returns the kinds of trayId's events, in order.
+func (m *MockTrayEventRepository) Kinds(trayId string) []trays.TrayEventKind {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var kinds []trays.TrayEventKind
+	for _, event := range m.Events {
+		if event.TrayId == trayId {
+			kinds = append(kinds, event.Kind)
+		}
+	}
+	return kinds
+}
@@ -1,9 +1,11 @@
 package testutil
 
 import (
+	"cattery/lib/config"
 	"cattery/lib/trays"
 	"cattery/lib/trays/repositories"
 	"context"
+	"sort"
 	"sync"
 	"time"
 )
@@ -47,10 +49,34 @@ func (m *MockTrayRepository) Save(_ context.Context, tray *trays.Tray) error {
 	if m.SaveErr != nil {
 		return m.SaveErr
 	}
+	tray.RecordStatusTime(tray.Status, time.Now())
 	m.Trays[tray.Id] = tray
 	return nil
 }
 
+func (m *MockTrayRepository) SaveWithinQuotas(_ context.Context, tray *trays.Tray, quotas []*config.Quota) (*config.Quota, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.SaveErr != nil {
+		return nil, m.SaveErr
+	}
+	for _, quota := range quotas {
+		count, weight := 1, tray.QuotaWeight()
+		for _, existing := range m.Trays {
+			if existing.Status != trays.TrayStatusDeleting && quota.Matches(existing.GitHubOrgName, existing.ProviderName) {
+				count++
+				weight += existing.QuotaWeight()
+			}
+		}
+		if quota.Exceeded(count, weight) {
+			return quota, nil
+		}
+	}
+	tray.RecordStatusTime(tray.Status, time.Now())
+	m.Trays[tray.Id] = tray
+	return nil, nil
+}
+
 func (m *MockTrayRepository) Delete(_ context.Context, trayId string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -91,6 +117,7 @@ func (m *MockTrayRepository) UpdateStatus(_ context.Context, trayId string, stat
 		tray.WorkflowName = workflowName
 	}
 	tray.StatusChanged = time.Now()
+	tray.RecordStatusTime(status, tray.StatusChanged)
 	return tray, nil
 }
 
@@ -113,6 +140,20 @@ func (m *MockTrayRepository) SetProviderData(_ context.Context, trayId string, d
 	return tray, nil
 }
 
+func (m *MockTrayRepository) SetDraining(_ context.Context, trayId string, draining bool) (*trays.Tray, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.SetErr != nil {
+		return nil, m.SetErr
+	}
+	tray, ok := m.Trays[trayId]
+	if !ok {
+		return nil, nil
+	}
+	tray.Draining = draining
+	return tray, nil
+}
+
 func (m *MockTrayRepository) CountActive(_ context.Context, _ string) (int, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -122,6 +163,41 @@ func (m *MockTrayRepository) CountActive(_ context.Context, _ string) (int, erro
 	return m.CountResult, nil
 }
 
+// CountByStatus counts the trays in the Trays map; unlike CountActive it does
+// not use a canned result.
+func (m *MockTrayRepository) CountByStatus(_ context.Context, trayType string) (map[trays.TrayStatus]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.CountErr != nil {
+		return nil, m.CountErr
+	}
+	result := make(map[trays.TrayStatus]int)
+	for _, t := range m.Trays {
+		if t.TrayTypeName == trayType {
+			result[t.Status]++
+		}
+	}
+	return result, nil
+}
+
+func (m *MockTrayRepository) GetByStatus(_ context.Context, trayType string, status trays.TrayStatus) ([]*trays.Tray, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.GetErr != nil {
+		return nil, m.GetErr
+	}
+	var result []*trays.Tray
+	for _, t := range m.Trays {
+		if t.TrayTypeName == trayType && t.Status == status {
+			result = append(result, t)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].StatusChanged.Before(result[j].StatusChanged)
+	})
+	return result, nil
+}
+
 func (m *MockTrayRepository) List(_ context.Context) ([]*trays.Tray, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -107,3 +107,25 @@ func TestMock_SetProviderData_RespectsSetErr(t *testing.T) {
 	require.Error(t, err)
 	assert.Nil(t, updated)
 }
+
+func TestMock_GetByStatus_OldestFirst(t *testing.T) {
+	repo := NewMockTrayRepository()
+	now := time.Now()
+	repo.Trays["newer"] = &trays.Tray{Id: "newer", TrayTypeName: "tt", Status: trays.TrayStatusRegistered, StatusChanged: now}
+	repo.Trays["older"] = &trays.Tray{Id: "older", TrayTypeName: "tt", Status: trays.TrayStatusRegistered, StatusChanged: now.Add(-time.Hour)}
+	repo.Trays["running"] = &trays.Tray{Id: "running", TrayTypeName: "tt", Status: trays.TrayStatusRunning}
+	repo.Trays["other"] = &trays.Tray{Id: "other", TrayTypeName: "other", Status: trays.TrayStatusRegistered}
+
+	got, err := repo.GetByStatus(context.Background(), "tt", trays.TrayStatusRegistered)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, "older", got[0].Id)
+	assert.Equal(t, "newer", got[1].Id)
+
+	counts, err := repo.CountByStatus(context.Background(), "tt")
+	require.NoError(t, err)
+	assert.Equal(t, map[trays.TrayStatus]int{
+		trays.TrayStatusRegistered: 2,
+		trays.TrayStatusRunning:    1,
+	}, counts)
+}
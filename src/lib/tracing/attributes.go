@@ -0,0 +1,26 @@
+package tracing
+
+import (
+	"cattery/lib/trays"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Attribute keys set on cattery's spans.
+const (
+	TrayIdKey     = attribute.Key("cattery.tray.id")
+	TrayStatusKey = attribute.Key("cattery.tray.status")
+	TrayTypeKey   = attribute.Key("cattery.tray_type")
+	ProviderKey   = attribute.Key("cattery.provider")
+	OrgKey        = attribute.Key("cattery.org")
+)
+
+// TrayAttributes describes tray on a span.
+func TrayAttributes(tray *trays.Tray) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		TrayIdKey.String(tray.Id),
+		TrayTypeKey.String(tray.TrayTypeName),
+		ProviderKey.String(tray.ProviderName),
+		OrgKey.String(tray.GitHubOrgName),
+	}
+}
@@ -0,0 +1,180 @@
+// Package tracing sets up OpenTelemetry tracing and holds the helpers cattery
+// uses to start spans and to carry trace context between processes.
+package tracing
+
+import (
+	"cattery/lib/config"
+	"cattery/lib/version"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "cattery"
+
+// Propagator carries trace context as W3C traceparent headers. It is used
+// directly rather than through otel's global propagator, which is a no-op
+// unless Setup ran, so trace context is passed on even by a process that
+// does not export spans itself.
+var Propagator propagation.TextMapPropagator = propagation.TraceContext{}
+
+// Setup installs the global tracer provider exporting spans as cfg says and
+// returns a function that flushes and stops it. With tracing disabled it
+// installs nothing, and spans are no-ops.
+func Setup(ctx context.Context, cfg config.TracingConfig) (func(context.Context) error, error) {
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+	cfg = cfg.WithDefaults()
+
+	exporter, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", cfg.Protocol, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", cfg.ServiceName),
+		attribute.String("service.version", version.Get()),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(Propagator)
+	return provider.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, cfg config.TracingConfig) (sdktrace.SpanExporter, error) {
+	switch cfg.Protocol {
+	case config.TracingProtocolHttp:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		if len(cfg.Headers) > 0 {
+			opts = append(opts, otlptracehttp.WithHeaders(cfg.Headers))
+		}
+		return otlptracehttp.New(ctx, opts...)
+	default:
+		var opts []otlptracegrpc.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		if len(cfg.Headers) > 0 {
+			opts = append(opts, otlptracegrpc.WithHeaders(cfg.Headers))
+		}
+		return otlptracegrpc.New(ctx, opts...)
+	}
+}
+
+// SetupFromEnv is Setup for the agent, which has no config file. Tracing is
+// on when OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT is
+// set; the exporter reads its other settings from the standard OTEL_*
+// variables too.
+func SetupFromEnv(ctx context.Context, serviceName string) (func(context.Context) error, error) {
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	protocol := config.TracingProtocolGrpc
+	if strings.HasPrefix(os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL"), "http") {
+		protocol = config.TracingProtocolHttp
+	}
+	return Setup(ctx, config.TracingConfig{Enabled: true, Protocol: protocol, ServiceName: serviceName})
+}
+
+// Start starts a span named name as a child of the span in ctx, if any.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err on span, if it is not nil, and ends the span.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceParent returns the traceparent header value of the span in ctx, or ""
+// if ctx has no valid span.
+func TraceParent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	Propagator.Inject(ctx, carrier)
+	return carrier.Get("traceparent")
+}
+
+// spanContext parses a traceparent header value.
+func spanContext(traceParent string) trace.SpanContext {
+	if traceParent == "" {
+		return trace.SpanContext{}
+	}
+	ctx := Propagator.Extract(context.Background(), propagation.MapCarrier{"traceparent": traceParent})
+	return trace.SpanContextFromContext(ctx)
+}
+
+// WithTraceParent returns ctx with the span described by traceParent as the
+// parent of spans started from it. ctx is returned unchanged if traceParent
+// is empty or invalid.
+func WithTraceParent(ctx context.Context, traceParent string) context.Context {
+	sc := spanContext(traceParent)
+	if !sc.IsValid() {
+		return ctx
+	}
+	return trace.ContextWithRemoteSpanContext(ctx, sc)
+}
+
+// StartInTrace starts a span named name in the trace of traceParent, linked
+// to the span in ctx. It is for work on a tray driven by something outside
+// the tray's own trace, like an agent request. Without a valid traceParent it
+// is Start.
+func StartInTrace(ctx context.Context, traceParent string, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	sc := spanContext(traceParent)
+	if !sc.IsValid() {
+		return Start(ctx, name, attrs...)
+	}
+	opts := []trace.SpanStartOption{trace.WithAttributes(attrs...)}
+	if current := trace.SpanContextFromContext(ctx); current.IsValid() {
+		opts = append(opts, trace.WithLinks(trace.Link{SpanContext: current}))
+	}
+	return otel.Tracer(instrumentationName).Start(trace.ContextWithRemoteSpanContext(ctx, sc), name, opts...)
+}
+
+// Link links the span in ctx to the span described by traceParent, so a
+// span outside a tray's trace (a GitHub job message) can be found from it.
+// Spans already in that trace are left alone.
+func Link(ctx context.Context, traceParent string) {
+	sc := spanContext(traceParent)
+	if !sc.IsValid() {
+		return
+	}
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().TraceID() == sc.TraceID() {
+		return
+	}
+	span.AddLink(trace.Link{SpanContext: sc})
+}
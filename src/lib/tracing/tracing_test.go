@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"cattery/lib/config"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestTraceParent_RoundTrip(t *testing.T) {
+	recordSpans(t)
+
+	assert.Equal(t, "", TraceParent(context.Background()), "no span, no trace context")
+
+	ctx, span := Start(context.Background(), "create")
+	defer span.End()
+	traceParent := TraceParent(ctx)
+	require.NotEmpty(t, traceParent)
+
+	child, childSpan := Start(WithTraceParent(context.Background(), traceParent), "register")
+	defer childSpan.End()
+	assert.Equal(t, span.SpanContext().TraceID(), trace.SpanContextFromContext(child).TraceID())
+}
+
+func TestWithTraceParent_IgnoresInvalid(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, ctx, WithTraceParent(ctx, ""))
+	assert.Equal(t, ctx, WithTraceParent(ctx, "not-a-traceparent"))
+}
+
+func TestStartInTrace_JoinsTrayTraceAndLinksCaller(t *testing.T) {
+	recorder := recordSpans(t)
+
+	trayCtx, traySpan := Start(context.Background(), "trayManager.CreateTray")
+	traySpan.End()
+	callerCtx, callerSpan := Start(context.Background(), "GET /agent/register/{id}")
+
+	_, span := StartInTrace(callerCtx, TraceParent(trayCtx), "agent.register")
+	span.End()
+	callerSpan.End()
+
+	ended := recorder.Ended()
+	require.Len(t, ended, 3)
+	register := ended[1]
+	assert.Equal(t, "agent.register", register.Name())
+	assert.Equal(t, traySpan.SpanContext().TraceID(), register.SpanContext().TraceID())
+	assert.Equal(t, traySpan.SpanContext().SpanID(), register.Parent().SpanID())
+	require.Len(t, register.Links(), 1)
+	assert.Equal(t, callerSpan.SpanContext().SpanID(), register.Links()[0].SpanContext.SpanID())
+}
+
+func TestLink_SkipsOwnTrace(t *testing.T) {
+	recorder := recordSpans(t)
+
+	trayCtx, traySpan := Start(context.Background(), "trayManager.CreateTray")
+	Link(trayCtx, TraceParent(trayCtx))
+	traySpan.End()
+
+	ctx, span := Start(context.Background(), "poller.JobCompleted")
+	Link(ctx, TraceParent(trayCtx))
+	Link(ctx, "")
+	span.End()
+
+	ended := recorder.Ended()
+	require.Len(t, ended, 2)
+	assert.Empty(t, ended[0].Links())
+	require.Len(t, ended[1].Links(), 1)
+	assert.Equal(t, traySpan.SpanContext().TraceID(), ended[1].Links()[0].SpanContext.TraceID())
+}
+
+func TestSetup_DisabledInstallsNothing(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	shutdown, err := Setup(context.Background(), config.TracingConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, previous, otel.GetTracerProvider())
+	assert.NoError(t, shutdown(context.Background()))
+}
@@ -0,0 +1,188 @@
+package trayManager
+
+import (
+	"cattery/lib/config"
+	"cattery/lib/logging"
+	"cattery/lib/metrics"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// BreakerState is the state of a tray type's circuit breaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets every creation through.
+	BreakerClosed BreakerState = iota
+	// BreakerHalfOpen lets a single probe creation through after a backoff.
+	BreakerHalfOpen
+	// BreakerOpen lets no creation through until its backoff runs out.
+	BreakerOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerHalfOpen:
+		return "half-open"
+	case BreakerOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerStatus is a snapshot of a tray type's circuit breaker.
+type BreakerStatus struct {
+	TrayType string
+	Provider string
+	State    BreakerState
+	// Failures is the number of consecutive failed creations.
+	Failures int
+	// RetryAt is when an open circuit lets the next probe through.
+	RetryAt   time.Time
+	LastError string
+}
+
+type breakerKey struct {
+	trayType string
+	provider string
+}
+
+// circuitBreaker tracks consecutive tray creation failures of one tray type on
+// one provider, so a provider that keeps failing (a broken instance template,
+// an expired token) is not hit with a burst of creations on every scale
+// message. See config.BreakerConfig for how it opens and closes.
+type circuitBreaker struct {
+	key breakerKey
+
+	mu        sync.Mutex
+	state     BreakerState
+	failures  int
+	backoff   time.Duration
+	retryAt   time.Time
+	lastError string
+}
+
+func (b *circuitBreaker) logger() *log.Entry {
+	return logger.WithFields(log.Fields{logging.TrayTypeKey: b.key.trayType, logging.ProviderKey: b.key.provider})
+}
+
+// admit returns how many of count creations may go ahead now: count while
+// closed, one probe once an open circuit's backoff has run out, else none.
+func (b *circuitBreaker) admit(count int, now time.Time) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if now.Before(b.retryAt) {
+			return 0
+		}
+		b.logger().Infof("Circuit breaker for tray type %s on provider %s half-open; probing with one tray", b.key.trayType, b.key.provider)
+		b.setState(BreakerHalfOpen)
+		return 1
+	case BreakerHalfOpen:
+		// The probe is still in flight.
+		return 0
+	default:
+		return count
+	}
+}
+
+// record updates the breaker with the results of admitted creations. Quota
+// limits and creations cut short by ctx are neither successes nor failures.
+func (b *circuitBreaker) record(ctx context.Context, results []error, cfg config.BreakerConfig, now time.Time) {
+	succeeded, failed := 0, 0
+	var lastErr error
+	for _, err := range results {
+		switch {
+		case err == nil:
+			succeeded++
+		case errors.Is(err, ErrQuotaExceeded), ctx.Err() != nil:
+		default:
+			failed++
+			lastErr = err
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch {
+	case succeeded > 0:
+		if b.state != BreakerClosed {
+			b.logger().Infof("Circuit breaker for tray type %s on provider %s closed", b.key.trayType, b.key.provider)
+		}
+		b.failures = 0
+		b.backoff = 0
+		b.lastError = ""
+		b.setState(BreakerClosed)
+	case failed > 0:
+		b.failures += failed
+		b.lastError = lastErr.Error()
+		if b.state == BreakerHalfOpen {
+			b.open(min(b.backoff*2, cfg.MaxBackoff), now)
+		} else if b.failures >= cfg.FailureThreshold {
+			b.open(cfg.InitialBackoff, now)
+		}
+	case b.state == BreakerHalfOpen:
+		// The probe neither succeeded nor failed; probe again next time.
+		b.setState(BreakerOpen)
+	}
+	metrics.BreakerFailuresSet(b.key.provider, b.key.trayType, b.failures)
+}
+
+func (b *circuitBreaker) open(backoff time.Duration, now time.Time) {
+	b.backoff = backoff
+	b.retryAt = now.Add(backoff)
+	b.logger().Warnf("Circuit breaker for tray type %s on provider %s open after %d consecutive failures; next attempt in %s: %s",
+		b.key.trayType, b.key.provider, b.failures, backoff, b.lastError)
+	b.setState(BreakerOpen)
+}
+
+func (b *circuitBreaker) setState(state BreakerState) {
+	b.state = state
+	metrics.BreakerStateSet(b.key.provider, b.key.trayType, int(state))
+}
+
+func (b *circuitBreaker) status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	status := BreakerStatus{
+		TrayType:  b.key.trayType,
+		Provider:  b.key.provider,
+		State:     b.state,
+		Failures:  b.failures,
+		LastError: b.lastError,
+	}
+	if b.state == BreakerOpen {
+		status.RetryAt = b.retryAt
+	}
+	return status
+}
+
+// breaker returns the circuit breaker of trayType on its current provider.
+func (tm *TrayManager) breaker(trayType *config.TrayType) *circuitBreaker {
+	key := breakerKey{trayType: trayType.Name, provider: trayType.Provider}
+
+	tm.breakerMu.Lock()
+	defer tm.breakerMu.Unlock()
+	b, ok := tm.breakers[key]
+	if !ok {
+		b = &circuitBreaker{key: key}
+		tm.breakers[key] = b
+	}
+	return b
+}
+
+// BreakerStatus returns the circuit breaker state of trayType, closed if it
+// has not created trays yet.
+func (tm *TrayManager) BreakerStatus(trayType *config.TrayType) BreakerStatus {
+	return tm.breaker(trayType).status()
+}
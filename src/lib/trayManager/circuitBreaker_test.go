@@ -0,0 +1,119 @@
+package trayManager
+
+import (
+	"cattery/lib/config"
+	"cattery/lib/testutil"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker_OpensAndBacksOff(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.BreakerConfig{FailureThreshold: 3, InitialBackoff: time.Minute, MaxBackoff: 3 * time.Minute}
+	b := &circuitBreaker{key: breakerKey{trayType: "linux", provider: "gce"}}
+	now := time.Now()
+	fail := errors.New("bad instance template")
+
+	assert.Equal(t, 5, b.admit(5, now))
+	b.record(ctx, []error{fail, fail}, cfg, now)
+	assert.Equal(t, BreakerClosed, b.status().State, "below the threshold")
+
+	assert.Equal(t, 5, b.admit(5, now))
+	b.record(ctx, []error{fail}, cfg, now)
+	status := b.status()
+	assert.Equal(t, BreakerOpen, status.State)
+	assert.Equal(t, 3, status.Failures)
+	assert.Equal(t, now.Add(time.Minute), status.RetryAt)
+	assert.Equal(t, "bad instance template", status.LastError)
+
+	assert.Equal(t, 0, b.admit(5, now.Add(59*time.Second)))
+
+	// A single probe once the backoff runs out, and nothing while it runs.
+	now = now.Add(time.Minute)
+	assert.Equal(t, 1, b.admit(5, now))
+	assert.Equal(t, BreakerHalfOpen, b.status().State)
+	assert.Equal(t, 0, b.admit(5, now))
+
+	// Each failed probe doubles the backoff, up to the maximum.
+	b.record(ctx, []error{fail}, cfg, now)
+	assert.Equal(t, now.Add(2*time.Minute), b.status().RetryAt)
+	now = now.Add(2 * time.Minute)
+	assert.Equal(t, 1, b.admit(5, now))
+	b.record(ctx, []error{fail}, cfg, now)
+	assert.Equal(t, now.Add(3*time.Minute), b.status().RetryAt)
+
+	// A successful probe closes the circuit.
+	now = now.Add(3 * time.Minute)
+	assert.Equal(t, 1, b.admit(5, now))
+	b.record(ctx, []error{nil}, cfg, now)
+	status = b.status()
+	assert.Equal(t, BreakerClosed, status.State)
+	assert.Zero(t, status.Failures)
+	assert.Empty(t, status.LastError)
+	assert.Equal(t, 5, b.admit(5, now))
+}
+
+func TestCircuitBreaker_IgnoresQuotaAndCancellation(t *testing.T) {
+	cfg := config.BreakerConfig{FailureThreshold: 1, InitialBackoff: time.Minute, MaxBackoff: time.Minute}
+	b := &circuitBreaker{key: breakerKey{trayType: "linux", provider: "gce"}}
+	now := time.Now()
+
+	b.record(context.Background(), []error{fmt.Errorf("tray type linux: %w: global", ErrQuotaExceeded)}, cfg, now)
+	assert.Equal(t, BreakerClosed, b.status().State)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	b.record(cancelled, []error{context.Canceled}, cfg, now)
+	assert.Equal(t, BreakerClosed, b.status().State)
+
+	// A probe that neither succeeds nor fails is retried on the next pass.
+	b.record(context.Background(), []error{errors.New("boom")}, cfg, now)
+	now = now.Add(time.Minute)
+	assert.Equal(t, 1, b.admit(1, now))
+	b.record(cancelled, []error{context.Canceled}, cfg, now)
+	assert.Equal(t, BreakerOpen, b.status().State)
+	assert.Equal(t, 1, b.admit(1, now))
+}
+
+func TestScaleForDemand_CircuitBreaker(t *testing.T) {
+	config.SetForTest(t, &config.CatteryConfig{
+		Breaker: config.BreakerConfig{FailureThreshold: 2, InitialBackoff: time.Hour},
+	})
+
+	repo := testutil.NewMockTrayRepository()
+	prov := &mockProvider{name: "gce", startErr: errors.New("quota exceeded in region")}
+	tm := newTestManager(repo, &mockProviderFactory{provider: prov})
+	trayType := &config.TrayType{Name: "linux", Provider: "gce", GitHubOrg: "test-org", MaxTrays: 10}
+
+	assert.Error(t, tm.ScaleForDemand(context.Background(), trayType, 3))
+	assert.Equal(t, 3, prov.startCalls)
+	assert.Equal(t, BreakerOpen, tm.BreakerStatus(trayType).State)
+
+	// While open, scale messages create nothing and are not errors.
+	assert.NoError(t, tm.ScaleForDemand(context.Background(), trayType, 3))
+	assert.Equal(t, 3, prov.startCalls)
+
+	// Another tray type on the same provider has its own breaker.
+	other := &config.TrayType{Name: "linux-large", Provider: "gce", GitHubOrg: "test-org", MaxTrays: 10}
+	assert.Equal(t, BreakerClosed, tm.BreakerStatus(other).State)
+}
+
+func TestScaleForDemand_CircuitBreakerDisabled(t *testing.T) {
+	config.SetForTest(t, &config.CatteryConfig{
+		Breaker: config.BreakerConfig{Disabled: true, FailureThreshold: 1},
+	})
+
+	repo := testutil.NewMockTrayRepository()
+	prov := &mockProvider{name: "gce", startErr: errors.New("boom")}
+	tm := newTestManager(repo, &mockProviderFactory{provider: prov})
+	trayType := &config.TrayType{Name: "linux", Provider: "gce", GitHubOrg: "test-org", MaxTrays: 10}
+
+	assert.Error(t, tm.ScaleForDemand(context.Background(), trayType, 2))
+	assert.Error(t, tm.ScaleForDemand(context.Background(), trayType, 2))
+	assert.Equal(t, 4, prov.startCalls)
+}
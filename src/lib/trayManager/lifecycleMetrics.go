@@ -0,0 +1,20 @@
+package trayManager
+
+import (
+	"cattery/lib/trays"
+	"time"
+)
+
+// observeTransition reports the time tray spent between entering from and
+// entering to, if tray is the row returned by the update that first moved it
+// to to. Repeated updates to the same status (a retried delete, a re-sent
+// register) leave the status time alone, so they are not observed twice.
+// Trays that never entered from, or were stored before status times were
+// recorded, are skipped.
+func observeTransition(tray *trays.Tray, from trays.TrayStatus, to trays.TrayStatus, observe func(org string, provider string, trayType string, d time.Duration)) {
+	start, end := tray.StatusTime(from), tray.StatusTime(to)
+	if start.IsZero() || end.IsZero() || !end.Equal(tray.StatusChanged) {
+		return
+	}
+	observe(tray.GitHubOrgName, tray.ProviderName, tray.TrayTypeName, end.Sub(start))
+}
@@ -0,0 +1,61 @@
+package trayManager
+
+import (
+	"cattery/lib/testutil"
+	"cattery/lib/trays"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObserveTransition(t *testing.T) {
+	created := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	registered := created.Add(90 * time.Second)
+
+	var observed []time.Duration
+	observe := func(org string, provider string, trayType string, d time.Duration) {
+		assert.Equal(t, "test-org", org)
+		assert.Equal(t, "gce", provider)
+		assert.Equal(t, "linux", trayType)
+		observed = append(observed, d)
+	}
+	tray := &trays.Tray{
+		GitHubOrgName: "test-org", ProviderName: "gce", TrayTypeName: "linux",
+		CreatedAt: created, RegisteredAt: registered, StatusChanged: registered,
+	}
+
+	observeTransition(tray, trays.TrayStatusCreating, trays.TrayStatusRegistered, observe)
+	assert.Equal(t, []time.Duration{90 * time.Second}, observed)
+
+	// A repeated update to the same status moves StatusChanged but not the
+	// status time.
+	tray.StatusChanged = registered.Add(time.Minute)
+	observeTransition(tray, trays.TrayStatusCreating, trays.TrayStatusRegistered, observe)
+	assert.Len(t, observed, 1)
+
+	// Never entered the start status (or stored before status times).
+	observeTransition(tray, trays.TrayStatusRunning, trays.TrayStatusRegistered, observe)
+	assert.Len(t, observed, 1)
+}
+
+func TestTrayLifecycle_RecordsStatusTimes(t *testing.T) {
+	repo := testutil.NewMockTrayRepository()
+	tm := newTestManager(repo, &mockProviderFactory{provider: &mockProvider{name: "docker"}})
+	ctx := context.Background()
+
+	tray := &trays.Tray{Id: "t1", TrayTypeName: "linux", Status: trays.TrayStatusCreating}
+	require.NoError(t, repo.Save(ctx, tray))
+
+	registered, err := tm.Registered(ctx, "t1", 7)
+	require.NoError(t, err)
+	running, err := tm.SetJob(ctx, "t1", 1, 2, "org/repo", "build", "CI")
+	require.NoError(t, err)
+
+	assert.False(t, running.CreatedAt.IsZero())
+	assert.Equal(t, registered.RegisteredAt, running.RegisteredAt)
+	assert.False(t, running.RunningAt.Before(running.RegisteredAt))
+	assert.True(t, running.DeletingAt.IsZero())
+}
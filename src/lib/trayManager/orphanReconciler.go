@@ -0,0 +1,147 @@
+package trayManager
+
+import (
+	"cattery/lib/config"
+	"cattery/lib/metrics"
+	"cattery/lib/trays"
+	"cattery/lib/trays/providers"
+	"context"
+	"time"
+)
+
+// HandleOrphans periodically deletes upstream resources that look like trays
+// of a configured tray type but have no tray row. The stale handler only
+// sees trays that still have a row, so a resource whose row was deleted
+// first (crash between Delete and the upstream delete, manual DB edits) would
+// otherwise leak. Providers opt in by implementing providers.TrayInventory.
+//
+// Every replica runs the reconciler; deleting an already deleted resource is
+// a no-op for all providers, so no leadership is needed.
+//
+// The reconciler section is re-read before every round, so a config reload
+// that enables or disables it or changes its settings applies from the next
+// round. Turning it off forgets the resources seen so far, so turning it
+// back on starts their grace period over.
+func (tm *TrayManager) HandleOrphans(ctx context.Context) {
+	cfg := currentConfig().Reconciler.WithDefaults()
+	logReconcilerConfig(config.ReconcilerConfig{}, cfg)
+
+	go func() {
+		timer := time.NewTimer(cfg.Interval)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-timer.C:
+				previous := cfg
+				cfg = currentConfig().Reconciler.WithDefaults()
+				logReconcilerConfig(previous, cfg)
+				if cfg.Enabled {
+					tm.reconcileOrphans(ctx, cfg, now)
+				} else {
+					clear(tm.orphansSeen)
+				}
+				timer.Reset(cfg.Interval)
+			}
+		}
+	}()
+}
+
+// logReconcilerConfig logs the reconciler starting, stopping or changing
+// settings between rounds.
+func logReconcilerConfig(previous config.ReconcilerConfig, cfg config.ReconcilerConfig) {
+	switch {
+	case cfg.Enabled && !previous.Enabled:
+		logger.Infof("Orphan reconciler starting: interval=%s gracePeriod=%s dryRun=%t", cfg.Interval, cfg.GracePeriod, cfg.DryRun)
+	case !cfg.Enabled && previous.Enabled:
+		logger.Info("Orphan reconciler stopped")
+	case cfg.Enabled && cfg != previous:
+		logger.Infof("Orphan reconciler settings changed: interval=%s gracePeriod=%s dryRun=%t", cfg.Interval, cfg.GracePeriod, cfg.DryRun)
+	}
+}
+
+// reconcileOrphans runs one reconciliation round. Only the reconciler
+// goroutine touches orphansSeen, so it needs no lock.
+func (tm *TrayManager) reconcileOrphans(ctx context.Context, cfg config.ReconcilerConfig, now time.Time) {
+	listed := make(map[string]bool)
+
+	for _, trayType := range config.Get().TrayTypes {
+		provider, err := tm.providerFactory.GetProvider(trayType.Provider)
+		if err != nil {
+			trayTypeLogger(trayType).Errorf("Orphan reconciler: failed to get provider for type %s: %v", trayType.Name, err)
+			continue
+		}
+		inventory, ok := provider.(providers.TrayInventory)
+		if !ok {
+			trayTypeLogger(trayType).Debugf("Orphan reconciler: provider %s cannot list trays; skipping type %s", trayType.Provider, trayType.Name)
+			continue
+		}
+
+		upstream, err := inventory.ListTrays(ctx, trayType)
+		if err != nil {
+			trayTypeLogger(trayType).Errorf("Orphan reconciler: failed to list trays of type %s: %v", trayType.Name, err)
+			metrics.TrayProviderErrors(trayType.GitHubOrg, trayType.Provider, trayType.Name, "list")
+			continue
+		}
+
+		orphans := 0
+		for _, tray := range upstream {
+			listed[tray.Id] = true
+			if tm.reconcileOrphan(ctx, cfg, now, provider, tray) {
+				orphans++
+			}
+		}
+		metrics.OrphanedTraysSet(trayType.GitHubOrg, trayType.Provider, trayType.Name, orphans)
+	}
+
+	for id := range tm.orphansSeen {
+		if !listed[id] {
+			delete(tm.orphansSeen, id)
+		}
+	}
+}
+
+// reconcileOrphan checks one listed resource and deletes it if it has been
+// without a row for the grace period. Returns whether it counts as an
+// orphan, i.e. is past the grace period but still exists (dry run or a
+// failed delete).
+func (tm *TrayManager) reconcileOrphan(ctx context.Context, cfg config.ReconcilerConfig, now time.Time, provider providers.TrayProvider, tray *trays.Tray) bool {
+	row, err := tm.trayRepository.GetById(ctx, tray.Id)
+	if err != nil {
+		trayLogger(tray).Errorf("Orphan reconciler: failed to look up tray %s: %v", tray.Id, err)
+		return false
+	}
+	if row != nil {
+		delete(tm.orphansSeen, tray.Id)
+		return false
+	}
+
+	firstSeen, ok := tm.orphansSeen[tray.Id]
+	if !ok {
+		firstSeen = now
+		tm.orphansSeen[tray.Id] = now
+	}
+	if now.Sub(firstSeen) < cfg.GracePeriod {
+		trayLogger(tray).Debugf("Orphan reconciler: tray %s has no row; waiting out grace period", tray.Id)
+		return false
+	}
+
+	if cfg.DryRun {
+		trayLogger(tray).Warnf("Orphan reconciler: tray %s (type %s, provider %s) has no row since %s; dry run, not deleting",
+			tray.Id, tray.TrayTypeName, tray.ProviderName, firstSeen.Format(time.RFC3339))
+		return true
+	}
+
+	if err := provider.CleanTray(ctx, tray); err != nil {
+		trayLogger(tray).Errorf("Orphan reconciler: failed to delete orphaned tray %s: %v", tray.Id, err)
+		metrics.TrayProviderErrors(tray.GitHubOrgName, tray.ProviderName, tray.TrayTypeName, "delete")
+		return true
+	}
+
+	trayLogger(tray).Infof("Orphan reconciler: deleted orphaned tray %s (type %s, provider %s)", tray.Id, tray.TrayTypeName, tray.ProviderName)
+	metrics.OrphanedTraysDeletedInc(tray.GitHubOrgName, tray.ProviderName, tray.TrayTypeName)
+	delete(tm.orphansSeen, tray.Id)
+	return false
+}
@@ -0,0 +1,176 @@
+package trayManager
+
+import (
+	"cattery/lib/config"
+	"cattery/lib/testutil"
+	"cattery/lib/trays"
+	"cattery/lib/trays/providers"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// inventoryProvider is a mockProvider that can also list its trays.
+type inventoryProvider struct {
+	*mockProvider
+	listed  []string
+	listErr error
+}
+
+func (p *inventoryProvider) ListTrays(_ context.Context, trayType *config.TrayType) ([]*trays.Tray, error) {
+	if p.listErr != nil {
+		return nil, p.listErr
+	}
+	var result []*trays.Tray
+	for _, id := range p.listed {
+		result = append(result, &trays.Tray{Id: id, TrayTypeName: trayType.Name, ProviderName: p.name, ProviderData: map[string]string{}})
+	}
+	return result, nil
+}
+
+type singleProviderFactory struct {
+	provider providers.TrayProvider
+}
+
+func (f singleProviderFactory) GetProvider(_ string) (providers.TrayProvider, error) {
+	return f.provider, nil
+}
+
+func (f singleProviderFactory) GetProviderForTray(_ *trays.Tray) (providers.TrayProvider, error) {
+	return f.provider, nil
+}
+
+func setupOrphanTest(t *testing.T, provider providers.TrayProvider) (*TrayManager, *testutil.MockTrayRepository) {
+	t.Helper()
+	cfg := &config.CatteryConfig{
+		TrayTypes: []*config.TrayType{{Name: "test-type", Provider: "docker", GitHubOrg: "test-org"}},
+	}
+	cfg.InitMaps()
+	config.SetForTest(t, cfg)
+
+	repo := testutil.NewMockTrayRepository()
+	return NewTrayManager(repo, testutil.NewMockPauseRepository(), testutil.NewMockTrayEventRepository(), singleProviderFactory{provider: provider}), repo
+}
+
+func TestReconcileOrphans_DeletesAfterGracePeriod(t *testing.T) {
+	prov := &inventoryProvider{
+		mockProvider: &mockProvider{name: "docker"},
+		listed:       []string{"test-type-known", "test-type-orphan"},
+	}
+	tm, repo := setupOrphanTest(t, prov)
+	repo.Trays["test-type-known"] = &trays.Tray{Id: "test-type-known", TrayTypeName: "test-type"}
+
+	cfg := config.ReconcilerConfig{Enabled: true, GracePeriod: 10 * time.Minute}
+	now := time.Now()
+
+	tm.reconcileOrphans(context.Background(), cfg, now)
+	assert.Empty(t, prov.cleaned, "first sighting starts the grace period")
+
+	tm.reconcileOrphans(context.Background(), cfg, now.Add(5*time.Minute))
+	assert.Empty(t, prov.cleaned, "still within grace period")
+
+	tm.reconcileOrphans(context.Background(), cfg, now.Add(10*time.Minute))
+	assert.Equal(t, []string{"test-type-orphan"}, prov.cleaned)
+	assert.Empty(t, tm.orphansSeen)
+}
+
+func TestHandleOrphans_RereadsConfigEachRound(t *testing.T) {
+	prov := &inventoryProvider{
+		mockProvider: &mockProvider{name: "docker"},
+		listed:       []string{"test-type-orphan"},
+	}
+	tm, _ := setupOrphanTest(t, prov)
+	cfg := config.Get()
+	cfg.Reconciler = config.ReconcilerConfig{Interval: 10 * time.Millisecond, GracePeriod: time.Nanosecond}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tm.HandleOrphans(ctx)
+
+	cleaned := func() []string {
+		prov.mu.Lock()
+		defer prov.mu.Unlock()
+		return append([]string(nil), prov.cleaned...)
+	}
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, cleaned(), "the reconciler is disabled")
+
+	// A reload that enables it takes effect on the next round.
+	reloaded := *cfg
+	reloaded.Reconciler.Enabled = true
+	config.Set(&reloaded)
+
+	assert.Eventually(t, func() bool { return len(cleaned()) > 0 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "test-type-orphan", cleaned()[0])
+}
+
+func TestReconcileOrphans_DryRun(t *testing.T) {
+	prov := &inventoryProvider{
+		mockProvider: &mockProvider{name: "docker"},
+		listed:       []string{"test-type-orphan"},
+	}
+	tm, _ := setupOrphanTest(t, prov)
+
+	cfg := config.ReconcilerConfig{Enabled: true, GracePeriod: time.Minute, DryRun: true}
+	now := time.Now()
+
+	tm.reconcileOrphans(context.Background(), cfg, now)
+	tm.reconcileOrphans(context.Background(), cfg, now.Add(time.Hour))
+	assert.Empty(t, prov.cleaned)
+	assert.Contains(t, tm.orphansSeen, "test-type-orphan")
+}
+
+func TestReconcileOrphans_ForgetsResourcesThatReappearOrVanish(t *testing.T) {
+	prov := &inventoryProvider{
+		mockProvider: &mockProvider{name: "docker"},
+		listed:       []string{"test-type-a", "test-type-b"},
+	}
+	tm, repo := setupOrphanTest(t, prov)
+
+	cfg := config.ReconcilerConfig{Enabled: true, GracePeriod: 10 * time.Minute}
+	now := time.Now()
+	tm.reconcileOrphans(context.Background(), cfg, now)
+	assert.Len(t, tm.orphansSeen, 2)
+
+	// a got its row back (e.g. a slow CreateTray); b disappeared upstream.
+	repo.Trays["test-type-a"] = &trays.Tray{Id: "test-type-a", TrayTypeName: "test-type"}
+	prov.listed = []string{"test-type-a"}
+
+	tm.reconcileOrphans(context.Background(), cfg, now.Add(time.Minute))
+	assert.Empty(t, tm.orphansSeen)
+
+	tm.reconcileOrphans(context.Background(), cfg, now.Add(time.Hour))
+	assert.Empty(t, prov.cleaned)
+}
+
+func TestReconcileOrphans_ListAndLookupErrors(t *testing.T) {
+	t.Run("list error skips the tray type", func(t *testing.T) {
+		prov := &inventoryProvider{mockProvider: &mockProvider{name: "docker"}, listErr: errors.New("boom")}
+		tm, _ := setupOrphanTest(t, prov)
+
+		tm.reconcileOrphans(context.Background(), config.ReconcilerConfig{GracePeriod: time.Minute}, time.Now())
+		assert.Empty(t, tm.orphansSeen)
+	})
+
+	t.Run("lookup error never counts as missing row", func(t *testing.T) {
+		prov := &inventoryProvider{mockProvider: &mockProvider{name: "docker"}, listed: []string{"test-type-a"}}
+		tm, repo := setupOrphanTest(t, prov)
+		repo.GetErr = errors.New("db down")
+
+		now := time.Now()
+		tm.reconcileOrphans(context.Background(), config.ReconcilerConfig{GracePeriod: time.Minute}, now)
+		tm.reconcileOrphans(context.Background(), config.ReconcilerConfig{GracePeriod: time.Minute}, now.Add(time.Hour))
+		assert.Empty(t, prov.cleaned)
+	})
+
+	t.Run("providers without inventory are skipped", func(t *testing.T) {
+		prov := &mockProvider{name: "docker"}
+		tm, _ := setupOrphanTest(t, prov)
+
+		tm.reconcileOrphans(context.Background(), config.ReconcilerConfig{GracePeriod: time.Minute}, time.Now())
+		assert.Empty(t, prov.cleaned)
+	})
+}
@@ -0,0 +1,107 @@
+package trayManager
+
+import (
+	"cattery/lib/config"
+	"cattery/lib/trays/repositories"
+	"context"
+	"fmt"
+	"time"
+)
+
+// Pause sources, reported in TrayTypePause.Source.
+const (
+	PauseSourceConfig = "config"
+	PauseSourceAPI    = "api"
+)
+
+// TrayTypePause is the pause in effect for a tray type, either from its
+// paused config flag or set at runtime through the admin API. A runtime pause
+// takes precedence, so its DeleteIdle applies; PausedAt is zero for a config
+// pause.
+type TrayTypePause struct {
+	TrayTypeName string    `json:"trayTypeName"`
+	Source       string    `json:"source"`
+	DeleteIdle   bool      `json:"deleteIdle"`
+	PausedAt     time.Time `json:"pausedAt,omitzero"`
+}
+
+func newTrayTypePause(trayType *config.TrayType, stored *repositories.TrayTypePause) *TrayTypePause {
+	if stored != nil {
+		return &TrayTypePause{
+			TrayTypeName: stored.TrayTypeName,
+			Source:       PauseSourceAPI,
+			DeleteIdle:   stored.DeleteIdle,
+			PausedAt:     stored.PausedAt,
+		}
+	}
+	if trayType.Paused {
+		return &TrayTypePause{
+			TrayTypeName: trayType.Name,
+			Source:       PauseSourceConfig,
+			DeleteIdle:   trayType.DeleteIdleWhenPaused,
+		}
+	}
+	return nil
+}
+
+// GetPause returns the pause in effect for trayType, or nil if it is not
+// paused.
+func (tm *TrayManager) GetPause(ctx context.Context, trayType *config.TrayType) (*TrayTypePause, error) {
+	stored, err := tm.pauseRepository.GetPause(ctx, trayType.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pause of tray type %s: %w", trayType.Name, err)
+	}
+	return newTrayTypePause(trayType, stored), nil
+}
+
+// ListPauses returns the pause in effect for each tray type in trayTypes,
+// keyed by name. Tray types that are not paused are absent.
+func (tm *TrayManager) ListPauses(ctx context.Context, trayTypes []*config.TrayType) (map[string]*TrayTypePause, error) {
+	stored, err := tm.pauseRepository.ListPauses(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tray type pauses: %w", err)
+	}
+
+	byName := make(map[string]*repositories.TrayTypePause, len(stored))
+	for i := range stored {
+		byName[stored[i].TrayTypeName] = &stored[i]
+	}
+
+	result := make(map[string]*TrayTypePause)
+	for _, trayType := range trayTypes {
+		if pause := newTrayTypePause(trayType, byName[trayType.Name]); pause != nil {
+			result[trayType.Name] = pause
+		}
+	}
+	return result, nil
+}
+
+// PauseTrayType stops tray creation for trayType until ResumeTrayType. Jobs
+// already running finish on their trays. With deleteIdle, registered trays
+// waiting for a job are deleted now, and on later scale rounds as in-flight
+// trays register.
+func (tm *TrayManager) PauseTrayType(ctx context.Context, trayType *config.TrayType, deleteIdle bool) error {
+	if err := tm.pauseRepository.Pause(ctx, trayType.Name, deleteIdle); err != nil {
+		return fmt.Errorf("failed to pause tray type %s: %w", trayType.Name, err)
+	}
+	trayTypeLogger(trayType).Infof("Tray type %s paused (deleteIdle=%t)", trayType.Name, deleteIdle)
+
+	if deleteIdle {
+		return tm.deleteIdleTrays(ctx, trayType.Name)
+	}
+	return nil
+}
+
+// ResumeTrayType removes a runtime pause of trayType. A tray type paused in
+// the config stays paused. Demand is picked up on the next listener message.
+func (tm *TrayManager) ResumeTrayType(ctx context.Context, trayType *config.TrayType) error {
+	if err := tm.pauseRepository.Resume(ctx, trayType.Name); err != nil {
+		return fmt.Errorf("failed to resume tray type %s: %w", trayType.Name, err)
+	}
+	if trayType.Paused {
+		trayTypeLogger(trayType).Warnf("Tray type %s resumed, but stays paused by its config", trayType.Name)
+		return nil
+	}
+	trayTypeLogger(trayType).Infof("Tray type %s resumed", trayType.Name)
+	return nil
+}
@@ -0,0 +1,108 @@
+package trayManager
+
+import (
+	"cattery/lib/config"
+	"cattery/lib/testutil"
+	"cattery/lib/trays"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPauseTestManager(repo *testutil.MockTrayRepository, prov *mockProvider) (*TrayManager, *testutil.MockPauseRepository) {
+	pauses := testutil.NewMockPauseRepository()
+	return NewTrayManager(repo, pauses, testutil.NewMockTrayEventRepository(), &mockProviderFactory{provider: prov}), pauses
+}
+
+func TestScaleForDemand_Paused_CreatesNothing(t *testing.T) {
+	repo := testutil.NewMockTrayRepository()
+	addTrays(repo, "idle", trays.TrayStatusRegistered, 2)
+	prov := &mockProvider{name: "docker"}
+	tm, _ := newPauseTestManager(repo, prov)
+
+	trayType := &config.TrayType{Name: "test-type", Provider: "docker", MaxTrays: 10, MinIdle: 3, Paused: true}
+
+	require.NoError(t, tm.ScaleForDemand(context.Background(), trayType, 5))
+	assert.Equal(t, 0, prov.startCalls)
+	assert.Len(t, repo.Trays, 2, "idle trays are kept without deleteIdleWhenPaused")
+}
+
+func TestScaleForDemand_Paused_DeletesIdle(t *testing.T) {
+	repo := testutil.NewMockTrayRepository()
+	addTrays(repo, "idle", trays.TrayStatusRegistered, 2)
+	addTrays(repo, "running", trays.TrayStatusRunning, 1)
+	addTrays(repo, "creating", trays.TrayStatusCreating, 1)
+	prov := &mockProvider{name: "docker"}
+	tm, pauses := newPauseTestManager(repo, prov)
+	require.NoError(t, pauses.Pause(context.Background(), "test-type", true))
+
+	trayType := &config.TrayType{Name: "test-type", Provider: "docker", MaxTrays: 10}
+
+	require.NoError(t, tm.ScaleForDemand(context.Background(), trayType, 3))
+	assert.Equal(t, 0, prov.startCalls)
+	assert.ElementsMatch(t, []string{"idle-0", "idle-1"}, prov.cleaned)
+	assert.Contains(t, repo.Trays, "running-0", "running jobs finish")
+	assert.Contains(t, repo.Trays, "creating-0")
+}
+
+func TestScaleForDemand_PauseError(t *testing.T) {
+	prov := &mockProvider{name: "docker"}
+	tm, pauses := newPauseTestManager(testutil.NewMockTrayRepository(), prov)
+	pauses.Err = errors.New("db down")
+
+	trayType := &config.TrayType{Name: "test-type", Provider: "docker", MaxTrays: 10}
+
+	assert.Error(t, tm.ScaleForDemand(context.Background(), trayType, 3))
+	assert.Equal(t, 0, prov.startCalls, "an unknown pause state creates nothing")
+}
+
+func TestGetPause_RuntimePauseOverridesConfig(t *testing.T) {
+	tm, pauses := newPauseTestManager(testutil.NewMockTrayRepository(), &mockProvider{})
+	ctx := context.Background()
+	trayType := &config.TrayType{Name: "test-type", Paused: true, DeleteIdleWhenPaused: true}
+
+	pause, err := tm.GetPause(ctx, trayType)
+	require.NoError(t, err)
+	require.NotNil(t, pause)
+	assert.Equal(t, PauseSourceConfig, pause.Source)
+	assert.True(t, pause.DeleteIdle)
+	assert.True(t, pause.PausedAt.IsZero())
+
+	require.NoError(t, pauses.Pause(ctx, "test-type", false))
+	pause, err = tm.GetPause(ctx, trayType)
+	require.NoError(t, err)
+	require.NotNil(t, pause)
+	assert.Equal(t, PauseSourceAPI, pause.Source)
+	assert.False(t, pause.DeleteIdle)
+	assert.False(t, pause.PausedAt.IsZero())
+}
+
+func TestPauseAndResumeTrayType(t *testing.T) {
+	repo := testutil.NewMockTrayRepository()
+	addTrays(repo, "idle", trays.TrayStatusRegistered, 1)
+	addTrays(repo, "running", trays.TrayStatusRunning, 1)
+	prov := &mockProvider{name: "docker"}
+	tm, _ := newPauseTestManager(repo, prov)
+	ctx := context.Background()
+	trayType := &config.TrayType{Name: "test-type", Provider: "docker", MaxTrays: 10}
+
+	require.NoError(t, tm.PauseTrayType(ctx, trayType, true))
+	assert.Equal(t, []string{"idle-0"}, prov.cleaned, "idle trays are deleted right away")
+	assert.Contains(t, repo.Trays, "running-0")
+
+	listed, err := tm.ListPauses(ctx, []*config.TrayType{trayType, {Name: "other"}})
+	require.NoError(t, err)
+	assert.Len(t, listed, 1)
+	assert.Contains(t, listed, "test-type")
+
+	require.NoError(t, tm.ResumeTrayType(ctx, trayType))
+	pause, err := tm.GetPause(ctx, trayType)
+	require.NoError(t, err)
+	assert.Nil(t, pause)
+
+	require.NoError(t, tm.ScaleForDemand(ctx, trayType, 2))
+	assert.Positive(t, prov.startCalls, "demand is served again after resume")
+}
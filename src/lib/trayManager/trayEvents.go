@@ -0,0 +1,99 @@
+package trayManager
+
+import (
+	"cattery/lib/config"
+	"cattery/lib/logging"
+	"cattery/lib/trays"
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// TrayChange is a tray event recorded on this replica. Tray is the tray as
+// of the event; it is nil when the event was recorded by tray id only and
+// after the tray was deleted, so Deleted tells those apart.
+type TrayChange struct {
+	Event   trays.TrayEvent
+	Tray    *trays.Tray
+	Deleted bool
+}
+
+// SubscribeTrayChanges returns a channel receiving every tray event recorded
+// on this replica from now on, and a func that unsubscribes it. The channel
+// is closed if the subscriber falls buffer changes behind.
+func (tm *TrayManager) SubscribeTrayChanges(buffer int) (<-chan TrayChange, func()) {
+	return tm.changes.Subscribe(buffer)
+}
+
+// RecordEvent appends an event to the lifecycle log of tray trayId. The log
+// is for debugging, so failing to write it is logged and otherwise ignored.
+func (tm *TrayManager) RecordEvent(ctx context.Context, trayId string, trayTypeName string, kind trays.TrayEventKind, message string) {
+	tm.addEvent(ctx, trayId, trayTypeName, kind, message, nil)
+}
+
+func (tm *TrayManager) recordEvent(ctx context.Context, tray *trays.Tray, kind trays.TrayEventKind, message string) {
+	tm.addEvent(ctx, tray.Id, tray.TrayTypeName, kind, message, tray)
+}
+
+func (tm *TrayManager) addEvent(ctx context.Context, trayId string, trayTypeName string, kind trays.TrayEventKind, message string, tray *trays.Tray) {
+	event := &trays.TrayEvent{
+		TrayId:       trayId,
+		TrayTypeName: trayTypeName,
+		Kind:         kind,
+		Message:      message,
+		Time:         time.Now().UTC(),
+	}
+	if err := tm.eventRepository.AddEvent(ctx, event); err != nil {
+		logger.WithFields(log.Fields{logging.TrayIdKey: trayId, logging.TrayTypeKey: trayTypeName}).Warnf("Failed to record %s event for tray %s: %v", kind, trayId, err)
+	}
+
+	change := TrayChange{Event: *event, Deleted: kind == trays.TrayEventDeleted}
+	if tray != nil && !change.Deleted {
+		// Subscribers read the tray later, after the caller may have moved on
+		// and changed it.
+		snapshot := *tray
+		change.Tray = &snapshot
+	}
+	tm.changes.Publish(change)
+}
+
+// ListTrayEvents returns the lifecycle events of tray trayId, oldest first.
+// Events of deleted trays are kept until pruned.
+func (tm *TrayManager) ListTrayEvents(ctx context.Context, trayId string) ([]trays.TrayEvent, error) {
+	return tm.eventRepository.ListEvents(ctx, trayId)
+}
+
+// HandleTrayEvents periodically deletes tray events older than the
+// configured retention. Every replica prunes; deleting already deleted
+// events is harmless.
+func (tm *TrayManager) HandleTrayEvents(ctx context.Context) {
+	cfg := config.Get().TrayEvents.WithDefaults()
+
+	logger.Infof("Tray event pruning starting: retention=%s pruneInterval=%s", cfg.Retention, cfg.PruneInterval)
+
+	go func() {
+		ticker := time.NewTicker(cfg.PruneInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				tm.pruneTrayEvents(ctx, now.Add(-cfg.Retention))
+			}
+		}
+	}()
+}
+
+func (tm *TrayManager) pruneTrayEvents(ctx context.Context, before time.Time) {
+	pruned, err := tm.eventRepository.PruneEvents(ctx, before)
+	if err != nil {
+		logger.Errorf("Failed to prune tray events: %v", err)
+		return
+	}
+	if pruned > 0 {
+		logger.Debugf("Pruned %d tray events older than %s", pruned, before.UTC().Format(time.RFC3339))
+	}
+}
@@ -0,0 +1,148 @@
+package trayManager
+
+import (
+	"cattery/lib/config"
+	"cattery/lib/testutil"
+	"cattery/lib/trays"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newEventTestManager(repo *testutil.MockTrayRepository, prov *mockProvider) (*TrayManager, *testutil.MockTrayEventRepository) {
+	events := testutil.NewMockTrayEventRepository()
+	return NewTrayManager(repo, testutil.NewMockPauseRepository(), events, &mockProviderFactory{provider: prov}), events
+}
+
+func TestTrayEvents_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	repo := testutil.NewMockTrayRepository()
+	tm, events := newEventTestManager(repo, &mockProvider{name: "docker"})
+
+	trayType := &config.TrayType{Name: "test-type", Provider: "docker", GitHubOrg: "test-org"}
+	require.NoError(t, tm.CreateTray(ctx, trayType))
+	require.Len(t, repo.Trays, 1)
+	var trayId string
+	for id := range repo.Trays {
+		trayId = id
+	}
+
+	_, err := tm.Registering(ctx, trayId)
+	require.NoError(t, err)
+	_, err = tm.Registered(ctx, trayId, 42)
+	require.NoError(t, err)
+	_, err = tm.SetJob(ctx, trayId, 1, 2, "org/repo", "build", "ci")
+	require.NoError(t, err)
+	_, err = tm.DeleteTray(ctx, trayId)
+	require.NoError(t, err)
+
+	assert.Equal(t, []trays.TrayEventKind{
+		trays.TrayEventCreated,
+		trays.TrayEventStartDeploy,
+		trays.TrayEventWaitDeploy,
+		trays.TrayEventRegistering,
+		trays.TrayEventRegistered,
+		trays.TrayEventJobAssigned,
+		trays.TrayEventDeleting,
+		trays.TrayEventDeleted,
+	}, events.Kinds(trayId))
+
+	logged, err := tm.ListTrayEvents(ctx, trayId)
+	require.NoError(t, err)
+	assert.Equal(t, "test-type", logged[0].TrayTypeName)
+	assert.Equal(t, "runner id 42", logged[4].Message)
+	assert.Equal(t, "build in org/repo (workflow run 2)", logged[5].Message)
+}
+
+func TestTrayEvents_DeployAndCleanFailures(t *testing.T) {
+	ctx := context.Background()
+	repo := testutil.NewMockTrayRepository()
+	prov := &mockProvider{name: "docker", startErr: errors.New("no capacity"), cleanErr: errors.New("api down")}
+	tm, events := newEventTestManager(repo, prov)
+
+	trayType := &config.TrayType{Name: "test-type", Provider: "docker", GitHubOrg: "test-org"}
+	require.Error(t, tm.CreateTray(ctx, trayType))
+	require.Len(t, repo.Trays, 1, "the failed cleanup leaves the row for the stale handler")
+	var trayId string
+	for id := range repo.Trays {
+		trayId = id
+	}
+
+	// The stale handler's retry logs the clean failure again, but not a
+	// second deleting event.
+	_, err := tm.DeleteTray(ctx, trayId)
+	require.NoError(t, err)
+
+	assert.Equal(t, []trays.TrayEventKind{
+		trays.TrayEventCreated,
+		trays.TrayEventStartDeployFailed,
+		trays.TrayEventDeleting,
+		trays.TrayEventCleanFailed,
+		trays.TrayEventCleanFailed,
+	}, events.Kinds(trayId))
+	assert.Equal(t, "no capacity", events.Events[1].Message)
+	assert.Equal(t, "api down", events.Events[3].Message)
+}
+
+func TestTrayEvents_RecordErrorIsIgnored(t *testing.T) {
+	repo := testutil.NewMockTrayRepository()
+	repo.Trays["tray-1"] = &trays.Tray{Id: "tray-1", TrayTypeName: "test-type"}
+	tm, events := newEventTestManager(repo, &mockProvider{name: "docker"})
+	events.Err = errors.New("db down")
+
+	tray, err := tm.Registered(context.Background(), "tray-1", 42)
+	require.NoError(t, err)
+	assert.Equal(t, trays.TrayStatusRegistered, tray.Status)
+}
+
+func TestSubscribeTrayChanges(t *testing.T) {
+	ctx := context.Background()
+	repo := testutil.NewMockTrayRepository()
+	repo.Trays["tray-1"] = &trays.Tray{Id: "tray-1", TrayTypeName: "test-type", Status: trays.TrayStatusRegistering}
+	tm, _ := newEventTestManager(repo, &mockProvider{name: "docker"})
+
+	changes, stop := tm.SubscribeTrayChanges(10)
+	defer stop()
+
+	_, err := tm.Registered(ctx, "tray-1", 42)
+	require.NoError(t, err)
+	tm.RecordEvent(ctx, "tray-1", "test-type", trays.TrayEventUnregistered, "done")
+	_, err = tm.DeleteTray(ctx, "tray-1")
+	require.NoError(t, err)
+
+	change := <-changes
+	assert.Equal(t, trays.TrayEventRegistered, change.Event.Kind)
+	require.NotNil(t, change.Tray)
+	assert.Equal(t, trays.TrayStatusRegistered, change.Tray.Status)
+
+	change = <-changes
+	assert.Equal(t, trays.TrayEventUnregistered, change.Event.Kind)
+	assert.Nil(t, change.Tray, "events recorded by id carry no tray")
+	assert.False(t, change.Deleted)
+
+	change = <-changes
+	assert.Equal(t, trays.TrayEventDeleting, change.Event.Kind)
+	assert.Equal(t, trays.TrayStatusDeleting, change.Tray.Status)
+
+	change = <-changes
+	assert.Equal(t, trays.TrayEventDeleted, change.Event.Kind)
+	assert.True(t, change.Deleted)
+	assert.Nil(t, change.Tray)
+}
+
+func TestPruneTrayEvents(t *testing.T) {
+	ctx := context.Background()
+	tm, events := newEventTestManager(testutil.NewMockTrayRepository(), &mockProvider{})
+	now := time.Now().UTC()
+	events.Events = []trays.TrayEvent{
+		{TrayId: "tray-1", Kind: trays.TrayEventCreated, Time: now.Add(-8 * 24 * time.Hour)},
+		{TrayId: "tray-1", Kind: trays.TrayEventDeleted, Time: now.Add(-time.Hour)},
+	}
+
+	tm.pruneTrayEvents(ctx, now.Add(-config.DefaultTrayEventsRetention))
+	assert.Equal(t, []trays.TrayEventKind{trays.TrayEventDeleted}, events.Kinds("tray-1"))
+}
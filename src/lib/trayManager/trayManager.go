@@ -1,89 +1,223 @@
 package trayManager
 
 import (
+	"cattery/lib/broadcast"
 	"cattery/lib/config"
+	"cattery/lib/logging"
 	"cattery/lib/metrics"
+	"cattery/lib/tracing"
 	"cattery/lib/trays"
 	"cattery/lib/trays/providers"
 	"cattery/lib/trays/repositories"
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// ErrQuotaExceeded is returned (wrapped) by CreateTray when creating the tray
+// would exceed one of the quotas counting its tray type.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
 type TrayManager struct {
 	trayRepository  repositories.TrayRepository
+	pauseRepository repositories.PauseRepository
+	eventRepository repositories.TrayEventRepository
 	providerFactory providers.TrayProviderFactory
+
+	// scaleMu guards scaleStates. Each tray type's scaleState serializes its
+	// ScaleForDemand calls, which come from the listener and the schedule timer.
+	scaleMu     sync.Mutex
+	scaleStates map[string]*scaleState
+
+	// orphansSeen records when the reconciler first saw each upstream
+	// resource without a tray row, keyed by tray id.
+	orphansSeen map[string]time.Time
+
+	breakerMu sync.Mutex
+	breakers  map[breakerKey]*circuitBreaker
+
+	// changes receives every tray event recorded on this replica, for the
+	// live status page.
+	changes broadcast.Broadcaster[TrayChange]
+}
+
+// scaleState is the last scaling decision for a tray type.
+type scaleState struct {
+	mu        sync.Mutex
+	desired   int
+	updated   time.Time
+	profile   string
+	evaluated bool
 }
 
-func NewTrayManager(trayRepository repositories.TrayRepository, providerFactory providers.TrayProviderFactory) *TrayManager {
+func NewTrayManager(trayRepository repositories.TrayRepository, pauseRepository repositories.PauseRepository, eventRepository repositories.TrayEventRepository, providerFactory providers.TrayProviderFactory) *TrayManager {
 	return &TrayManager{
 		trayRepository:  trayRepository,
+		pauseRepository: pauseRepository,
+		eventRepository: eventRepository,
 		providerFactory: providerFactory,
+		scaleStates:     make(map[string]*scaleState),
+		orphansSeen:     make(map[string]time.Time),
+		breakers:        make(map[breakerKey]*circuitBreaker),
+	}
+}
+
+var logger = logging.Logger("trayManager")
+
+// trayLogger returns the logger for lines concerning tray.
+func trayLogger(tray *trays.Tray) *log.Entry {
+	return logging.WithTray(logger, tray)
+}
+
+// trayTypeLogger returns the logger for lines concerning trayType as a whole.
+func trayTypeLogger(trayType *config.TrayType) *log.Entry {
+	return logger.WithFields(log.Fields{
+		logging.TrayTypeKey: trayType.Name,
+		logging.OrgKey:      trayType.GitHubOrg,
+		logging.ProviderKey: trayType.Provider,
+	})
+}
+
+// currentConfig returns the current config, or an empty one if none has been
+// loaded, as in tests that drive the manager directly.
+func currentConfig() *config.CatteryConfig {
+	if cfg := config.Get(); cfg != nil {
+		return cfg
+	}
+	return &config.CatteryConfig{}
+}
+
+// trayBatch is the trays reserveTrays saved under a tray type's scale lock,
+// for deployTrays to deploy once the lock is released.
+type trayBatch struct {
+	trayType *config.TrayType
+	provider providers.TrayProvider
+
+	// trays and results line up: a tray whose reservation failed is nil, with
+	// the reason in results.
+	trays   []*trays.Tray
+	results []error
+
+	// breaker is nil when the circuit breaker is disabled.
+	breaker       *circuitBreaker
+	breakerConfig config.BreakerConfig
+}
+
+// reserveTrays saves the rows of up to count new trays of trayType, as many
+// as the circuit breaker admits, without calling the provider. Reserved rows
+// count towards the tray type's active trays right away, so a scaling pass
+// that runs while they deploy does not create them again. It returns nil if
+// nothing was admitted.
+func (tm *TrayManager) reserveTrays(ctx context.Context, trayType *config.TrayType, count int) *trayBatch {
+	batch := &trayBatch{trayType: trayType, breakerConfig: currentConfig().Breaker.WithDefaults()}
+	if !batch.breakerConfig.Disabled {
+		batch.breaker = tm.breaker(trayType)
+		admitted := batch.breaker.admit(count, time.Now())
+		if admitted == 0 {
+			trayTypeLogger(trayType).Debugf("Circuit breaker for tray type %s is open; not creating %d trays", trayType.Name, count)
+			return nil
+		}
+		count = admitted
 	}
+
+	batch.trays = make([]*trays.Tray, count)
+	batch.results = make([]error, count)
+	provider, err := tm.providerFactory.GetProvider(trayType.Provider)
+	if err != nil {
+		for i := range count {
+			batch.results[i] = fmt.Errorf("failed to get provider for type %s: %w", trayType.Name, err)
+		}
+		return batch
+	}
+	batch.provider = provider
+	for i := range count {
+		batch.trays[i], batch.results[i] = tm.reserveTray(ctx, trayType)
+	}
+	return batch
 }
 
-func (tm *TrayManager) createTrays(ctx context.Context, trayType *config.TrayType, count int) error {
+// deployTrays deploys the reserved trays of batch concurrently, limited to
+// the tray type's maxParallelCreation at a time, and records the results on
+// its circuit breaker.
+func (tm *TrayManager) deployTrays(ctx context.Context, batch *trayBatch) (err error) {
+	trayType := batch.trayType
 	maxParallel := trayType.MaxParallelCreation
 	if maxParallel <= 0 {
 		maxParallel = config.DefaultMaxParallelCreation
 	}
 
-	results := tm.createTraysParallel(ctx, trayType, count, maxParallel)
-	return tm.logCreationResults(trayType.Name, results)
-}
+	ctx, span := tracing.Start(ctx, "trayManager.deployTrays",
+		tracing.TrayTypeKey.String(trayType.Name), attribute.Int("cattery.count", len(batch.trays)))
+	defer func() { tracing.End(span, err) }()
 
-// createTraysParallel creates trays concurrently, limited to maxParallel at a time.
-// Returns a slice of errors, one per tray (nil means success).
-func (tm *TrayManager) createTraysParallel(ctx context.Context, trayType *config.TrayType, count int, maxParallel int) []error {
 	var wg sync.WaitGroup
 	semaphore := make(chan struct{}, maxParallel)
-	errors := make([]error, count)
-
-	for i := 0; i < count; i++ {
+	for i, tray := range batch.trays {
+		if batch.results[i] != nil {
+			continue
+		}
 		semaphore <- struct{}{} // block if maxParallel goroutines are already running
 		wg.Add(1)
-
-		go func(index int) {
+		go func(index int, tray *trays.Tray) {
 			defer wg.Done()
 			defer func() { <-semaphore }()
 
-			log.Infof("Creating tray %d/%d for type: %s", index+1, count, trayType.Name)
-			errors[index] = tm.CreateTray(ctx, trayType)
-		}(i)
+			trayLogger(tray).Infof("Creating tray %d/%d for type: %s", index+1, len(batch.trays), trayType.Name)
+			batch.results[index] = tm.deployTray(ctx, batch.provider, tray)
+		}(i, tray)
 	}
-
 	wg.Wait()
-	return errors
+
+	if batch.breaker != nil {
+		batch.breaker.record(ctx, batch.results, batch.breakerConfig, time.Now())
+	}
+	return tm.logCreationResults(trayType.Name, batch.results)
 }
 
 func (tm *TrayManager) logCreationResults(trayTypeName string, results []error) error {
 	total := len(results)
 	failed := 0
+	limited := 0
 
 	for _, err := range results {
-		if err != nil {
-			log.Errorf("Failed to create tray for type %s: %v", trayTypeName, err)
+		switch {
+		case err == nil:
+		case errors.Is(err, ErrQuotaExceeded):
+			limited++
+		default:
+			logger.WithField(logging.TrayTypeKey, trayTypeName).Errorf("Failed to create tray for type %s: %v", trayTypeName, err)
 			failed++
 		}
 	}
 
-	if failed == total {
+	// Hitting a quota is the quota working, not a failure; the demand is
+	// retried on the next scaling pass.
+	if limited > 0 {
+		logger.WithField(logging.TrayTypeKey, trayTypeName).Infof("%d out of %d tray creations for type %s held back by quota", limited, total, trayTypeName)
+	}
+	total -= limited
+
+	if total > 0 && failed == total {
 		return fmt.Errorf("all %d tray creations failed for type %s", total, trayTypeName)
 	}
 	if failed > 0 {
-		log.Warnf("%d out of %d tray creations failed for type %s", failed, total, trayTypeName)
+		logger.WithField(logging.TrayTypeKey, trayTypeName).Warnf("%d out of %d tray creations failed for type %s", failed, total, trayTypeName)
 	}
 
 	return nil
 }
 
 // CreateTray reserves a tray row before any provider call so that an agent
-// booting on the new VM can register against an existing record. The deploy
-// runs in two phases:
+// booting on the new VM can register against an existing record. If quotas
+// count the tray type, the row is only reserved while they have room; else
+// CreateTray returns ErrQuotaExceeded without touching the provider. The
+// deploy runs in two phases:
 //
 //  1. StartDeploy submits the create request and populates tray.ProviderData
 //     with cleanup-relevant fields. We persist that data immediately so a
@@ -93,56 +227,105 @@ func (tm *TrayManager) logCreationResults(trayTypeName string, results []error)
 //     during either phase flip the row to TrayStatusDeleting; we observe
 //     that on the post-WaitDeploy persist and trigger cleanup. We don't
 //     check status mid-deploy — at worst we waste the wait phase.
-func (tm *TrayManager) CreateTray(ctx context.Context, trayType *config.TrayType) error {
+//
+// The tray records the trace context of CreateTray's span, so its agent's
+// spans and later operations on it join the trace that created it.
+func (tm *TrayManager) CreateTray(ctx context.Context, trayType *config.TrayType) (err error) {
+	ctx, span := tracing.Start(ctx, "trayManager.CreateTray",
+		tracing.TrayTypeKey.String(trayType.Name), tracing.ProviderKey.String(trayType.Provider))
+	defer func() { tracing.End(span, err) }()
+
 	provider, err := tm.providerFactory.GetProvider(trayType.Provider)
 	if err != nil {
 		return fmt.Errorf("failed to get provider for type %s: %w", trayType.Name, err)
 	}
 
-	tray, err := trays.NewTray(*trayType)
+	tray, err := tm.reserveTray(ctx, trayType)
 	if err != nil {
 		return err
 	}
+	span.SetAttributes(tracing.TrayAttributes(tray)...)
 
-	if err := tm.trayRepository.Save(ctx, tray); err != nil {
-		return fmt.Errorf("failed to save tray %s: %w", tray.Id, err)
+	return tm.deployTray(ctx, provider, tray)
+}
+
+// reserveTray saves the row of a new tray of trayType, within the quotas
+// counting it, and records its creation. The tray records the trace context
+// of ctx.
+func (tm *TrayManager) reserveTray(ctx context.Context, trayType *config.TrayType) (*trays.Tray, error) {
+	tray, err := trays.NewTray(*trayType)
+	if err != nil {
+		return nil, err
+	}
+	tray.TraceParent = tracing.TraceParent(ctx)
+
+	if quotas := currentConfig().QuotasFor(trayType); len(quotas) > 0 {
+		exceeded, err := tm.trayRepository.SaveWithinQuotas(ctx, tray, quotas)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save tray %s: %w", tray.Id, err)
+		}
+		if exceeded != nil {
+			metrics.TrayQuotaLimitedInc(exceeded.Name, trayType.Name)
+			return nil, fmt.Errorf("tray type %s: %w: %s", trayType.Name, ErrQuotaExceeded, exceeded.Name)
+		}
+	} else if err := tm.trayRepository.Save(ctx, tray); err != nil {
+		return nil, fmt.Errorf("failed to save tray %s: %w", tray.Id, err)
 	}
+	tm.recordEvent(ctx, tray, trays.TrayEventCreated, fmt.Sprintf("provider %s", tray.ProviderName))
+	return tray, nil
+}
 
+// deployTray runs the provider deploy of a reserved tray; see CreateTray.
+func (tm *TrayManager) deployTray(ctx context.Context, provider providers.TrayProvider, tray *trays.Tray) error {
+	// Providers that reroute the tray mid-deploy save where it went first.
+	ctx = providers.WithProviderDataSaver(ctx, func(ctx context.Context, tray *trays.Tray) error {
+		_, err := tm.trayRepository.SetProviderData(ctx, tray.Id, tray.ProviderData)
+		return err
+	})
+
+	started := time.Now()
 	if err := provider.StartDeploy(ctx, tray); err != nil {
-		log.Errorf("Failed start deploy for tray %s: %v", tray.Id, err)
+		trayLogger(tray).Errorf("Failed start deploy for tray %s: %v", tray.Id, err)
+		tm.recordEvent(ctx, tray, trays.TrayEventStartDeployFailed, err.Error())
 		metrics.TrayProviderErrors(tray.GitHubOrgName, tray.ProviderName, tray.TrayTypeName, "create")
 		// Persist any provider data the failed StartDeploy populated (e.g.,
 		// nomad's parentJobId for leaked-child recovery) before DeleteTray
 		// reloads the row and dispatches CleanTray on it.
 		if _, pErr := tm.trayRepository.SetProviderData(ctx, tray.Id, tray.ProviderData); pErr != nil {
-			log.Errorf("Failed to persist provider data after start deploy error for tray %s: %v", tray.Id, pErr)
+			trayLogger(tray).Errorf("Failed to persist provider data after start deploy error for tray %s: %v", tray.Id, pErr)
 		}
 		if _, dErr := tm.DeleteTray(ctx, tray.Id); dErr != nil {
-			log.Errorf("Failed to delete tray %s after start deploy error: %v", tray.Id, dErr)
+			trayLogger(tray).Errorf("Failed to delete tray %s after start deploy error: %v", tray.Id, dErr)
 		}
 		return err
 	}
+	metrics.TrayStartDeployObserve(tray.GitHubOrgName, tray.ProviderName, tray.TrayTypeName, time.Since(started))
+	tm.recordEvent(ctx, tray, trays.TrayEventStartDeploy, fmt.Sprintf("took %s", time.Since(started).Round(time.Millisecond)))
 
 	if _, err := tm.trayRepository.SetProviderData(ctx, tray.Id, tray.ProviderData); err != nil {
-		log.Errorf("Failed to persist provider data for tray %s: %v", tray.Id, err)
+		trayLogger(tray).Errorf("Failed to persist provider data for tray %s: %v", tray.Id, err)
 	}
 
+	waitStarted := time.Now()
 	waitErr := provider.WaitDeploy(ctx, tray)
 	merged, _ := tm.trayRepository.SetProviderData(ctx, tray.Id, tray.ProviderData)
 
 	if waitErr != nil {
-		log.Errorf("Failed wait deploy for tray %s: %v", tray.Id, waitErr)
+		trayLogger(tray).Errorf("Failed wait deploy for tray %s: %v", tray.Id, waitErr)
+		tm.recordEvent(ctx, tray, trays.TrayEventWaitDeployFailed, waitErr.Error())
 		metrics.TrayProviderErrors(tray.GitHubOrgName, tray.ProviderName, tray.TrayTypeName, "create")
 		if _, dErr := tm.DeleteTray(ctx, tray.Id); dErr != nil {
-			log.Errorf("Failed to delete tray %s after wait deploy error: %v", tray.Id, dErr)
+			trayLogger(tray).Errorf("Failed to delete tray %s after wait deploy error: %v", tray.Id, dErr)
 		}
 		return waitErr
 	}
+	metrics.TrayWaitDeployObserve(tray.GitHubOrgName, tray.ProviderName, tray.TrayTypeName, time.Since(waitStarted))
+	tm.recordEvent(ctx, tray, trays.TrayEventWaitDeploy, fmt.Sprintf("took %s", time.Since(waitStarted).Round(time.Millisecond)))
 
 	if merged != nil && merged.Status == trays.TrayStatusDeleting {
-		log.Infof("Tray %s marked for deletion during deploy; cleaning up", tray.Id)
+		trayLogger(tray).Infof("Tray %s marked for deletion during deploy; cleaning up", tray.Id)
 		if _, dErr := tm.DeleteTray(ctx, tray.Id); dErr != nil {
-			log.Errorf("Failed to delete tray %s after concurrent unregister: %v", tray.Id, dErr)
+			trayLogger(tray).Errorf("Failed to delete tray %s after concurrent unregister: %v", tray.Id, dErr)
 		}
 	}
 
@@ -155,39 +338,56 @@ func (tm *TrayManager) GetTrayById(ctx context.Context, trayId string) (*trays.T
 		return nil, err
 	}
 	if tray == nil {
-		log.Debugf("Tray '%s' not found", trayId)
+		logger.WithField(logging.TrayIdKey, trayId).Debugf("Tray '%s' not found", trayId)
 		return nil, nil
 	}
 	return tray, nil
 }
 
-func (tm *TrayManager) Registering(ctx context.Context, trayId string) (*trays.Tray, error) {
-	tray, err := tm.trayRepository.UpdateStatus(ctx, trayId, trays.TrayStatusRegistering, 0, 0, 0, "", "", "")
+func (tm *TrayManager) Registering(ctx context.Context, trayId string) (tray *trays.Tray, err error) {
+	ctx, span := tracing.Start(ctx, "trayManager.Registering", tracing.TrayIdKey.String(trayId))
+	defer func() { tracing.End(span, err) }()
+
+	tray, err = tm.trayRepository.UpdateStatus(ctx, trayId, trays.TrayStatusRegistering, 0, 0, 0, "", "", "")
 	if err != nil {
 		return nil, err
 	}
 	if tray == nil {
 		return nil, fmt.Errorf("failed to update tray status for tray '%s'", trayId)
 	}
+	tm.recordEvent(ctx, tray, trays.TrayEventRegistering, "")
 	return tray, nil
 }
 
-func (tm *TrayManager) Registered(ctx context.Context, trayId string, ghRunnerId int64) (*trays.Tray, error) {
-	tray, err := tm.trayRepository.UpdateStatus(ctx, trayId, trays.TrayStatusRegistered, 0, 0, ghRunnerId, "", "", "")
+func (tm *TrayManager) Registered(ctx context.Context, trayId string, ghRunnerId int64) (tray *trays.Tray, err error) {
+	ctx, span := tracing.Start(ctx, "trayManager.Registered", tracing.TrayIdKey.String(trayId))
+	defer func() { tracing.End(span, err) }()
+
+	tray, err = tm.trayRepository.UpdateStatus(ctx, trayId, trays.TrayStatusRegistered, 0, 0, ghRunnerId, "", "", "")
 	if err != nil {
 		return nil, err
 	}
 	if tray == nil {
 		return nil, fmt.Errorf("failed to update tray status for tray '%s'", trayId)
 	}
+	observeTransition(tray, trays.TrayStatusCreating, trays.TrayStatusRegistered, metrics.TrayTimeToRegisteredObserve)
+	tm.recordEvent(ctx, tray, trays.TrayEventRegistered, fmt.Sprintf("runner id %d", ghRunnerId))
 	return tray, nil
 }
 
-func (tm *TrayManager) SetJob(ctx context.Context, trayId string, jobRunId int64, workflowRunId int64, repository string, jobName string, workflowName string) (*trays.Tray, error) {
-	tray, err := tm.trayRepository.UpdateStatus(ctx, trayId, trays.TrayStatusRunning, jobRunId, workflowRunId, 0, repository, jobName, workflowName)
+func (tm *TrayManager) SetJob(ctx context.Context, trayId string, jobRunId int64, workflowRunId int64, repository string, jobName string, workflowName string) (tray *trays.Tray, err error) {
+	ctx, span := tracing.Start(ctx, "trayManager.SetJob", tracing.TrayIdKey.String(trayId))
+	defer func() { tracing.End(span, err) }()
+
+	tray, err = tm.trayRepository.UpdateStatus(ctx, trayId, trays.TrayStatusRunning, jobRunId, workflowRunId, 0, repository, jobName, workflowName)
 	if err != nil {
 		return nil, err
 	}
+	if tray != nil {
+		tracing.Link(ctx, tray.TraceParent)
+		observeTransition(tray, trays.TrayStatusRegistered, trays.TrayStatusRunning, metrics.TrayIdleObserve)
+		tm.recordEvent(ctx, tray, trays.TrayEventJobAssigned, fmt.Sprintf("%s in %s (workflow run %d)", jobName, repository, workflowRunId))
+	}
 	return tray, nil
 }
 
@@ -196,39 +396,72 @@ func (tm *TrayManager) SetJob(ctx context.Context, trayId string, jobRunId int64
 // the stale handler to retry; only repository-level failures propagate to the
 // caller. Callers (unregister handler, stale loop) should treat a non-error
 // return as "deletion was requested," not "the upstream resource is gone."
-func (tm *TrayManager) DeleteTray(ctx context.Context, trayId string) (*trays.Tray, error) {
-	tray, err := tm.trayRepository.UpdateStatus(ctx, trayId, trays.TrayStatusDeleting, 0, 0, 0, "", "", "")
+func (tm *TrayManager) DeleteTray(ctx context.Context, trayId string) (tray *trays.Tray, err error) {
+	ctx, span := tracing.Start(ctx, "trayManager.DeleteTray", tracing.TrayIdKey.String(trayId))
+	defer func() { tracing.End(span, err) }()
+
+	tray, err = tm.trayRepository.UpdateStatus(ctx, trayId, trays.TrayStatusDeleting, 0, 0, 0, "", "", "")
 	if err != nil {
 		return nil, err
 	}
 	if tray == nil {
 		return nil, nil
 	}
+	tracing.Link(ctx, tray.TraceParent)
+	observeTransition(tray, trays.TrayStatusRunning, trays.TrayStatusDeleting, metrics.TrayRunningObserve)
+	// Retries of a delete the stale handler picks up again are not logged
+	// as a new deleting event; their clean failures are.
+	if tray.StatusTime(trays.TrayStatusDeleting).Equal(tray.StatusChanged) {
+		tm.recordEvent(ctx, tray, trays.TrayEventDeleting, "")
+	}
 
 	provider, err := tm.providerFactory.GetProviderForTray(tray)
 	if err != nil {
-		log.Errorf("Failed to get provider for tray %s: %v; leaving for stale handler", tray.Id, err)
+		trayLogger(tray).Errorf("Failed to get provider for tray %s: %v; leaving for stale handler", tray.Id, err)
+		tm.recordEvent(ctx, tray, trays.TrayEventCleanFailed, err.Error())
+		span.RecordError(err)
 		return tray, nil
 	}
 
 	if err := provider.CleanTray(ctx, tray); err != nil {
-		log.Errorf("Failed to clean tray %s; leaving for stale handler: %v", tray.Id, err)
+		trayLogger(tray).Errorf("Failed to clean tray %s; leaving for stale handler: %v", tray.Id, err)
 		metrics.TrayProviderErrors(tray.GitHubOrgName, tray.ProviderName, tray.TrayTypeName, "delete")
+		tm.recordEvent(ctx, tray, trays.TrayEventCleanFailed, err.Error())
+		span.RecordError(err)
 		return tray, nil
 	}
 
 	if err := tm.trayRepository.Delete(ctx, trayId); err != nil {
 		return tray, err
 	}
+	tm.recordEvent(ctx, tray, trays.TrayEventDeleted, "")
+	if !tray.DeletingAt.IsZero() {
+		metrics.TrayDeleteObserve(tray.GitHubOrgName, tray.ProviderName, tray.TrayTypeName, time.Since(tray.DeletingAt))
+	}
 
 	return tray, nil
 }
 
+// DrainTray marks the tray as draining, so it is removed once it is not
+// running a job: its agent is told to terminate on its next ping, which for a
+// running tray means after the job finishes. Returns (nil, nil) if the tray
+// does not exist.
+func (tm *TrayManager) DrainTray(ctx context.Context, trayId string) (*trays.Tray, error) {
+	tray, err := tm.trayRepository.SetDraining(ctx, trayId, true)
+	if err != nil {
+		return nil, err
+	}
+	if tray != nil {
+		trayLogger(tray).Infof("Tray %s (type %s, status %s) marked as draining", tray.Id, tray.TrayTypeName, tray.Status)
+	}
+	return tray, nil
+}
+
 func (tm *TrayManager) HandleStale(ctx context.Context) {
 	cfg := config.Get().Stale.WithDefaults()
 	thresholds := resolveStaleThresholds(cfg.Thresholds)
 
-	log.Infof("Stale handler starting: pollInterval=%s thresholds=%v", cfg.PollInterval, formatThresholds(thresholds))
+	logger.Infof("Stale handler starting: pollInterval=%s thresholds=%v", cfg.PollInterval, formatThresholds(thresholds))
 
 	go func() {
 		ticker := time.NewTicker(cfg.PollInterval)
@@ -241,19 +474,25 @@ func (tm *TrayManager) HandleStale(ctx context.Context) {
 			case <-ticker.C:
 				stale, err := tm.trayRepository.GetStale(ctx, thresholds)
 				if err != nil {
-					log.Errorf("Failed to get stale trays: %v", err)
+					logger.Errorf("Failed to get stale trays: %v", err)
 					continue
 				}
+				stale = tm.keepWarmPool(ctx, stale)
 
 				if len(stale) > 0 {
-					log.Infof("Found %d stale trays: %v", len(stale), stale)
+					logger.Infof("Found %d stale trays: %v", len(stale), stale)
 				}
 
 				for _, tray := range stale {
-					log.Debugf("Deleting stale tray: %s (status=%s)", tray.Id, tray.Status)
-					if _, err := tm.DeleteTray(ctx, tray.Id); err != nil {
-						log.Errorf("Failed to delete tray %s: %v", tray.Id, err)
+					trayLogger(tray).Debugf("Deleting stale tray: %s (status=%s)", tray.Id, tray.Status)
+					reapCtx, span := tracing.StartInTrace(ctx, tray.TraceParent, "trayManager.ReapStale", tracing.TrayAttributes(tray)...)
+					tm.recordEvent(reapCtx, tray, trays.TrayEventStaleReaped,
+						fmt.Sprintf("%s for %s", tray.Status, time.Since(tray.StatusChanged).Round(time.Second)))
+					_, err := tm.DeleteTray(reapCtx, tray.Id)
+					if err != nil {
+						trayLogger(tray).Errorf("Failed to delete tray %s: %v", tray.Id, err)
 					}
+					tracing.End(span, err)
 					metrics.StaleTraysInc(tray.GitHubOrgName, tray.TrayTypeName)
 				}
 			}
@@ -267,17 +506,9 @@ func (tm *TrayManager) HandleStale(ctx context.Context) {
 func resolveStaleThresholds(in map[string]time.Duration) map[trays.TrayStatus]time.Duration {
 	out := make(map[trays.TrayStatus]time.Duration, len(in))
 	for name, d := range in {
-		status, err := trays.TrayStatusFromString(name)
+		status, err := resolveStaleThreshold(name, d)
 		if err != nil {
-			log.Warnf("Ignoring stale threshold for unknown status %q", name)
-			continue
-		}
-		if status == trays.TrayStatusRunning {
-			log.Warnf("Ignoring stale threshold for status %q: running trays are never stale", name)
-			continue
-		}
-		if d <= 0 {
-			log.Warnf("Ignoring non-positive stale threshold for status %q: %s", name, d)
+			logger.Warnf("Ignoring %v", err)
 			continue
 		}
 		out[status] = d
@@ -285,6 +516,38 @@ func resolveStaleThresholds(in map[string]time.Duration) map[trays.TrayStatus]ti
 	return out
 }
 
+// StaleThresholdProblems returns an error for each stale threshold that
+// HandleStale would ignore, sorted by status name.
+func StaleThresholdProblems(in map[string]time.Duration) []error {
+	names := make([]string, 0, len(in))
+	for name := range in {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var problems []error
+	for _, name := range names {
+		if _, err := resolveStaleThreshold(name, in[name]); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	return problems
+}
+
+func resolveStaleThreshold(name string, d time.Duration) (trays.TrayStatus, error) {
+	status, err := trays.TrayStatusFromString(name)
+	if err != nil {
+		return 0, fmt.Errorf("stale threshold for unknown status %q", name)
+	}
+	if status == trays.TrayStatusRunning {
+		return 0, fmt.Errorf("stale threshold for status %q: running trays are never stale", name)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("non-positive stale threshold for status %q: %s", name, d)
+	}
+	return status, nil
+}
+
 func formatThresholds(m map[trays.TrayStatus]time.Duration) map[string]time.Duration {
 	out := make(map[string]time.Duration, len(m))
 	for s, d := range m {
@@ -296,23 +559,263 @@ func formatThresholds(m map[trays.TrayStatus]time.Duration) map[string]time.Dura
 // ScaleForDemand scales trays for a given tray type based on the desired runner count.
 // Follows ARC's pattern: scale up when needed, let HandleJobCompleted and the stale
 // handler take care of scale-down. No ghost detection — trust local tray state.
-func (tm *TrayManager) ScaleForDemand(ctx context.Context, trayType *config.TrayType, desiredCount int) error {
+// Tray types with a warm pool (minIdle/maxIdle) are handled by scaleWarmPool.
+// Limits come from the tray type's active schedule window, if any. A paused
+// tray type creates nothing; see scalePaused.
+//
+// The tray type's scale lock is held only while counting trays and
+// reserving the rows of new ones (see planScale); the provider deploys run
+// after it is released, so a slow deploy does not hold up the next scaling
+// pass, which counts the reserved rows as active.
+func (tm *TrayManager) ScaleForDemand(ctx context.Context, trayType *config.TrayType, desiredCount int) (err error) {
+	ctx, span := tracing.Start(ctx, "trayManager.ScaleForDemand",
+		tracing.TrayTypeKey.String(trayType.Name), attribute.Int("cattery.desired_count", desiredCount))
+	defer func() { tracing.End(span, err) }()
+
+	batch, err := tm.planScale(ctx, trayType, desiredCount)
+	if err != nil || batch == nil {
+		return err
+	}
+	return tm.deployTrays(ctx, batch)
+}
+
+// planScale is the part of ScaleForDemand that runs under the tray type's
+// scale lock. It returns the trays reserved for deployment, or nil if none
+// are to be created.
+func (tm *TrayManager) planScale(ctx context.Context, trayType *config.TrayType, desiredCount int) (*trayBatch, error) {
+	state := tm.scaleState(trayType.Name)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	trayType, profile := trayType.Scheduled(time.Now())
+	if state.evaluated && profile != state.profile {
+		trayTypeLogger(trayType).Infof("Tray type %s switched to schedule profile %s (maxTrays=%d, maxParallelCreation=%d, minIdle=%d)",
+			trayType.Name, profileName(profile), trayType.MaxTrays, trayType.MaxParallelCreation, trayType.MinIdle)
+	}
+	state.desired = desiredCount
+	state.updated = time.Now()
+	state.profile = profile
+	state.evaluated = true
+
+	pause, err := tm.GetPause(ctx, trayType)
+	if err != nil {
+		return nil, err
+	}
+	if pause != nil {
+		return nil, tm.scalePaused(ctx, trayType, pause, desiredCount)
+	}
+
+	if trayType.HasWarmPool() {
+		return tm.scaleWarmPool(ctx, trayType, desiredCount)
+	}
+
 	activeCount, err := tm.CountTrays(ctx, trayType.Name)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if desiredCount <= activeCount {
-		return nil
+		return nil, nil
 	}
 
 	traysToCreate := min(desiredCount-activeCount, trayType.MaxTrays-activeCount)
 	if traysToCreate > 0 {
-		return tm.createTrays(ctx, trayType, traysToCreate)
+		return tm.reserveTrays(ctx, trayType, traysToCreate), nil
+	}
+	return nil, nil
+}
+
+// scaleWarmPool keeps MinIdle spare trays on top of the demand not yet
+// covered by a running tray. desiredCount is the scale set's assigned job
+// count, which includes jobs already running, so uncovered demand is
+// desiredCount minus running trays. Spare trays are those that can still
+// take a job: creating, registering or registered.
+//
+// The listener calls this after every message, including the JobStarted
+// that moves a warm tray to running, so the pool is topped up right after it
+// is drawn from. With MaxIdle set, registered trays beyond demand+MaxIdle are
+// trimmed, longest idle first.
+func (tm *TrayManager) scaleWarmPool(ctx context.Context, trayType *config.TrayType, desiredCount int) (*trayBatch, error) {
+	counts, err := tm.trayRepository.CountByStatus(ctx, trayType.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	running := counts[trays.TrayStatusRunning]
+	spare := counts[trays.TrayStatusCreating] + counts[trays.TrayStatusRegistering] + counts[trays.TrayStatusRegistered]
+	activeCount := running + spare
+	pending := max(desiredCount-running, 0)
+
+	if wanted := pending + trayType.MinIdle; spare < wanted {
+		traysToCreate := min(wanted-spare, trayType.MaxTrays-activeCount)
+		if traysToCreate > 0 {
+			return tm.reserveTrays(ctx, trayType, traysToCreate), nil
+		}
+		return nil, nil
+	}
+
+	if trayType.MaxIdle > 0 {
+		if excess := spare - (pending + trayType.MaxIdle); excess > 0 {
+			return nil, tm.trimIdleTrays(ctx, trayType, excess)
+		}
+	}
+	return nil, nil
+}
+
+// trimIdleTrays deletes up to count registered trays, longest idle first.
+// Trays still creating or registering are left to finish; the next scale
+// round trims them once registered if they are still in excess.
+func (tm *TrayManager) trimIdleTrays(ctx context.Context, trayType *config.TrayType, count int) error {
+	idle, err := tm.trayRepository.GetByStatus(ctx, trayType.Name, trays.TrayStatusRegistered)
+	if err != nil {
+		return err
+	}
+
+	for _, tray := range idle[:min(count, len(idle))] {
+		trayLogger(tray).Infof("Trimming idle tray %s (type %s, maxIdle=%d)", tray.Id, trayType.Name, trayType.MaxIdle)
+		if _, err := tm.DeleteTray(ctx, tray.Id); err != nil {
+			return fmt.Errorf("failed to trim idle tray %s: %w", tray.Id, err)
+		}
 	}
 	return nil
 }
 
+// scalePaused is ScaleForDemand for a paused tray type: no trays are created,
+// running jobs finish on their trays and, with DeleteIdle, registered trays
+// still waiting for a job are deleted. Trays that were creating when the pause
+// started are deleted on a later round, once registered.
+func (tm *TrayManager) scalePaused(ctx context.Context, trayType *config.TrayType, pause *TrayTypePause, desiredCount int) error {
+	trayTypeLogger(trayType).Debugf("Tray type %s is paused (%s); ignoring demand of %d", trayType.Name, pause.Source, desiredCount)
+	if !pause.DeleteIdle {
+		return nil
+	}
+	return tm.deleteIdleTrays(ctx, trayType.Name)
+}
+
+// deleteIdleTrays deletes every registered tray of a paused tray type.
+func (tm *TrayManager) deleteIdleTrays(ctx context.Context, trayTypeName string) error {
+	idle, err := tm.trayRepository.GetByStatus(ctx, trayTypeName, trays.TrayStatusRegistered)
+	if err != nil {
+		return err
+	}
+
+	for _, tray := range idle {
+		trayLogger(tray).Infof("Deleting idle tray %s of paused tray type %s", tray.Id, trayTypeName)
+		if _, err := tm.DeleteTray(ctx, tray.Id); err != nil {
+			return fmt.Errorf("failed to delete idle tray %s: %w", tray.Id, err)
+		}
+	}
+	return nil
+}
+
+// HandleSchedules re-evaluates tray types with a schedule every minute, so a
+// window that opens or closes takes effect without waiting for the next
+// listener message. Only tray types whose demand was reported recently are
+// rescaled, i.e. those whose listener runs on this replica.
+func (tm *TrayManager) HandleSchedules(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				tm.applySchedules(ctx, now)
+			}
+		}
+	}()
+}
+
+// scheduleDemandTTL is how old the last reported demand may be for the
+// schedule timer to act on it. The listener reports at least once per long
+// poll (under a minute), so older demand means the listener is not running
+// here.
+const scheduleDemandTTL = 5 * time.Minute
+
+func (tm *TrayManager) applySchedules(ctx context.Context, now time.Time) {
+	for _, trayType := range config.Get().TrayTypes {
+		if trayType.Schedule == nil {
+			continue
+		}
+
+		_, profile := trayType.Scheduled(now)
+
+		state := tm.scaleState(trayType.Name)
+		state.mu.Lock()
+		desired := state.desired
+		fresh := state.evaluated && now.Sub(state.updated) < scheduleDemandTTL
+		changed := state.profile != profile
+		state.mu.Unlock()
+
+		if !fresh || !changed {
+			continue
+		}
+		if err := tm.ScaleForDemand(ctx, trayType, desired); err != nil {
+			trayTypeLogger(trayType).Errorf("Failed to apply schedule for tray type %s: %v", trayType.Name, err)
+		}
+	}
+}
+
+func (tm *TrayManager) scaleState(trayTypeName string) *scaleState {
+	tm.scaleMu.Lock()
+	defer tm.scaleMu.Unlock()
+
+	state, ok := tm.scaleStates[trayTypeName]
+	if !ok {
+		state = &scaleState{}
+		tm.scaleStates[trayTypeName] = state
+	}
+	return state
+}
+
+func profileName(profile string) string {
+	if profile == "" {
+		return "default"
+	}
+	return profile
+}
+
+// keepWarmPool drops registered trays from stale that are needed to keep
+// their tray type's MinIdle, so warm trays waiting for a job are not reaped
+// by the registered threshold. Only registered trays beyond MinIdle can be
+// reaped; other statuses and tray types without a warm pool are unaffected.
+func (tm *TrayManager) keepWarmPool(ctx context.Context, stale []*trays.Tray) []*trays.Tray {
+	cfg := config.Get()
+	reapable := make(map[string]int)
+	result := make([]*trays.Tray, 0, len(stale))
+
+	for _, tray := range stale {
+		trayType := cfg.GetTrayType(tray.TrayTypeName)
+		if trayType != nil {
+			trayType, _ = trayType.Scheduled(time.Now())
+		}
+		if tray.Status != trays.TrayStatusRegistered || trayType == nil || trayType.MinIdle <= 0 {
+			result = append(result, tray)
+			continue
+		}
+
+		budget, ok := reapable[trayType.Name]
+		if !ok {
+			counts, err := tm.trayRepository.CountByStatus(ctx, trayType.Name)
+			if err != nil {
+				trayTypeLogger(trayType).Errorf("Failed to count trays for type %s; keeping its stale registered trays: %v", trayType.Name, err)
+			}
+			budget = counts[trays.TrayStatusRegistered] - trayType.MinIdle
+		}
+
+		if budget > 0 {
+			result = append(result, tray)
+			budget--
+		} else {
+			trayLogger(tray).Debugf("Keeping stale tray %s in warm pool of type %s (minIdle=%d)", tray.Id, trayType.Name, trayType.MinIdle)
+		}
+		reapable[trayType.Name] = budget
+	}
+	return result
+}
+
 // CountTrays returns the number of active (non-deleting) trays for a given tray type.
 func (tm *TrayManager) CountTrays(ctx context.Context, trayTypeName string) (int, error) {
 	return tm.trayRepository.CountActive(ctx, trayTypeName)
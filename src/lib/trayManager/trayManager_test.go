@@ -7,11 +7,15 @@ import (
 	"cattery/lib/trays/providers"
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 // --- Mock provider ---
@@ -89,7 +93,7 @@ func (m *mockProviderFactory) GetProviderForTray(_ *trays.Tray) (providers.TrayP
 // --- Helper ---
 
 func newTestManager(repo *testutil.MockTrayRepository, pf *mockProviderFactory) *TrayManager {
-	return NewTrayManager(repo, pf)
+	return NewTrayManager(repo, testutil.NewMockPauseRepository(), testutil.NewMockTrayEventRepository(), pf)
 }
 
 // --- Tests ---
@@ -184,6 +188,313 @@ func TestScaleForDemand_CappedByMaxTrays(t *testing.T) {
 	assert.Equal(t, 2, prov.startCalls)
 }
 
+func TestScaleForDemand_DeploysOutsideScaleLock(t *testing.T) {
+	repo := testutil.NewMockTrayRepository()
+	release := make(chan struct{})
+	waiting := make(chan struct{}, 2)
+	prov := &mockProvider{name: "docker", onWait: func(*trays.Tray) {
+		waiting <- struct{}{}
+		<-release
+	}}
+	tm := newTestManager(repo, &mockProviderFactory{provider: prov})
+
+	trayType := &config.TrayType{
+		Name:     "test-type",
+		Provider: "docker",
+		MaxTrays: 10,
+		MinIdle:  2,
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- tm.ScaleForDemand(context.Background(), trayType, 0) }()
+	for range 2 {
+		select {
+		case <-waiting:
+		case <-time.After(5 * time.Second):
+			t.Fatal("trays were not deployed")
+		}
+	}
+
+	// With both deploys stuck in WaitDeploy, the next pass still runs, and
+	// counts the reserved trays instead of creating more.
+	second := make(chan error, 1)
+	go func() { second <- tm.ScaleForDemand(context.Background(), trayType, 0) }()
+	select {
+	case err := <-second:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("ScaleForDemand blocked on a deploy in progress")
+	}
+	prov.mu.Lock()
+	assert.Equal(t, 2, prov.startCalls)
+	prov.mu.Unlock()
+
+	close(release)
+	assert.NoError(t, <-done)
+}
+
+// addTrays puts count trays of type "test-type" in status into repo.
+func addTrays(repo *testutil.MockTrayRepository, prefix string, status trays.TrayStatus, count int) {
+	for i := 0; i < count; i++ {
+		id := fmt.Sprintf("%s-%d", prefix, i)
+		repo.Trays[id] = &trays.Tray{
+			Id:            id,
+			TrayTypeName:  "test-type",
+			Status:        status,
+			StatusChanged: time.Now().Add(-time.Duration(count-i) * time.Minute),
+		}
+	}
+}
+
+func TestScaleForDemand_WarmPool_FillsMinIdle(t *testing.T) {
+	repo := testutil.NewMockTrayRepository()
+	prov := &mockProvider{name: "docker"}
+	tm := newTestManager(repo, &mockProviderFactory{provider: prov})
+
+	trayType := &config.TrayType{
+		Name:     "test-type",
+		Provider: "docker",
+		MaxTrays: 10,
+		MinIdle:  3,
+	}
+
+	err := tm.ScaleForDemand(context.Background(), trayType, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, prov.startCalls)
+}
+
+func TestScaleForDemand_WarmPool_TopsUpAfterJobStarts(t *testing.T) {
+	repo := testutil.NewMockTrayRepository()
+	// One warm tray was just taken by a job: 1 running, 1 still registered.
+	addTrays(repo, "running", trays.TrayStatusRunning, 1)
+	addTrays(repo, "idle", trays.TrayStatusRegistered, 1)
+	prov := &mockProvider{name: "docker"}
+	tm := newTestManager(repo, &mockProviderFactory{provider: prov})
+
+	trayType := &config.TrayType{
+		Name:     "test-type",
+		Provider: "docker",
+		MaxTrays: 10,
+		MinIdle:  2,
+	}
+
+	// The running job is the only assigned job: no uncovered demand.
+	err := tm.ScaleForDemand(context.Background(), trayType, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, prov.startCalls)
+}
+
+func TestScaleForDemand_WarmPool_AddsDemandOnTop(t *testing.T) {
+	repo := testutil.NewMockTrayRepository()
+	addTrays(repo, "idle", trays.TrayStatusRegistered, 2)
+	prov := &mockProvider{name: "docker"}
+	tm := newTestManager(repo, &mockProviderFactory{provider: prov})
+
+	trayType := &config.TrayType{
+		Name:     "test-type",
+		Provider: "docker",
+		MaxTrays: 10,
+		MinIdle:  2,
+	}
+
+	// 3 queued jobs + 2 warm = 5 spare wanted, 2 present.
+	err := tm.ScaleForDemand(context.Background(), trayType, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, prov.startCalls)
+}
+
+func TestScaleForDemand_WarmPool_CappedByMaxTrays(t *testing.T) {
+	repo := testutil.NewMockTrayRepository()
+	addTrays(repo, "running", trays.TrayStatusRunning, 4)
+	prov := &mockProvider{name: "docker"}
+	tm := newTestManager(repo, &mockProviderFactory{provider: prov})
+
+	trayType := &config.TrayType{
+		Name:     "test-type",
+		Provider: "docker",
+		MaxTrays: 5,
+		MinIdle:  3,
+	}
+
+	err := tm.ScaleForDemand(context.Background(), trayType, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, prov.startCalls)
+}
+
+func TestScaleForDemand_WarmPool_TrimsAboveMaxIdle(t *testing.T) {
+	repo := testutil.NewMockTrayRepository()
+	addTrays(repo, "idle", trays.TrayStatusRegistered, 5)
+	addTrays(repo, "creating", trays.TrayStatusCreating, 1)
+	prov := &mockProvider{name: "docker"}
+	tm := newTestManager(repo, &mockProviderFactory{provider: prov})
+
+	trayType := &config.TrayType{
+		Name:     "test-type",
+		Provider: "docker",
+		MaxTrays: 10,
+		MinIdle:  1,
+		MaxIdle:  3,
+	}
+
+	// 6 spare, 0 demand, maxIdle 3: trim 3 registered, oldest first.
+	err := tm.ScaleForDemand(context.Background(), trayType, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, prov.startCalls)
+	assert.Equal(t, []string{"idle-0", "idle-1", "idle-2"}, prov.cleaned)
+	assert.Contains(t, repo.Trays, "creating-0", "creating trays are never trimmed")
+}
+
+func TestScaleForDemand_WarmPool_NoTrimWithinMaxIdle(t *testing.T) {
+	repo := testutil.NewMockTrayRepository()
+	addTrays(repo, "idle", trays.TrayStatusRegistered, 4)
+	prov := &mockProvider{name: "docker"}
+	tm := newTestManager(repo, &mockProviderFactory{provider: prov})
+
+	trayType := &config.TrayType{
+		Name:     "test-type",
+		Provider: "docker",
+		MaxTrays: 10,
+		MaxIdle:  2,
+	}
+
+	// 2 queued jobs + maxIdle 2 covers all 4 registered trays.
+	err := tm.ScaleForDemand(context.Background(), trayType, 2)
+	assert.NoError(t, err)
+	assert.Empty(t, prov.cleaned)
+}
+
+func TestKeepWarmPool(t *testing.T) {
+	cfg := &config.CatteryConfig{
+		TrayTypes: []*config.TrayType{
+			{Name: "test-type", MinIdle: 2},
+			{Name: "no-pool"},
+		},
+	}
+	cfg.InitMaps()
+	config.SetForTest(t, cfg)
+
+	repo := testutil.NewMockTrayRepository()
+	addTrays(repo, "idle", trays.TrayStatusRegistered, 3)
+	tm := newTestManager(repo, &mockProviderFactory{})
+
+	stale := []*trays.Tray{
+		repo.Trays["idle-0"],
+		repo.Trays["idle-1"],
+		repo.Trays["idle-2"],
+		{Id: "creating", TrayTypeName: "test-type", Status: trays.TrayStatusCreating},
+		{Id: "other", TrayTypeName: "no-pool", Status: trays.TrayStatusRegistered},
+	}
+
+	// 3 registered, minIdle 2: only one of them may be reaped.
+	got := tm.keepWarmPool(context.Background(), stale)
+
+	ids := make([]string, 0, len(got))
+	for _, tray := range got {
+		ids = append(ids, tray.Id)
+	}
+	assert.Equal(t, []string{"idle-0", "creating", "other"}, ids)
+}
+
+// alwaysSchedule returns an initialized schedule with one window named
+// "always" that is active at any time.
+func alwaysSchedule(t *testing.T, window config.ScheduleWindow) *config.TrayTypeSchedule {
+	t.Helper()
+	window.Name, window.Start, window.End = "always", "00:00", "00:00"
+	schedule := &config.TrayTypeSchedule{Windows: []config.ScheduleWindow{window}}
+	if err := schedule.Init(); err != nil {
+		t.Fatalf("schedule init: %v", err)
+	}
+	return schedule
+}
+
+func intPtr(i int) *int { return &i }
+
+func TestScaleForDemand_ScheduleOverridesMaxTrays(t *testing.T) {
+	repo := testutil.NewMockTrayRepository()
+	prov := &mockProvider{name: "docker"}
+	tm := newTestManager(repo, &mockProviderFactory{provider: prov})
+
+	trayType := &config.TrayType{
+		Name:     "test-type",
+		Provider: "docker",
+		MaxTrays: 2,
+		Schedule: alwaysSchedule(t, config.ScheduleWindow{MaxTrays: intPtr(5)}),
+	}
+
+	err := tm.ScaleForDemand(context.Background(), trayType, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, prov.startCalls)
+}
+
+func TestScaleForDemand_ScheduleEnablesWarmPool(t *testing.T) {
+	repo := testutil.NewMockTrayRepository()
+	prov := &mockProvider{name: "docker"}
+	tm := newTestManager(repo, &mockProviderFactory{provider: prov})
+
+	trayType := &config.TrayType{
+		Name:     "test-type",
+		Provider: "docker",
+		MaxTrays: 10,
+		Schedule: alwaysSchedule(t, config.ScheduleWindow{MinIdle: intPtr(2)}),
+	}
+
+	err := tm.ScaleForDemand(context.Background(), trayType, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, prov.startCalls)
+}
+
+func TestApplySchedules(t *testing.T) {
+	trayType := &config.TrayType{
+		Name:     "test-type",
+		Provider: "docker",
+		MaxTrays: 1,
+		Schedule: alwaysSchedule(t, config.ScheduleWindow{MaxTrays: intPtr(4)}),
+	}
+	cfg := &config.CatteryConfig{
+		TrayTypes: []*config.TrayType{trayType, {Name: "unscheduled", Provider: "docker", MaxTrays: 10}},
+	}
+	cfg.InitMaps()
+	config.SetForTest(t, cfg)
+
+	seed := func(tm *TrayManager, name string, desired int, updated time.Time) {
+		state := tm.scaleState(name)
+		state.desired, state.updated, state.evaluated = desired, updated, true
+	}
+	now := time.Now()
+
+	t.Run("applies new profile with last demand", func(t *testing.T) {
+		prov := &mockProvider{name: "docker"}
+		tm := newTestManager(testutil.NewMockTrayRepository(), &mockProviderFactory{provider: prov})
+		seed(tm, "test-type", 3, now)
+		seed(tm, "unscheduled", 3, now)
+
+		tm.applySchedules(context.Background(), now)
+		assert.Equal(t, 3, prov.startCalls)
+		assert.Equal(t, "always", tm.scaleState("test-type").profile)
+
+		// Profile unchanged: nothing to do until the listener reports again.
+		tm.applySchedules(context.Background(), now)
+		assert.Equal(t, 3, prov.startCalls)
+	})
+
+	t.Run("ignores stale demand", func(t *testing.T) {
+		prov := &mockProvider{name: "docker"}
+		tm := newTestManager(testutil.NewMockTrayRepository(), &mockProviderFactory{provider: prov})
+		seed(tm, "test-type", 3, now.Add(-time.Hour))
+
+		tm.applySchedules(context.Background(), now)
+		assert.Equal(t, 0, prov.startCalls)
+	})
+
+	t.Run("ignores tray types without reported demand", func(t *testing.T) {
+		prov := &mockProvider{name: "docker"}
+		tm := newTestManager(testutil.NewMockTrayRepository(), &mockProviderFactory{provider: prov})
+
+		tm.applySchedules(context.Background(), now)
+		assert.Equal(t, 0, prov.startCalls)
+	})
+}
+
 func TestCreateTray_Success(t *testing.T) {
 	repo := testutil.NewMockTrayRepository()
 	prov := &mockProvider{name: "docker"}
@@ -223,6 +534,32 @@ func TestCreateTray_RowExistsBeforeProviderCall(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestCreateTray_StoresTraceParent(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	repo := testutil.NewMockTrayRepository()
+	tm := newTestManager(repo, &mockProviderFactory{provider: &mockProvider{name: "docker"}})
+
+	trayType := &config.TrayType{Name: "test-type", Provider: "docker", GitHubOrg: "test-org"}
+	assert.NoError(t, tm.CreateTray(context.Background(), trayType))
+
+	var createSpan sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == "trayManager.CreateTray" {
+			createSpan = span
+		}
+	}
+	if assert.NotNil(t, createSpan) && assert.Len(t, repo.Trays, 1) {
+		for _, tray := range repo.Trays {
+			assert.Contains(t, tray.TraceParent, createSpan.SpanContext().SpanID().String(),
+				"the tray's agent continues the CreateTray span")
+		}
+	}
+}
+
 func TestCreateTray_StartDeployError_CleansUp(t *testing.T) {
 	repo := testutil.NewMockTrayRepository()
 	prov := &mockProvider{name: "docker", startErr: errors.New("docker failed")}
@@ -415,6 +752,26 @@ func TestDeleteTray_NotFound(t *testing.T) {
 	assert.Equal(t, 0, len(prov.cleaned))
 }
 
+func TestDrainTray(t *testing.T) {
+	repo := testutil.NewMockTrayRepository()
+	repo.Trays["tray-1"] = &trays.Tray{Id: "tray-1", TrayTypeName: "test-type", Status: trays.TrayStatusRunning}
+	prov := &mockProvider{name: "docker"}
+	tm := newTestManager(repo, &mockProviderFactory{provider: prov})
+
+	tray, err := tm.DrainTray(context.Background(), "tray-1")
+	assert.NoError(t, err)
+	if !assert.NotNil(t, tray) {
+		return
+	}
+	assert.True(t, tray.Draining)
+	assert.Equal(t, trays.TrayStatusRunning, tray.Status, "draining does not touch status")
+	assert.Empty(t, prov.cleaned, "draining does not delete")
+
+	missing, err := tm.DrainTray(context.Background(), "nonexistent")
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
 func TestDeleteTray_ProviderCleanError(t *testing.T) {
 	// Cleanup failure is logged and swallowed: the row stays in "deleting"
 	// state so the stale handler can retry, and the caller (typically the
@@ -642,6 +999,24 @@ func TestResolveStaleThresholds(t *testing.T) {
 	}
 }
 
+func TestStaleThresholdProblems(t *testing.T) {
+	problems := StaleThresholdProblems(map[string]time.Duration{
+		"creating":   5 * time.Minute,
+		"running":    time.Minute,
+		"bogus":      time.Minute,
+		"registered": 0,
+	})
+
+	if !assert.Len(t, problems, 3) {
+		return
+	}
+	assert.Contains(t, problems[0].Error(), `unknown status "bogus"`)
+	assert.Contains(t, problems[1].Error(), `non-positive stale threshold for status "registered"`)
+	assert.Contains(t, problems[2].Error(), "running trays are never stale")
+
+	assert.Empty(t, StaleThresholdProblems(config.DefaultStaleThresholds))
+}
+
 func TestHandleStale_DeletesStaleTrays(t *testing.T) {
 	// Override config so the loop polls fast.
 	config.SetForTest(t, &config.CatteryConfig{
@@ -782,3 +1157,39 @@ func TestStaleConfigWithDefaults(t *testing.T) {
 		assert.Equal(t, config.DefaultStaleThresholds, out.Thresholds)
 	})
 }
+
+func TestCreateTray_QuotaExceeded(t *testing.T) {
+	config.SetForTest(t, &config.CatteryConfig{
+		Quotas: []*config.Quota{
+			{Name: "docker-cpus", Provider: "docker", MaxWeight: 8},
+		},
+	})
+
+	repo := testutil.NewMockTrayRepository()
+	prov := &mockProvider{name: "docker"}
+	tm := newTestManager(repo, &mockProviderFactory{provider: prov})
+	trayType := &config.TrayType{Name: "test-type", Provider: "docker", GitHubOrg: "test-org", Weight: 4}
+
+	assert.NoError(t, tm.CreateTray(context.Background(), trayType))
+	assert.NoError(t, tm.CreateTray(context.Background(), trayType))
+
+	err := tm.CreateTray(context.Background(), trayType)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	assert.ErrorContains(t, err, "docker-cpus")
+	assert.Equal(t, 2, prov.startCalls, "no deploy once the quota is reached")
+	assert.Len(t, repo.Trays, 2)
+
+	// Another provider's tray types are not counted.
+	other := &config.TrayType{Name: "other-type", Provider: "gce", GitHubOrg: "test-org", Weight: 4}
+	assert.NoError(t, tm.CreateTray(context.Background(), other))
+}
+
+func TestLogCreationResults_QuotaExceededIsNotAFailure(t *testing.T) {
+	tm := newTestManager(testutil.NewMockTrayRepository(), &mockProviderFactory{})
+
+	quotaErr := fmt.Errorf("tray type test-type: %w: global", ErrQuotaExceeded)
+	assert.NoError(t, tm.logCreationResults("test-type", []error{quotaErr, quotaErr}))
+
+	err := tm.logCreationResults("test-type", []error{quotaErr, errors.New("fail")})
+	assert.ErrorContains(t, err, "all 1 tray creations failed")
+}
@@ -0,0 +1,35 @@
+package trays
+
+import "time"
+
+// TrayEventKind names a step in a tray's lifecycle.
+type TrayEventKind string
+
+const (
+	TrayEventCreated           TrayEventKind = "created"
+	TrayEventStartDeploy       TrayEventKind = "start-deploy"
+	TrayEventStartDeployFailed TrayEventKind = "start-deploy-failed"
+	TrayEventWaitDeploy        TrayEventKind = "wait-deploy"
+	TrayEventWaitDeployFailed  TrayEventKind = "wait-deploy-failed"
+	TrayEventRegistering       TrayEventKind = "registering"
+	TrayEventRegistered        TrayEventKind = "registered"
+	TrayEventJobAssigned       TrayEventKind = "job-assigned"
+	TrayEventJobCompleted      TrayEventKind = "job-completed"
+	TrayEventUnregistered      TrayEventKind = "unregistered"
+	TrayEventStaleReaped       TrayEventKind = "stale-reaped"
+	TrayEventDeleting          TrayEventKind = "deleting"
+	TrayEventCleanFailed       TrayEventKind = "clean-failed"
+	TrayEventDeleted           TrayEventKind = "deleted"
+)
+
+// TrayEvent is one entry of a tray's lifecycle log. Events are append-only
+// and outlive the tray row, so what happened to a tray can be looked up after
+// it is gone, until the events are pruned.
+type TrayEvent struct {
+	TrayId       string        `bson:"trayId" json:"trayId"`
+	TrayTypeName string        `bson:"trayTypeName" json:"trayTypeName"`
+	Kind         TrayEventKind `bson:"kind" json:"kind"`
+	// Message is free-form detail: an error, a runner id, a job name.
+	Message string    `bson:"message" json:"message"`
+	Time    time.Time `bson:"time" json:"time"`
+}
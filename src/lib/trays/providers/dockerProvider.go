@@ -1,10 +1,13 @@
 package providers
 
 import (
+	"cattery/lib/agents"
 	"cattery/lib/config"
+	"cattery/lib/logging"
 	"cattery/lib/trays"
 	"context"
 	"fmt"
+	"os"
 	"os/exec"
 	"strings"
 
@@ -22,10 +25,9 @@ func NewDockerProvider(name string, providerConfig config.ProviderConfig) *Docke
 	return &DockerProvider{
 		name:   name,
 		config: providerConfig,
-		logger: log.WithFields(log.Fields{
-			"name":         "DockerProvider",
-			"providerName": name,
-			"providerType": "docker",
+		logger: logging.Logger("dockerProvider").WithFields(log.Fields{
+			logging.ProviderKey: name,
+			"providerType":      "docker",
 		}),
 	}
 }
@@ -37,6 +39,8 @@ func (d *DockerProvider) GetProviderName() string {
 // StartDeploy launches the container in detached mode. The container name is
 // the trayId, which is the only handle CleanTray needs. `docker run -d`
 // returns once the container is started, so there is no separate wait phase.
+// The tray token is passed through docker's environment rather than on the
+// command line, so it does not show up in the logged command.
 func (d *DockerProvider) StartDeploy(ctx context.Context, tray *trays.Tray) error {
 	containerName := tray.Id
 
@@ -51,14 +55,17 @@ func (d *DockerProvider) StartDeploy(ctx context.Context, tray *trays.Tray) erro
 	dockerCommand := exec.CommandContext(ctx, "docker", "run", "-d", "--rm",
 		"--add-host=host.docker.internal:host-gateway",
 		"--name", containerName,
+		"--env", agents.TokenEnv,
 		image,
 		"/action-runner/cattery/cattery", "agent", "-i", tray.Id, "-s", serverUrl, "--runner-folder", "/action-runner")
 
-	d.logger.Info("Running docker command: ", dockerCommand.String())
+	dockerCommand.Env = append(os.Environ(), agents.TokenEnv+"="+tray.Token)
+
+	logging.WithTray(d.logger, tray).Info("Running docker command: ", dockerCommand.String())
 	err := dockerCommand.Run()
 
 	if err != nil {
-		d.logger.Error("Failed to run docker command: ", err)
+		logging.WithTray(d.logger, tray).Error("Failed to run docker command: ", err)
 		return err
 	}
 
@@ -74,10 +81,10 @@ func (d *DockerProvider) CleanTray(ctx context.Context, tray *trays.Tray) error
 	dockerCommandOutput, err := dockerCommand.CombinedOutput()
 	if err != nil {
 		output := string(dockerCommandOutput)
-		d.logger.Trace(output)
+		logging.WithTray(d.logger, tray).Trace(output)
 
 		if strings.Contains(strings.ToLower(output), "no such container") {
-			d.logger.Trace("No such container: ", tray.Id)
+			logging.WithTray(d.logger, tray).Trace("No such container: ", tray.Id)
 			return nil
 		}
 		return err
@@ -85,3 +92,23 @@ func (d *DockerProvider) CleanTray(ctx context.Context, tray *trays.Tray) error
 
 	return nil
 }
+
+// ListTrays lists the running containers named like trays of trayType.
+// Containers run with --rm, so stopped ones are already gone.
+func (d *DockerProvider) ListTrays(ctx context.Context, trayType *config.TrayType) ([]*trays.Tray, error) {
+	dockerCommand := exec.CommandContext(ctx, "docker", "ps",
+		"--filter", "name="+trayType.Name+"-",
+		"--format", "{{.Names}}")
+	output, err := dockerCommand.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list docker containers: %w", err)
+	}
+
+	var result []*trays.Tray
+	for _, name := range strings.Fields(string(output)) {
+		if trays.IsTrayOfType(name, trayType.Name) {
+			result = append(result, listedTray(trayType, d.name, name, nil))
+		}
+	}
+	return result, nil
+}
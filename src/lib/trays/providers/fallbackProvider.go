@@ -0,0 +1,216 @@
+package providers
+
+import (
+	"cattery/lib/config"
+	"cattery/lib/logging"
+	"cattery/lib/metrics"
+	"cattery/lib/trays"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/sirupsen/logrus"
+)
+
+// fallbackProviderDataMember records which member provider currently owns the
+// tray's upstream resource. It is written, and saved through the context's
+// ProviderDataSaver, *before* each member's StartDeploy, so a crash mid-deploy
+// or mid-reroute still leaves CleanTray pointed at the right backend.
+const fallbackProviderDataMember = "fallbackProvider"
+
+// FallbackProvider chains an ordered list of member providers. A tray is
+// deployed on the first member; if that member reports ErrCapacityBlocked —
+// from StartDeploy or WaitDeploy — its partial resource is cleaned up and the
+// tray is redeployed on the next member. Any other error is returned as-is:
+// rerouting only makes sense when the member is full, not when it is broken.
+//
+// Members are resolved by name through the factory on each call rather than
+// at construction, because the factory holds its lock while constructing this
+// provider and member configs may not be initialized yet.
+//
+// Each member sees the tray with ProviderName set to the member's name, so
+// Tray.TrayConfig resolves the member's entry of the tray type's
+// FallbackTrayConfig. ProviderData is shared between the views, so whatever
+// the winning member stores is persisted for its CleanTray.
+type FallbackProvider struct {
+	name    string
+	members []string
+	factory TrayProviderFactory
+
+	logger *logrus.Entry
+}
+
+func NewFallbackProvider(name string, providerConfig config.ProviderConfig, factory TrayProviderFactory) *FallbackProvider {
+	logger := logging.Logger("fallbackProvider").WithFields(logrus.Fields{
+		logging.ProviderKey: name,
+		"providerType":      config.ProviderTypeFallback,
+	})
+
+	members := providerConfig.Members()
+	if len(members) == 0 {
+		logger.Error("fallback provider requires a non-empty 'providers' list")
+		return nil
+	}
+
+	return &FallbackProvider{
+		name:    name,
+		members: members,
+		factory: factory,
+		logger:  logger,
+	}
+}
+
+func (f *FallbackProvider) GetProviderName() string {
+	return f.name
+}
+
+// StartDeploy starts the tray on the first member that has capacity.
+func (f *FallbackProvider) StartDeploy(ctx context.Context, tray *trays.Tray) error {
+	return f.startFrom(ctx, tray, 0)
+}
+
+// WaitDeploy waits on the member that accepted the tray. If it turns out to
+// be out of capacity, the tray moves on to the next member (start and wait)
+// until one succeeds or the chain is exhausted.
+func (f *FallbackProvider) WaitDeploy(ctx context.Context, tray *trays.Tray) error {
+	for {
+		index, provider, err := f.current(tray)
+		if err != nil {
+			return err
+		}
+
+		err = provider.WaitDeploy(ctx, f.memberTray(tray, index))
+		if !f.canReroute(err, index) {
+			return err
+		}
+
+		if err := f.abandon(ctx, tray, index, provider, err); err != nil {
+			return err
+		}
+		if err := f.startFrom(ctx, tray, index+1); err != nil {
+			return err
+		}
+	}
+}
+
+// CleanTray delegates to the member recorded in ProviderData. A tray with no
+// recorded member never reached any member's StartDeploy, so there is nothing
+// to clean.
+func (f *FallbackProvider) CleanTray(ctx context.Context, tray *trays.Tray) error {
+	member := tray.ProviderData[fallbackProviderDataMember]
+	if member == "" {
+		logging.WithTray(f.logger, tray).Tracef("CleanTray called without a recorded member for tray %s; nothing to do", tray.Id)
+		return nil
+	}
+
+	provider, err := f.factory.GetProvider(member)
+	if err != nil {
+		return fmt.Errorf("failed to get fallback member %s for tray %s: %w", member, tray.Id, err)
+	}
+
+	view := *tray
+	view.ProviderName = member
+	return provider.CleanTray(ctx, &view)
+}
+
+// ListTrays lists the tray type's resources on every member that supports
+// listing. Each tray is reported as owned by this provider, with its member
+// recorded in ProviderData so CleanTray routes to it.
+func (f *FallbackProvider) ListTrays(ctx context.Context, trayType *config.TrayType) ([]*trays.Tray, error) {
+	var result []*trays.Tray
+	for _, member := range f.members {
+		provider, err := f.factory.GetProvider(member)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get fallback member %s: %w", member, err)
+		}
+		inventory, ok := provider.(TrayInventory)
+		if !ok {
+			continue
+		}
+
+		listed, err := inventory.ListTrays(ctx, trayType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list trays on fallback member %s: %w", member, err)
+		}
+		for _, tray := range listed {
+			tray.ProviderName = f.name
+			tray.ProviderData[fallbackProviderDataMember] = member
+			result = append(result, tray)
+		}
+	}
+	return result, nil
+}
+
+// startFrom tries StartDeploy on members[from:], in order, and returns after
+// the first success.
+func (f *FallbackProvider) startFrom(ctx context.Context, tray *trays.Tray, from int) error {
+	for index := from; index < len(f.members); index++ {
+		member := f.members[index]
+		provider, err := f.factory.GetProvider(member)
+		if err != nil {
+			return fmt.Errorf("failed to get fallback member %s: %w", member, err)
+		}
+
+		tray.ProviderData[fallbackProviderDataMember] = member
+		if err := saveProviderData(ctx, tray); err != nil {
+			return fmt.Errorf("failed to save fallback member %s of tray %s: %w", member, tray.Id, err)
+		}
+
+		err = provider.StartDeploy(ctx, f.memberTray(tray, index))
+		if !f.canReroute(err, index) {
+			return err
+		}
+
+		if err := f.abandon(ctx, tray, index, provider, err); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("no fallback member left for tray %s", tray.Id)
+}
+
+// canReroute reports whether err from members[index] should send the tray to
+// the next member.
+func (f *FallbackProvider) canReroute(err error, index int) bool {
+	return errors.Is(err, ErrCapacityBlocked) && index+1 < len(f.members)
+}
+
+// abandon cleans up whatever members[index] created for the tray before the
+// tray moves on. If that cleanup fails the chain stops: the member stays
+// recorded in ProviderData so the stale handler's CleanTray retries it,
+// rather than leaking a queued deployment that may still start later.
+func (f *FallbackProvider) abandon(ctx context.Context, tray *trays.Tray, index int, provider TrayProvider, cause error) error {
+	member := f.members[index]
+	logging.WithTray(f.logger, tray).Warnf("Provider %s has no capacity for tray %s, falling back to %s: %v",
+		member, tray.Id, f.members[index+1], cause)
+
+	if err := provider.CleanTray(ctx, f.memberTray(tray, index)); err != nil {
+		return fmt.Errorf("failed to clean tray %s on %s before falling back: %w", tray.Id, member, err)
+	}
+
+	metrics.TrayProviderFallbacksInc(tray.GitHubOrgName, member, tray.TrayTypeName)
+	return nil
+}
+
+// current returns the member recorded in ProviderData and its position.
+func (f *FallbackProvider) current(tray *trays.Tray) (int, TrayProvider, error) {
+	member := tray.ProviderData[fallbackProviderDataMember]
+	index := slices.Index(f.members, member)
+	if index < 0 {
+		return 0, nil, fmt.Errorf("tray %s has no known fallback member (recorded: %q)", tray.Id, member)
+	}
+
+	provider, err := f.factory.GetProvider(member)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to get fallback member %s: %w", member, err)
+	}
+	return index, provider, nil
+}
+
+// memberTray returns the view of tray that members[index] operates on. It is
+// a shallow copy: ProviderData is shared, so member writes land on tray.
+func (f *FallbackProvider) memberTray(tray *trays.Tray, index int) *trays.Tray {
+	view := *tray
+	view.ProviderName = f.members[index]
+	return &view
+}
@@ -0,0 +1,290 @@
+package providers
+
+import (
+	"cattery/lib/config"
+	"cattery/lib/trays"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeMember records the calls made to one fallback member.
+type fakeMember struct {
+	name     string
+	startErr error
+	waitErr  error
+	cleanErr error
+
+	// listed are the tray ids ListTrays reports.
+	listed []string
+
+	started  int
+	waited   int
+	cleaned  int
+	lastName string
+}
+
+func (m *fakeMember) GetProviderName() string { return m.name }
+
+func (m *fakeMember) StartDeploy(_ context.Context, tray *trays.Tray) error {
+	m.started++
+	m.lastName = tray.ProviderName
+	tray.ProviderData[m.name+"-id"] = tray.Id
+	return m.startErr
+}
+
+func (m *fakeMember) WaitDeploy(_ context.Context, tray *trays.Tray) error {
+	m.waited++
+	m.lastName = tray.ProviderName
+	return m.waitErr
+}
+
+func (m *fakeMember) CleanTray(_ context.Context, tray *trays.Tray) error {
+	m.cleaned++
+	m.lastName = tray.ProviderName
+	return m.cleanErr
+}
+
+func (m *fakeMember) ListTrays(_ context.Context, trayType *config.TrayType) ([]*trays.Tray, error) {
+	var result []*trays.Tray
+	for _, id := range m.listed {
+		result = append(result, listedTray(trayType, m.name, id, nil))
+	}
+	return result, nil
+}
+
+type fakeMemberFactory map[string]*fakeMember
+
+func (f fakeMemberFactory) GetProvider(name string) (TrayProvider, error) {
+	m, ok := f[name]
+	if !ok {
+		return nil, errors.New("no provider found for " + name)
+	}
+	return m, nil
+}
+
+func (f fakeMemberFactory) GetProviderForTray(tray *trays.Tray) (TrayProvider, error) {
+	return f.GetProvider(tray.ProviderName)
+}
+
+func newFallbackTestProvider(t *testing.T, members ...*fakeMember) (*FallbackProvider, fakeMemberFactory) {
+	t.Helper()
+	factory := fakeMemberFactory{}
+	names := ""
+	for i, m := range members {
+		factory[m.name] = m
+		if i > 0 {
+			names += ","
+		}
+		names += m.name
+	}
+	p := NewFallbackProvider("burst", config.ProviderConfig{"type": "fallback", "providers": names}, factory)
+	assert.NotNil(t, p)
+	return p, factory
+}
+
+func newFallbackTestTray() *trays.Tray {
+	return &trays.Tray{
+		Id:            "tray-1",
+		TrayTypeName:  "burst-runner",
+		ProviderName:  "burst",
+		GitHubOrgName: "test-org",
+		ProviderData:  map[string]string{},
+	}
+}
+
+func TestNewFallbackProvider_NoMembers(t *testing.T) {
+	p := NewFallbackProvider("burst", config.ProviderConfig{"type": "fallback"}, fakeMemberFactory{})
+	assert.Nil(t, p)
+}
+
+func TestFallbackProvider_StartDeploy(t *testing.T) {
+	t.Run("first member with capacity wins", func(t *testing.T) {
+		primary := &fakeMember{name: "primary"}
+		secondary := &fakeMember{name: "secondary"}
+		p, _ := newFallbackTestProvider(t, primary, secondary)
+		tray := newFallbackTestTray()
+
+		assert.NoError(t, p.StartDeploy(context.Background(), tray))
+		assert.Equal(t, 1, primary.started)
+		assert.Equal(t, 0, secondary.started)
+		assert.Equal(t, "primary", primary.lastName, "member must see its own provider name")
+		assert.Equal(t, "primary", tray.ProviderData[fallbackProviderDataMember])
+		assert.Equal(t, "tray-1", tray.ProviderData["primary-id"], "member writes must land on the tray")
+		assert.Equal(t, "burst", tray.ProviderName, "tray itself must keep the fallback provider name")
+	})
+
+	t.Run("capacity error cleans up and moves to next member", func(t *testing.T) {
+		primary := &fakeMember{name: "primary", startErr: fmt.Errorf("stockout: %w", ErrCapacityBlocked)}
+		secondary := &fakeMember{name: "secondary"}
+		p, _ := newFallbackTestProvider(t, primary, secondary)
+		tray := newFallbackTestTray()
+
+		assert.NoError(t, p.StartDeploy(context.Background(), tray))
+		assert.Equal(t, 1, primary.cleaned)
+		assert.Equal(t, 1, secondary.started)
+		assert.Equal(t, "secondary", tray.ProviderData[fallbackProviderDataMember])
+	})
+
+	t.Run("other errors are not rerouted", func(t *testing.T) {
+		primary := &fakeMember{name: "primary", startErr: errors.New("bad credentials")}
+		secondary := &fakeMember{name: "secondary"}
+		p, _ := newFallbackTestProvider(t, primary, secondary)
+		tray := newFallbackTestTray()
+
+		err := p.StartDeploy(context.Background(), tray)
+		assert.EqualError(t, err, "bad credentials")
+		assert.Equal(t, 0, primary.cleaned)
+		assert.Equal(t, 0, secondary.started)
+		assert.Equal(t, "primary", tray.ProviderData[fallbackProviderDataMember])
+	})
+
+	t.Run("last member capacity error is returned", func(t *testing.T) {
+		primary := &fakeMember{name: "primary", startErr: fmt.Errorf("stockout: %w", ErrCapacityBlocked)}
+		secondary := &fakeMember{name: "secondary", startErr: fmt.Errorf("quota: %w", ErrCapacityBlocked)}
+		p, _ := newFallbackTestProvider(t, primary, secondary)
+		tray := newFallbackTestTray()
+
+		err := p.StartDeploy(context.Background(), tray)
+		assert.ErrorIs(t, err, ErrCapacityBlocked)
+		assert.Contains(t, err.Error(), "quota")
+		assert.Equal(t, 0, secondary.cleaned, "last member is left for the caller's CleanTray")
+		assert.Equal(t, "secondary", tray.ProviderData[fallbackProviderDataMember])
+	})
+
+	t.Run("failed cleanup stops the chain", func(t *testing.T) {
+		primary := &fakeMember{
+			name:     "primary",
+			startErr: fmt.Errorf("stockout: %w", ErrCapacityBlocked),
+			cleanErr: errors.New("api down"),
+		}
+		secondary := &fakeMember{name: "secondary"}
+		p, _ := newFallbackTestProvider(t, primary, secondary)
+		tray := newFallbackTestTray()
+
+		err := p.StartDeploy(context.Background(), tray)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "api down")
+		assert.Equal(t, 0, secondary.started)
+		assert.Equal(t, "primary", tray.ProviderData[fallbackProviderDataMember])
+	})
+}
+
+func TestFallbackProvider_WaitDeploy(t *testing.T) {
+	t.Run("waits on recorded member", func(t *testing.T) {
+		primary := &fakeMember{name: "primary"}
+		secondary := &fakeMember{name: "secondary"}
+		p, _ := newFallbackTestProvider(t, primary, secondary)
+		tray := newFallbackTestTray()
+		tray.ProviderData[fallbackProviderDataMember] = "secondary"
+
+		assert.NoError(t, p.WaitDeploy(context.Background(), tray))
+		assert.Equal(t, 0, primary.waited)
+		assert.Equal(t, 1, secondary.waited)
+	})
+
+	t.Run("blocked wait redeploys on next member", func(t *testing.T) {
+		primary := &fakeMember{name: "primary", waitErr: fmt.Errorf("eval blocked: %w", ErrCapacityBlocked)}
+		secondary := &fakeMember{name: "secondary"}
+		p, _ := newFallbackTestProvider(t, primary, secondary)
+		tray := newFallbackTestTray()
+
+		assert.NoError(t, p.StartDeploy(context.Background(), tray))
+		assert.NoError(t, p.WaitDeploy(context.Background(), tray))
+
+		assert.Equal(t, 1, primary.waited)
+		assert.Equal(t, 1, primary.cleaned)
+		assert.Equal(t, 1, secondary.started)
+		assert.Equal(t, 1, secondary.waited)
+		assert.Equal(t, "secondary", tray.ProviderData[fallbackProviderDataMember])
+	})
+
+	t.Run("reroute is saved before the next member starts", func(t *testing.T) {
+		primary := &fakeMember{name: "primary", waitErr: fmt.Errorf("eval blocked: %w", ErrCapacityBlocked)}
+		secondary := &fakeMember{name: "secondary"}
+		p, _ := newFallbackTestProvider(t, primary, secondary)
+		tray := newFallbackTestTray()
+
+		// saved is the member recorded on each save, with whether the
+		// secondary had started by then.
+		var saved []string
+		ctx := WithProviderDataSaver(context.Background(), func(_ context.Context, saving *trays.Tray) error {
+			saved = append(saved, fmt.Sprintf("%s started=%d", saving.ProviderData[fallbackProviderDataMember], secondary.started))
+			return nil
+		})
+
+		assert.NoError(t, p.StartDeploy(ctx, tray))
+		assert.NoError(t, p.WaitDeploy(ctx, tray))
+		assert.Equal(t, []string{"primary started=0", "secondary started=0"}, saved)
+	})
+
+	t.Run("failed save stops the reroute", func(t *testing.T) {
+		primary := &fakeMember{name: "primary", waitErr: fmt.Errorf("eval blocked: %w", ErrCapacityBlocked)}
+		secondary := &fakeMember{name: "secondary"}
+		p, _ := newFallbackTestProvider(t, primary, secondary)
+		tray := newFallbackTestTray()
+		tray.ProviderData[fallbackProviderDataMember] = "primary"
+
+		ctx := WithProviderDataSaver(context.Background(), func(context.Context, *trays.Tray) error {
+			return errors.New("db down")
+		})
+		err := p.WaitDeploy(ctx, tray)
+		assert.ErrorContains(t, err, "db down")
+		assert.Equal(t, 0, secondary.started, "an unsaved member is never started")
+	})
+
+	t.Run("unknown recorded member is an error", func(t *testing.T) {
+		p, _ := newFallbackTestProvider(t, &fakeMember{name: "primary"})
+		tray := newFallbackTestTray()
+		tray.ProviderData[fallbackProviderDataMember] = "removed"
+
+		assert.Error(t, p.WaitDeploy(context.Background(), tray))
+	})
+}
+
+func TestFallbackProvider_CleanTray(t *testing.T) {
+	t.Run("delegates to recorded member", func(t *testing.T) {
+		primary := &fakeMember{name: "primary"}
+		secondary := &fakeMember{name: "secondary"}
+		p, _ := newFallbackTestProvider(t, primary, secondary)
+		tray := newFallbackTestTray()
+		tray.ProviderData[fallbackProviderDataMember] = "secondary"
+
+		assert.NoError(t, p.CleanTray(context.Background(), tray))
+		assert.Equal(t, 0, primary.cleaned)
+		assert.Equal(t, 1, secondary.cleaned)
+		assert.Equal(t, "secondary", secondary.lastName)
+	})
+
+	t.Run("no recorded member is a no-op", func(t *testing.T) {
+		primary := &fakeMember{name: "primary"}
+		p, _ := newFallbackTestProvider(t, primary)
+
+		assert.NoError(t, p.CleanTray(context.Background(), newFallbackTestTray()))
+		assert.Equal(t, 0, primary.cleaned)
+	})
+}
+
+func TestFallbackProvider_ListTrays(t *testing.T) {
+	primary := &fakeMember{name: "primary", listed: []string{"burst-runner-0000000000000001"}}
+	secondary := &fakeMember{name: "secondary", listed: []string{"burst-runner-0000000000000002"}}
+	p, _ := newFallbackTestProvider(t, primary, secondary)
+
+	listed, err := p.ListTrays(context.Background(), &config.TrayType{Name: "burst-runner", Provider: "burst", GitHubOrg: "test-org"})
+	assert.NoError(t, err)
+	assert.Len(t, listed, 2)
+
+	for i, member := range []string{"primary", "secondary"} {
+		assert.Equal(t, "burst", listed[i].ProviderName)
+		assert.Equal(t, "test-org", listed[i].GitHubOrgName)
+		assert.Equal(t, member, listed[i].ProviderData[fallbackProviderDataMember])
+	}
+
+	// Listed trays clean up on the member that reported them.
+	assert.NoError(t, p.CleanTray(context.Background(), listed[1]))
+	assert.Equal(t, 0, primary.cleaned)
+	assert.Equal(t, 1, secondary.cleaned)
+}
@@ -2,17 +2,21 @@ package providers
 
 import (
 	"cattery/lib/config"
+	"cattery/lib/logging"
 	"cattery/lib/trays"
 	"context"
 	"errors"
 	"fmt"
 	"math/rand"
+	"regexp"
+	"strings"
 	"sync"
 
 	compute "cloud.google.com/go/compute/apiv1"
 	"cloud.google.com/go/compute/apiv1/computepb"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 	"google.golang.org/protobuf/proto"
 )
@@ -36,7 +40,7 @@ func NewGceProvider(name string, providerConfig config.ProviderConfig) *GceProvi
 	provider := &GceProvider{
 		Name:           name,
 		providerConfig: providerConfig,
-		logger:         logrus.WithFields(logrus.Fields{"name": "gceProvider"}),
+		logger:         logging.Logger("gceProvider").WithFields(logrus.Fields{logging.ProviderKey: name, "providerType": "google"}),
 	}
 
 	client, err := provider.createInstancesClient()
@@ -88,8 +92,9 @@ func (g *GceProvider) StartDeploy(ctx context.Context, tray *trays.Tray) error {
 
 	metadata := createGcpMetadata(
 		map[string]string{
-			"cattery-url":      config.Get().Server.AdvertiseUrl,
-			"cattery-agent-id": tray.Id,
+			"cattery-url":         config.Get().Server.AdvertiseUrl,
+			"cattery-agent-id":    tray.Id,
+			"cattery-agent-token": tray.Token,
 		},
 		extraMetadata,
 	)
@@ -105,8 +110,8 @@ func (g *GceProvider) StartDeploy(ctx context.Context, tray *trays.Tray) error {
 		},
 	})
 	if err != nil {
-		g.logger.Errorf("Failed to start tray creation: %v", err)
-		return err
+		logging.WithTray(g.logger, tray).Errorf("Failed to start tray creation: %v", err)
+		return wrapGceCapacityError(err)
 	}
 
 	g.pendingOps.Store(tray.Id, op)
@@ -120,13 +125,13 @@ func (g *GceProvider) StartDeploy(ctx context.Context, tray *trays.Tray) error {
 func (g *GceProvider) WaitDeploy(ctx context.Context, tray *trays.Tray) error {
 	v, ok := g.pendingOps.LoadAndDelete(tray.Id)
 	if !ok {
-		g.logger.Tracef("No pending operation for tray %s; skipping wait", tray.Id)
+		logging.WithTray(g.logger, tray).Tracef("No pending operation for tray %s; skipping wait", tray.Id)
 		return nil
 	}
 	op := v.(*compute.Operation)
 	if err := op.Wait(ctx); err != nil {
-		g.logger.Errorf("Failed waiting for tray creation to complete: %v", err)
-		return err
+		logging.WithTray(g.logger, tray).Errorf("Failed waiting for tray creation to complete: %v", err)
+		return wrapGceCapacityError(err)
 	}
 	return nil
 }
@@ -136,7 +141,7 @@ func (g *GceProvider) CleanTray(ctx context.Context, tray *trays.Tray) error {
 
 	zone := tray.ProviderData["zone"]
 	if zone == "" {
-		g.logger.Warnf("CleanTray called without zone for tray %s; nothing to delete", tray.Id)
+		logging.WithTray(g.logger, tray).Warnf("CleanTray called without zone for tray %s; nothing to delete", tray.Id)
 		return nil
 	}
 
@@ -158,7 +163,7 @@ func (g *GceProvider) CleanTray(ctx context.Context, tray *trays.Tray) error {
 			if e.Code != 404 {
 				return err
 			} else {
-				g.logger.Tracef("Tray not found during deletion; skipping: %v (tray %s)", err, tray.Id)
+				logging.WithTray(g.logger, tray).Tracef("Tray not found during deletion; skipping: %v (tray %s)", err, tray.Id)
 				return nil
 			}
 		}
@@ -168,6 +173,46 @@ func (g *GceProvider) CleanTray(ctx context.Context, tray *trays.Tray) error {
 	return nil
 }
 
+// ListTrays lists the instances named like trays of trayType in the tray
+// type's zones. Instances already being deleted are skipped.
+func (g *GceProvider) ListTrays(ctx context.Context, trayType *config.TrayType) ([]*trays.Tray, error) {
+	trayConfig, ok := trayConfigFor(trayType, g.Name).(config.GoogleTrayConfig)
+	if !ok {
+		return nil, fmt.Errorf("unexpected tray config type for gce provider, tray type %s", trayType.Name)
+	}
+
+	client, err := g.createInstancesClient()
+	if err != nil {
+		return nil, err
+	}
+
+	project := g.providerConfig.Get("project")
+	filter := fmt.Sprintf(`name eq "%s-[0-9a-f]{16}"`, regexp.QuoteMeta(trayType.Name))
+
+	var result []*trays.Tray
+	for _, zone := range trayConfig.Zones {
+		it := client.List(ctx, &computepb.ListInstancesRequest{
+			Project: project,
+			Zone:    zone,
+			Filter:  proto.String(filter),
+		})
+		for {
+			instance, err := it.Next()
+			if errors.Is(err, iterator.Done) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to list instances in zone %s: %w", zone, err)
+			}
+			if instance.GetStatus() == "STOPPING" || !trays.IsTrayOfType(instance.GetName(), trayType.Name) {
+				continue
+			}
+			result = append(result, listedTray(trayType, g.Name, instance.GetName(), map[string]string{"zone": zone}))
+		}
+	}
+	return result, nil
+}
+
 func (g *GceProvider) createInstancesClient() (*compute.InstancesClient, error) {
 
 	if g.instanceClient != nil {
@@ -195,6 +240,30 @@ func (g *GceProvider) createInstancesClient() (*compute.InstancesClient, error)
 	return instancesClient, nil
 }
 
+// gceCapacityErrorCodes are the GCE error codes/reasons that mean "no room
+// right now" rather than a broken request: zone stockouts and exhausted
+// quotas. Insert reports quota errors synchronously; stockouts usually
+// surface on the operation, so both StartDeploy and WaitDeploy check.
+var gceCapacityErrorCodes = []string{
+	"ZONE_RESOURCE_POOL_EXHAUSTED",
+	"ZONE_RESOURCE_POOL_EXHAUSTED_WITH_DETAILS",
+	"QUOTA_EXCEEDED",
+	"quotaExceeded",
+}
+
+// wrapGceCapacityError wraps err in ErrCapacityBlocked when it is a stockout
+// or quota error, so a fallback provider can reroute the tray. The operation
+// errors only carry the code in their message, hence the substring match.
+func wrapGceCapacityError(err error) error {
+	msg := err.Error()
+	for _, code := range gceCapacityErrorCodes {
+		if strings.Contains(msg, code) {
+			return fmt.Errorf("%w: %w", ErrCapacityBlocked, err)
+		}
+	}
+	return err
+}
+
 func createGcpMetadata(fieldMaps ...map[string]string) *computepb.Metadata {
 
 	var items []*computepb.Items
@@ -0,0 +1,481 @@
+package providers
+
+import (
+	"cattery/lib/agents"
+	"cattery/lib/config"
+	"cattery/lib/logging"
+	"cattery/lib/trays"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	kubernetesProviderDataPodName   = "podName"
+	kubernetesProviderDataNamespace = "namespace"
+
+	kubernetesManagedByLabel     = "app.kubernetes.io/managed-by"
+	kubernetesTrayIdAnnotation   = "cattery.io/tray-id"
+	kubernetesTrayTypeAnnotation = "cattery.io/tray-type"
+
+	// defaultAgentPath is used when KubernetesTrayConfig.AgentPath is empty;
+	// the image is expected to have the cattery binary on its PATH.
+	defaultAgentPath = "cattery"
+
+	// defaultUnschedulableTimeout is used when
+	// KubernetesTrayConfig.UnschedulableTimeout is zero.
+	defaultUnschedulableTimeout = 2 * time.Minute
+)
+
+// KubernetesProvider runs each tray as a single Pod built from the tray
+// type's pod template. The pod runs `cattery agent` directly as the agent
+// container's command, so there is no bootstrap payload as with nomad.
+type KubernetesProvider struct {
+	name string
+
+	// Only the core/v1 client is needed since we only touch Pods.
+	client    corev1client.CoreV1Interface
+	namespace string
+
+	logger *logrus.Entry
+}
+
+// NewKubernetesProvider builds the provider from `kubeconfig` if set,
+// otherwise from in-cluster credentials (the server's service account needs
+// create/get/watch/delete on pods in the target namespaces).
+func NewKubernetesProvider(name string, providerConfig config.ProviderConfig) *KubernetesProvider {
+	logger := logging.Logger("kubernetesProvider").WithFields(logrus.Fields{
+		logging.ProviderKey: name,
+		"providerType":      "kubernetes",
+	})
+
+	// With both arguments empty this falls back to rest.InClusterConfig.
+	restCfg, err := clientcmd.BuildConfigFromFlags("", providerConfig.Get("kubeconfig"))
+	if err != nil {
+		logger.Errorf("failed to build kubernetes client config: %v", err)
+		return nil
+	}
+
+	client, err := corev1client.NewForConfig(restCfg)
+	if err != nil {
+		logger.Errorf("failed to create kubernetes client: %v", err)
+		return nil
+	}
+
+	return newKubernetesProvider(name, client, providerConfig.Get("namespace"))
+}
+
+// newKubernetesProvider builds the provider from an already-constructed
+// client. Split from NewKubernetesProvider so tests can inject the fake
+// clientset.
+func newKubernetesProvider(name string, client corev1client.CoreV1Interface, namespace string) *KubernetesProvider {
+	return &KubernetesProvider{
+		name:      name,
+		client:    client,
+		namespace: resolvePodNamespace(namespace),
+		logger: logging.Logger("kubernetesProvider").WithFields(logrus.Fields{
+			logging.ProviderKey: name,
+			"providerType":      "kubernetes",
+		}),
+	}
+}
+
+func (k *KubernetesProvider) GetProviderName() string {
+	return k.name
+}
+
+// StartDeploy creates the tray pod. The pod name and namespace are staged on
+// tray.ProviderData before the Create call: the name is deterministic, so
+// CleanTray can delete the pod even if the Create response was lost.
+func (k *KubernetesProvider) StartDeploy(ctx context.Context, tray *trays.Tray) error {
+	trayConfig, ok := tray.TrayConfig().(config.KubernetesTrayConfig)
+	if !ok {
+		return fmt.Errorf("unexpected tray config type for kubernetes provider, tray %s", tray.Id)
+	}
+
+	namespace := k.namespace
+	if trayConfig.Namespace != "" {
+		namespace = trayConfig.Namespace
+	}
+
+	pod, err := buildTrayPod(tray, trayConfig, config.Get().Server.AdvertiseUrl)
+	if err != nil {
+		return fmt.Errorf("failed to build pod for tray %s: %w", tray.Id, err)
+	}
+	pod.Namespace = namespace
+
+	tray.ProviderData[kubernetesProviderDataNamespace] = namespace
+	tray.ProviderData[kubernetesProviderDataPodName] = pod.Name
+
+	_, err = k.client.Pods(namespace).Create(ctx, pod, metav1.CreateOptions{})
+	if err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			logging.WithTray(k.logger, tray).Infof("Pod %s/%s for tray %s already exists", namespace, pod.Name, tray.Id)
+			return nil
+		}
+		logging.WithTray(k.logger, tray).Errorf("Failed to create pod %s/%s for tray %s: %v", namespace, pod.Name, tray.Id, err)
+		return err
+	}
+
+	logging.WithTray(k.logger, tray).Infof("Created pod %s/%s for tray %s", namespace, pod.Name, tray.Id)
+	return nil
+}
+
+// WaitDeploy blocks until the pod is scheduled onto a node. Mapping:
+//
+//   - PodScheduled=True or phase Running/Succeeded: nil (agent registration
+//     is the readiness signal from here)
+//   - PodScheduled=False with reason Unschedulable for UnschedulableTimeout:
+//     ErrCapacityBlocked (the pod stays pending until CleanTray deletes it).
+//     A shorter spell is waited out, since the cluster autoscaler may be
+//     adding a node for it.
+//   - phase Failed: plain error
+//   - pod deleted while waiting: plain error
+//   - ctx cancellation: ctx error (caller-imposed timeout)
+func (k *KubernetesProvider) WaitDeploy(ctx context.Context, tray *trays.Tray) error {
+	podName := tray.ProviderData[kubernetesProviderDataPodName]
+	if podName == "" {
+		logging.WithTray(k.logger, tray).Tracef("No pod name stored for tray %s; skipping wait", tray.Id)
+		return nil
+	}
+	pods := k.client.Pods(k.trayNamespace(tray))
+
+	timeout := defaultUnschedulableTimeout
+	if trayConfig, ok := tray.TrayConfig().(config.KubernetesTrayConfig); ok && trayConfig.UnschedulableTimeout > 0 {
+		timeout = trayConfig.UnschedulableTimeout
+	}
+	unschedulable := &unschedulableTimer{podName: podName, timeout: timeout}
+	defer unschedulable.stop()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// Check the current state first, then watch from its resourceVersion
+		// so no transition between the two calls is missed.
+		pod, err := pods.Get(ctx, podName, metav1.GetOptions{})
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to get pod %s: %w", podName, err)
+		}
+		if done, err := unschedulable.observe(pod); done {
+			return err
+		}
+
+		w, err := pods.Watch(ctx, metav1.ListOptions{
+			FieldSelector:   fields.OneTermEqualSelector("metadata.name", podName).String(),
+			ResourceVersion: pod.ResourceVersion,
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to watch pod %s: %w", podName, err)
+		}
+
+		done, err := k.watchPod(ctx, w, podName, unschedulable)
+		w.Stop()
+		if done {
+			return err
+		}
+		// The watch closed (server timeout, expired resourceVersion): re-sync.
+	}
+}
+
+// watchPod consumes watch events until the pod reaches a final deploy state
+// or the watch closes (done=false).
+func (k *KubernetesProvider) watchPod(ctx context.Context, w watch.Interface, podName string, unschedulable *unschedulableTimer) (bool, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return true, ctx.Err()
+		case <-unschedulable.expired():
+			return true, unschedulable.err()
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				return false, nil
+			}
+			switch event.Type {
+			case watch.Deleted:
+				return true, fmt.Errorf("pod %s was deleted while waiting for it to be scheduled", podName)
+			case watch.Error:
+				k.logger.Debugf("Watch error for pod %s: %v", podName, apierrors.FromObject(event.Object))
+				return false, nil
+			case watch.Added, watch.Modified:
+				pod, ok := event.Object.(*corev1.Pod)
+				if !ok || pod.Name != podName {
+					continue
+				}
+				if done, err := unschedulable.observe(pod); done {
+					return true, err
+				}
+			}
+		}
+	}
+}
+
+// CleanTray deletes the tray pod by name. A missing pod counts as success.
+func (k *KubernetesProvider) CleanTray(ctx context.Context, tray *trays.Tray) error {
+	podName := tray.ProviderData[kubernetesProviderDataPodName]
+	if podName == "" {
+		podName = podNameForTray(tray.Id)
+	}
+	namespace := k.trayNamespace(tray)
+
+	err := k.client.Pods(namespace).Delete(ctx, podName, metav1.DeleteOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			logging.WithTray(k.logger, tray).Tracef("Pod %s/%s already gone; nothing to do", namespace, podName)
+			return nil
+		}
+		return err
+	}
+
+	logging.WithTray(k.logger, tray).Infof("Deleted pod %s/%s for tray %s", namespace, podName, tray.Id)
+	return nil
+}
+
+// ListTrays lists the cattery-managed pods of trayType, identified by the
+// tray annotations buildTrayPod sets. Pods already terminating are skipped.
+func (k *KubernetesProvider) ListTrays(ctx context.Context, trayType *config.TrayType) ([]*trays.Tray, error) {
+	namespace := k.namespace
+	if trayConfig, ok := trayConfigFor(trayType, k.name).(config.KubernetesTrayConfig); ok && trayConfig.Namespace != "" {
+		namespace = trayConfig.Namespace
+	}
+
+	pods, err := k.client.Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: kubernetesManagedByLabel + "=cattery",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods in namespace %s: %w", namespace, err)
+	}
+
+	var result []*trays.Tray
+	for _, pod := range pods.Items {
+		trayId := pod.Annotations[kubernetesTrayIdAnnotation]
+		if pod.DeletionTimestamp != nil ||
+			pod.Annotations[kubernetesTrayTypeAnnotation] != trayType.Name ||
+			!trays.IsTrayOfType(trayId, trayType.Name) {
+			continue
+		}
+		result = append(result, listedTray(trayType, k.name, trayId, map[string]string{
+			kubernetesProviderDataPodName:   pod.Name,
+			kubernetesProviderDataNamespace: namespace,
+		}))
+	}
+	return result, nil
+}
+
+func (k *KubernetesProvider) trayNamespace(tray *trays.Tray) string {
+	if ns := tray.ProviderData[kubernetesProviderDataNamespace]; ns != "" {
+		return ns
+	}
+	return k.namespace
+}
+
+// unschedulableTimer tracks how long WaitDeploy has seen the pod
+// Unschedulable. It starts on the first such observation and is reset when
+// the pod stops being Unschedulable without being scheduled.
+type unschedulableTimer struct {
+	podName string
+	timeout time.Duration
+	timer   *time.Timer
+	message string
+}
+
+// observe reports whether pod reached a final WaitDeploy state, and the
+// error to return for it, starting or resetting the timer on the way.
+func (u *unschedulableTimer) observe(pod *corev1.Pod) (bool, error) {
+	if done, err := podDeployResult(pod); done {
+		return true, err
+	}
+	message, ok := podUnschedulable(pod)
+	if !ok {
+		u.stop()
+		return false, nil
+	}
+	u.message = message
+	if u.timer == nil {
+		u.timer = time.NewTimer(u.timeout)
+	}
+	return false, nil
+}
+
+// expired fires once the pod has been Unschedulable for the timeout. It is
+// nil, and never fires, while the timer is not running.
+func (u *unschedulableTimer) expired() <-chan time.Time {
+	if u.timer == nil {
+		return nil
+	}
+	return u.timer.C
+}
+
+func (u *unschedulableTimer) err() error {
+	return fmt.Errorf("pod %s unschedulable for %s: %w: %s", u.podName, u.timeout, ErrCapacityBlocked, u.message)
+}
+
+func (u *unschedulableTimer) stop() {
+	if u.timer != nil {
+		u.timer.Stop()
+		u.timer = nil
+	}
+}
+
+// podDeployResult reports whether pod reached a final WaitDeploy state, and
+// the error to return for it.
+func podDeployResult(pod *corev1.Pod) (bool, error) {
+	switch pod.Status.Phase {
+	case corev1.PodRunning, corev1.PodSucceeded:
+		return true, nil
+	case corev1.PodFailed:
+		return true, fmt.Errorf("pod %s failed: %s %s", pod.Name, pod.Status.Reason, pod.Status.Message)
+	}
+
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type != corev1.PodScheduled {
+			continue
+		}
+		if cond.Status == corev1.ConditionTrue {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// podUnschedulable reports whether the scheduler marked pod Unschedulable,
+// and its message (e.g. "0/3 nodes are available: 3 Insufficient cpu.").
+func podUnschedulable(pod *corev1.Pod) (string, bool) {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodScheduled && cond.Status == corev1.ConditionFalse && cond.Reason == corev1.PodReasonUnschedulable {
+			return cond.Message, true
+		}
+	}
+	return "", false
+}
+
+// buildTrayPod renders the tray type's pod template into the tray's Pod.
+func buildTrayPod(tray *trays.Tray, trayConfig config.KubernetesTrayConfig, serverUrl string) (*corev1.Pod, error) {
+	var template corev1.PodTemplateSpec
+	if trayConfig.PodTemplate != nil {
+		// The template arrives as a generic map from viper; round-trip it
+		// through JSON so the k8s types do the decoding. encoding/json matches
+		// field names case-insensitively, which undoes viper's lowercasing.
+		raw, err := json.Marshal(trayConfig.PodTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid podTemplate: %w", err)
+		}
+		if err := json.Unmarshal(raw, &template); err != nil {
+			return nil, fmt.Errorf("invalid podTemplate: %w", err)
+		}
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: template.ObjectMeta,
+		Spec:       template.Spec,
+	}
+	pod.Name = podNameForTray(tray.Id)
+	pod.GenerateName = ""
+	pod.Spec.RestartPolicy = corev1.RestartPolicyNever
+
+	if pod.Labels == nil {
+		pod.Labels = map[string]string{}
+	}
+	pod.Labels[kubernetesManagedByLabel] = "cattery"
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	pod.Annotations[kubernetesTrayIdAnnotation] = tray.Id
+	pod.Annotations[kubernetesTrayTypeAnnotation] = tray.TrayTypeName
+
+	if len(pod.Spec.Containers) == 0 {
+		pod.Spec.Containers = []corev1.Container{{Name: "agent"}}
+	}
+	container := &pod.Spec.Containers[0]
+	if trayConfig.Container != "" {
+		container = nil
+		for i := range pod.Spec.Containers {
+			if pod.Spec.Containers[i].Name == trayConfig.Container {
+				container = &pod.Spec.Containers[i]
+				break
+			}
+		}
+		if container == nil {
+			return nil, fmt.Errorf("container %q not found in podTemplate", trayConfig.Container)
+		}
+	}
+	if trayConfig.Image != "" {
+		container.Image = trayConfig.Image
+	}
+	if container.Image == "" {
+		return nil, fmt.Errorf("container %q has no image", container.Name)
+	}
+
+	agentPath := trayConfig.AgentPath
+	if agentPath == "" {
+		agentPath = defaultAgentPath
+	}
+	runnerFolder := trayConfig.RunnerFolder
+	if runnerFolder == "" {
+		runnerFolder = defaultRunnerFolder
+	}
+	container.Command = []string{agentPath, "agent", "-i", tray.Id, "-s", serverUrl, "--runner-folder", runnerFolder}
+	container.Args = nil
+
+	// The tray token replaces any value the template sets for it.
+	env := container.Env[:0:0]
+	for _, e := range container.Env {
+		if e.Name != agents.TokenEnv {
+			env = append(env, e)
+		}
+	}
+	container.Env = append(env, corev1.EnvVar{Name: agents.TokenEnv, Value: tray.Token})
+
+	return pod, nil
+}
+
+var invalidPodNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// podNameForTray maps a tray ID onto a valid pod name (RFC 1123 label:
+// lowercase alphanumerics and '-', at most 63 chars). Tray IDs end with a
+// random hex suffix, so truncation keeps the tail.
+func podNameForTray(trayId string) string {
+	name := invalidPodNameChars.ReplaceAllString(strings.ToLower(trayId), "-")
+	if len(name) > 63 {
+		name = name[len(name)-63:]
+	}
+	return strings.Trim(name, "-")
+}
+
+// resolvePodNamespace returns ns, or the namespace cattery itself runs in
+// (POD_NAMESPACE, then the service-account namespace file), then "default".
+func resolvePodNamespace(ns string) string {
+	if ns != "" {
+		return ns
+	}
+	if v := os.Getenv("POD_NAMESPACE"); v != "" {
+		return v
+	}
+	const saNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+	if b, err := os.ReadFile(saNamespaceFile); err == nil {
+		if s := strings.TrimSpace(string(b)); s != "" {
+			return s
+		}
+	}
+	return "default"
+}
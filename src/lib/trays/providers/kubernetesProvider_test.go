@@ -0,0 +1,341 @@
+package providers
+
+import (
+	"cattery/lib/config"
+	"cattery/lib/trays"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func setupKubernetesTest(t *testing.T, trayConfig config.KubernetesTrayConfig) (*KubernetesProvider, *fake.Clientset, *trays.Tray) {
+	t.Helper()
+
+	cfg := &config.CatteryConfig{
+		Server: config.ServerConfig{AdvertiseUrl: "http://cattery:5137"},
+		Providers: []*config.ProviderConfig{
+			{"name": "k8s", "type": "kubernetes"},
+		},
+		TrayTypes: []*config.TrayType{
+			{Name: "K8s_Runner", Provider: "k8s", GitHubOrg: "test-org", Config: trayConfig},
+		},
+	}
+	cfg.InitMaps()
+	config.SetForTest(t, cfg)
+
+	clientset := fake.NewClientset()
+	provider := newKubernetesProvider("k8s", clientset.CoreV1(), "runners")
+
+	tray := &trays.Tray{
+		Id:           "K8s_Runner-0123456789abcdef",
+		TrayTypeName: "K8s_Runner",
+		ProviderName: "k8s",
+		ProviderData: map[string]string{},
+		Token:        "tray-token",
+	}
+	return provider, clientset, tray
+}
+
+func TestPodNameForTray(t *testing.T) {
+	assert.Equal(t, "k8s-runner-0123456789abcdef", podNameForTray("K8s_Runner-0123456789abcdef"))
+
+	long := strings.Repeat("a", 80) + "-0123456789abcdef"
+	name := podNameForTray(long)
+	assert.Len(t, name, 63)
+	assert.True(t, strings.HasSuffix(name, "-0123456789abcdef"), "random suffix must survive truncation")
+}
+
+func TestResolvePodNamespace(t *testing.T) {
+	assert.Equal(t, "explicit", resolvePodNamespace("explicit"))
+
+	t.Setenv("POD_NAMESPACE", "from-env")
+	assert.Equal(t, "from-env", resolvePodNamespace(""))
+}
+
+func TestKubernetesProvider_StartDeploy(t *testing.T) {
+	t.Run("creates pod from template", func(t *testing.T) {
+		provider, clientset, tray := setupKubernetesTest(t, config.KubernetesTrayConfig{
+			PodTemplate: map[string]any{
+				"metadata": map[string]any{
+					"labels": map[string]any{"team": "ci"},
+				},
+				"spec": map[string]any{
+					// viper hands us lowercased keys
+					"nodeselector": map[string]any{"pool": "runners"},
+					"containers": []any{
+						map[string]any{"name": "sidecar", "image": "busybox"},
+						map[string]any{"name": "runner", "image": "ghcr.io/acme/runner:1", "env": []any{
+							map[string]any{"name": "TZ", "value": "UTC"},
+							map[string]any{"name": "CATTERY_AGENT_TOKEN", "value": "from-template"},
+						}},
+					},
+				},
+			},
+			Container: "runner",
+		})
+
+		require.NoError(t, provider.StartDeploy(context.Background(), tray))
+		assert.Equal(t, "runners", tray.ProviderData[kubernetesProviderDataNamespace])
+		assert.Equal(t, "k8s-runner-0123456789abcdef", tray.ProviderData[kubernetesProviderDataPodName])
+
+		pod, err := clientset.CoreV1().Pods("runners").Get(context.Background(), "k8s-runner-0123456789abcdef", metav1.GetOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, corev1.RestartPolicyNever, pod.Spec.RestartPolicy)
+		assert.Equal(t, map[string]string{"pool": "runners"}, pod.Spec.NodeSelector)
+		assert.Equal(t, "ci", pod.Labels["team"])
+		assert.Equal(t, "cattery", pod.Labels[kubernetesManagedByLabel])
+		assert.Equal(t, tray.Id, pod.Annotations[kubernetesTrayIdAnnotation])
+
+		assert.Nil(t, pod.Spec.Containers[0].Command, "non-agent containers are left alone")
+		assert.Equal(t,
+			[]string{"cattery", "agent", "-i", tray.Id, "-s", "http://cattery:5137", "--runner-folder", "/cattery"},
+			pod.Spec.Containers[1].Command)
+		assert.Equal(t,
+			[]corev1.EnvVar{{Name: "TZ", Value: "UTC"}, {Name: "CATTERY_AGENT_TOKEN", Value: "tray-token"}},
+			pod.Spec.Containers[1].Env)
+	})
+
+	t.Run("image-only config and namespace override", func(t *testing.T) {
+		provider, clientset, tray := setupKubernetesTest(t, config.KubernetesTrayConfig{
+			Namespace:    "other",
+			Image:        "runner:latest",
+			AgentPath:    "/opt/cattery",
+			RunnerFolder: "/actions-runner",
+		})
+
+		require.NoError(t, provider.StartDeploy(context.Background(), tray))
+
+		pod, err := clientset.CoreV1().Pods("other").Get(context.Background(), tray.ProviderData[kubernetesProviderDataPodName], metav1.GetOptions{})
+		require.NoError(t, err)
+		require.Len(t, pod.Spec.Containers, 1)
+		assert.Equal(t, "runner:latest", pod.Spec.Containers[0].Image)
+		assert.Equal(t, "/opt/cattery", pod.Spec.Containers[0].Command[0])
+		assert.Equal(t, "/actions-runner", pod.Spec.Containers[0].Command[len(pod.Spec.Containers[0].Command)-1])
+	})
+
+	t.Run("missing image is an error", func(t *testing.T) {
+		provider, _, tray := setupKubernetesTest(t, config.KubernetesTrayConfig{})
+		assert.Error(t, provider.StartDeploy(context.Background(), tray))
+	})
+
+	t.Run("unknown container is an error", func(t *testing.T) {
+		provider, _, tray := setupKubernetesTest(t, config.KubernetesTrayConfig{Image: "runner", Container: "nope"})
+		assert.Error(t, provider.StartDeploy(context.Background(), tray))
+	})
+
+	t.Run("already existing pod is success", func(t *testing.T) {
+		provider, _, tray := setupKubernetesTest(t, config.KubernetesTrayConfig{Image: "runner"})
+		require.NoError(t, provider.StartDeploy(context.Background(), tray))
+		assert.NoError(t, provider.StartDeploy(context.Background(), tray))
+	})
+}
+
+func TestKubernetesProvider_WaitDeploy(t *testing.T) {
+	createPod := func(t *testing.T, clientset *fake.Clientset, status corev1.PodStatus) {
+		t.Helper()
+		_, err := clientset.CoreV1().Pods("runners").Create(context.Background(), &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "runners"},
+			Status:     status,
+		}, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+
+	t.Run("no pod name skips wait", func(t *testing.T) {
+		provider, _, tray := setupKubernetesTest(t, config.KubernetesTrayConfig{})
+		assert.NoError(t, provider.WaitDeploy(context.Background(), tray))
+	})
+
+	t.Run("already scheduled", func(t *testing.T) {
+		provider, clientset, tray := setupKubernetesTest(t, config.KubernetesTrayConfig{})
+		createPod(t, clientset, corev1.PodStatus{
+			Phase:      corev1.PodPending,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodScheduled, Status: corev1.ConditionTrue}},
+		})
+		tray.ProviderData[kubernetesProviderDataPodName] = "pod-1"
+
+		assert.NoError(t, provider.WaitDeploy(context.Background(), tray))
+	})
+
+	unschedulableStatus := corev1.PodStatus{
+		Phase: corev1.PodPending,
+		Conditions: []corev1.PodCondition{{
+			Type:    corev1.PodScheduled,
+			Status:  corev1.ConditionFalse,
+			Reason:  corev1.PodReasonUnschedulable,
+			Message: "0/3 nodes are available: 3 Insufficient cpu.",
+		}},
+	}
+
+	t.Run("unschedulable past the timeout is ErrCapacityBlocked", func(t *testing.T) {
+		provider, clientset, tray := setupKubernetesTest(t, config.KubernetesTrayConfig{UnschedulableTimeout: 50 * time.Millisecond})
+		createPod(t, clientset, unschedulableStatus)
+		tray.ProviderData[kubernetesProviderDataPodName] = "pod-1"
+
+		watcher := watch.NewFake()
+		clientset.PrependWatchReactor("pods", k8stesting.DefaultWatchReactor(watcher, nil))
+
+		err := provider.WaitDeploy(context.Background(), tray)
+		assert.ErrorIs(t, err, ErrCapacityBlocked)
+		assert.Contains(t, err.Error(), "Insufficient cpu")
+	})
+
+	t.Run("unschedulable within the timeout waits for scheduling", func(t *testing.T) {
+		provider, clientset, tray := setupKubernetesTest(t, config.KubernetesTrayConfig{UnschedulableTimeout: time.Minute})
+		createPod(t, clientset, unschedulableStatus)
+		tray.ProviderData[kubernetesProviderDataPodName] = "pod-1"
+
+		watcher := watch.NewFake()
+		clientset.PrependWatchReactor("pods", k8stesting.DefaultWatchReactor(watcher, nil))
+
+		done := make(chan error, 1)
+		go func() { done <- provider.WaitDeploy(context.Background(), tray) }()
+
+		// A new node came up and the pod got scheduled onto it.
+		watcher.Modify(&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "runners"},
+			Status: corev1.PodStatus{
+				Phase:      corev1.PodPending,
+				Conditions: []corev1.PodCondition{{Type: corev1.PodScheduled, Status: corev1.ConditionTrue}},
+			},
+		})
+
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("WaitDeploy did not return after pod was scheduled")
+		}
+	})
+
+	t.Run("failed pod is a plain error", func(t *testing.T) {
+		provider, clientset, tray := setupKubernetesTest(t, config.KubernetesTrayConfig{})
+		createPod(t, clientset, corev1.PodStatus{Phase: corev1.PodFailed, Reason: "Evicted"})
+		tray.ProviderData[kubernetesProviderDataPodName] = "pod-1"
+
+		err := provider.WaitDeploy(context.Background(), tray)
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrCapacityBlocked)
+	})
+
+	t.Run("watches pending pod until running", func(t *testing.T) {
+		provider, clientset, tray := setupKubernetesTest(t, config.KubernetesTrayConfig{})
+		createPod(t, clientset, corev1.PodStatus{Phase: corev1.PodPending})
+		tray.ProviderData[kubernetesProviderDataPodName] = "pod-1"
+
+		watcher := watch.NewFake()
+		clientset.PrependWatchReactor("pods", k8stesting.DefaultWatchReactor(watcher, nil))
+
+		done := make(chan error, 1)
+		go func() { done <- provider.WaitDeploy(context.Background(), tray) }()
+
+		watcher.Modify(&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "runners"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		})
+
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("WaitDeploy did not return after pod started running")
+		}
+	})
+
+	t.Run("pod deleted while waiting", func(t *testing.T) {
+		provider, clientset, tray := setupKubernetesTest(t, config.KubernetesTrayConfig{})
+		createPod(t, clientset, corev1.PodStatus{Phase: corev1.PodPending})
+		tray.ProviderData[kubernetesProviderDataPodName] = "pod-1"
+
+		watcher := watch.NewFake()
+		clientset.PrependWatchReactor("pods", k8stesting.DefaultWatchReactor(watcher, nil))
+
+		done := make(chan error, 1)
+		go func() { done <- provider.WaitDeploy(context.Background(), tray) }()
+
+		watcher.Delete(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "runners"}})
+
+		select {
+		case err := <-done:
+			assert.Error(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("WaitDeploy did not return after pod was deleted")
+		}
+	})
+
+	t.Run("ctx cancellation", func(t *testing.T) {
+		provider, clientset, tray := setupKubernetesTest(t, config.KubernetesTrayConfig{})
+		createPod(t, clientset, corev1.PodStatus{Phase: corev1.PodPending})
+		tray.ProviderData[kubernetesProviderDataPodName] = "pod-1"
+
+		watcher := watch.NewFake()
+		clientset.PrependWatchReactor("pods", k8stesting.DefaultWatchReactor(watcher, nil))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, provider.WaitDeploy(ctx, tray), context.DeadlineExceeded)
+	})
+}
+
+func TestKubernetesProvider_CleanTray(t *testing.T) {
+	t.Run("deletes the pod", func(t *testing.T) {
+		provider, clientset, tray := setupKubernetesTest(t, config.KubernetesTrayConfig{Image: "runner"})
+		require.NoError(t, provider.StartDeploy(context.Background(), tray))
+
+		require.NoError(t, provider.CleanTray(context.Background(), tray))
+
+		pods, err := clientset.CoreV1().Pods("runners").List(context.Background(), metav1.ListOptions{})
+		require.NoError(t, err)
+		assert.Empty(t, pods.Items)
+	})
+
+	t.Run("missing pod is success", func(t *testing.T) {
+		provider, _, tray := setupKubernetesTest(t, config.KubernetesTrayConfig{})
+		assert.NoError(t, provider.CleanTray(context.Background(), tray))
+	})
+}
+
+func TestKubernetesProvider_ListTrays(t *testing.T) {
+	provider, clientset, tray := setupKubernetesTest(t, config.KubernetesTrayConfig{Image: "runner"})
+	require.NoError(t, provider.StartDeploy(context.Background(), tray))
+
+	other := tray.Id[:len(tray.Id)-16] + "fedcba9876543210"
+	terminating := metav1.Now()
+	for _, pod := range []*corev1.Pod{
+		// Another tray type's pod in the same namespace.
+		{ObjectMeta: metav1.ObjectMeta{Name: "other-type", Namespace: "runners",
+			Labels:      map[string]string{kubernetesManagedByLabel: "cattery"},
+			Annotations: map[string]string{kubernetesTrayIdAnnotation: "Other-0123456789abcdef", kubernetesTrayTypeAnnotation: "Other"}}},
+		// Already being deleted.
+		{ObjectMeta: metav1.ObjectMeta{Name: "terminating", Namespace: "runners", DeletionTimestamp: &terminating, Finalizers: []string{"x"},
+			Labels:      map[string]string{kubernetesManagedByLabel: "cattery"},
+			Annotations: map[string]string{kubernetesTrayIdAnnotation: other, kubernetesTrayTypeAnnotation: "K8s_Runner"}}},
+		// Not managed by cattery.
+		{ObjectMeta: metav1.ObjectMeta{Name: "unmanaged", Namespace: "runners",
+			Annotations: map[string]string{kubernetesTrayIdAnnotation: other, kubernetesTrayTypeAnnotation: "K8s_Runner"}}},
+	} {
+		_, err := clientset.CoreV1().Pods("runners").Create(context.Background(), pod, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+
+	listed, err := provider.ListTrays(context.Background(), config.Get().GetTrayType("K8s_Runner"))
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, tray.Id, listed[0].Id)
+	assert.Equal(t, "k8s", listed[0].ProviderName)
+	assert.Equal(t, "test-org", listed[0].GitHubOrgName)
+	assert.Equal(t, tray.ProviderData, listed[0].ProviderData)
+
+	require.NoError(t, provider.CleanTray(context.Background(), listed[0]))
+	listed, err = provider.ListTrays(context.Background(), config.Get().GetTrayType("K8s_Runner"))
+	require.NoError(t, err)
+	assert.Empty(t, listed)
+}
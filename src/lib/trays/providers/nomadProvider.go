@@ -1,12 +1,11 @@
 package providers
 
 import (
+	"cattery/lib/agents"
 	"cattery/lib/config"
+	"cattery/lib/logging"
 	"cattery/lib/trays"
 	"context"
-	"crypto/rand"
-	"encoding/hex"
-	"errors"
 	"fmt"
 	"strings"
 
@@ -14,12 +13,6 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-// ErrCapacityBlocked indicates Nomad accepted the dispatch but cannot place
-// the alloc due to insufficient capacity or unsatisfied constraints. The eval
-// remains queued; a future fallback provider will use this sentinel to
-// reroute to another provider.
-var ErrCapacityBlocked = errors.New("nomad: dispatch blocked, no capacity")
-
 const (
 	nomadProviderDataDispatchedJobID = "dispatchedJobId"
 	nomadProviderDataEvalID          = "evalId"
@@ -59,10 +52,9 @@ type NomadProvider struct {
 }
 
 func NewNomadProvider(name string, providerConfig config.ProviderConfig) *NomadProvider {
-	logger := logrus.WithFields(logrus.Fields{
-		"name":         "NomadProvider",
-		"providerName": name,
-		"providerType": "nomad",
+	logger := logging.Logger("nomadProvider").WithFields(logrus.Fields{
+		logging.ProviderKey: name,
+		"providerType":      "nomad",
 	})
 
 	address := providerConfig.Get("address")
@@ -143,15 +135,6 @@ func (n *NomadProvider) StartDeploy(ctx context.Context, tray *trays.Tray) error
 		return fmt.Errorf("nomad tray config missing jobId, tray %s", tray.Id)
 	}
 
-	// bootstrapToken is forwarded as a meta value and surfaces inside the
-	// guest as $BOOTSTRAP_TOKEN. It is not validated by the cattery server
-	// today; the field is plumbed through so a future change can adopt
-	// per-dispatch token validation without touching the parent-job contract.
-	bootstrapToken, err := generateBootstrapToken()
-	if err != nil {
-		return fmt.Errorf("failed to generate bootstrap token: %w", err)
-	}
-
 	payload := buildBootstrapPayload(trayConfig.Script, trayConfig.RunnerFolder)
 
 	// Provider-owned keys are written *last* so that user-supplied
@@ -163,7 +146,9 @@ func (n *NomadProvider) StartDeploy(ctx context.Context, tray *trays.Tray) error
 		}
 	}
 	meta["tray_name"] = tray.Id
-	meta["bootstrap_token"] = bootstrapToken
+	// The tray token surfaces inside the guest as $BOOTSTRAP_TOKEN; the
+	// payload hands it to the agent, which presents it on every call.
+	meta["bootstrap_token"] = tray.Token
 	meta["cattery_url"] = config.Get().Server.AdvertiseUrl
 
 	// Staged on tray.ProviderData before the Dispatch call so that when
@@ -184,14 +169,14 @@ func (n *NomadProvider) StartDeploy(ctx context.Context, tray *trays.Tray) error
 		}).WithContext(ctx),
 	)
 	if err != nil {
-		n.logger.Errorf("Failed to dispatch nomad job %s for tray %s: %v", trayConfig.JobId, tray.Id, err)
+		logging.WithTray(n.logger, tray).Errorf("Failed to dispatch nomad job %s for tray %s: %v", trayConfig.JobId, tray.Id, err)
 		return err
 	}
 
 	tray.ProviderData[nomadProviderDataDispatchedJobID] = resp.DispatchedJobID
 	tray.ProviderData[nomadProviderDataEvalID] = resp.EvalID
 
-	n.logger.Infof("Dispatched nomad job %s for tray %s (dispatchedJobId=%s, evalId=%s)",
+	logging.WithTray(n.logger, tray).Infof("Dispatched nomad job %s for tray %s (dispatchedJobId=%s, evalId=%s)",
 		trayConfig.JobId, tray.Id, resp.DispatchedJobID, resp.EvalID)
 
 	return nil
@@ -202,13 +187,15 @@ func (n *NomadProvider) StartDeploy(ctx context.Context, tray *trays.Tray) error
 //
 //   - complete:           nil (Nomad scheduled the alloc; agent registration
 //     is the readiness signal from here)
-//   - blocked:            ErrCapacityBlocked
+//   - blocked:            ErrCapacityBlocked (Nomad accepted the dispatch but
+//     cannot place the alloc due to insufficient capacity or unsatisfied
+//     constraints; the eval stays queued until CleanTray deregisters it)
 //   - failed/canceled:    plain error
 //   - ctx cancellation:   ctx error (caller-imposed timeout)
 func (n *NomadProvider) WaitDeploy(ctx context.Context, tray *trays.Tray) error {
 	evalID := tray.ProviderData[nomadProviderDataEvalID]
 	if evalID == "" {
-		n.logger.Tracef("No eval id stored for tray %s; skipping wait", tray.Id)
+		logging.WithTray(n.logger, tray).Tracef("No eval id stored for tray %s; skipping wait", tray.Id)
 		return nil
 	}
 
@@ -235,8 +222,8 @@ func (n *NomadProvider) WaitDeploy(ctx context.Context, tray *trays.Tray) error
 		case "complete":
 			return nil
 		case "blocked":
-			n.logger.Warnf("Nomad eval %s blocked for tray %s: %s", evalID, tray.Id, eval.StatusDescription)
-			return fmt.Errorf("%w: %s", ErrCapacityBlocked, formatBlockedReason(eval))
+			logging.WithTray(n.logger, tray).Warnf("Nomad eval %s blocked for tray %s: %s", evalID, tray.Id, eval.StatusDescription)
+			return fmt.Errorf("nomad eval %s blocked: %w: %s", evalID, ErrCapacityBlocked, formatBlockedReason(eval))
 		case "failed", "canceled":
 			return fmt.Errorf("nomad eval %s ended with status %s: %s", evalID, eval.Status, eval.StatusDescription)
 		case "pending", "":
@@ -274,7 +261,7 @@ func (n *NomadProvider) CleanTray(ctx context.Context, tray *trays.Tray) error {
 
 	parentJobID := tray.ProviderData[nomadProviderDataParentJobID]
 	if parentJobID == "" {
-		n.logger.Warnf("CleanTray called without dispatchedJobId or parentJobId for tray %s; nothing to do", tray.Id)
+		logging.WithTray(n.logger, tray).Warnf("CleanTray called without dispatchedJobId or parentJobId for tray %s; nothing to do", tray.Id)
 		return nil
 	}
 
@@ -329,26 +316,65 @@ func (n *NomadProvider) cleanupLeakedDispatch(ctx context.Context, ns, parentJob
 		}
 		matched++
 		if err := n.deregister(ctx, ns, stub.ID); err != nil {
-			n.logger.Errorf("Failed to deregister leaked dispatched job %s for tray %s: %v", stub.ID, trayID, err)
+			n.logger.WithField(logging.TrayIdKey, trayID).Errorf("Failed to deregister leaked dispatched job %s for tray %s: %v", stub.ID, trayID, err)
 			if firstErr == nil {
 				firstErr = err
 			}
 			continue
 		}
-		n.logger.Infof("Deregistered leaked dispatched job %s for tray %s", stub.ID, trayID)
+		n.logger.WithField(logging.TrayIdKey, trayID).Infof("Deregistered leaked dispatched job %s for tray %s", stub.ID, trayID)
 	}
 	if matched == 0 {
-		n.logger.Tracef("No leaked dispatched children found for tray %s under parent %s", trayID, parentJobID)
+		n.logger.WithField(logging.TrayIdKey, trayID).Tracef("No leaked dispatched children found for tray %s under parent %s", trayID, parentJobID)
 	}
 	return firstErr
 }
 
-func generateBootstrapToken() (string, error) {
-	b := make([]byte, 32)
-	if _, err := rand.Read(b); err != nil {
-		return "", err
+// ListTrays lists the live children of the tray type's parent job that were
+// dispatched for trays of trayType. Child IDs have the shape
+// "<parentJobId>/dispatch-<trayId>-<timestamp>-<uuid>" (see StartDeploy), and
+// tray IDs have a fixed length after the type name, which is how the tray ID
+// is recovered from the child ID.
+func (n *NomadProvider) ListTrays(ctx context.Context, trayType *config.TrayType) ([]*trays.Tray, error) {
+	trayConfig, ok := trayConfigFor(trayType, n.name).(config.NomadTrayConfig)
+	if !ok {
+		return nil, fmt.Errorf("unexpected tray config type for nomad provider, tray type %s", trayType.Name)
+	}
+	if trayConfig.JobId == "" {
+		return nil, fmt.Errorf("nomad tray config missing jobId, tray type %s", trayType.Name)
+	}
+
+	dispatchPrefix := trayConfig.JobId + "/dispatch-"
+	q := (&api.QueryOptions{
+		Namespace: n.namespace,
+		Prefix:    dispatchPrefix + trayType.Name + "-",
+	}).WithContext(ctx)
+
+	stubs, _, err := n.client.Jobs().List(q)
+	if err != nil {
+		if isNomad404(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list nomad jobs: %w", err)
+	}
+
+	trayIdLength := len(trayType.Name) + len("-") + 16
+	var result []*trays.Tray
+	for _, stub := range stubs {
+		if stub.ParentID != trayConfig.JobId || stub.Status == "dead" {
+			continue
+		}
+		rest := strings.TrimPrefix(stub.ID, dispatchPrefix)
+		if len(rest) <= trayIdLength || rest[trayIdLength] != '-' || !trays.IsTrayOfType(rest[:trayIdLength], trayType.Name) {
+			continue
+		}
+		result = append(result, listedTray(trayType, n.name, rest[:trayIdLength], map[string]string{
+			nomadProviderDataDispatchedJobID: stub.ID,
+			nomadProviderDataNamespace:       n.namespace,
+			nomadProviderDataParentJobID:     trayConfig.JobId,
+		}))
 	}
-	return hex.EncodeToString(b), nil
+	return result, nil
 }
 
 // buildBootstrapPayload composes the dispatched bash payload. runnerFolder
@@ -369,6 +395,7 @@ func buildBootstrapPayload(userScript, runnerFolder string) []byte {
 		}
 		sb.WriteString("\n")
 	}
+	sb.WriteString("export " + agents.TokenEnv + "=\"$BOOTSTRAP_TOKEN\"\n")
 	fmt.Fprintf(&sb, "exec /usr/local/bin/cattery agent -i \"$TRAY_NAME\" -s \"$CATTERY_URL\" --runner-folder %q\n", runnerFolder)
 	return []byte(sb.String())
 }
@@ -142,6 +142,7 @@ func newTestTray(trayTypeName, id string) *trays.Tray {
 		Id:           id,
 		TrayTypeName: trayTypeName,
 		ProviderData: map[string]string{},
+		Token:        "token-" + id,
 	}
 }
 
@@ -190,7 +191,7 @@ func TestStartDeploy_Success(t *testing.T) {
 
 	// Meta is the bootstrap contract.
 	assert.Equal(t, "trayid-001", seenReq.Meta["tray_name"])
-	assert.NotEmpty(t, seenReq.Meta["bootstrap_token"])
+	assert.Equal(t, "token-trayid-001", seenReq.Meta["bootstrap_token"], "bootstrap_token must be the tray's token")
 	assert.Equal(t, "https://cattery.test", seenReq.Meta["cattery_url"])
 
 	// Payload spliced our user script in and emitted the cattery exec.
@@ -297,7 +298,7 @@ func TestStartDeploy_ExtraMetadataCannotClobberContractKeys(t *testing.T) {
 	require.NoError(t, err)
 
 	assert.Equal(t, "trayid-005", seenMeta["tray_name"], "provider must overwrite operator-supplied tray_name")
-	assert.NotEqual(t, "ATTACKER", seenMeta["bootstrap_token"], "bootstrap_token must be the freshly generated one")
+	assert.Equal(t, "token-trayid-005", seenMeta["bootstrap_token"], "bootstrap_token must be the tray's token")
 	assert.Equal(t, "https://cattery.test", seenMeta["cattery_url"], "cattery_url must be the server's advertised URL")
 	assert.Equal(t, "bar", seenMeta["foo"], "non-contract extraMetadata keys must pass through")
 }
@@ -453,3 +454,39 @@ func TestCleanTray_NoIdsRecorded_IsNoOp(t *testing.T) {
 	require.NoError(t, p.CleanTray(context.Background(), tray))
 	assert.Empty(t, fake.deregCalls)
 }
+
+// ---------------------------------------------------------------------------
+// ListTrays
+// ---------------------------------------------------------------------------
+
+func TestListTrays_RecoversTrayIdsFromDispatchedChildren(t *testing.T) {
+	setupTrayConfig(t, "tt", config.NomadTrayConfig{JobId: "parent-job"}, "http://x")
+	fake, p := startFake(t, "ci")
+
+	fake.onJobsList = func(q url.Values) ([]*api.JobListStub, int) {
+		assert.Equal(t, "parent-job/dispatch-tt-", q.Get("prefix"))
+		assert.Equal(t, "ci", q.Get("namespace"))
+		return []*api.JobListStub{
+			{ID: "parent-job/dispatch-tt-0123456789abcdef-1700000000-aaaa", ParentID: "parent-job", Status: "running"},
+			// Finished children are left to Nomad's GC.
+			{ID: "parent-job/dispatch-tt-1111111111111111-1700000000-bbbb", ParentID: "parent-job", Status: "dead"},
+			// Another tray type whose name extends this one.
+			{ID: "parent-job/dispatch-tt-large-0123456789abcdef-1700000000-cccc", ParentID: "parent-job", Status: "running"},
+			{ID: "parent-job/dispatch-tt-2222222222222222-1700000000-dddd", ParentID: "different-parent", Status: "running"},
+		}, http.StatusOK
+	}
+
+	listed, err := p.ListTrays(context.Background(), config.Get().GetTrayType("tt"))
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, "tt-0123456789abcdef", listed[0].Id)
+	assert.Equal(t, "test-nomad", listed[0].ProviderName)
+	assert.Equal(t, map[string]string{
+		nomadProviderDataDispatchedJobID: "parent-job/dispatch-tt-0123456789abcdef-1700000000-aaaa",
+		nomadProviderDataNamespace:       "ci",
+		nomadProviderDataParentJobID:     "parent-job",
+	}, listed[0].ProviderData)
+
+	require.NoError(t, p.CleanTray(context.Background(), listed[0]))
+	assert.Equal(t, []string{"parent-job/dispatch-tt-0123456789abcdef-1700000000-aaaa"}, fake.deregCalls)
+}
@@ -2,7 +2,6 @@ package providers
 
 import (
 	"cattery/lib/config"
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"strings"
@@ -12,22 +11,6 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-func TestGenerateBootstrapToken(t *testing.T) {
-	t.Run("returns 64 hex chars", func(t *testing.T) {
-		tok, err := generateBootstrapToken()
-		assert.NoError(t, err)
-		assert.Len(t, tok, 64)
-		_, err = hex.DecodeString(tok)
-		assert.NoError(t, err, "token must be valid hex")
-	})
-
-	t.Run("two calls produce distinct tokens", func(t *testing.T) {
-		a, _ := generateBootstrapToken()
-		b, _ := generateBootstrapToken()
-		assert.NotEqual(t, a, b)
-	})
-}
-
 func TestBuildBootstrapPayload(t *testing.T) {
 	t.Run("empty runnerFolder defaults to /cattery", func(t *testing.T) {
 		out := string(buildBootstrapPayload("", ""))
@@ -45,6 +28,13 @@ func TestBuildBootstrapPayload(t *testing.T) {
 		assert.Contains(t, out, "chmod +x /usr/local/bin/cattery")
 	})
 
+	t.Run("hands the bootstrap token to the agent", func(t *testing.T) {
+		out := string(buildBootstrapPayload("", ""))
+		exportIdx := strings.Index(out, `export CATTERY_AGENT_TOKEN="$BOOTSTRAP_TOKEN"`)
+		execIdx := strings.Index(out, "exec /usr/local/bin/cattery agent")
+		assert.True(t, exportIdx >= 0 && exportIdx < execIdx, "token must be exported before the agent exec, got: %q", out)
+	})
+
 	t.Run("starts with shebang and strict mode", func(t *testing.T) {
 		out := string(buildBootstrapPayload("", ""))
 		assert.True(t, strings.HasPrefix(out, "#!/bin/bash\nset -euo pipefail\n"),
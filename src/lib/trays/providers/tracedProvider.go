@@ -0,0 +1,59 @@
+package providers
+
+import (
+	"cattery/lib/config"
+	"cattery/lib/tracing"
+	"cattery/lib/trays"
+	"context"
+)
+
+// tracedProvider wraps a TrayProvider with a span around each of its calls.
+// GetProvider hands out providers wrapped in it.
+type tracedProvider struct {
+	provider TrayProvider
+}
+
+// tracedInventory is tracedProvider for a provider that is also a
+// TrayInventory, so wrapping keeps the orphan reconciler's type assertion
+// working.
+type tracedInventory struct {
+	*tracedProvider
+	inventory TrayInventory
+}
+
+func traced(provider TrayProvider) TrayProvider {
+	t := &tracedProvider{provider: provider}
+	if inventory, ok := provider.(TrayInventory); ok {
+		return &tracedInventory{tracedProvider: t, inventory: inventory}
+	}
+	return t
+}
+
+func (t *tracedProvider) GetProviderName() string {
+	return t.provider.GetProviderName()
+}
+
+func (t *tracedProvider) StartDeploy(ctx context.Context, tray *trays.Tray) (err error) {
+	ctx, span := tracing.Start(ctx, "provider.StartDeploy", tracing.TrayAttributes(tray)...)
+	defer func() { tracing.End(span, err) }()
+	return t.provider.StartDeploy(ctx, tray)
+}
+
+func (t *tracedProvider) WaitDeploy(ctx context.Context, tray *trays.Tray) (err error) {
+	ctx, span := tracing.Start(ctx, "provider.WaitDeploy", tracing.TrayAttributes(tray)...)
+	defer func() { tracing.End(span, err) }()
+	return t.provider.WaitDeploy(ctx, tray)
+}
+
+func (t *tracedProvider) CleanTray(ctx context.Context, tray *trays.Tray) (err error) {
+	ctx, span := tracing.Start(ctx, "provider.CleanTray", tracing.TrayAttributes(tray)...)
+	defer func() { tracing.End(span, err) }()
+	return t.provider.CleanTray(ctx, tray)
+}
+
+func (t *tracedInventory) ListTrays(ctx context.Context, trayType *config.TrayType) (_ []*trays.Tray, err error) {
+	ctx, span := tracing.Start(ctx, "provider.ListTrays",
+		tracing.TrayTypeKey.String(trayType.Name), tracing.ProviderKey.String(t.GetProviderName()))
+	defer func() { tracing.End(span, err) }()
+	return t.inventory.ListTrays(ctx, trayType)
+}
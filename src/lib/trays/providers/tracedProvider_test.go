@@ -0,0 +1,65 @@
+package providers
+
+import (
+	"cattery/lib/config"
+	"cattery/lib/trays"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestTraced_SpansEachCall(t *testing.T) {
+	recorder := recordSpans(t)
+	member := &fakeMember{name: "docker", waitErr: errors.New("boom")}
+	provider := traced(member)
+	tray := &trays.Tray{Id: "t1", TrayTypeName: "runner", ProviderName: "docker", ProviderData: map[string]string{}}
+
+	require.NoError(t, provider.StartDeploy(context.Background(), tray))
+	assert.Error(t, provider.WaitDeploy(context.Background(), tray))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "provider.StartDeploy", spans[0].Name())
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, "provider.WaitDeploy", spans[1].Name())
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, 1, member.started)
+	assert.Equal(t, 1, member.waited)
+}
+
+func TestTraced_KeepsInventory(t *testing.T) {
+	provider := traced(&fakeMember{name: "docker", listed: []string{"runner-1"}})
+
+	inventory, ok := provider.(TrayInventory)
+	require.True(t, ok, "a wrapped TrayInventory is still one")
+	listed, err := inventory.ListTrays(context.Background(), &config.TrayType{Name: "runner"})
+	require.NoError(t, err)
+	assert.Len(t, listed, 1)
+
+	_, ok = traced(&onlyProvider{}).(TrayInventory)
+	assert.False(t, ok, "a provider without an inventory does not gain one")
+}
+
+// onlyProvider is a TrayProvider that is not a TrayInventory.
+type onlyProvider struct{}
+
+func (*onlyProvider) GetProviderName() string                        { return "only" }
+func (*onlyProvider) StartDeploy(context.Context, *trays.Tray) error { return nil }
+func (*onlyProvider) WaitDeploy(context.Context, *trays.Tray) error  { return nil }
+func (*onlyProvider) CleanTray(context.Context, *trays.Tray) error   { return nil }
@@ -1,10 +1,18 @@
 package providers
 
 import (
+	"cattery/lib/config"
 	"cattery/lib/trays"
 	"context"
+	"errors"
 )
 
+// ErrCapacityBlocked indicates the provider cannot place the tray for lack of
+// capacity: a blocked Nomad eval, a GCE zone stockout or exhausted quota.
+// Providers wrap it (with %w) around the upstream detail; FallbackProvider
+// matches it with errors.Is to reroute the tray to its next member.
+var ErrCapacityBlocked = errors.New("no capacity")
+
 // TrayProvider models a two-phase deployment lifecycle:
 //
 //   - StartDeploy submits the create request to the upstream provider and
@@ -30,6 +38,65 @@ type TrayProvider interface {
 	CleanTray(ctx context.Context, tray *trays.Tray) error
 }
 
+// TrayInventory is implemented by providers that can list the upstream
+// resources they run for a tray type. The orphan reconciler uses it to find
+// resources whose tray row is gone, which CleanTray alone never revisits.
+//
+// ListTrays returns one tray per resource, with Id, TrayTypeName,
+// ProviderName and enough ProviderData for CleanTray to remove it. Only
+// resources whose name matches trays.IsTrayOfType are returned; resources in
+// the middle of being deleted may be skipped.
+type TrayInventory interface {
+	ListTrays(ctx context.Context, trayType *config.TrayType) ([]*trays.Tray, error)
+}
+
+// listedTray returns the tray a TrayInventory reports for an upstream
+// resource of trayType owned by providerName.
+func listedTray(trayType *config.TrayType, providerName string, trayId string, providerData map[string]string) *trays.Tray {
+	if providerData == nil {
+		providerData = make(map[string]string)
+	}
+	return &trays.Tray{
+		Id:            trayId,
+		TrayTypeName:  trayType.Name,
+		ProviderName:  providerName,
+		GitHubOrgName: trayType.GitHubOrg,
+		ProviderData:  providerData,
+	}
+}
+
+// trayConfigFor returns the tray config providerName uses for trayType,
+// resolving fallback members the same way Tray.TrayConfig does.
+func trayConfigFor(trayType *config.TrayType, providerName string) config.TrayConfig {
+	if fc, ok := trayType.Config.(config.FallbackTrayConfig); ok {
+		return fc[providerName]
+	}
+	return trayType.Config
+}
+
+// ProviderDataSaver persists tray.ProviderData to the stored tray.
+type ProviderDataSaver func(ctx context.Context, tray *trays.Tray) error
+
+type providerDataSaverKey struct{}
+
+// WithProviderDataSaver returns a copy of ctx carrying save. A provider that
+// moves a tray to another upstream resource in the middle of StartDeploy or
+// WaitDeploy calls it before starting the new resource, so the stored tray
+// keeps pointing at what CleanTray must remove even if the process stops
+// before the call returns.
+func WithProviderDataSaver(ctx context.Context, save ProviderDataSaver) context.Context {
+	return context.WithValue(ctx, providerDataSaverKey{}, save)
+}
+
+// saveProviderData calls the ProviderDataSaver carried by ctx, if any.
+func saveProviderData(ctx context.Context, tray *trays.Tray) error {
+	save, ok := ctx.Value(providerDataSaverKey{}).(ProviderDataSaver)
+	if !ok {
+		return nil
+	}
+	return save(ctx, tray)
+}
+
 // TrayProviderFactory resolves providers by name or by tray.
 type TrayProviderFactory interface {
 	GetProvider(providerName string) (TrayProvider, error)
@@ -2,21 +2,26 @@ package providers
 
 import (
 	"cattery/lib/config"
+	"cattery/lib/logging"
 	"cattery/lib/trays"
 	"errors"
+	"maps"
 	"sync"
-
-	log "github.com/sirupsen/logrus"
 )
 
 var (
 	providersMu sync.Mutex
-	providers   = make(map[string]TrayProvider)
+	providers   = make(map[string]cachedProvider)
 )
 
-var logger = log.WithFields(log.Fields{
-	"name": "trayProviderFactory",
-})
+// cachedProvider is a provider built by GetProvider together with the settings
+// it was built from, so a config reload that changes them rebuilds it.
+type cachedProvider struct {
+	provider TrayProvider
+	config   config.ProviderConfig
+}
+
+var logger = logging.Logger("trayProviderFactory")
 
 // DefaultFactory is the standard provider factory backed by config.
 type DefaultFactory struct{}
@@ -29,8 +34,15 @@ func (DefaultFactory) GetProviderForTray(tray *trays.Tray) (TrayProvider, error)
 	return GetProviderForTray(tray)
 }
 
+// GetProviderForTray returns the provider the tray was created on, so a
+// reload that moves its tray type to another provider still cleans it up
+// where it runs. Trays stored without a provider name fall back to their tray
+// type's current provider.
 func GetProviderForTray(tray *trays.Tray) (TrayProvider, error) {
-	return GetProviderByTrayTypeName(tray.TrayTypeName)
+	if tray.ProviderName == "" {
+		return GetProviderByTrayTypeName(tray.TrayTypeName)
+	}
+	return GetProvider(tray.ProviderName)
 }
 
 func GetProviderByTrayTypeName(trayTypeName string) (TrayProvider, error) {
@@ -47,10 +59,6 @@ func GetProvider(providerName string) (TrayProvider, error) {
 	providersMu.Lock()
 	defer providersMu.Unlock()
 
-	if existingProvider, ok := providers[providerName]; ok {
-		return existingProvider, nil
-	}
-
 	p := config.Get().GetProvider(providerName)
 	if p == nil {
 		return nil, errors.New("no provider found for " + providerName)
@@ -58,6 +66,15 @@ func GetProvider(providerName string) (TrayProvider, error) {
 
 	provider := *p
 
+	if existing, ok := providers[providerName]; ok {
+		if maps.Equal(existing.config, provider) {
+			return existing.provider, nil
+		}
+		// Calls already running on the old instance finish on it; it is not
+		// closed, as they may still be using its client.
+		logger.WithField(logging.ProviderKey, providerName).Infof("Settings of provider %s changed; rebuilding it", providerName)
+	}
+
 	var result TrayProvider
 	switch provider["type"] {
 	case "docker":
@@ -72,6 +89,14 @@ func GetProvider(providerName string) (TrayProvider, error) {
 		if p := NewNomadProvider(providerName, provider); p != nil {
 			result = p
 		}
+	case "kubernetes":
+		if p := NewKubernetesProvider(providerName, provider); p != nil {
+			result = p
+		}
+	case config.ProviderTypeFallback:
+		if p := NewFallbackProvider(providerName, provider, DefaultFactory{}); p != nil {
+			result = p
+		}
 	default:
 		return nil, errors.New("unknown provider type: " + provider["type"])
 	}
@@ -80,6 +105,7 @@ func GetProvider(providerName string) (TrayProvider, error) {
 		return nil, errors.New("failed to initialize provider: " + providerName)
 	}
 
-	providers[providerName] = result
+	result = traced(result)
+	providers[providerName] = cachedProvider{provider: result, config: maps.Clone(provider)}
 	return result, nil
 }
@@ -0,0 +1,78 @@
+package providers
+
+import (
+	"cattery/lib/config"
+	"cattery/lib/trays"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetProvider_RebuildsOnConfigChange(t *testing.T) {
+	setProviders := func(extra string) {
+		config.SetForTest(t, &config.CatteryConfig{
+			Providers: []*config.ProviderConfig{
+				{"name": "factory-docker", "type": "docker", "extra": extra},
+			},
+		})
+	}
+
+	setProviders("a")
+	first, err := GetProvider("factory-docker")
+	require.NoError(t, err)
+	again, err := GetProvider("factory-docker")
+	require.NoError(t, err)
+	assert.Same(t, first, again, "unchanged settings reuse the provider")
+
+	setProviders("b")
+	rebuilt, err := GetProvider("factory-docker")
+	require.NoError(t, err)
+	assert.NotSame(t, first, rebuilt, "changed settings rebuild the provider")
+
+	config.SetForTest(t, &config.CatteryConfig{})
+	_, err = GetProvider("factory-docker")
+	assert.Error(t, err, "a provider removed from the config is gone")
+}
+
+func TestGetProviderForTray_KeepsOriginalProviderAcrossReload(t *testing.T) {
+	setTrayTypeProvider := func(provider string) {
+		config.SetForTest(t, &config.CatteryConfig{
+			Providers: []*config.ProviderConfig{
+				{"name": "factory-old", "type": "docker"},
+				{"name": "factory-new", "type": "docker"},
+			},
+			TrayTypes: []*config.TrayType{{Name: "factory-linux", Provider: provider}},
+		})
+	}
+
+	// Stand fakes in for the built providers; unchanged settings reuse them.
+	oldMember, newMember := &fakeMember{name: "factory-old"}, &fakeMember{name: "factory-new"}
+	providersMu.Lock()
+	providers["factory-old"] = cachedProvider{provider: oldMember, config: config.ProviderConfig{"name": "factory-old", "type": "docker"}}
+	providers["factory-new"] = cachedProvider{provider: newMember, config: config.ProviderConfig{"name": "factory-new", "type": "docker"}}
+	providersMu.Unlock()
+	t.Cleanup(func() {
+		providersMu.Lock()
+		defer providersMu.Unlock()
+		delete(providers, "factory-old")
+		delete(providers, "factory-new")
+	})
+
+	setTrayTypeProvider("factory-old")
+	tray, err := trays.NewTray(*config.Get().GetTrayType("factory-linux"))
+	require.NoError(t, err)
+	assert.Equal(t, "factory-old", tray.ProviderName)
+
+	setTrayTypeProvider("factory-new")
+	provider, err := DefaultFactory{}.GetProviderForTray(tray)
+	require.NoError(t, err)
+	require.NoError(t, provider.CleanTray(context.Background(), tray))
+	assert.Equal(t, 1, oldMember.cleaned, "the tray is cleaned where it was created")
+	assert.Zero(t, newMember.cleaned)
+
+	provider, err = GetProviderForTray(&trays.Tray{TrayTypeName: "factory-linux"})
+	require.NoError(t, err)
+	assert.Same(t, newMember, provider, "a tray without a provider name uses its tray type's")
+}
@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"cattery/lib/bolt"
+	"context"
+	"encoding/json"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// BoltPauseRepository stores tray type pauses in the pauses bucket of a local
+// bbolt file, keyed by tray type name.
+type BoltPauseRepository struct {
+	db *bbolt.DB
+}
+
+func NewBoltPauseRepository() *BoltPauseRepository {
+	return &BoltPauseRepository{}
+}
+
+func (b *BoltPauseRepository) Connect(db *bbolt.DB) {
+	b.db = db
+}
+
+func (b *BoltPauseRepository) Pause(_ context.Context, trayTypeName string, deleteIdle bool) error {
+	data, err := json.Marshal(TrayTypePause{
+		TrayTypeName: trayTypeName,
+		DeleteIdle:   deleteIdle,
+		PausedAt:     time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bolt.PausesBucket).Put([]byte(trayTypeName), data)
+	})
+}
+
+func (b *BoltPauseRepository) Resume(_ context.Context, trayTypeName string) error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bolt.PausesBucket).Delete([]byte(trayTypeName))
+	})
+}
+
+func (b *BoltPauseRepository) GetPause(_ context.Context, trayTypeName string) (*TrayTypePause, error) {
+	var pause *TrayTypePause
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		data := tx.Bucket(bolt.PausesBucket).Get([]byte(trayTypeName))
+		if data == nil {
+			return nil
+		}
+		pause = &TrayTypePause{}
+		return json.Unmarshal(data, pause)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pause, nil
+}
+
+func (b *BoltPauseRepository) ListPauses(_ context.Context) ([]TrayTypePause, error) {
+	var pauses []TrayTypePause
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bolt.PausesBucket).ForEach(func(_, data []byte) error {
+			var pause TrayTypePause
+			if err := json.Unmarshal(data, &pause); err != nil {
+				return err
+			}
+			pauses = append(pauses, pause)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pauses, nil
+}
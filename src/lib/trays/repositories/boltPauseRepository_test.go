@@ -0,0 +1,47 @@
+package repositories
+
+import (
+	"cattery/lib/bolt"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoltPauseRepository(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "cattery.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	repo := NewBoltPauseRepository()
+	repo.Connect(db)
+	ctx := context.Background()
+
+	pause, err := repo.GetPause(ctx, "linux")
+	require.NoError(t, err)
+	assert.Nil(t, pause)
+
+	require.NoError(t, repo.Pause(ctx, "linux", false))
+	require.NoError(t, repo.Pause(ctx, "linux", true), "pausing again replaces the pause")
+	require.NoError(t, repo.Pause(ctx, "mac", false))
+
+	pause, err = repo.GetPause(ctx, "linux")
+	require.NoError(t, err)
+	require.NotNil(t, pause)
+	assert.Equal(t, "linux", pause.TrayTypeName)
+	assert.True(t, pause.DeleteIdle)
+	assert.False(t, pause.PausedAt.IsZero())
+
+	pauses, err := repo.ListPauses(ctx)
+	require.NoError(t, err)
+	assert.Len(t, pauses, 2)
+
+	require.NoError(t, repo.Resume(ctx, "linux"))
+	require.NoError(t, repo.Resume(ctx, "linux"), "resuming twice is not an error")
+
+	pause, err = repo.GetPause(ctx, "linux")
+	require.NoError(t, err)
+	assert.Nil(t, pause)
+}
@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"bytes"
+	"cattery/lib/bolt"
+	"cattery/lib/trays"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// BoltTrayEventRepository stores tray events in the trayEvents bucket of a
+// local bbolt file. Keys are the tray id, a zero byte and the bucket's next
+// sequence number, so a tray's events are adjacent and in insertion order.
+type BoltTrayEventRepository struct {
+	db *bbolt.DB
+}
+
+func NewBoltTrayEventRepository() *BoltTrayEventRepository {
+	return &BoltTrayEventRepository{}
+}
+
+func (b *BoltTrayEventRepository) Connect(db *bbolt.DB) {
+	b.db = db
+}
+
+func trayEventPrefix(trayId string) []byte {
+	return append([]byte(trayId), 0)
+}
+
+func (b *BoltTrayEventRepository) AddEvent(_ context.Context, event *trays.TrayEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bolt.TrayEventsBucket)
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := binary.BigEndian.AppendUint64(trayEventPrefix(event.TrayId), seq)
+		return bucket.Put(key, data)
+	})
+}
+
+func (b *BoltTrayEventRepository) ListEvents(_ context.Context, trayId string) ([]trays.TrayEvent, error) {
+	prefix := trayEventPrefix(trayId)
+	var events []trays.TrayEvent
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(bolt.TrayEventsBucket).Cursor()
+		for k, data := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, data = c.Next() {
+			var event trays.TrayEvent
+			if err := json.Unmarshal(data, &event); err != nil {
+				return err
+			}
+			events = append(events, event)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// PruneEvents scans the whole bucket; it runs rarely and an embedded
+// deployment keeps few events.
+func (b *BoltTrayEventRepository) PruneEvents(_ context.Context, before time.Time) (int64, error) {
+	var pruned int64
+	err := b.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bolt.TrayEventsBucket)
+		var expired [][]byte
+		err := bucket.ForEach(func(k, data []byte) error {
+			var event trays.TrayEvent
+			if err := json.Unmarshal(data, &event); err != nil {
+				return err
+			}
+			if event.Time.Before(before) {
+				expired = append(expired, bytes.Clone(k))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		// Keys are deleted after the scan; bolt cursors do not support
+		// deleting while iterating with ForEach.
+		for _, k := range expired {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		pruned = int64(len(expired))
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return pruned, nil
+}
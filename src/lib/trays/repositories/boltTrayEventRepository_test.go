@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"cattery/lib/bolt"
+	"cattery/lib/trays"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoltTrayEventRepository(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "cattery.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	repo := NewBoltTrayEventRepository()
+	repo.Connect(db)
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	add := func(trayId string, kind trays.TrayEventKind, at time.Time) {
+		require.NoError(t, repo.AddEvent(ctx, &trays.TrayEvent{TrayId: trayId, TrayTypeName: "linux", Kind: kind, Time: at}))
+	}
+	add("linux-1", trays.TrayEventCreated, now.Add(-2*time.Hour))
+	add("linux-10", trays.TrayEventCreated, now.Add(-time.Hour))
+	add("linux-1", trays.TrayEventRegistered, now.Add(-time.Hour))
+	add("linux-1", trays.TrayEventDeleted, now)
+
+	events, err := repo.ListEvents(ctx, "linux-1")
+	require.NoError(t, err)
+	require.Len(t, events, 3, "events of a tray whose id is a prefix of another's are not mixed")
+	assert.Equal(t, trays.TrayEventCreated, events[0].Kind)
+	assert.Equal(t, trays.TrayEventRegistered, events[1].Kind)
+	assert.Equal(t, trays.TrayEventDeleted, events[2].Kind)
+
+	events, err = repo.ListEvents(ctx, "missing")
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	pruned, err := repo.PruneEvents(ctx, now.Add(-30*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), pruned)
+
+	events, err = repo.ListEvents(ctx, "linux-1")
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, trays.TrayEventDeleted, events[0].Kind)
+}
@@ -0,0 +1,282 @@
+package repositories
+
+import (
+	"cattery/lib/bolt"
+	"cattery/lib/config"
+	"cattery/lib/trays"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// BoltTrayRepository implements TrayRepository on a local bbolt file, one
+// JSON-encoded tray per key in the trays bucket. Every write runs in a bolt
+// read-write transaction, which bolt serializes, so read-modify-write updates
+// are atomic. Queries scan the bucket; that is fine at the scale an embedded,
+// single-replica deployment runs.
+type BoltTrayRepository struct {
+	db *bbolt.DB
+}
+
+func NewBoltTrayRepository() *BoltTrayRepository {
+	return &BoltTrayRepository{}
+}
+
+func (b *BoltTrayRepository) Connect(db *bbolt.DB) {
+	b.db = db
+}
+
+func getTray(tx *bbolt.Tx, trayId string) (*trays.Tray, error) {
+	data := tx.Bucket(bolt.TraysBucket).Get([]byte(trayId))
+	if data == nil {
+		return nil, nil
+	}
+	return decodeTray(data)
+}
+
+func putTray(tx *bbolt.Tx, tray *trays.Tray) error {
+	data, err := json.Marshal(tray)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(bolt.TraysBucket).Put([]byte(tray.Id), data)
+}
+
+func decodeTray(data []byte) (*trays.Tray, error) {
+	var tray trays.Tray
+	if err := json.Unmarshal(data, &tray); err != nil {
+		return nil, err
+	}
+	if tray.ProviderData == nil {
+		tray.ProviderData = make(map[string]string)
+	}
+	return &tray, nil
+}
+
+// findTrays returns the trays for which match returns true.
+func (b *BoltTrayRepository) findTrays(match func(*trays.Tray) bool) ([]*trays.Tray, error) {
+	var result []*trays.Tray
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bolt.TraysBucket).ForEach(func(_, data []byte) error {
+			tray, err := decodeTray(data)
+			if err != nil {
+				return err
+			}
+			if match(tray) {
+				result = append(result, tray)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// updateTray applies mutate to the stored tray inside one read-write
+// transaction and returns the tray as written, or (nil, nil) if it is missing.
+func (b *BoltTrayRepository) updateTray(trayId string, mutate func(*trays.Tray)) (*trays.Tray, error) {
+	var result *trays.Tray
+	err := b.db.Update(func(tx *bbolt.Tx) error {
+		tray, err := getTray(tx, trayId)
+		if err != nil || tray == nil {
+			return err
+		}
+		mutate(tray)
+		if err := putTray(tx, tray); err != nil {
+			return err
+		}
+		result = tray
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (b *BoltTrayRepository) GetById(_ context.Context, trayId string) (*trays.Tray, error) {
+	var result *trays.Tray
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		result, err = getTray(tx, trayId)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (b *BoltTrayRepository) List(_ context.Context) ([]*trays.Tray, error) {
+	result, err := b.findTrays(func(*trays.Tray) bool { return true })
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].StatusChanged.After(result[j].StatusChanged)
+	})
+	return result, nil
+}
+
+func (b *BoltTrayRepository) GetStale(_ context.Context, thresholds map[trays.TrayStatus]time.Duration) ([]*trays.Tray, error) {
+	if len(thresholds) == 0 {
+		return nil, nil
+	}
+
+	now := time.Now().UTC()
+	return b.findTrays(func(tray *trays.Tray) bool {
+		d, ok := thresholds[tray.Status]
+		return ok && !tray.StatusChanged.After(now.Add(-d))
+	})
+}
+
+func (b *BoltTrayRepository) CountActive(_ context.Context, trayType string) (int, error) {
+	result, err := b.findTrays(func(tray *trays.Tray) bool {
+		return tray.TrayTypeName == trayType && tray.Status != trays.TrayStatusDeleting
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(result), nil
+}
+
+func (b *BoltTrayRepository) CountByStatus(_ context.Context, trayType string) (map[trays.TrayStatus]int, error) {
+	matching, err := b.findTrays(func(tray *trays.Tray) bool { return tray.TrayTypeName == trayType })
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[trays.TrayStatus]int)
+	for _, tray := range matching {
+		result[tray.Status]++
+	}
+	return result, nil
+}
+
+func (b *BoltTrayRepository) GetByStatus(_ context.Context, trayType string, status trays.TrayStatus) ([]*trays.Tray, error) {
+	result, err := b.findTrays(func(tray *trays.Tray) bool {
+		return tray.TrayTypeName == trayType && tray.Status == status
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].StatusChanged.Before(result[j].StatusChanged)
+	})
+	return result, nil
+}
+
+func (b *BoltTrayRepository) Save(_ context.Context, tray *trays.Tray) error {
+	tray.StatusChanged = time.Now().UTC()
+	tray.RecordStatusTime(tray.Status, tray.StatusChanged)
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		if tx.Bucket(bolt.TraysBucket).Get([]byte(tray.Id)) != nil {
+			return fmt.Errorf("tray %s already exists", tray.Id)
+		}
+		return putTray(tx, tray)
+	})
+}
+
+// SaveWithinQuotas counts and inserts in one read-write transaction, which
+// bolt serializes with every other write.
+func (b *BoltTrayRepository) SaveWithinQuotas(_ context.Context, tray *trays.Tray, quotas []*config.Quota) (*config.Quota, error) {
+	tray.StatusChanged = time.Now().UTC()
+	tray.RecordStatusTime(tray.Status, tray.StatusChanged)
+	var exceeded *config.Quota
+	err := b.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bolt.TraysBucket)
+		if bucket.Get([]byte(tray.Id)) != nil {
+			return fmt.Errorf("tray %s already exists", tray.Id)
+		}
+
+		counts := make([]int, len(quotas))
+		weights := make([]int, len(quotas))
+		err := bucket.ForEach(func(_, data []byte) error {
+			existing, err := decodeTray(data)
+			if err != nil {
+				return err
+			}
+			if existing.Status == trays.TrayStatusDeleting {
+				return nil
+			}
+			for i, quota := range quotas {
+				if quota.Matches(existing.GitHubOrgName, existing.ProviderName) {
+					counts[i]++
+					weights[i] += existing.QuotaWeight()
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for i, quota := range quotas {
+			if quota.Exceeded(counts[i]+1, weights[i]+tray.QuotaWeight()) {
+				exceeded = quota
+				return nil
+			}
+		}
+		return putTray(tx, tray)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return exceeded, nil
+}
+
+func (b *BoltTrayRepository) UpdateStatus(_ context.Context, trayId string, status trays.TrayStatus, jobRunId int64, workflowRunId int64, ghRunnerId int64, repository string, jobName string, workflowName string) (*trays.Tray, error) {
+	return b.updateTray(trayId, func(tray *trays.Tray) {
+		tray.Status = status
+		tray.StatusChanged = time.Now().UTC()
+		tray.RecordStatusTime(status, tray.StatusChanged)
+		if jobRunId != 0 {
+			tray.JobRunId = jobRunId
+		}
+		if ghRunnerId != 0 {
+			tray.GitHubRunnerId = ghRunnerId
+		}
+		if workflowRunId != 0 {
+			tray.WorkflowRunId = workflowRunId
+		}
+		if repository != "" {
+			tray.Repository = repository
+		}
+		if jobName != "" {
+			tray.JobName = jobName
+		}
+		if workflowName != "" {
+			tray.WorkflowName = workflowName
+		}
+	})
+}
+
+func (b *BoltTrayRepository) SetProviderData(ctx context.Context, trayId string, data map[string]string) (*trays.Tray, error) {
+	if len(data) == 0 {
+		return b.GetById(ctx, trayId)
+	}
+
+	return b.updateTray(trayId, func(tray *trays.Tray) {
+		for k, v := range data {
+			tray.ProviderData[k] = v
+		}
+	})
+}
+
+func (b *BoltTrayRepository) SetDraining(_ context.Context, trayId string, draining bool) (*trays.Tray, error) {
+	return b.updateTray(trayId, func(tray *trays.Tray) {
+		tray.Draining = draining
+	})
+}
+
+func (b *BoltTrayRepository) Delete(_ context.Context, trayId string) error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bolt.TraysBucket).Delete([]byte(trayId))
+	})
+}
@@ -0,0 +1,309 @@
+package repositories
+
+import (
+	"cattery/lib/bolt"
+	"cattery/lib/config"
+	"cattery/lib/trays"
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
+)
+
+func setupBoltTrayRepository(t *testing.T) *BoltTrayRepository {
+	t.Helper()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "cattery.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	repo := NewBoltTrayRepository()
+	repo.Connect(db)
+	return repo
+}
+
+func saveBoltTray(t *testing.T, repo *BoltTrayRepository, id string, trayType string, status trays.TrayStatus) *trays.Tray {
+	t.Helper()
+	tray := &trays.Tray{
+		Id:            id,
+		TrayTypeName:  trayType,
+		ProviderName:  "docker",
+		GitHubOrgName: "test-org",
+		Status:        status,
+		ProviderData:  map[string]string{"zone": "a"},
+		TokenHash:     "hash-" + id,
+		Token:         "token-" + id,
+	}
+	require.NoError(t, repo.Save(context.Background(), tray))
+	return tray
+}
+
+func TestBoltTrayRepository_SaveAndGetById(t *testing.T) {
+	repo := setupBoltTrayRepository(t)
+	ctx := context.Background()
+
+	saved := saveBoltTray(t, repo, "t1", "linux", trays.TrayStatusCreating)
+
+	got, err := repo.GetById(ctx, "t1")
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, "linux", got.TrayTypeName)
+	assert.Equal(t, "docker", got.ProviderName)
+	assert.Equal(t, trays.TrayStatusCreating, got.Status)
+	assert.Equal(t, map[string]string{"zone": "a"}, got.ProviderData)
+	assert.Equal(t, "hash-t1", got.TokenHash)
+	assert.Empty(t, got.Token, "the plain token is never stored")
+	assert.True(t, saved.StatusChanged.Equal(got.StatusChanged))
+
+	missing, err := repo.GetById(ctx, "nope")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	assert.Error(t, repo.Save(ctx, &trays.Tray{Id: "t1", TrayTypeName: "linux"}), "duplicate id must fail")
+}
+
+func TestBoltTrayRepository_UpdateStatus(t *testing.T) {
+	repo := setupBoltTrayRepository(t)
+	ctx := context.Background()
+
+	saveBoltTray(t, repo, "t1", "linux", trays.TrayStatusRegistered)
+
+	updated, err := repo.UpdateStatus(ctx, "t1", trays.TrayStatusRunning, 11, 22, 33, "repo", "job", "workflow")
+	require.NoError(t, err)
+	require.NotNil(t, updated)
+	assert.Equal(t, trays.TrayStatusRunning, updated.Status)
+	assert.Equal(t, int64(11), updated.JobRunId)
+	assert.Equal(t, int64(22), updated.WorkflowRunId)
+	assert.Equal(t, int64(33), updated.GitHubRunnerId)
+
+	// zero values leave the existing fields alone
+	updated, err = repo.UpdateStatus(ctx, "t1", trays.TrayStatusDeleting, 0, 0, 0, "", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, trays.TrayStatusDeleting, updated.Status)
+	assert.Equal(t, int64(11), updated.JobRunId)
+	assert.Equal(t, "repo", updated.Repository)
+	assert.Equal(t, "job", updated.JobName)
+	assert.Equal(t, "workflow", updated.WorkflowName)
+
+	// the write is persisted, not just returned
+	stored, err := repo.GetById(ctx, "t1")
+	require.NoError(t, err)
+	assert.Equal(t, trays.TrayStatusDeleting, stored.Status)
+
+	missing, err := repo.UpdateStatus(ctx, "nope", trays.TrayStatusRunning, 0, 0, 0, "", "", "")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func TestBoltTrayRepository_SetProviderData(t *testing.T) {
+	repo := setupBoltTrayRepository(t)
+	ctx := context.Background()
+
+	saveBoltTray(t, repo, "t1", "linux", trays.TrayStatusCreating)
+
+	updated, err := repo.SetProviderData(ctx, "t1", map[string]string{"zone": "b", "instanceId": "i-1"})
+	require.NoError(t, err)
+	require.NotNil(t, updated)
+	assert.Equal(t, map[string]string{"zone": "b", "instanceId": "i-1"}, updated.ProviderData)
+	assert.Equal(t, trays.TrayStatusCreating, updated.Status, "status untouched")
+
+	missing, err := repo.SetProviderData(ctx, "nope", map[string]string{"zone": "b"})
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func TestBoltTrayRepository_SetDraining(t *testing.T) {
+	repo := setupBoltTrayRepository(t)
+	ctx := context.Background()
+
+	saveBoltTray(t, repo, "t1", "linux", trays.TrayStatusRunning)
+
+	updated, err := repo.SetDraining(ctx, "t1", true)
+	require.NoError(t, err)
+	require.NotNil(t, updated)
+	assert.True(t, updated.Draining)
+	assert.Equal(t, trays.TrayStatusRunning, updated.Status, "status untouched")
+
+	missing, err := repo.SetDraining(ctx, "nope", true)
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func TestBoltTrayRepository_Queries(t *testing.T) {
+	repo := setupBoltTrayRepository(t)
+	ctx := context.Background()
+
+	saveBoltTray(t, repo, "t1", "linux", trays.TrayStatusCreating)
+	saveBoltTray(t, repo, "t2", "linux", trays.TrayStatusRunning)
+	saveBoltTray(t, repo, "t3", "linux", trays.TrayStatusDeleting)
+	saveBoltTray(t, repo, "t4", "other", trays.TrayStatusRunning)
+
+	active, err := repo.CountActive(ctx, "linux")
+	require.NoError(t, err)
+	assert.Equal(t, 2, active)
+
+	counts, err := repo.CountByStatus(ctx, "linux")
+	require.NoError(t, err)
+	assert.Equal(t, map[trays.TrayStatus]int{
+		trays.TrayStatusCreating: 1,
+		trays.TrayStatusRunning:  1,
+		trays.TrayStatusDeleting: 1,
+	}, counts)
+
+	running, err := repo.GetByStatus(ctx, "linux", trays.TrayStatusRunning)
+	require.NoError(t, err)
+	require.Len(t, running, 1)
+	assert.Equal(t, "t2", running[0].Id)
+
+	all, err := repo.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 4)
+	assert.Equal(t, "t4", all[0].Id, "newest first")
+
+	require.NoError(t, repo.Delete(ctx, "t4"))
+	all, err = repo.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, all, 3)
+}
+
+func TestBoltTrayRepository_GetStale(t *testing.T) {
+	repo := setupBoltTrayRepository(t)
+	ctx := context.Background()
+
+	saveBoltTray(t, repo, "creating-new", "linux", trays.TrayStatusCreating)
+	for _, tray := range []*trays.Tray{
+		{Id: "creating-old", TrayTypeName: "linux", Status: trays.TrayStatusCreating},
+		{Id: "running-old", TrayTypeName: "linux", Status: trays.TrayStatusRunning},
+		{Id: "deleting-old", TrayTypeName: "linux", Status: trays.TrayStatusDeleting},
+	} {
+		tray.StatusChanged = time.Now().UTC().Add(-time.Hour)
+		require.NoError(t, repo.db.Update(func(tx *bbolt.Tx) error { return putTray(tx, tray) }))
+	}
+
+	stale, err := repo.GetStale(ctx, map[trays.TrayStatus]time.Duration{
+		trays.TrayStatusCreating: 10 * time.Minute,
+		trays.TrayStatusDeleting: 2 * time.Hour,
+	})
+	require.NoError(t, err)
+
+	var ids []string
+	for _, tray := range stale {
+		ids = append(ids, tray.Id)
+	}
+	assert.Equal(t, []string{"creating-old"}, ids, "running is unchecked; deleting is under its threshold")
+
+	none, err := repo.GetStale(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, none)
+}
+
+// Reopening the file must give back what was written: that is the point of
+// the embedded store over the in-memory mock.
+func TestBoltTrayRepository_Durable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cattery.db")
+
+	db, err := bolt.Open(path)
+	require.NoError(t, err)
+	repo := NewBoltTrayRepository()
+	repo.Connect(db)
+	saveBoltTray(t, repo, "t1", "linux", trays.TrayStatusRunning)
+	require.NoError(t, db.Close())
+
+	db, err = bolt.Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	repo.Connect(db)
+
+	got, err := repo.GetById(context.Background(), "t1")
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, trays.TrayStatusRunning, got.Status)
+}
+
+func TestBoltTrayRepository_SaveWithinQuotas(t *testing.T) {
+	repo := setupBoltTrayRepository(t)
+	ctx := context.Background()
+
+	saveBoltTray(t, repo, "t1", "linux", trays.TrayStatusRunning)
+	saveBoltTray(t, repo, "t2", "linux", trays.TrayStatusDeleting)
+
+	byCount := &config.Quota{Name: "org", GitHubOrg: "test-org", MaxTrays: 2}
+	byWeight := &config.Quota{Name: "docker", Provider: "docker", MaxWeight: 4}
+	otherOrg := &config.Quota{Name: "other", GitHubOrg: "other-org", MaxTrays: 1}
+	newTray := func(id string, weight int) *trays.Tray {
+		return &trays.Tray{Id: id, TrayTypeName: "linux", ProviderName: "docker", GitHubOrgName: "test-org", Weight: weight}
+	}
+
+	// t1 counts with weight 1 (stored without one); the deleting t2 does not count.
+	exceeded, err := repo.SaveWithinQuotas(ctx, newTray("t3", 4), []*config.Quota{byCount, byWeight})
+	require.NoError(t, err)
+	assert.Equal(t, byWeight, exceeded)
+
+	exceeded, err = repo.SaveWithinQuotas(ctx, newTray("t3", 3), []*config.Quota{otherOrg, byCount, byWeight})
+	require.NoError(t, err)
+	assert.Nil(t, exceeded)
+
+	exceeded, err = repo.SaveWithinQuotas(ctx, newTray("t4", 1), []*config.Quota{byCount})
+	require.NoError(t, err)
+	assert.Equal(t, byCount, exceeded)
+
+	all, err := repo.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, all, 3)
+	got, err := repo.GetById(ctx, "t3")
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, 3, got.Weight)
+}
+
+func TestBoltTrayRepository_SaveWithinQuotas_Concurrent(t *testing.T) {
+	repo := setupBoltTrayRepository(t)
+	ctx := context.Background()
+	quota := &config.Quota{Name: "global", MaxTrays: 5}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tray := &trays.Tray{Id: fmt.Sprintf("t%d", i), TrayTypeName: "linux", ProviderName: "docker", GitHubOrgName: "test-org"}
+			_, err := repo.SaveWithinQuotas(ctx, tray, []*config.Quota{quota})
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	all, err := repo.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, all, 5)
+}
+
+func TestBoltTrayRepository_StatusTimes(t *testing.T) {
+	repo := setupBoltTrayRepository(t)
+	ctx := context.Background()
+
+	saved := saveBoltTray(t, repo, "t1", "linux", trays.TrayStatusCreating)
+	assert.True(t, saved.CreatedAt.Equal(saved.StatusChanged))
+
+	registered, err := repo.UpdateStatus(ctx, "t1", trays.TrayStatusRegistered, 0, 0, 7, "", "", "")
+	require.NoError(t, err)
+	assert.True(t, registered.CreatedAt.Equal(saved.CreatedAt))
+	assert.True(t, registered.RegisteredAt.Equal(registered.StatusChanged))
+	assert.True(t, registered.RunningAt.IsZero())
+
+	// Entering a status again keeps the first time.
+	again, err := repo.UpdateStatus(ctx, "t1", trays.TrayStatusRegistered, 0, 0, 0, "", "", "")
+	require.NoError(t, err)
+	assert.True(t, again.RegisteredAt.Equal(registered.RegisteredAt))
+
+	got, err := repo.GetById(ctx, "t1")
+	require.NoError(t, err)
+	assert.True(t, got.RegisteredAt.Equal(registered.RegisteredAt))
+	assert.True(t, got.DeletingAt.IsZero())
+}
@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type MongodbPauseRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongodbPauseRepository() *MongodbPauseRepository {
+	return &MongodbPauseRepository{}
+}
+
+func (m *MongodbPauseRepository) Connect(collection *mongo.Collection) {
+	m.collection = collection
+}
+
+func (m *MongodbPauseRepository) Pause(ctx context.Context, trayTypeName string, deleteIdle bool) error {
+	_, err := m.collection.UpdateOne(
+		ctx,
+		bson.M{"trayTypeName": trayTypeName},
+		bson.M{
+			"$set": bson.M{
+				"trayTypeName": trayTypeName,
+				"deleteIdle":   deleteIdle,
+				"pausedAt":     time.Now().UTC(),
+			},
+		},
+		options.UpdateOne().SetUpsert(true),
+	)
+	return err
+}
+
+func (m *MongodbPauseRepository) Resume(ctx context.Context, trayTypeName string) error {
+	_, err := m.collection.DeleteOne(ctx, bson.M{"trayTypeName": trayTypeName})
+	return err
+}
+
+func (m *MongodbPauseRepository) GetPause(ctx context.Context, trayTypeName string) (*TrayTypePause, error) {
+	var pause TrayTypePause
+	err := m.collection.FindOne(ctx, bson.M{"trayTypeName": trayTypeName}).Decode(&pause)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &pause, nil
+}
+
+func (m *MongodbPauseRepository) ListPauses(ctx context.Context) ([]TrayTypePause, error) {
+	cursor, err := m.collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	var pauses []TrayTypePause
+	if err := cursor.All(ctx, &pauses); err != nil {
+		return nil, err
+	}
+	return pauses, nil
+}
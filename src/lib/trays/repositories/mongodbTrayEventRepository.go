@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"cattery/lib/trays"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type MongodbTrayEventRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongodbTrayEventRepository() *MongodbTrayEventRepository {
+	return &MongodbTrayEventRepository{}
+}
+
+func (m *MongodbTrayEventRepository) Connect(collection *mongo.Collection) {
+	m.collection = collection
+}
+
+func (m *MongodbTrayEventRepository) AddEvent(ctx context.Context, event *trays.TrayEvent) error {
+	_, err := m.collection.InsertOne(ctx, event)
+	return err
+}
+
+// ListEvents sorts by time, then by _id, whose ObjectIds increase with
+// insertion order on each client.
+func (m *MongodbTrayEventRepository) ListEvents(ctx context.Context, trayId string) ([]trays.TrayEvent, error) {
+	cursor, err := m.collection.Find(ctx, bson.M{"trayId": trayId},
+		options.Find().SetSort(bson.D{{Key: "time", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	var events []trays.TrayEvent
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (m *MongodbTrayEventRepository) PruneEvents(ctx context.Context, before time.Time) (int64, error) {
+	result, err := m.collection.DeleteMany(ctx, bson.M{"time": bson.M{"$lt": before}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
@@ -1,16 +1,43 @@
 package repositories
 
 import (
+	"cattery/lib/config"
 	"cattery/lib/trays"
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
+	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
+// statusTimeFields are the fields holding when a tray first entered each
+// status (see trays.Tray.StatusTime).
+var statusTimeFields = map[trays.TrayStatus]string{
+	trays.TrayStatusCreating:    "createdAt",
+	trays.TrayStatusRegistering: "registeringAt",
+	trays.TrayStatusRegistered:  "registeredAt",
+	trays.TrayStatusRunning:     "runningAt",
+	trays.TrayStatusDeleting:    "deletingAt",
+}
+
+// quotaLocksCollection holds the lock document SaveWithinQuotas takes, in
+// the trays collection's database.
+const quotaLocksCollection = "quotaLocks"
+
+// mongoQuotaLockId is the _id of the quota lock document.
+const mongoQuotaLockId = "quotas"
+
+// quotaLockTTL bounds how long a lock left behind by a dead replica blocks
+// SaveWithinQuotas elsewhere. Counting and inserting take far less.
+const quotaLockTTL = 30 * time.Second
+
+// quotaLockRetry is how often a waiting SaveWithinQuotas retries the lock.
+const quotaLockRetry = 20 * time.Millisecond
+
 type MongodbTrayRepository struct {
 	collection *mongo.Collection
 }
@@ -88,14 +115,156 @@ func (m *MongodbTrayRepository) CountActive(ctx context.Context, trayType string
 	return int(count), nil
 }
 
+func (m *MongodbTrayRepository) CountByStatus(ctx context.Context, trayType string) (map[trays.TrayStatus]int, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"trayTypeName": trayType}}},
+		{{Key: "$group", Value: bson.M{"_id": "$status", "count": bson.M{"$sum": 1}}}},
+	}
+	cursor, err := m.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []struct {
+		Status trays.TrayStatus `bson:"_id"`
+		Count  int              `bson:"count"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	result := make(map[trays.TrayStatus]int, len(groups))
+	for _, g := range groups {
+		result[g.Status] = g.Count
+	}
+	return result, nil
+}
+
+func (m *MongodbTrayRepository) GetByStatus(ctx context.Context, trayType string, status trays.TrayStatus) ([]*trays.Tray, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "statusChanged", Value: 1}})
+	cursor, err := m.collection.Find(ctx, bson.M{"trayTypeName": trayType, "status": status}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var result []*trays.Tray
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 func (m *MongodbTrayRepository) Save(ctx context.Context, tray *trays.Tray) error {
 	tray.StatusChanged = time.Now().UTC()
+	tray.RecordStatusTime(tray.Status, tray.StatusChanged)
 	_, err := m.collection.InsertOne(ctx, tray)
 	return err
 }
 
+// SaveWithinQuotas counts and inserts while holding the quota lock document,
+// so no other replica can insert between the count and the insert.
+func (m *MongodbTrayRepository) SaveWithinQuotas(ctx context.Context, tray *trays.Tray, quotas []*config.Quota) (*config.Quota, error) {
+	unlock, err := m.lockQuotas(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to take quota lock: %w", err)
+	}
+	defer unlock()
+
+	for _, quota := range quotas {
+		count, weight, err := m.countQuota(ctx, quota)
+		if err != nil {
+			return nil, err
+		}
+		if quota.Exceeded(count+1, weight+tray.QuotaWeight()) {
+			return quota, nil
+		}
+	}
+	return nil, m.Save(ctx, tray)
+}
+
+// lockQuotas takes the quota lock, waiting while another SaveWithinQuotas
+// holds it, and returns the function releasing it. The lock is a document in
+// quotaLocksCollection, next to the trays, taken the way MongoLeaseStore
+// takes a lease: the upsert only matches a free or expired lock, and a live
+// one held elsewhere surfaces as a duplicate key. It expires after
+// quotaLockTTL, so a replica dying while holding it does not block the others
+// for good.
+func (m *MongodbTrayRepository) lockQuotas(ctx context.Context) (func(), error) {
+	locks := m.collection.Database().Collection(quotaLocksCollection)
+	holder := uuid.NewString()
+
+	for {
+		now := time.Now().UTC()
+		_, err := locks.UpdateOne(ctx,
+			bson.M{"_id": mongoQuotaLockId, "expiresAt": bson.M{"$lt": now}},
+			bson.M{"$set": bson.M{"holder": holder, "expiresAt": now.Add(quotaLockTTL)}},
+			options.UpdateOne().SetUpsert(true),
+		)
+		if err == nil {
+			break
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(quotaLockRetry):
+		}
+	}
+
+	return func() {
+		// Release even if ctx is done, rather than leave the others waiting
+		// out the TTL.
+		_, _ = locks.DeleteOne(context.WithoutCancel(ctx), bson.M{"_id": mongoQuotaLockId, "holder": holder})
+	}, nil
+}
+
+// countQuota returns the number and total weight of trays counted by quota.
+func (m *MongodbTrayRepository) countQuota(ctx context.Context, quota *config.Quota) (int, int, error) {
+	match := bson.M{"status": bson.M{"$ne": trays.TrayStatusDeleting}}
+	if quota.GitHubOrg != "" {
+		match["gitHubOrgName"] = quota.GitHubOrg
+	}
+	if quota.Provider != "" {
+		match["providerName"] = quota.Provider
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   nil,
+			"count": bson.M{"$sum": 1},
+			"weight": bson.M{"$sum": bson.M{"$max": bson.A{
+				bson.M{"$ifNull": bson.A{"$weight", 1}}, 1,
+			}}},
+		}}},
+	}
+	cursor, err := m.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var groups []struct {
+		Count  int `bson:"count"`
+		Weight int `bson:"weight"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return 0, 0, err
+	}
+	if len(groups) == 0 {
+		return 0, 0, nil
+	}
+	return groups[0].Count, groups[0].Weight, nil
+}
+
 func (m *MongodbTrayRepository) UpdateStatus(ctx context.Context, trayId string, status trays.TrayStatus, jobRunId int64, workflowRunId int64, ghRunnerId int64, repository string, jobName string, workflowName string) (*trays.Tray, error) {
-	setQuery := bson.M{"status": status, "statusChanged": time.Now().UTC()}
+	statusTime, ok := statusTimeFields[status]
+	if !ok {
+		return nil, fmt.Errorf("unknown tray status %d", status)
+	}
+
+	now := time.Now().UTC()
+	setQuery := bson.M{"status": status, "statusChanged": now}
 
 	if jobRunId != 0 {
 		setQuery["jobRunId"] = jobRunId
@@ -116,10 +285,12 @@ func (m *MongodbTrayRepository) UpdateStatus(ctx context.Context, trayId string,
 		setQuery["workflowName"] = workflowName
 	}
 
+	// $min sets the status's time field if it is missing and otherwise keeps
+	// the earlier, first time.
 	dbResult := m.collection.FindOneAndUpdate(
 		ctx,
 		bson.M{"id": trayId},
-		bson.M{"$set": setQuery},
+		bson.M{"$set": setQuery, "$min": bson.M{statusTime: now}},
 		options.FindOneAndUpdate().SetReturnDocument(options.After))
 
 	var result trays.Tray
@@ -161,6 +332,24 @@ func (m *MongodbTrayRepository) SetProviderData(ctx context.Context, trayId stri
 	return &result, nil
 }
 
+func (m *MongodbTrayRepository) SetDraining(ctx context.Context, trayId string, draining bool) (*trays.Tray, error) {
+	dbResult := m.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"id": trayId},
+		bson.M{"$set": bson.M{"draining": draining}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After))
+
+	var result trays.Tray
+	err := dbResult.Decode(&result)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &result, nil
+}
+
 func (m *MongodbTrayRepository) Delete(ctx context.Context, trayId string) error {
 	_, err := m.collection.DeleteOne(ctx, bson.M{"id": trayId})
 	return err
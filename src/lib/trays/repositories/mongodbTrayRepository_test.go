@@ -6,7 +6,9 @@ import (
 	"cattery/lib/config"
 	"cattery/lib/trays"
 	"context"
+	"fmt"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
@@ -339,6 +341,74 @@ func TestCountActive(t *testing.T) {
 	}
 }
 
+func TestCountByStatus(t *testing.T) {
+	client, collection := setupTestCollection(t)
+	defer client.Disconnect(context.Background())
+
+	repo := NewMongodbTrayRepository()
+	repo.Connect(collection)
+
+	testTrays := []*TestTray{
+		createTestTray("test-tray-1", "test-type", trays.TrayStatusCreating, 0),
+		createTestTray("test-tray-2", "test-type", trays.TrayStatusRegistered, 0),
+		createTestTray("test-tray-3", "test-type", trays.TrayStatusRegistered, 0),
+		createTestTray("test-tray-4", "test-type", trays.TrayStatusDeleting, 0),
+		createTestTray("other-tray-1", "other-type", trays.TrayStatusRegistered, 0),
+	}
+	insertTestTrays(t, collection, testTrays)
+
+	counts, err := repo.CountByStatus(context.Background(), "test-type")
+	if err != nil {
+		t.Fatalf("CountByStatus failed: %v", err)
+	}
+	expected := map[trays.TrayStatus]int{
+		trays.TrayStatusCreating:   1,
+		trays.TrayStatusRegistered: 2,
+		trays.TrayStatusDeleting:   1,
+	}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Errorf("Expected %v, got %v", expected, counts)
+	}
+
+	counts, err = repo.CountByStatus(context.Background(), "non-existent")
+	if err != nil {
+		t.Fatalf("CountByStatus for non-existent type failed: %v", err)
+	}
+	if len(counts) != 0 {
+		t.Errorf("Expected no counts for non-existent type, got %v", counts)
+	}
+}
+
+func TestGetByStatus(t *testing.T) {
+	client, collection := setupTestCollection(t)
+	defer client.Disconnect(context.Background())
+
+	repo := NewMongodbTrayRepository()
+	repo.Connect(collection)
+
+	newer := createTestTray("newer", "test-type", trays.TrayStatusRegistered, 0)
+	older := createTestTray("older", "test-type", trays.TrayStatusRegistered, 0)
+	older.StatusChanged = newer.StatusChanged.Add(-time.Hour)
+	testTrays := []*TestTray{
+		newer,
+		older,
+		createTestTray("running", "test-type", trays.TrayStatusRunning, 0),
+		createTestTray("other", "other-type", trays.TrayStatusRegistered, 0),
+	}
+	insertTestTrays(t, collection, testTrays)
+
+	result, err := repo.GetByStatus(context.Background(), "test-type", trays.TrayStatusRegistered)
+	if err != nil {
+		t.Fatalf("GetByStatus failed: %v", err)
+	}
+	if len(result) != 2 {
+		t.Fatalf("Expected 2 trays, got %d", len(result))
+	}
+	if result[0].Id != "older" || result[1].Id != "newer" {
+		t.Errorf("Expected oldest first, got %s, %s", result[0].Id, result[1].Id)
+	}
+}
+
 // TestGetStale tests the GetStale method
 func TestGetStale(t *testing.T) {
 	client, collection := setupTestCollection(t)
@@ -807,3 +877,81 @@ func TestConnect(t *testing.T) {
 	}
 }
 
+
+// TestUpdateStatus_StatusTimes tests that UpdateStatus records when a tray
+// first entered each status
+func TestUpdateStatus_StatusTimes(t *testing.T) {
+	client, collection := setupTestCollection(t)
+	defer client.Disconnect(context.Background())
+
+	repo := NewMongodbTrayRepository()
+	repo.Connect(collection)
+
+	// A tray stored before status times were recorded
+	insertTestTrays(t, collection, []*TestTray{createTestTray("test-tray-1", "test-type", trays.TrayStatusCreating, 0)})
+
+	registered, err := repo.UpdateStatus(context.Background(), "test-tray-1", trays.TrayStatusRegistered, 0, 0, 0, "", "", "")
+	if err != nil {
+		t.Fatalf("UpdateStatus failed: %v", err)
+	}
+	if !registered.CreatedAt.IsZero() {
+		t.Errorf("Expected no CreatedAt, got %v", registered.CreatedAt)
+	}
+	if !registered.RegisteredAt.Equal(registered.StatusChanged) {
+		t.Errorf("Expected RegisteredAt %v, got %v", registered.StatusChanged, registered.RegisteredAt)
+	}
+
+	again, err := repo.UpdateStatus(context.Background(), "test-tray-1", trays.TrayStatusRegistered, 0, 0, 0, "", "", "")
+	if err != nil {
+		t.Fatalf("UpdateStatus failed: %v", err)
+	}
+	if !again.RegisteredAt.Equal(registered.RegisteredAt) {
+		t.Errorf("Expected RegisteredAt to stay %v, got %v", registered.RegisteredAt, again.RegisteredAt)
+	}
+	if !again.RunningAt.IsZero() {
+		t.Errorf("Expected no RunningAt, got %v", again.RunningAt)
+	}
+}
+
+// TestSaveWithinQuotas_Concurrent tests that racing SaveWithinQuotas calls
+// never oversubscribe a quota, nor leave a slot unused
+func TestSaveWithinQuotas_Concurrent(t *testing.T) {
+	client, collection := setupTestCollection(t)
+	defer client.Disconnect(context.Background())
+	if err := collection.Database().Collection(quotaLocksCollection).Drop(context.Background()); err != nil {
+		t.Fatalf("Failed to drop quota locks: %v", err)
+	}
+
+	// Two repositories stand in for two replicas.
+	repos := []*MongodbTrayRepository{NewMongodbTrayRepository(), NewMongodbTrayRepository()}
+	for _, repo := range repos {
+		repo.Connect(collection)
+	}
+	quota := &config.Quota{Name: "global", MaxTrays: 5}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tray := &trays.Tray{Id: fmt.Sprintf("t%d", i), TrayTypeName: "linux", ProviderName: "docker", GitHubOrgName: "test-org"}
+			if _, err := repos[i%2].SaveWithinQuotas(context.Background(), tray, []*config.Quota{quota}); err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("SaveWithinQuotas failed: %v", err)
+	}
+
+	all, err := repos[0].List(context.Background())
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(all) != 5 {
+		t.Errorf("Expected 5 trays, got %d", len(all))
+	}
+}
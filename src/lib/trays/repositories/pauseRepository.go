@@ -0,0 +1,30 @@
+package repositories
+
+import (
+	"context"
+	"time"
+)
+
+// TrayTypePause is a runtime pause of a tray type, set through the admin API.
+// While it exists no trays are created for the tray type; with DeleteIdle,
+// its registered trays waiting for a job are deleted too.
+type TrayTypePause struct {
+	TrayTypeName string    `bson:"trayTypeName" json:"trayTypeName"`
+	DeleteIdle   bool      `bson:"deleteIdle" json:"deleteIdle"`
+	PausedAt     time.Time `bson:"pausedAt" json:"pausedAt"`
+}
+
+// PauseRepository stores tray type pauses in the shared database, so a pause
+// set on any replica applies to whichever replica runs the tray type's
+// listener.
+type PauseRepository interface {
+	// Pause creates or replaces the pause of trayTypeName.
+	Pause(ctx context.Context, trayTypeName string, deleteIdle bool) error
+	// Resume removes the pause of trayTypeName. Resuming a tray type that is
+	// not paused is not an error.
+	Resume(ctx context.Context, trayTypeName string) error
+	// GetPause returns the pause of trayTypeName, or (nil, nil) if it is not
+	// paused.
+	GetPause(ctx context.Context, trayTypeName string) (*TrayTypePause, error)
+	ListPauses(ctx context.Context) ([]TrayTypePause, error)
+}
@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresPauseRepository implements PauseRepository on the tray_type_pauses
+// table.
+type PostgresPauseRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresPauseRepository() *PostgresPauseRepository {
+	return &PostgresPauseRepository{}
+}
+
+func (p *PostgresPauseRepository) Connect(pool *pgxpool.Pool) {
+	p.pool = pool
+}
+
+func scanPause(row pgx.CollectableRow) (TrayTypePause, error) {
+	var pause TrayTypePause
+	err := row.Scan(&pause.TrayTypeName, &pause.DeleteIdle, &pause.PausedAt)
+	pause.PausedAt = pause.PausedAt.UTC()
+	return pause, err
+}
+
+func (p *PostgresPauseRepository) Pause(ctx context.Context, trayTypeName string, deleteIdle bool) error {
+	_, err := p.pool.Exec(ctx, `INSERT INTO tray_type_pauses (tray_type_name, delete_idle, paused_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (tray_type_name) DO UPDATE SET
+			delete_idle = excluded.delete_idle,
+			paused_at   = excluded.paused_at`,
+		trayTypeName, deleteIdle, time.Now().UTC())
+	return err
+}
+
+func (p *PostgresPauseRepository) Resume(ctx context.Context, trayTypeName string) error {
+	_, err := p.pool.Exec(ctx, `DELETE FROM tray_type_pauses WHERE tray_type_name = $1`, trayTypeName)
+	return err
+}
+
+func (p *PostgresPauseRepository) GetPause(ctx context.Context, trayTypeName string) (*TrayTypePause, error) {
+	rows, err := p.pool.Query(ctx, `SELECT tray_type_name, delete_idle, paused_at FROM tray_type_pauses
+		WHERE tray_type_name = $1`, trayTypeName)
+	if err != nil {
+		return nil, err
+	}
+	pause, err := pgx.CollectExactlyOneRow(rows, scanPause)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &pause, nil
+}
+
+func (p *PostgresPauseRepository) ListPauses(ctx context.Context) ([]TrayTypePause, error) {
+	rows, err := p.pool.Query(ctx, `SELECT tray_type_name, delete_idle, paused_at FROM tray_type_pauses`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, scanPause)
+}
@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"cattery/lib/trays"
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresTrayEventRepository implements TrayEventRepository on the
+// tray_events table.
+type PostgresTrayEventRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresTrayEventRepository() *PostgresTrayEventRepository {
+	return &PostgresTrayEventRepository{}
+}
+
+func (p *PostgresTrayEventRepository) Connect(pool *pgxpool.Pool) {
+	p.pool = pool
+}
+
+func scanTrayEvent(row pgx.CollectableRow) (trays.TrayEvent, error) {
+	var event trays.TrayEvent
+	err := row.Scan(&event.TrayId, &event.TrayTypeName, &event.Kind, &event.Message, &event.Time)
+	event.Time = event.Time.UTC()
+	return event, err
+}
+
+func (p *PostgresTrayEventRepository) AddEvent(ctx context.Context, event *trays.TrayEvent) error {
+	_, err := p.pool.Exec(ctx, `INSERT INTO tray_events (tray_id, tray_type_name, kind, message, time)
+		VALUES ($1, $2, $3, $4, $5)`,
+		event.TrayId, event.TrayTypeName, string(event.Kind), event.Message, event.Time)
+	return err
+}
+
+func (p *PostgresTrayEventRepository) ListEvents(ctx context.Context, trayId string) ([]trays.TrayEvent, error) {
+	rows, err := p.pool.Query(ctx, `SELECT tray_id, tray_type_name, kind, message, time FROM tray_events
+		WHERE tray_id = $1 ORDER BY id`, trayId)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, scanTrayEvent)
+}
+
+func (p *PostgresTrayEventRepository) PruneEvents(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := p.pool.Exec(ctx, `DELETE FROM tray_events WHERE time < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,294 @@
+package repositories
+
+import (
+	"cattery/lib/config"
+	"cattery/lib/trays"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresTrayRepository implements TrayRepository on the trays table created
+// by the postgres package migrations. Status is stored as its integer value and
+// providerData as a jsonb object.
+type PostgresTrayRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresTrayRepository() *PostgresTrayRepository {
+	return &PostgresTrayRepository{}
+}
+
+func (p *PostgresTrayRepository) Connect(pool *pgxpool.Pool) {
+	p.pool = pool
+}
+
+const trayColumns = `id, tray_type_name, provider_name, github_org_name, github_runner_id,
+	job_run_id, job_name, workflow_run_id, workflow_name, repository,
+	status, status_changed, provider_data, draining, token_hash, weight,
+	created_at, registering_at, registered_at, running_at, deleting_at, trace_parent`
+
+// statusTimeColumns are the columns holding when a tray first entered each
+// status (see trays.Tray.StatusTime).
+var statusTimeColumns = map[trays.TrayStatus]string{
+	trays.TrayStatusCreating:    "created_at",
+	trays.TrayStatusRegistering: "registering_at",
+	trays.TrayStatusRegistered:  "registered_at",
+	trays.TrayStatusRunning:     "running_at",
+	trays.TrayStatusDeleting:    "deleting_at",
+}
+
+// quotaLockId is the advisory lock key SaveWithinQuotas holds while it counts
+// and inserts, so quota checks on different replicas run one at a time.
+const quotaLockId = 0x71756f74 // "quot"
+
+func scanTray(row pgx.CollectableRow) (*trays.Tray, error) {
+	var tray trays.Tray
+	var status int16
+	var createdAt, registeringAt, registeredAt, runningAt, deletingAt *time.Time
+	err := row.Scan(
+		&tray.Id, &tray.TrayTypeName, &tray.ProviderName, &tray.GitHubOrgName, &tray.GitHubRunnerId,
+		&tray.JobRunId, &tray.JobName, &tray.WorkflowRunId, &tray.WorkflowName, &tray.Repository,
+		&status, &tray.StatusChanged, &tray.ProviderData, &tray.Draining, &tray.TokenHash, &tray.Weight,
+		&createdAt, &registeringAt, &registeredAt, &runningAt, &deletingAt, &tray.TraceParent,
+	)
+	if err != nil {
+		return nil, err
+	}
+	tray.Status = trays.TrayStatus(status)
+	tray.StatusChanged = tray.StatusChanged.UTC()
+	tray.CreatedAt = fromNullTime(createdAt)
+	tray.RegisteringAt = fromNullTime(registeringAt)
+	tray.RegisteredAt = fromNullTime(registeredAt)
+	tray.RunningAt = fromNullTime(runningAt)
+	tray.DeletingAt = fromNullTime(deletingAt)
+	if tray.ProviderData == nil {
+		tray.ProviderData = make(map[string]string)
+	}
+	return &tray, nil
+}
+
+// nullTime maps a zero time to NULL.
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func fromNullTime(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.UTC()
+}
+
+// queryTray runs a single-row query and returns (nil, nil) when it matches no
+// row, mirroring the Mongo implementation's ErrNoDocuments handling.
+func (p *PostgresTrayRepository) queryTray(ctx context.Context, sql string, args ...any) (*trays.Tray, error) {
+	rows, err := p.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	tray, err := pgx.CollectExactlyOneRow(rows, scanTray)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return tray, nil
+}
+
+func (p *PostgresTrayRepository) queryTrays(ctx context.Context, sql string, args ...any) ([]*trays.Tray, error) {
+	rows, err := p.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, scanTray)
+}
+
+func (p *PostgresTrayRepository) GetById(ctx context.Context, trayId string) (*trays.Tray, error) {
+	return p.queryTray(ctx, `SELECT `+trayColumns+` FROM trays WHERE id = $1`, trayId)
+}
+
+func (p *PostgresTrayRepository) List(ctx context.Context) ([]*trays.Tray, error) {
+	return p.queryTrays(ctx, `SELECT `+trayColumns+` FROM trays ORDER BY status_changed DESC`)
+}
+
+// GetStale joins the trays against the thresholds unnested into
+// (status, cutoff) pairs, so one statement covers every status regardless of
+// how many thresholds are configured.
+func (p *PostgresTrayRepository) GetStale(ctx context.Context, thresholds map[trays.TrayStatus]time.Duration) ([]*trays.Tray, error) {
+	if len(thresholds) == 0 {
+		return nil, nil
+	}
+
+	now := time.Now().UTC()
+	statuses := make([]int16, 0, len(thresholds))
+	cutoffs := make([]time.Time, 0, len(thresholds))
+	for status, d := range thresholds {
+		statuses = append(statuses, int16(status))
+		cutoffs = append(cutoffs, now.Add(-d))
+	}
+
+	return p.queryTrays(ctx, `SELECT `+trayColumns+` FROM trays
+		JOIN unnest($1::smallint[], $2::timestamptz[]) AS threshold(status, cutoff)
+		  ON trays.status = threshold.status AND trays.status_changed <= threshold.cutoff`,
+		statuses, cutoffs)
+}
+
+func (p *PostgresTrayRepository) CountActive(ctx context.Context, trayType string) (int, error) {
+	var count int
+	err := p.pool.QueryRow(ctx,
+		`SELECT count(*) FROM trays WHERE tray_type_name = $1 AND status <> $2`,
+		trayType, int16(trays.TrayStatusDeleting),
+	).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (p *PostgresTrayRepository) CountByStatus(ctx context.Context, trayType string) (map[trays.TrayStatus]int, error) {
+	rows, err := p.pool.Query(ctx,
+		`SELECT status, count(*) FROM trays WHERE tray_type_name = $1 GROUP BY status`,
+		trayType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[trays.TrayStatus]int)
+	for rows.Next() {
+		var status int16
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		result[trays.TrayStatus(status)] = count
+	}
+	return result, rows.Err()
+}
+
+func (p *PostgresTrayRepository) GetByStatus(ctx context.Context, trayType string, status trays.TrayStatus) ([]*trays.Tray, error) {
+	return p.queryTrays(ctx,
+		`SELECT `+trayColumns+` FROM trays WHERE tray_type_name = $1 AND status = $2 ORDER BY status_changed ASC`,
+		trayType, int16(status))
+}
+
+func (p *PostgresTrayRepository) Save(ctx context.Context, tray *trays.Tray) error {
+	return insertTray(ctx, p.pool, tray)
+}
+
+// SaveWithinQuotas counts and inserts in one transaction holding an advisory
+// lock, so no other replica can insert between the count and the insert.
+func (p *PostgresTrayRepository) SaveWithinQuotas(ctx context.Context, tray *trays.Tray, quotas []*config.Quota) (*config.Quota, error) {
+	var exceeded *config.Quota
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", quotaLockId); err != nil {
+			return fmt.Errorf("failed to take quota lock: %w", err)
+		}
+
+		for _, quota := range quotas {
+			var count, weight int
+			err := tx.QueryRow(ctx, `SELECT count(*), COALESCE(sum(GREATEST(weight, 1)), 0) FROM trays
+				WHERE status <> $1
+				  AND ($2 = '' OR github_org_name = $2)
+				  AND ($3 = '' OR provider_name = $3)`,
+				int16(trays.TrayStatusDeleting), quota.GitHubOrg, quota.Provider,
+			).Scan(&count, &weight)
+			if err != nil {
+				return err
+			}
+			if quota.Exceeded(count+1, weight+tray.QuotaWeight()) {
+				exceeded = quota
+				return nil
+			}
+		}
+
+		return insertTray(ctx, tx, tray)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return exceeded, nil
+}
+
+// execer is what insertTray needs from a pool or a transaction.
+type execer interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+func insertTray(ctx context.Context, db execer, tray *trays.Tray) error {
+	tray.StatusChanged = time.Now().UTC()
+	tray.RecordStatusTime(tray.Status, tray.StatusChanged)
+
+	providerData := tray.ProviderData
+	if providerData == nil {
+		providerData = map[string]string{}
+	}
+
+	_, err := db.Exec(ctx, `INSERT INTO trays (`+trayColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22)`,
+		tray.Id, tray.TrayTypeName, tray.ProviderName, tray.GitHubOrgName, tray.GitHubRunnerId,
+		tray.JobRunId, tray.JobName, tray.WorkflowRunId, tray.WorkflowName, tray.Repository,
+		int16(tray.Status), tray.StatusChanged, providerData, tray.Draining, tray.TokenHash, tray.Weight,
+		nullTime(tray.CreatedAt), nullTime(tray.RegisteringAt), nullTime(tray.RegisteredAt), nullTime(tray.RunningAt), nullTime(tray.DeletingAt),
+		tray.TraceParent,
+	)
+	return err
+}
+
+// UpdateStatus sets status and statusChanged, and each of the remaining
+// fields only when it is non-zero, in a single UPDATE ... RETURNING so the
+// returned row is exactly the one this call wrote. The status's time column
+// keeps the earliest time (LEAST ignores NULL).
+func (p *PostgresTrayRepository) UpdateStatus(ctx context.Context, trayId string, status trays.TrayStatus, jobRunId int64, workflowRunId int64, ghRunnerId int64, repository string, jobName string, workflowName string) (*trays.Tray, error) {
+	statusTime, ok := statusTimeColumns[status]
+	if !ok {
+		return nil, fmt.Errorf("unknown tray status %d", status)
+	}
+
+	return p.queryTray(ctx, `UPDATE trays SET
+			status           = $2,
+			status_changed   = $3,
+			`+statusTime+` = LEAST(`+statusTime+`, $3),
+			job_run_id       = COALESCE(NULLIF($4::bigint, 0), job_run_id),
+			workflow_run_id  = COALESCE(NULLIF($5::bigint, 0), workflow_run_id),
+			github_runner_id = COALESCE(NULLIF($6::bigint, 0), github_runner_id),
+			repository       = COALESCE(NULLIF($7::text, ''), repository),
+			job_name         = COALESCE(NULLIF($8::text, ''), job_name),
+			workflow_name    = COALESCE(NULLIF($9::text, ''), workflow_name)
+		WHERE id = $1
+		RETURNING `+trayColumns,
+		trayId, int16(status), time.Now().UTC(), jobRunId, workflowRunId, ghRunnerId, repository, jobName, workflowName)
+}
+
+// SetProviderData merges data into the row's providerData with jsonb
+// concatenation, which overwrites the supplied keys and keeps the rest.
+func (p *PostgresTrayRepository) SetProviderData(ctx context.Context, trayId string, data map[string]string) (*trays.Tray, error) {
+	if len(data) == 0 {
+		return p.GetById(ctx, trayId)
+	}
+
+	return p.queryTray(ctx,
+		`UPDATE trays SET provider_data = provider_data || $2::jsonb WHERE id = $1 RETURNING `+trayColumns,
+		trayId, data)
+}
+
+func (p *PostgresTrayRepository) SetDraining(ctx context.Context, trayId string, draining bool) (*trays.Tray, error) {
+	return p.queryTray(ctx,
+		`UPDATE trays SET draining = $2 WHERE id = $1 RETURNING `+trayColumns,
+		trayId, draining)
+}
+
+func (p *PostgresTrayRepository) Delete(ctx context.Context, trayId string) error {
+	_, err := p.pool.Exec(ctx, `DELETE FROM trays WHERE id = $1`, trayId)
+	return err
+}
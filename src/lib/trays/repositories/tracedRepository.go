@@ -0,0 +1,177 @@
+package repositories
+
+import (
+	"cattery/lib/config"
+	"cattery/lib/tracing"
+	"cattery/lib/trays"
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// dbSystemKey is the OpenTelemetry semantic convention attribute naming the
+// database behind a repository span.
+const dbSystemKey = attribute.Key("db.system")
+
+// TracedTrayRepository wraps a TrayRepository with a span around each call.
+type TracedTrayRepository struct {
+	repository TrayRepository
+	driver     string
+}
+
+// NewTracedTrayRepository wraps repository, which stores trays in driver.
+func NewTracedTrayRepository(repository TrayRepository, driver string) *TracedTrayRepository {
+	return &TracedTrayRepository{repository: repository, driver: driver}
+}
+
+func (r *TracedTrayRepository) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "trayRepository."+name, append(attrs, dbSystemKey.String(r.driver))...)
+}
+
+func (r *TracedTrayRepository) GetById(ctx context.Context, trayId string) (_ *trays.Tray, err error) {
+	ctx, span := r.start(ctx, "GetById", tracing.TrayIdKey.String(trayId))
+	defer func() { tracing.End(span, err) }()
+	return r.repository.GetById(ctx, trayId)
+}
+
+func (r *TracedTrayRepository) List(ctx context.Context) (_ []*trays.Tray, err error) {
+	ctx, span := r.start(ctx, "List")
+	defer func() { tracing.End(span, err) }()
+	return r.repository.List(ctx)
+}
+
+func (r *TracedTrayRepository) Save(ctx context.Context, tray *trays.Tray) (err error) {
+	ctx, span := r.start(ctx, "Save", tracing.TrayIdKey.String(tray.Id))
+	defer func() { tracing.End(span, err) }()
+	return r.repository.Save(ctx, tray)
+}
+
+func (r *TracedTrayRepository) SaveWithinQuotas(ctx context.Context, tray *trays.Tray, quotas []*config.Quota) (_ *config.Quota, err error) {
+	ctx, span := r.start(ctx, "SaveWithinQuotas", tracing.TrayIdKey.String(tray.Id))
+	defer func() { tracing.End(span, err) }()
+	return r.repository.SaveWithinQuotas(ctx, tray, quotas)
+}
+
+func (r *TracedTrayRepository) Delete(ctx context.Context, trayId string) (err error) {
+	ctx, span := r.start(ctx, "Delete", tracing.TrayIdKey.String(trayId))
+	defer func() { tracing.End(span, err) }()
+	return r.repository.Delete(ctx, trayId)
+}
+
+func (r *TracedTrayRepository) UpdateStatus(ctx context.Context, trayId string, status trays.TrayStatus, jobRunId int64, workflowRunId int64, ghRunnerId int64, repository string, jobName string, workflowName string) (_ *trays.Tray, err error) {
+	ctx, span := r.start(ctx, "UpdateStatus", tracing.TrayIdKey.String(trayId), tracing.TrayStatusKey.String(status.String()))
+	defer func() { tracing.End(span, err) }()
+	return r.repository.UpdateStatus(ctx, trayId, status, jobRunId, workflowRunId, ghRunnerId, repository, jobName, workflowName)
+}
+
+func (r *TracedTrayRepository) SetProviderData(ctx context.Context, trayId string, data map[string]string) (_ *trays.Tray, err error) {
+	ctx, span := r.start(ctx, "SetProviderData", tracing.TrayIdKey.String(trayId))
+	defer func() { tracing.End(span, err) }()
+	return r.repository.SetProviderData(ctx, trayId, data)
+}
+
+func (r *TracedTrayRepository) SetDraining(ctx context.Context, trayId string, draining bool) (_ *trays.Tray, err error) {
+	ctx, span := r.start(ctx, "SetDraining", tracing.TrayIdKey.String(trayId))
+	defer func() { tracing.End(span, err) }()
+	return r.repository.SetDraining(ctx, trayId, draining)
+}
+
+func (r *TracedTrayRepository) CountActive(ctx context.Context, trayType string) (_ int, err error) {
+	ctx, span := r.start(ctx, "CountActive", tracing.TrayTypeKey.String(trayType))
+	defer func() { tracing.End(span, err) }()
+	return r.repository.CountActive(ctx, trayType)
+}
+
+func (r *TracedTrayRepository) CountByStatus(ctx context.Context, trayType string) (_ map[trays.TrayStatus]int, err error) {
+	ctx, span := r.start(ctx, "CountByStatus", tracing.TrayTypeKey.String(trayType))
+	defer func() { tracing.End(span, err) }()
+	return r.repository.CountByStatus(ctx, trayType)
+}
+
+func (r *TracedTrayRepository) GetByStatus(ctx context.Context, trayType string, status trays.TrayStatus) (_ []*trays.Tray, err error) {
+	ctx, span := r.start(ctx, "GetByStatus", tracing.TrayTypeKey.String(trayType), tracing.TrayStatusKey.String(status.String()))
+	defer func() { tracing.End(span, err) }()
+	return r.repository.GetByStatus(ctx, trayType, status)
+}
+
+func (r *TracedTrayRepository) GetStale(ctx context.Context, thresholds map[trays.TrayStatus]time.Duration) (_ []*trays.Tray, err error) {
+	ctx, span := r.start(ctx, "GetStale")
+	defer func() { tracing.End(span, err) }()
+	return r.repository.GetStale(ctx, thresholds)
+}
+
+// TracedPauseRepository wraps a PauseRepository with a span around each call.
+type TracedPauseRepository struct {
+	repository PauseRepository
+	driver     string
+}
+
+// NewTracedPauseRepository wraps repository, which stores pauses in driver.
+func NewTracedPauseRepository(repository PauseRepository, driver string) *TracedPauseRepository {
+	return &TracedPauseRepository{repository: repository, driver: driver}
+}
+
+func (r *TracedPauseRepository) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "pauseRepository."+name, append(attrs, dbSystemKey.String(r.driver))...)
+}
+
+func (r *TracedPauseRepository) Pause(ctx context.Context, trayTypeName string, deleteIdle bool) (err error) {
+	ctx, span := r.start(ctx, "Pause", tracing.TrayTypeKey.String(trayTypeName))
+	defer func() { tracing.End(span, err) }()
+	return r.repository.Pause(ctx, trayTypeName, deleteIdle)
+}
+
+func (r *TracedPauseRepository) Resume(ctx context.Context, trayTypeName string) (err error) {
+	ctx, span := r.start(ctx, "Resume", tracing.TrayTypeKey.String(trayTypeName))
+	defer func() { tracing.End(span, err) }()
+	return r.repository.Resume(ctx, trayTypeName)
+}
+
+func (r *TracedPauseRepository) GetPause(ctx context.Context, trayTypeName string) (_ *TrayTypePause, err error) {
+	ctx, span := r.start(ctx, "GetPause", tracing.TrayTypeKey.String(trayTypeName))
+	defer func() { tracing.End(span, err) }()
+	return r.repository.GetPause(ctx, trayTypeName)
+}
+
+func (r *TracedPauseRepository) ListPauses(ctx context.Context) (_ []TrayTypePause, err error) {
+	ctx, span := r.start(ctx, "ListPauses")
+	defer func() { tracing.End(span, err) }()
+	return r.repository.ListPauses(ctx)
+}
+
+// TracedTrayEventRepository wraps a TrayEventRepository with a span around
+// each call.
+type TracedTrayEventRepository struct {
+	repository TrayEventRepository
+	driver     string
+}
+
+// NewTracedTrayEventRepository wraps repository, which stores tray events in
+// driver.
+func NewTracedTrayEventRepository(repository TrayEventRepository, driver string) *TracedTrayEventRepository {
+	return &TracedTrayEventRepository{repository: repository, driver: driver}
+}
+
+func (r *TracedTrayEventRepository) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "trayEventRepository."+name, append(attrs, dbSystemKey.String(r.driver))...)
+}
+
+func (r *TracedTrayEventRepository) AddEvent(ctx context.Context, event *trays.TrayEvent) (err error) {
+	ctx, span := r.start(ctx, "AddEvent", tracing.TrayIdKey.String(event.TrayId))
+	defer func() { tracing.End(span, err) }()
+	return r.repository.AddEvent(ctx, event)
+}
+
+func (r *TracedTrayEventRepository) ListEvents(ctx context.Context, trayId string) (_ []trays.TrayEvent, err error) {
+	ctx, span := r.start(ctx, "ListEvents", tracing.TrayIdKey.String(trayId))
+	defer func() { tracing.End(span, err) }()
+	return r.repository.ListEvents(ctx, trayId)
+}
+
+func (r *TracedTrayEventRepository) PruneEvents(ctx context.Context, before time.Time) (_ int64, err error) {
+	ctx, span := r.start(ctx, "PruneEvents")
+	defer func() { tracing.End(span, err) }()
+	return r.repository.PruneEvents(ctx, before)
+}
@@ -0,0 +1,19 @@
+package repositories
+
+import (
+	"cattery/lib/trays"
+	"context"
+	"time"
+)
+
+// TrayEventRepository stores the lifecycle events of trays. Events are kept
+// after their tray is deleted until PruneEvents removes them.
+type TrayEventRepository interface {
+	AddEvent(ctx context.Context, event *trays.TrayEvent) error
+	// ListEvents returns the events of trayId, oldest first, or none if it
+	// has no events.
+	ListEvents(ctx context.Context, trayId string) ([]trays.TrayEvent, error)
+	// PruneEvents deletes events older than before and returns how many were
+	// deleted.
+	PruneEvents(ctx context.Context, before time.Time) (int64, error)
+}
@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"cattery/lib/config"
 	"cattery/lib/trays"
 	"context"
 	"time"
@@ -10,6 +11,12 @@ type TrayRepository interface {
 	GetById(ctx context.Context, trayId string) (*trays.Tray, error)
 	List(ctx context.Context) ([]*trays.Tray, error)
 	Save(ctx context.Context, tray *trays.Tray) error
+	// SaveWithinQuotas saves tray unless the trays counted by one of quotas
+	// (those not being deleted and matching its org and provider), with tray
+	// added, would exceed it. The check and the insert are atomic with respect
+	// to other SaveWithinQuotas calls, on any replica. Returns the first
+	// quota that would be exceeded, in which case tray is not saved.
+	SaveWithinQuotas(ctx context.Context, tray *trays.Tray, quotas []*config.Quota) (*config.Quota, error)
 	Delete(ctx context.Context, trayId string) error
 	UpdateStatus(ctx context.Context, trayId string, status trays.TrayStatus, jobRunId int64, workflowRunId int64, ghRunnerId int64, repository string, jobName string, workflowName string) (*trays.Tray, error)
 	// SetProviderData writes the supplied keys into the row's providerData map
@@ -17,7 +24,17 @@ type TrayRepository interface {
 	// fields. Returns the row as it exists after the write, or (nil, nil) if
 	// the row is missing.
 	SetProviderData(ctx context.Context, trayId string, data map[string]string) (*trays.Tray, error)
+	// SetDraining sets the row's draining flag without modifying status or
+	// other fields. Returns the row as it exists after the write, or
+	// (nil, nil) if the row is missing.
+	SetDraining(ctx context.Context, trayId string, draining bool) (*trays.Tray, error)
 	CountActive(ctx context.Context, trayType string) (int, error)
+	// CountByStatus returns the number of trays of trayType in each status.
+	// Statuses with no trays are absent from the map.
+	CountByStatus(ctx context.Context, trayType string) (map[trays.TrayStatus]int, error)
+	// GetByStatus returns the trays of trayType in status, oldest
+	// statusChanged first.
+	GetByStatus(ctx context.Context, trayType string, status trays.TrayStatus) ([]*trays.Tray, error)
 	// GetStale returns trays whose status is a key in thresholds and whose
 	// statusChanged is older than the corresponding duration. A status absent
 	// from the map is not checked.
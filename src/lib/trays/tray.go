@@ -3,8 +3,11 @@ package trays
 import (
 	"cattery/lib/config"
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -22,6 +25,30 @@ type Tray struct {
 	Repository     string     `bson:"repository"`
 	Status         TrayStatus `bson:"status"`
 	StatusChanged  time.Time  `bson:"statusChanged"`
+	// CreatedAt, RegisteringAt, RegisteredAt, RunningAt and DeletingAt are
+	// when the tray first entered each status. Zero if it has not, and for
+	// trays stored before they were recorded.
+	CreatedAt     time.Time `bson:"createdAt,omitempty"`
+	RegisteringAt time.Time `bson:"registeringAt,omitempty"`
+	RegisteredAt  time.Time `bson:"registeredAt,omitempty"`
+	RunningAt     time.Time `bson:"runningAt,omitempty"`
+	DeletingAt    time.Time `bson:"deletingAt,omitempty"`
+	// Draining asks the tray to go away once it is not running a job: the
+	// agent is told to terminate on its next ping instead of waiting for one.
+	Draining bool `bson:"draining"`
+	// TokenHash is the SHA-256 of the tray's bootstrap token, which its agent
+	// presents on every call. Empty for trays created before tokens existed.
+	TokenHash string `bson:"tokenHash"`
+	// Token is the bootstrap token itself. It is only set on the tray returned
+	// by NewTray, for the provider to deliver to the agent, and never stored.
+	Token string `bson:"-" json:"-"`
+	// Weight is the tray type's weight when the tray was created, counted
+	// against quotas' maxWeight. Zero for trays created before weights existed.
+	Weight int `bson:"weight"`
+	// TraceParent is the W3C traceparent of the span that created the tray,
+	// handed to its agent so agent-side spans join the tray's trace. Empty
+	// when tracing is disabled.
+	TraceParent string `bson:"traceParent"`
 
 	ProviderData map[string]string `bson:"providerData"`
 }
@@ -35,6 +62,12 @@ func NewTray(trayType config.TrayType) (*Tray, error) {
 
 	id := hex.EncodeToString(b)
 
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("failed to generate tray token: %w", err)
+	}
+	tokenHex := hex.EncodeToString(token)
+
 	return &Tray{
 		Id:            fmt.Sprintf("%s-%s", trayType.Name, id),
 		TrayTypeName:  trayType.Name,
@@ -42,9 +75,78 @@ func NewTray(trayType config.TrayType) (*Tray, error) {
 		Status:        TrayStatusCreating,
 		GitHubOrgName: trayType.GitHubOrg,
 		ProviderData:  make(map[string]string),
+		TokenHash:     hashToken(tokenHex),
+		Token:         tokenHex,
+		Weight:        trayType.QuotaWeight(),
 	}, nil
 }
 
+// StatusTime returns when the tray first entered status, or zero.
+func (tray *Tray) StatusTime(status TrayStatus) time.Time {
+	if field := tray.statusTimeField(status); field != nil {
+		return *field
+	}
+	return time.Time{}
+}
+
+// RecordStatusTime records t as when the tray entered status, unless an
+// earlier time is already recorded.
+func (tray *Tray) RecordStatusTime(status TrayStatus, t time.Time) {
+	if field := tray.statusTimeField(status); field != nil && (field.IsZero() || t.Before(*field)) {
+		*field = t
+	}
+}
+
+func (tray *Tray) statusTimeField(status TrayStatus) *time.Time {
+	switch status {
+	case TrayStatusCreating:
+		return &tray.CreatedAt
+	case TrayStatusRegistering:
+		return &tray.RegisteringAt
+	case TrayStatusRegistered:
+		return &tray.RegisteredAt
+	case TrayStatusRunning:
+		return &tray.RunningAt
+	case TrayStatusDeleting:
+		return &tray.DeletingAt
+	default:
+		return nil
+	}
+}
+
+// QuotaWeight returns the tray's weight, counting trays stored without one
+// as 1.
+func (tray *Tray) QuotaWeight() int {
+	return max(tray.Weight, 1)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyToken reports whether token is the tray's bootstrap token. Always
+// false for a tray without a token.
+func (tray *Tray) VerifyToken(token string) bool {
+	if tray.TokenHash == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hashToken(token)), []byte(tray.TokenHash)) == 1
+}
+
+// IsTrayOfType reports whether id has the shape NewTray gives trays of
+// trayTypeName: the type name, a dash and 16 hex digits. Used to recognize
+// cattery's own resources in provider inventories; the fixed-length suffix
+// keeps type "linux" from claiming trays of type "linux-large".
+func IsTrayOfType(id string, trayTypeName string) bool {
+	suffix, ok := strings.CutPrefix(id, trayTypeName+"-")
+	if !ok || len(suffix) != 16 {
+		return false
+	}
+	_, err := hex.DecodeString(suffix)
+	return err == nil
+}
+
 // TrayType returns the configuration for this tray's type from the current config.
 // Returns nil if the tray type no longer exists in config.
 func (tray *Tray) TrayType() *config.TrayType {
@@ -52,12 +154,17 @@ func (tray *Tray) TrayType() *config.TrayType {
 }
 
 // TrayConfig returns the provider-specific config (DockerTrayConfig, GoogleTrayConfig, etc.).
+// For a tray type on a fallback provider, it returns the entry for the tray's
+// ProviderName, which the fallback provider sets to the member it delegates to.
 // Returns nil if the tray type no longer exists in config.
 func (tray *Tray) TrayConfig() config.TrayConfig {
 	tt := tray.TrayType()
 	if tt == nil {
 		return nil
 	}
+	if fc, ok := tt.Config.(config.FallbackTrayConfig); ok {
+		return fc[tray.ProviderName]
+	}
 	return tt.Config
 }
 
@@ -96,6 +96,35 @@ func TestNewTrayIdIsUnique(t *testing.T) {
 	assert.NotEqual(t, tray1.Id, tray2.Id)
 }
 
+func TestTrayVerifyToken(t *testing.T) {
+	tray, err := NewTray(config.TrayType{Name: "linux"})
+	assert.NoError(t, err)
+	assert.Len(t, tray.Token, 64)
+	assert.NotEqual(t, tray.Token, tray.TokenHash, "only the hash is stored")
+
+	assert.True(t, tray.VerifyToken(tray.Token))
+	assert.False(t, tray.VerifyToken(""))
+	assert.False(t, tray.VerifyToken(tray.TokenHash))
+
+	other, _ := NewTray(config.TrayType{Name: "linux"})
+	assert.False(t, tray.VerifyToken(other.Token))
+
+	legacy := &Tray{Id: "linux-legacy"}
+	assert.False(t, legacy.VerifyToken(""), "a tray without a token accepts nothing")
+}
+
+func TestIsTrayOfType(t *testing.T) {
+	tray, err := NewTray(config.TrayType{Name: "linux"})
+	assert.NoError(t, err)
+	assert.True(t, IsTrayOfType(tray.Id, "linux"))
+
+	assert.False(t, IsTrayOfType("linux-large-0123456789abcdef", "linux"), "longer type name sharing the prefix")
+	assert.True(t, IsTrayOfType("linux-large-0123456789abcdef", "linux-large"))
+	assert.False(t, IsTrayOfType("linux-0123456789abcdeg", "linux"), "non-hex suffix")
+	assert.False(t, IsTrayOfType("linux-0123", "linux"), "short suffix")
+	assert.False(t, IsTrayOfType("other-0123456789abcdef", "linux"))
+}
+
 func TestTrayString(t *testing.T) {
 	now := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
 	tray := &Tray{
@@ -114,3 +143,19 @@ func TestTrayString(t *testing.T) {
 	assert.Contains(t, result, "test-org")
 	assert.Contains(t, result, "2025-01-15T10:30:00Z")
 }
+
+func TestRecordStatusTime(t *testing.T) {
+	tray := &Tray{}
+	first := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tray.RecordStatusTime(TrayStatusRegistered, first)
+	tray.RecordStatusTime(TrayStatusRegistered, first.Add(time.Minute))
+	assert.Equal(t, first, tray.RegisteredAt, "the first time is kept")
+	assert.Equal(t, first, tray.StatusTime(TrayStatusRegistered))
+
+	tray.RecordStatusTime(TrayStatusRegistered, first.Add(-time.Minute))
+	assert.Equal(t, first.Add(-time.Minute), tray.RegisteredAt)
+
+	assert.True(t, tray.StatusTime(TrayStatusRunning).IsZero())
+	assert.True(t, tray.StatusTime(TrayStatus(42)).IsZero())
+}
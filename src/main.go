@@ -2,12 +2,15 @@ package main
 
 import (
 	"cattery/cmd"
-	log "github.com/sirupsen/logrus"
+	"cattery/lib/config"
+	"cattery/lib/logging"
 )
 
 func main() {
 
-	log.SetLevel(log.DebugLevel)
+	// The server applies its logging config once it has loaded it; until
+	// then, and in the agent, the defaults apply.
+	_ = logging.Configure(config.LoggingConfig{})
 
 	cmd.Execute()
 }
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"cattery/lib/config"
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+)
+
+// configReloadDelay debounces file events: editors and Kubernetes ConfigMap
+// updates write a file in several steps.
+const configReloadDelay = time.Second
+
+// watchConfig reloads the config file when it changes or the process gets
+// SIGHUP, and passes each valid new config to apply. An invalid file is logged
+// and the current config stays in effect.
+//
+// The directory is watched rather than the file, so replacing the file (an
+// editor's rename-on-save, a ConfigMap's symlink swap) is picked up too.
+func watchConfig(ctx context.Context, logger *log.Entry, apply func(*config.CatteryConfig)) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	var events <-chan fsnotify.Event
+	path := config.FilePath()
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		logger.Errorf("Failed to watch config file; reload with SIGHUP only: %v", err)
+	} else if err := watcher.Add(filepath.Dir(path)); err != nil {
+		logger.Errorf("Failed to watch config file %s; reload with SIGHUP only: %v", path, err)
+		_ = watcher.Close()
+		watcher = nil
+	} else {
+		events = watcher.Events
+	}
+
+	go func() {
+		defer signal.Stop(hup)
+		if watcher != nil {
+			defer watcher.Close()
+		}
+
+		var debounce <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				logger.Info("Got SIGHUP, reloading config")
+				reloadConfig(logger, apply)
+			case event, ok := <-events:
+				if !ok {
+					events = nil
+					continue
+				}
+				if isConfigEvent(event, path) {
+					debounce = time.After(configReloadDelay)
+				}
+			case <-debounce:
+				debounce = nil
+				logger.Infof("Config file %s changed, reloading config", path)
+				reloadConfig(logger, apply)
+			}
+		}
+	}()
+}
+
+// isConfigEvent reports whether event may have changed the file at path. A
+// ConfigMap mount swaps a hidden "..data" symlink, so events on it count too.
+func isConfigEvent(event fsnotify.Event, path string) bool {
+	if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) && !event.Has(fsnotify.Rename) {
+		return false
+	}
+	name := filepath.Clean(event.Name)
+	return name == filepath.Clean(path) || filepath.Base(name) == "..data"
+}
+
+func reloadConfig(logger *log.Entry, apply func(*config.CatteryConfig)) {
+	old := config.Get()
+	cfg, err := config.Reload()
+	if err != nil {
+		logger.Errorf("Rejected config reload, keeping the current config: %v", err)
+		return
+	}
+
+	warnRestartRequired(logger, old, cfg)
+	apply(cfg)
+	logger.Info("Config reloaded")
+}
+
+// warnRestartRequired logs settings that are only read at startup and so
+// keep their old values until the server restarts.
+func warnRestartRequired(logger *log.Entry, old *config.CatteryConfig, cfg *config.CatteryConfig) {
+	startupOnly := []struct {
+		key     string
+		changed bool
+	}{
+		{"server.listenAddress", old.Server.ListenAddress != cfg.Server.ListenAddress},
+		{"server.statusListenAddress", old.Server.StatusListenAddress != cfg.Server.StatusListenAddress},
+		{"database", !reflect.DeepEqual(old.Database, cfg.Database)},
+		{"coordination", !reflect.DeepEqual(old.Coordination, cfg.Coordination)},
+		{"stale", !reflect.DeepEqual(old.Stale, cfg.Stale)},
+		{"trayEvents", !reflect.DeepEqual(old.TrayEvents, cfg.TrayEvents)},
+		{"pollerMessages", !reflect.DeepEqual(old.PollerMessages, cfg.PollerMessages)},
+		{"tracing", !reflect.DeepEqual(old.Tracing, cfg.Tracing)},
+	}
+	for _, setting := range startupOnly {
+		if setting.changed {
+			logger.Warnf("Config reload: %s changed; it takes effect after a restart", setting.key)
+		}
+	}
+}
@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"cattery/lib/config"
+	"cattery/lib/election"
+	"cattery/lib/logging"
+	"cattery/lib/trayManager"
+	"cattery/lib/trays"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var adminLogger = logging.Logger("adminHandler")
+
+// adminTrayJSON is a tray as the admin API returns it. ProviderData is only
+// filled in for single-tray responses.
+type adminTrayJSON struct {
+	Id             string            `json:"id"`
+	TrayTypeName   string            `json:"type"`
+	ProviderName   string            `json:"provider"`
+	GitHubOrgName  string            `json:"org"`
+	Status         string            `json:"status"`
+	StatusChanged  time.Time         `json:"statusChanged"`
+	Draining       bool              `json:"draining"`
+	GitHubRunnerId int64             `json:"githubRunnerId,omitempty"`
+	Repository     string            `json:"repository,omitempty"`
+	WorkflowRunId  int64             `json:"workflowRunId,omitempty"`
+	WorkflowName   string            `json:"workflow,omitempty"`
+	JobRunId       int64             `json:"jobRunId,omitempty"`
+	JobName        string            `json:"job,omitempty"`
+	JobURL         string            `json:"jobUrl,omitempty"`
+	ProviderData   map[string]string `json:"providerData,omitempty"`
+}
+
+func newAdminTrayJSON(tray *trays.Tray, withProviderData bool) adminTrayJSON {
+	result := adminTrayJSON{
+		Id:             tray.Id,
+		TrayTypeName:   tray.TrayTypeName,
+		ProviderName:   tray.ProviderName,
+		GitHubOrgName:  tray.GitHubOrgName,
+		Status:         tray.Status.String(),
+		StatusChanged:  tray.StatusChanged,
+		Draining:       tray.Draining,
+		GitHubRunnerId: tray.GitHubRunnerId,
+		Repository:     tray.Repository,
+		WorkflowRunId:  tray.WorkflowRunId,
+		WorkflowName:   tray.WorkflowName,
+		JobRunId:       tray.JobRunId,
+		JobName:        tray.JobName,
+		JobURL:         jobURL(tray),
+	}
+	if withProviderData {
+		result.ProviderData = tray.ProviderData
+	}
+	return result
+}
+
+// AdminAuth wraps an admin API handler with a check of the Bearer token
+// against server.adminToken. An empty token rejects every request, so the
+// API stays closed unless configured.
+func AdminAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := config.Get().Server.AdminToken
+		if token == "" {
+			http.Error(w, "admin API is disabled", http.StatusForbidden)
+			return
+		}
+
+		provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			adminLogger.WithField("path", r.URL.Path).Warn("Rejected admin API request: invalid token")
+			http.Error(w, "invalid admin token", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// AdminListTrays lists trays, most recently changed first. The type, org,
+// status and repo query parameters filter on exact matches.
+func (h *Handlers) AdminListTrays(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var status *trays.TrayStatus
+	if name := query.Get("status"); name != "" {
+		s, err := trays.TrayStatusFromString(name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		status = &s
+	}
+
+	trayList, err := h.TrayManager.ListTrays(r.Context())
+	if err != nil {
+		adminLogger.Errorf("Admin: failed to list trays: %v", err)
+		http.Error(w, "failed to list trays", http.StatusInternalServerError)
+		return
+	}
+
+	result := make([]adminTrayJSON, 0, len(trayList))
+	for _, tray := range trayList {
+		if !matchesQuery(query.Get("type"), tray.TrayTypeName) ||
+			!matchesQuery(query.Get("org"), tray.GitHubOrgName) ||
+			!matchesQuery(query.Get("repo"), tray.Repository) ||
+			(status != nil && tray.Status != *status) {
+			continue
+		}
+		result = append(result, newAdminTrayJSON(tray, false))
+	}
+
+	writeAdminJSON(w, http.StatusOK, result)
+}
+
+func matchesQuery(want string, value string) bool {
+	return want == "" || want == value
+}
+
+// AdminGetTray returns a single tray, including its provider data.
+func (h *Handlers) AdminGetTray(w http.ResponseWriter, r *http.Request) {
+	tray, err := h.TrayManager.GetTrayById(r.Context(), r.PathValue("id"))
+	if err != nil {
+		adminLogger.WithField(logging.TrayIdKey, r.PathValue("id")).Errorf("Admin: failed to get tray %s: %v", r.PathValue("id"), err)
+		http.Error(w, "failed to get tray", http.StatusInternalServerError)
+		return
+	}
+	if tray == nil {
+		http.Error(w, "tray not found", http.StatusNotFound)
+		return
+	}
+
+	writeAdminJSON(w, http.StatusOK, newAdminTrayJSON(tray, true))
+}
+
+// AdminDeleteTray force-deletes a tray regardless of its status: the row is
+// marked deleting and the provider resource cleaned up. As with the agent
+// unregister path, a provider failure leaves the row for the stale handler
+// to retry, so a 200 means deletion was requested.
+func (h *Handlers) AdminDeleteTray(w http.ResponseWriter, r *http.Request) {
+	trayId := r.PathValue("id")
+
+	tray, err := h.TrayManager.DeleteTray(r.Context(), trayId)
+	if err != nil {
+		adminLogger.WithField(logging.TrayIdKey, trayId).Errorf("Admin: failed to delete tray %s: %v", trayId, err)
+		http.Error(w, "failed to delete tray", http.StatusInternalServerError)
+		return
+	}
+	if tray == nil {
+		http.Error(w, "tray not found", http.StatusNotFound)
+		return
+	}
+
+	adminLogger.WithField(logging.TrayIdKey, trayId).Infof("Admin: tray %s force-deleted", trayId)
+	writeAdminJSON(w, http.StatusOK, newAdminTrayJSON(tray, true))
+}
+
+// AdminDrainTray marks a tray as draining, so it is removed once its current
+// job, if any, finishes.
+func (h *Handlers) AdminDrainTray(w http.ResponseWriter, r *http.Request) {
+	trayId := r.PathValue("id")
+
+	tray, err := h.TrayManager.DrainTray(r.Context(), trayId)
+	if err != nil {
+		adminLogger.WithField(logging.TrayIdKey, trayId).Errorf("Admin: failed to drain tray %s: %v", trayId, err)
+		http.Error(w, "failed to drain tray", http.StatusInternalServerError)
+		return
+	}
+	if tray == nil {
+		http.Error(w, "tray not found", http.StatusNotFound)
+		return
+	}
+
+	writeAdminJSON(w, http.StatusOK, newAdminTrayJSON(tray, true))
+}
+
+// AdminListPauses lists the paused tray types, from config or the admin API.
+func (h *Handlers) AdminListPauses(w http.ResponseWriter, r *http.Request) {
+	pauses, err := h.TrayManager.ListPauses(r.Context(), config.Get().TrayTypes)
+	if err != nil {
+		adminLogger.Errorf("Admin: failed to list pauses: %v", err)
+		http.Error(w, "failed to list pauses", http.StatusInternalServerError)
+		return
+	}
+
+	result := make([]*trayManager.TrayTypePause, 0, len(pauses))
+	for _, trayType := range config.Get().TrayTypes {
+		if pause, ok := pauses[trayType.Name]; ok {
+			result = append(result, pause)
+		}
+	}
+
+	writeAdminJSON(w, http.StatusOK, result)
+}
+
+// AdminGetPause returns the pause in effect for a tray type, or 404 if it is
+// not paused.
+func (h *Handlers) AdminGetPause(w http.ResponseWriter, r *http.Request) {
+	trayType := adminTrayType(w, r)
+	if trayType == nil {
+		return
+	}
+
+	pause, err := h.TrayManager.GetPause(r.Context(), trayType)
+	if err != nil {
+		adminLogger.WithField(logging.TrayTypeKey, trayType.Name).Errorf("Admin: failed to get pause of tray type %s: %v", trayType.Name, err)
+		http.Error(w, "failed to get pause", http.StatusInternalServerError)
+		return
+	}
+	if pause == nil {
+		http.Error(w, "tray type not paused", http.StatusNotFound)
+		return
+	}
+
+	writeAdminJSON(w, http.StatusOK, pause)
+}
+
+// AdminPauseTrayType pauses a tray type: no trays are created for it until it
+// is resumed. With deleteIdle=true, its registered trays waiting for a job are
+// deleted too.
+func (h *Handlers) AdminPauseTrayType(w http.ResponseWriter, r *http.Request) {
+	trayType := adminTrayType(w, r)
+	if trayType == nil {
+		return
+	}
+
+	deleteIdle := false
+	if value := r.URL.Query().Get("deleteIdle"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, "invalid deleteIdle value", http.StatusBadRequest)
+			return
+		}
+		deleteIdle = parsed
+	}
+
+	if err := h.TrayManager.PauseTrayType(r.Context(), trayType, deleteIdle); err != nil {
+		adminLogger.WithField(logging.TrayTypeKey, trayType.Name).Errorf("Admin: failed to pause tray type %s: %v", trayType.Name, err)
+		http.Error(w, "failed to pause tray type", http.StatusInternalServerError)
+		return
+	}
+
+	h.AdminGetPause(w, r)
+}
+
+// AdminResumeTrayType removes a runtime pause. A tray type paused in the
+// config stays paused, and its config pause is returned.
+func (h *Handlers) AdminResumeTrayType(w http.ResponseWriter, r *http.Request) {
+	trayType := adminTrayType(w, r)
+	if trayType == nil {
+		return
+	}
+
+	if err := h.TrayManager.ResumeTrayType(r.Context(), trayType); err != nil {
+		adminLogger.WithField(logging.TrayTypeKey, trayType.Name).Errorf("Admin: failed to resume tray type %s: %v", trayType.Name, err)
+		http.Error(w, "failed to resume tray type", http.StatusInternalServerError)
+		return
+	}
+
+	if trayType.Paused {
+		h.AdminGetPause(w, r)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AdminListLeadership lists this replica's view of the election of every tray
+// type's poller.
+func (h *Handlers) AdminListLeadership(w http.ResponseWriter, r *http.Request) {
+	writeAdminJSON(w, http.StatusOK, h.Elector.Leadership())
+}
+
+// AdminStepDown makes this replica give up leadership of a tray type, so
+// another replica takes its poller over. It returns 409 if this replica is not
+// the leader; the request has to reach the replica named by the holder.
+func (h *Handlers) AdminStepDown(w http.ResponseWriter, r *http.Request) {
+	trayType := adminTrayType(w, r)
+	if trayType == nil {
+		return
+	}
+
+	err := h.Elector.StepDown(trayType.Name)
+	switch {
+	case errors.Is(err, election.ErrNotLeader):
+		holder := "unknown"
+		for _, l := range h.Elector.Leadership() {
+			if l.Key == trayType.Name && l.Holder != "" {
+				holder = l.Holder
+			}
+		}
+		http.Error(w, "this replica is not the leader, the lease is held by "+holder, http.StatusConflict)
+		return
+	case errors.Is(err, election.ErrStepDownUnsupported):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		adminLogger.WithField(logging.TrayTypeKey, trayType.Name).Errorf("Admin: failed to step down from tray type %s: %v", trayType.Name, err)
+		http.Error(w, "failed to step down", http.StatusInternalServerError)
+		return
+	}
+
+	adminLogger.WithField(logging.TrayTypeKey, trayType.Name).Infof("Admin: stepped down from leadership of tray type %s", trayType.Name)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// adminTrayType looks up the tray type named in the path, writing a 404 and
+// returning nil if it is not configured.
+func adminTrayType(w http.ResponseWriter, r *http.Request) *config.TrayType {
+	trayType := config.Get().GetTrayType(r.PathValue("name"))
+	if trayType == nil {
+		http.Error(w, "tray type not found", http.StatusNotFound)
+	}
+	return trayType
+}
+
+func writeAdminJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		adminLogger.Errorf("Admin: failed to encode response: %v", err)
+	}
+}
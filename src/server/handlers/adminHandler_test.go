@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"cattery/lib/config"
+	"cattery/lib/election"
+	"cattery/lib/testutil"
+	"cattery/lib/trayManager"
+	"cattery/lib/trays"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAdminToken = "admin-token"
+
+func setupAdmin(t *testing.T, repo *testutil.MockTrayRepository, prov *mockProvider) *http.ServeMux {
+	t.Helper()
+	config.SetForTest(t, &config.CatteryConfig{
+		Server: config.ServerConfig{AdminToken: testAdminToken},
+	})
+
+	h := setupHandlersWithProvider(repo, prov)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/trays", AdminAuth(h.AdminListTrays))
+	mux.HandleFunc("GET /api/v1/trays/{id}", AdminAuth(h.AdminGetTray))
+	mux.HandleFunc("DELETE /api/v1/trays/{id}", AdminAuth(h.AdminDeleteTray))
+	mux.HandleFunc("POST /api/v1/trays/{id}/drain", AdminAuth(h.AdminDrainTray))
+	return mux
+}
+
+func adminRequest(mux *http.ServeMux, method string, target string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	return w
+}
+
+func adminTestRepo() *testutil.MockTrayRepository {
+	repo := testutil.NewMockTrayRepository()
+	repo.Trays["linux-1"] = &trays.Tray{
+		Id: "linux-1", TrayTypeName: "linux", GitHubOrgName: "org-a", Status: trays.TrayStatusRunning,
+		Repository: "org-a/app", StatusChanged: time.Now(), ProviderData: map[string]string{"zone": "eu-1"},
+	}
+	repo.Trays["linux-2"] = &trays.Tray{
+		Id: "linux-2", TrayTypeName: "linux", GitHubOrgName: "org-b", Status: trays.TrayStatusRegistered,
+		StatusChanged: time.Now(), ProviderData: map[string]string{},
+	}
+	repo.Trays["mac-1"] = &trays.Tray{
+		Id: "mac-1", TrayTypeName: "mac", GitHubOrgName: "org-a", Status: trays.TrayStatusRunning,
+		Repository: "org-a/lib", StatusChanged: time.Now(), ProviderData: map[string]string{},
+	}
+	return repo
+}
+
+func TestAdminAuth(t *testing.T) {
+	mux := setupAdmin(t, adminTestRepo(), &mockProvider{})
+
+	req := httptest.NewRequest("GET", "/api/v1/trays", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "missing token")
+
+	req = httptest.NewRequest("GET", "/api/v1/trays", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "wrong token")
+
+	assert.Equal(t, http.StatusOK, adminRequest(mux, "GET", "/api/v1/trays").Code)
+}
+
+func TestAdminAuth_DisabledWithoutToken(t *testing.T) {
+	config.SetForTest(t, &config.CatteryConfig{})
+
+	handler := AdminAuth(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler must not run without an admin token configured")
+	})
+
+	req := httptest.NewRequest("GET", "/api/v1/trays", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	w := httptest.NewRecorder()
+	handler(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestAdminListTrays_Filters(t *testing.T) {
+	mux := setupAdmin(t, adminTestRepo(), &mockProvider{})
+
+	ids := func(target string) []string {
+		w := adminRequest(mux, "GET", target)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var result []adminTrayJSON
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+		var ids []string
+		for _, tray := range result {
+			assert.Nil(t, tray.ProviderData, "list omits provider data")
+			ids = append(ids, tray.Id)
+		}
+		return ids
+	}
+
+	assert.Len(t, ids("/api/v1/trays"), 3)
+	assert.ElementsMatch(t, []string{"linux-1", "linux-2"}, ids("/api/v1/trays?type=linux"))
+	assert.ElementsMatch(t, []string{"linux-1", "mac-1"}, ids("/api/v1/trays?org=org-a"))
+	assert.ElementsMatch(t, []string{"linux-1", "mac-1"}, ids("/api/v1/trays?status=running"))
+	assert.Equal(t, []string{"mac-1"}, ids("/api/v1/trays?repo=org-a/lib"))
+	assert.Equal(t, []string{"linux-1"}, ids("/api/v1/trays?type=linux&status=Running"))
+
+	assert.Equal(t, http.StatusBadRequest, adminRequest(mux, "GET", "/api/v1/trays?status=bogus").Code)
+}
+
+func TestAdminGetTray(t *testing.T) {
+	mux := setupAdmin(t, adminTestRepo(), &mockProvider{})
+
+	w := adminRequest(mux, "GET", "/api/v1/trays/linux-1")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var tray adminTrayJSON
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&tray))
+	assert.Equal(t, "linux-1", tray.Id)
+	assert.Equal(t, "running", tray.Status)
+	assert.Equal(t, map[string]string{"zone": "eu-1"}, tray.ProviderData)
+
+	assert.Equal(t, http.StatusNotFound, adminRequest(mux, "GET", "/api/v1/trays/nope").Code)
+}
+
+func TestAdminDeleteTray(t *testing.T) {
+	repo := adminTestRepo()
+	mux := setupAdmin(t, repo, &mockProvider{})
+
+	w := adminRequest(mux, "DELETE", "/api/v1/trays/linux-1")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, repo.Trays, "linux-1")
+
+	assert.Equal(t, http.StatusNotFound, adminRequest(mux, "DELETE", "/api/v1/trays/linux-1").Code)
+}
+
+func TestAdminDrainTray(t *testing.T) {
+	repo := adminTestRepo()
+	mux := setupAdmin(t, repo, &mockProvider{})
+
+	w := adminRequest(mux, "POST", "/api/v1/trays/linux-1/drain")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var tray adminTrayJSON
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&tray))
+	assert.True(t, tray.Draining)
+	assert.True(t, repo.Trays["linux-1"].Draining)
+	assert.Equal(t, trays.TrayStatusRunning, repo.Trays["linux-1"].Status, "the running job is left alone")
+
+	assert.Equal(t, http.StatusNotFound, adminRequest(mux, "POST", "/api/v1/trays/nope/drain").Code)
+}
+
+func setupAdminPauses(t *testing.T, repo *testutil.MockTrayRepository, prov *mockProvider) *http.ServeMux {
+	t.Helper()
+	config.SetForTest(t, &config.CatteryConfig{
+		Server: config.ServerConfig{AdminToken: testAdminToken},
+		TrayTypes: []*config.TrayType{
+			{Name: "linux"},
+			{Name: "mac", Paused: true},
+		},
+	})
+
+	h := setupHandlersWithProvider(repo, prov)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/pauses", AdminAuth(h.AdminListPauses))
+	mux.HandleFunc("GET /api/v1/trayTypes/{name}/pause", AdminAuth(h.AdminGetPause))
+	mux.HandleFunc("POST /api/v1/trayTypes/{name}/pause", AdminAuth(h.AdminPauseTrayType))
+	mux.HandleFunc("DELETE /api/v1/trayTypes/{name}/pause", AdminAuth(h.AdminResumeTrayType))
+	return mux
+}
+
+func TestAdminPauseTrayType(t *testing.T) {
+	repo := adminTestRepo()
+	mux := setupAdminPauses(t, repo, &mockProvider{})
+
+	assert.Equal(t, http.StatusNotFound, adminRequest(mux, "GET", "/api/v1/trayTypes/linux/pause").Code)
+	assert.Equal(t, http.StatusNotFound, adminRequest(mux, "POST", "/api/v1/trayTypes/nope/pause").Code)
+	assert.Equal(t, http.StatusBadRequest, adminRequest(mux, "POST", "/api/v1/trayTypes/linux/pause?deleteIdle=maybe").Code)
+
+	w := adminRequest(mux, "POST", "/api/v1/trayTypes/linux/pause?deleteIdle=true")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var pause trayManager.TrayTypePause
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&pause))
+	assert.Equal(t, "linux", pause.TrayTypeName)
+	assert.Equal(t, trayManager.PauseSourceAPI, pause.Source)
+	assert.True(t, pause.DeleteIdle)
+	assert.NotContains(t, repo.Trays, "linux-2", "the idle tray is deleted")
+	assert.Contains(t, repo.Trays, "linux-1", "the running tray finishes its job")
+
+	w = adminRequest(mux, "GET", "/api/v1/pauses")
+	require.Equal(t, http.StatusOK, w.Code)
+	var pauses []trayManager.TrayTypePause
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&pauses))
+	require.Len(t, pauses, 2)
+	assert.Equal(t, "linux", pauses[0].TrayTypeName)
+	assert.Equal(t, trayManager.PauseSourceConfig, pauses[1].Source)
+
+	assert.Equal(t, http.StatusNoContent, adminRequest(mux, "DELETE", "/api/v1/trayTypes/linux/pause").Code)
+	assert.Equal(t, http.StatusNotFound, adminRequest(mux, "GET", "/api/v1/trayTypes/linux/pause").Code)
+}
+
+func TestAdminResumeTrayType_ConfigPauseStays(t *testing.T) {
+	mux := setupAdminPauses(t, adminTestRepo(), &mockProvider{})
+
+	w := adminRequest(mux, "DELETE", "/api/v1/trayTypes/mac/pause")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var pause trayManager.TrayTypePause
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&pause))
+	assert.Equal(t, trayManager.PauseSourceConfig, pause.Source)
+}
+
+func TestAdminStepDown(t *testing.T) {
+	config.SetForTest(t, &config.CatteryConfig{
+		Server:    config.ServerConfig{AdminToken: testAdminToken},
+		TrayTypes: []*config.TrayType{{Name: "linux"}, {Name: "mac"}},
+	})
+
+	elector := testutil.NewMockElector()
+	elector.Leaderships = []election.Leadership{
+		{Key: "linux", Holder: "replica-a", Leader: true, AcquiredAt: time.Now(), LastRenew: time.Now(), Terms: 1},
+		{Key: "mac", Holder: "replica-b"},
+	}
+	h := setupHandlersWithProvider(adminTestRepo(), &mockProvider{})
+	h.Elector = elector
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/leadership", AdminAuth(h.AdminListLeadership))
+	mux.HandleFunc("POST /api/v1/trayTypes/{name}/stepDown", AdminAuth(h.AdminStepDown))
+
+	w := adminRequest(mux, "GET", "/api/v1/leadership")
+	require.Equal(t, http.StatusOK, w.Code)
+	var leaderships []election.Leadership
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&leaderships))
+	require.Len(t, leaderships, 2)
+	assert.True(t, leaderships[0].Leader)
+	assert.Equal(t, "replica-b", leaderships[1].Holder)
+
+	assert.Equal(t, http.StatusNotFound, adminRequest(mux, "POST", "/api/v1/trayTypes/nope/stepDown").Code)
+
+	w = adminRequest(mux, "POST", "/api/v1/trayTypes/mac/stepDown")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "replica-b", "names the replica to send the request to")
+
+	assert.Equal(t, http.StatusAccepted, adminRequest(mux, "POST", "/api/v1/trayTypes/linux/stepDown").Code)
+	assert.False(t, elector.Leadership()[0].Leader)
+	assert.Equal(t, http.StatusConflict, adminRequest(mux, "POST", "/api/v1/trayTypes/linux/stepDown").Code)
+}
@@ -3,30 +3,33 @@ package handlers
 import (
 	"cattery/lib/agents"
 	"cattery/lib/config"
+	"cattery/lib/logging"
 	"cattery/lib/messages"
 	"cattery/lib/metrics"
+	"cattery/lib/tracing"
 	"cattery/lib/trays"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 )
 
+var agentLogger = logging.Logger("agentHandler")
+
 // AgentRegister is a handler for agent registration requests
 func (h *Handlers) AgentRegister(responseWriter http.ResponseWriter, r *http.Request) {
 
-	logger := log.WithFields(log.Fields{
-		"handler": "agent",
-		"call":    "AgentRegister",
-	})
+	logger := agentLogger.WithField("call", "AgentRegister")
 
 	logger.Tracef("AgentRegister: %v", r)
 
-	_, code, errMsg := h.authenticateAgent(r)
+	authTray, code, errMsg := h.authenticateAgent(r)
 	if code != 0 {
 		logger.Warn(errMsg)
 		http.Error(responseWriter, errMsg, code)
@@ -35,13 +38,20 @@ func (h *Handlers) AgentRegister(responseWriter http.ResponseWriter, r *http.Req
 
 	agentId := r.PathValue("id")
 
-	logger = logger.WithFields(log.Fields{
-		"agentId": agentId,
-	})
+	// Registration continues the trace the tray was created in; the agent
+	// gets this span's context back to parent its own spans.
+	traceParent := ""
+	if authTray != nil {
+		traceParent = authTray.TraceParent
+	}
+	ctx, span := tracing.StartInTrace(r.Context(), traceParent, "agent.register", tracing.TrayIdKey.String(agentId))
+	defer span.End()
+
+	logger = logger.WithField(logging.TrayIdKey, agentId)
 
 	logger.Debug("Agent registration request")
 
-	tray, err := h.TrayManager.Registering(r.Context(), agentId)
+	tray, err := h.TrayManager.Registering(ctx, agentId)
 	if err != nil {
 		errMsg := fmt.Sprintf("Failed to update tray status for agent '%s': %v", agentId, err)
 		logger.Error(errMsg)
@@ -56,7 +66,7 @@ func (h *Handlers) AgentRegister(responseWriter http.ResponseWriter, r *http.Req
 		http.Error(responseWriter, errMsg, http.StatusInternalServerError)
 		return
 	}
-	logger = logger.WithFields(log.Fields{"trayType": trayType.Name})
+	logger = logging.WithTray(logger, tray)
 
 	logger.Debugf("Found tray %s for agent %s, with organization %s", tray.Id, agentId, tray.GitHubOrgName)
 
@@ -68,7 +78,7 @@ func (h *Handlers) AgentRegister(responseWriter http.ResponseWriter, r *http.Req
 		return
 	}
 
-	jitRunnerConfig, err := jitGenerator.GenerateJitRunnerConfig(r.Context(), tray.Id)
+	jitRunnerConfig, err := jitGenerator.GenerateJitRunnerConfig(ctx, tray.Id)
 	if err != nil {
 		logger.Errorf("Failed to generate jitRunnerConfig: %v", err)
 		http.Error(responseWriter, "Failed to generate jitRunnerConfig", http.StatusInternalServerError)
@@ -84,8 +94,9 @@ func (h *Handlers) AgentRegister(responseWriter http.ResponseWriter, r *http.Req
 	}
 
 	registerResponse := messages.RegisterResponse{
-		Agent:     newAgent,
-		JitConfig: jitConfig,
+		Agent:       newAgent,
+		JitConfig:   jitConfig,
+		TraceParent: tracing.TraceParent(ctx),
 	}
 
 	responseWriter.Header().Set("Content-Type", "application/json")
@@ -95,7 +106,7 @@ func (h *Handlers) AgentRegister(responseWriter http.ResponseWriter, r *http.Req
 		return
 	}
 
-	_, err = h.TrayManager.Registered(r.Context(), agentId, int64(jitRunnerConfig.Runner.ID))
+	_, err = h.TrayManager.Registered(ctx, agentId, int64(jitRunnerConfig.Runner.ID))
 	if err != nil {
 		logger.Errorf("%v", err)
 	}
@@ -103,47 +114,62 @@ func (h *Handlers) AgentRegister(responseWriter http.ResponseWriter, r *http.Req
 	logger.Infof("Agent %s registered with runner ID %d", agentId, newAgent.RunnerId)
 }
 
-// authenticateAgent checks the optional Bearer token and verifies the tray exists.
+// authenticateAgent checks the agent's Bearer token and verifies the tray
+// exists. A tray created with a bootstrap token only accepts that token, so one
+// tray's agent cannot act on another tray. Trays without a token, and paths
+// without a tray ID, fall back to the optional global agent secret.
 // Returns the tray on success, or (nil, statusCode, errorMessage) on failure.
 func (h *Handlers) authenticateAgent(r *http.Request) (*trays.Tray, int, string) {
 	secret := config.Get().Server.AgentSecret
-	if secret != "" {
-		header := r.Header.Get("Authorization")
-		if header == "" {
-			return nil, http.StatusUnauthorized, "missing Authorization header"
-		}
 
-		const prefix = "Bearer "
-		if len(header) < len(prefix) || header[:len(prefix)] != prefix {
+	token := ""
+	if header := r.Header.Get("Authorization"); header != "" {
+		var ok bool
+		token, ok = strings.CutPrefix(header, "Bearer ")
+		if !ok {
 			return nil, http.StatusUnauthorized, "invalid Authorization header format"
 		}
+	} else if secret != "" {
+		return nil, http.StatusUnauthorized, "missing Authorization header"
+	}
+
+	matchesSecret := secret == "" || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1
 
-		if header[len(prefix):] != secret {
+	agentId := r.PathValue("id")
+	if agentId == "" {
+		if !matchesSecret {
 			return nil, http.StatusUnauthorized, "invalid agent secret"
 		}
+		return nil, 0, ""
 	}
 
-	agentId := r.PathValue("id")
-	if agentId != "" {
-		tray, err := h.TrayManager.GetTrayById(r.Context(), agentId)
-		if err != nil {
-			return nil, http.StatusInternalServerError, "failed to look up agent"
+	tray, err := h.TrayManager.GetTrayById(r.Context(), agentId)
+	if err != nil {
+		return nil, http.StatusInternalServerError, "failed to look up agent"
+	}
+	if tray == nil {
+		// Callers without valid credentials cannot probe for tray IDs.
+		if !matchesSecret {
+			return nil, http.StatusUnauthorized, "invalid agent secret"
 		}
-		if tray == nil {
-			return nil, http.StatusNotFound, "unknown agent"
+		return nil, http.StatusNotFound, "unknown agent"
+	}
+
+	if tray.TokenHash != "" {
+		if !tray.VerifyToken(token) {
+			return nil, http.StatusUnauthorized, "invalid tray token"
 		}
 		return tray, 0, ""
 	}
-
-	return nil, 0, ""
+	if !matchesSecret {
+		return nil, http.StatusUnauthorized, "invalid agent secret"
+	}
+	return tray, 0, ""
 }
 
 // AgentUnregister is a handler for agent unregister requests
 func (h *Handlers) AgentUnregister(responseWriter http.ResponseWriter, r *http.Request) {
-	logger := log.WithFields(log.Fields{
-		"handler": "agent",
-		"call":    "AgentUnregister",
-	})
+	logger := agentLogger.WithField("call", "AgentUnregister")
 
 	logger.Tracef("AgentUnregister: %v", r)
 
@@ -153,6 +179,7 @@ func (h *Handlers) AgentUnregister(responseWriter http.ResponseWriter, r *http.R
 		http.Error(responseWriter, errMsg, code)
 		return
 	}
+	logger = logging.WithTray(logger, tray)
 
 	unregisterRequest := messages.UnregisterRequest{}
 	if err := json.NewDecoder(r.Body).Decode(&unregisterRequest); err != nil {
@@ -162,12 +189,14 @@ func (h *Handlers) AgentUnregister(responseWriter http.ResponseWriter, r *http.R
 		return
 	}
 
-	logger = logger.WithFields(log.Fields{
-		"trayId": tray.Id,
-	})
-
 	logger.Tracef("Agent unregister request")
 
+	reason := unregisterRequest.Reason.String()
+	if unregisterRequest.Message != "" {
+		reason += ": " + unregisterRequest.Message
+	}
+	h.TrayManager.RecordEvent(r.Context(), tray.Id, tray.TrayTypeName, trays.TrayEventUnregistered, reason)
+
 	_, deleteErr := h.TrayManager.DeleteTray(r.Context(), tray.Id)
 
 	if deleteErr != nil {
@@ -176,7 +205,7 @@ func (h *Handlers) AgentUnregister(responseWriter http.ResponseWriter, r *http.R
 		return
 	}
 
-	logger.Infof("Agent %s unregistered, reason: %d", unregisterRequest.Agent.AgentId, unregisterRequest.Reason)
+	logger.Infof("Agent %s unregistered, reason: %s", unregisterRequest.Agent.AgentId, unregisterRequest.Reason)
 
 	if unregisterRequest.Reason == messages.UnregisterReasonPreempted {
 		metrics.PreemptedTraysInc(tray.GitHubOrgName, tray.TrayTypeName)
@@ -197,10 +226,7 @@ func AgentDownloadBinary(responseWriter http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handlers) AgentPing(responseWriter http.ResponseWriter, r *http.Request) {
-	logger := log.WithFields(log.Fields{
-		"handler": "agent",
-		"call":    "AgentPing",
-	})
+	logger := agentLogger.WithField("call", "AgentPing")
 
 	logger.Tracef("AgentPing: %v", r)
 
@@ -210,6 +236,7 @@ func (h *Handlers) AgentPing(responseWriter http.ResponseWriter, r *http.Request
 		http.Error(responseWriter, authErr, code)
 		return
 	}
+	logger = logging.WithTray(logger, tray)
 
 	pingResponse := &messages.PingResponse{
 		Terminate: false,
@@ -221,6 +248,15 @@ func (h *Handlers) AgentPing(responseWriter http.ResponseWriter, r *http.Request
 		return
 	}
 
+	if tray.Draining {
+		logger.Infof("Tray '%s' is draining; requesting termination", tray.Id)
+
+		pingResponse.Terminate = true
+		pingResponse.Message = fmt.Sprintf("Tray '%s' drained", tray.Id)
+		writeResponse(responseWriter, pingResponse, logger)
+		return
+	}
+
 	if time.Now().UTC().Sub(tray.StatusChanged) > time.Minute*15 {
 		errMsg := fmt.Sprintf("Tray '%s' status not changed in 15 minutes", tray.Id)
 		logger.Error(errMsg)
@@ -244,10 +280,7 @@ func writeResponse(responseWriter http.ResponseWriter, pingResponse any, logger
 }
 
 func (h *Handlers) AgentInterrupt(responseWriter http.ResponseWriter, r *http.Request) {
-	logger := log.WithFields(log.Fields{
-		"handler": "agent",
-		"call":    "AgentRestart",
-	})
+	logger := agentLogger.WithField("call", "AgentRestart")
 
 	logger.Tracef("AgentRestart: %v", r)
 
@@ -258,9 +291,7 @@ func (h *Handlers) AgentInterrupt(responseWriter http.ResponseWriter, r *http.Re
 		return
 	}
 
-	logger = logger.WithFields(log.Fields{
-		"agentId": tray.Id,
-	})
+	logger = logging.WithTray(logger, tray)
 
 	logger.Debug("Agent restart request with id " + tray.Id)
 
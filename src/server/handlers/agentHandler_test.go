@@ -87,28 +87,31 @@ var _ restarterRepo.RestarterRepository = (*mockRestarterRepository)(nil)
 
 func setupHandlers(repo *testutil.MockTrayRepository) *Handlers {
 	return &Handlers{
-		TrayManager:     trayManager.NewTrayManager(repo, &mockProviderFactory{}),
+		TrayManager:     trayManager.NewTrayManager(repo, testutil.NewMockPauseRepository(), testutil.NewMockTrayEventRepository(), &mockProviderFactory{}),
 		RestartManager:  restarter.NewWorkflowRestarter(&mockRestarterRepository{}),
-		ScaleSetManager: scaleSetPoller.NewManager(),
+		ScaleSetManager: scaleSetPoller.NewManager(testutil.NewMockMessageRepository()),
 		JitRegistry:     scaleSetClient.NewJitRegistry(),
+		Elector:         testutil.NewMockElector(),
 	}
 }
 
 func setupHandlersWithRestarter(repo *testutil.MockTrayRepository, restarterRepo *mockRestarterRepository) *Handlers {
 	return &Handlers{
-		TrayManager:     trayManager.NewTrayManager(repo, &mockProviderFactory{}),
+		TrayManager:     trayManager.NewTrayManager(repo, testutil.NewMockPauseRepository(), testutil.NewMockTrayEventRepository(), &mockProviderFactory{}),
 		RestartManager:  restarter.NewWorkflowRestarter(restarterRepo),
-		ScaleSetManager: scaleSetPoller.NewManager(),
+		ScaleSetManager: scaleSetPoller.NewManager(testutil.NewMockMessageRepository()),
 		JitRegistry:     scaleSetClient.NewJitRegistry(),
+		Elector:         testutil.NewMockElector(),
 	}
 }
 
 func setupHandlersWithProvider(repo *testutil.MockTrayRepository, prov *mockProvider) *Handlers {
 	return &Handlers{
-		TrayManager:     trayManager.NewTrayManager(repo, &mockProviderFactory{provider: prov}),
+		TrayManager:     trayManager.NewTrayManager(repo, testutil.NewMockPauseRepository(), testutil.NewMockTrayEventRepository(), &mockProviderFactory{provider: prov}),
 		RestartManager:  restarter.NewWorkflowRestarter(&mockRestarterRepository{}),
-		ScaleSetManager: scaleSetPoller.NewManager(),
+		ScaleSetManager: scaleSetPoller.NewManager(testutil.NewMockMessageRepository()),
 		JitRegistry:     scaleSetClient.NewJitRegistry(),
+		Elector:         testutil.NewMockElector(),
 	}
 }
 
@@ -202,6 +205,41 @@ func TestAgentPing_RecentNonRunningTray(t *testing.T) {
 	assert.False(t, resp.Terminate)
 }
 
+// A draining tray is told to terminate as soon as it is not running a job.
+func TestAgentPing_DrainingTray(t *testing.T) {
+	for _, tc := range []struct {
+		status    trays.TrayStatus
+		terminate bool
+	}{
+		{trays.TrayStatusRegistered, true},
+		{trays.TrayStatusRunning, false},
+	} {
+		t.Run(tc.status.String(), func(t *testing.T) {
+			repo := testutil.NewMockTrayRepository()
+			repo.Trays["tray-1"] = &trays.Tray{
+				Id:            "tray-1",
+				Status:        tc.status,
+				StatusChanged: time.Now(),
+				Draining:      true,
+			}
+			h := setupHandlers(repo)
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST /agent/{id}/ping", h.AgentPing)
+
+			req := httptest.NewRequest("POST", "/agent/tray-1/ping", nil)
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var resp messages.PingResponse
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+			assert.Equal(t, tc.terminate, resp.Terminate)
+		})
+	}
+}
+
 // --- AgentUnregister tests ---
 
 func TestAgentUnregister_TrayNotFound(t *testing.T) {
@@ -252,6 +290,12 @@ func TestAgentUnregister_Success_RowDeleted(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Empty(t, repo.Trays, "row removed when cleanup succeeds")
+
+	events, err := h.TrayManager.ListTrayEvents(req.Context(), "tray-1")
+	require.NoError(t, err)
+	require.NotEmpty(t, events)
+	assert.Equal(t, trays.TrayEventUnregistered, events[0].Kind)
+	assert.Equal(t, "done", events[0].Message)
 }
 
 func TestAgentUnregister_Returns200_EvenWhenCleanupFails(t *testing.T) {
@@ -445,3 +489,53 @@ func TestAuthenticateAgent_WrongSecret(t *testing.T) {
 
 	assert.Equal(t, http.StatusUnauthorized, w.Code)
 }
+
+func pingWithToken(h *Handlers, trayId string, token string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /agent/{id}/ping", h.AgentPing)
+
+	req := httptest.NewRequest("POST", "/agent/"+trayId+"/ping", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthenticateAgent_TrayToken(t *testing.T) {
+	config.SetForTest(t, &config.CatteryConfig{
+		Server: config.ServerConfig{AgentSecret: "test-secret-123"},
+	})
+
+	tray1, err := trays.NewTray(config.TrayType{Name: "linux"})
+	require.NoError(t, err)
+	tray2, err := trays.NewTray(config.TrayType{Name: "linux"})
+	require.NoError(t, err)
+
+	repo := testutil.NewMockTrayRepository()
+	for _, tray := range []*trays.Tray{tray1, tray2} {
+		tray.Status = trays.TrayStatusRunning
+		repo.Trays[tray.Id] = tray
+	}
+	h := setupHandlers(repo)
+
+	assert.Equal(t, http.StatusOK, pingWithToken(h, tray1.Id, tray1.Token).Code)
+	assert.Equal(t, http.StatusUnauthorized, pingWithToken(h, tray1.Id, tray2.Token).Code, "another tray's token")
+	assert.Equal(t, http.StatusUnauthorized, pingWithToken(h, tray1.Id, "test-secret-123").Code, "the global secret no longer covers trays with a token")
+	assert.Equal(t, http.StatusUnauthorized, pingWithToken(h, tray1.Id, "").Code)
+}
+
+func TestAuthenticateAgent_TrayTokenWithoutSecret(t *testing.T) {
+	tray, err := trays.NewTray(config.TrayType{Name: "linux"})
+	require.NoError(t, err)
+	tray.Status = trays.TrayStatusRunning
+
+	repo := testutil.NewMockTrayRepository()
+	repo.Trays[tray.Id] = tray
+	h := setupHandlers(repo)
+
+	assert.Equal(t, http.StatusOK, pingWithToken(h, tray.Id, tray.Token).Code)
+	assert.Equal(t, http.StatusUnauthorized, pingWithToken(h, tray.Id, "").Code, "a tray token is required even without an agent secret")
+	assert.Equal(t, http.StatusNotFound, pingWithToken(h, "linux-unknown", "anything").Code)
+}
@@ -11,6 +11,7 @@ import (
 	restarterRepos "cattery/lib/restarter/repositories"
 	"cattery/lib/scaleSetClient"
 	"cattery/lib/scaleSetPoller"
+	"cattery/lib/testutil"
 	"cattery/lib/trays"
 	"cattery/lib/trays/providers"
 	"cattery/lib/trays/repositories"
@@ -126,12 +127,12 @@ func setupIntegrationTestWithFactory(t *testing.T, factory providers.TrayProvide
 	restartRepo.Connect(db.Collection("restarters"))
 
 	// Set up managers
-	tm := trayManager.NewTrayManager(trayRepo, factory)
+	tm := trayManager.NewTrayManager(trayRepo, testutil.NewMockPauseRepository(), testutil.NewMockTrayEventRepository(), factory)
 	rm := restarter.NewWorkflowRestarter(restartRepo)
 
 	// Scale set manager holds no pollers here; the agent register handler gets
 	// its JIT generator from the registry, decoupled from the poller.
-	ssm := scaleSetPoller.NewManager()
+	ssm := scaleSetPoller.NewManager(testutil.NewMockMessageRepository())
 	jitRegistry := scaleSetClient.NewJitRegistry()
 	jitRegistry.Register("test-type", &mockJitConfigGenerator{runnerID: 42})
 
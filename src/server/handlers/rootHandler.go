@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"cattery/lib/election"
 	"cattery/lib/restarter"
 	"cattery/lib/scaleSetClient"
 	"cattery/lib/scaleSetPoller"
@@ -15,6 +16,10 @@ type Handlers struct {
 	// JitRegistry serves JIT runner configs per tray type, independent of which
 	// replica holds the scale set session (see scaleSetClient.JitRegistry).
 	JitRegistry *scaleSetClient.JitRegistry
+	// Elector runs the leader election of the tray types' pollers.
+	Elector election.Elector
+
+	streams statusStreams
 }
 
 func (h *Handlers) Index(w http.ResponseWriter, r *http.Request) {
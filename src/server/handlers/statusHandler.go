@@ -2,90 +2,181 @@ package handlers
 
 import (
 	"cattery/lib/config"
-	"cattery/lib/scaleSetPoller"
+	"cattery/lib/election"
+	"cattery/lib/logging"
+	messageRepo "cattery/lib/scaleSetPoller/repositories"
+	"cattery/lib/trayManager"
 	"cattery/lib/trays"
 	"cattery/lib/version"
 	"cattery/ui"
+	"context"
 	"encoding/json"
 	"fmt"
 	"html/template"
 	"net/http"
+	"strconv"
 	"time"
-
-	log "github.com/sirupsen/logrus"
 )
 
-var statusTmpl = template.Must(
-	template.New("status.html").
-		Funcs(template.FuncMap{
-			"age": func(t time.Time) string {
-				d := time.Since(t)
-				switch {
-				case d < time.Minute:
-					return d.Round(time.Second).String()
-				case d < time.Hour:
-					return d.Round(time.Minute).String()
-				default:
-					return d.Round(time.Hour).String()
-				}
-			},
-			"joburl": func(t *trays.Tray) string {
-				return jobURL(t)
-			},
-			"msgJobURL": messageJobURL,
-			"providerType": func(name string) string {
-				if p := config.Get().GetProvider(name); p != nil {
-					return p.Get("type")
-				}
-				return ""
-			},
-		}).
-		ParseFS(ui.Templates, "status.html"),
-)
+var statusLogger = logging.Logger("statusHandler")
+
+// statusMessagesLimit is how many scale set messages the status page shows,
+// and the default page size of /status/data.
+const statusMessagesLimit = 100
+
+// statusMessagesMaxLimit caps the limit query parameter of /status/data.
+const statusMessagesMaxLimit = 1000
+
+var statusFuncs = template.FuncMap{
+	"age": func(t time.Time) string {
+		d := time.Since(t)
+		switch {
+		case d < time.Minute:
+			return d.Round(time.Second).String()
+		case d < time.Hour:
+			return d.Round(time.Minute).String()
+		default:
+			return d.Round(time.Hour).String()
+		}
+	},
+	"joburl": func(t *trays.Tray) string {
+		return jobURL(t)
+	},
+	"msgJobURL": messageJobURL,
+	"until":     formatUntil,
+	"providerType": func(name string) string {
+		if p := config.Get().GetProvider(name); p != nil {
+			return p.Get("type")
+		}
+		return ""
+	},
+}
+
+var statusTmpl = template.Must(template.New("status.html").Funcs(statusFuncs).ParseFS(ui.Templates, "status.html"))
+
+var trayTmpl = template.Must(template.New("tray.html").Funcs(statusFuncs).ParseFS(ui.Templates, "tray.html"))
 
 func (h *Handlers) Status(w http.ResponseWriter, r *http.Request) {
 	trayList, err := h.TrayManager.ListTrays(r.Context())
 	if err != nil {
-		log.Errorf("Status: failed to list trays: %v", err)
+		statusLogger.Errorf("Status: failed to list trays: %v", err)
 		http.Error(w, "failed to list trays", http.StatusInternalServerError)
 		return
 	}
 
+	messages, err := h.ScaleSetManager.MessageHistory(r.Context(), messageRepo.MessageFilter{Limit: statusMessagesLimit})
+	if err != nil {
+		statusLogger.Errorf("Status: failed to list messages: %v", err)
+		http.Error(w, "failed to list messages", http.StatusInternalServerError)
+		return
+	}
+
 	cfg := config.Get()
 	data := struct {
 		Now       time.Time
 		Version   string
 		Trays     []*trays.Tray
-		Messages  []*scaleSetPoller.Message
+		Messages  []*messageRepo.Message
 		Orgs      []*config.GitHubOrganization
 		Providers []*config.ProviderConfig
-		TrayTypes []*config.TrayType
+		TrayTypes []statusTrayType
 	}{
 		Now:       time.Now().UTC(),
 		Version:   version.Get(),
 		Trays:     trayList,
-		Messages:  h.ScaleSetManager.MessageHistory(),
+		Messages:  messages,
 		Orgs:      cfg.Github,
 		Providers: cfg.Providers,
-		TrayTypes: cfg.TrayTypes,
+		TrayTypes: newStatusTrayTypes(cfg.TrayTypes, time.Now(), h.TrayManager.BreakerStatus, h.Elector.Leadership()),
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	if err := statusTmpl.Execute(w, data); err != nil {
-		log.Errorf("Status: template error: %v", err)
+		statusLogger.Errorf("Status: template error: %v", err)
+	}
+}
+
+// statusTrayType is a tray type with its active schedule window applied.
+// Profile is the window's name, "" outside any window. Breaker is the state
+// of its tray creation circuit breaker. Leader is this replica's view of the
+// election of its poller, nil if no election runs for it.
+type statusTrayType struct {
+	*config.TrayType
+	Profile string
+	Breaker trayManager.BreakerStatus
+	Leader  *election.Leadership
+}
+
+func newStatusTrayTypes(trayTypes []*config.TrayType, now time.Time, breakerStatus func(*config.TrayType) trayManager.BreakerStatus, leaderships []election.Leadership) []statusTrayType {
+	byKey := make(map[string]*election.Leadership, len(leaderships))
+	for i := range leaderships {
+		byKey[leaderships[i].Key] = &leaderships[i]
+	}
+
+	result := make([]statusTrayType, len(trayTypes))
+	for i, tt := range trayTypes {
+		scheduled, profile := tt.Scheduled(now)
+		result[i] = statusTrayType{TrayType: scheduled, Profile: profile, Breaker: breakerStatus(tt), Leader: byKey[tt.Name]}
 	}
+	return result
+}
+
+type statusTrayTypeJSON struct {
+	Name     string            `json:"name"`
+	Profile  string            `json:"profile"`
+	MaxTrays int               `json:"maxTrays"`
+	Breaker  statusBreakerJSON `json:"breaker"`
+	Leader   *statusLeaderJSON `json:"leader,omitempty"`
+}
+
+// statusLeaderJSON mirrors the status page's leader cell. Since is the age of
+// this replica's current term, "" unless Leader.
+type statusLeaderJSON struct {
+	Holder string `json:"holder"`
+	Leader bool   `json:"leader"`
+	Since  string `json:"since,omitempty"`
+	Terms  int    `json:"terms"`
+}
+
+type statusBreakerJSON struct {
+	State     string `json:"state"`
+	Failures  int    `json:"failures"`
+	RetryIn   string `json:"retryIn,omitempty"`
+	LastError string `json:"lastError,omitempty"`
 }
 
 type statusTrayJSON struct {
-	Id            string `json:"id"`
-	TrayTypeName  string `json:"type"`
-	GitHubOrgName string `json:"org"`
-	Status        string `json:"status"`
-	Repository    string `json:"repository"`
-	WorkflowName  string `json:"workflow"`
-	JobName       string `json:"job"`
-	JobURL        string `json:"jobUrl"`
-	Since         string `json:"since"`
+	Id            string    `json:"id"`
+	TrayTypeName  string    `json:"type"`
+	GitHubOrgName string    `json:"org"`
+	ProviderName  string    `json:"provider"`
+	Status        string    `json:"status"`
+	StatusChanged time.Time `json:"statusChanged"`
+	Draining      bool      `json:"draining,omitempty"`
+	RunnerId      int64     `json:"runnerId,omitempty"`
+	Repository    string    `json:"repository"`
+	WorkflowName  string    `json:"workflow"`
+	JobName       string    `json:"job"`
+	JobURL        string    `json:"jobUrl"`
+	Since         string    `json:"since"`
+}
+
+func newStatusTrayJSON(t *trays.Tray) statusTrayJSON {
+	return statusTrayJSON{
+		Id:            t.Id,
+		TrayTypeName:  t.TrayTypeName,
+		GitHubOrgName: t.GitHubOrgName,
+		ProviderName:  t.ProviderName,
+		Status:        t.Status.String(),
+		StatusChanged: t.StatusChanged,
+		Draining:      t.Draining,
+		RunnerId:      t.GitHubRunnerId,
+		Repository:    t.Repository,
+		WorkflowName:  t.WorkflowName,
+		JobName:       t.JobName,
+		JobURL:        jobURL(t),
+		Since:         formatAge(t.StatusChanged),
+	}
 }
 
 type statusMessageJSON struct {
@@ -115,89 +206,186 @@ type statusScaleStatsJSON struct {
 	Registered int `json:"registered"`
 }
 
-func (h *Handlers) StatusData(w http.ResponseWriter, r *http.Request) {
-	trayList, err := h.TrayManager.ListTrays(r.Context())
+func newStatusMessageJSON(m *messageRepo.Message) statusMessageJSON {
+	item := statusMessageJSON{
+		Time:     m.Time.UTC().Format("15:04:05"),
+		TimeFull: m.Time.UTC().Format("2006-01-02 15:04:05 UTC"),
+		TrayType: m.TrayType,
+		Kind:     string(m.Kind),
+	}
+	if m.IsScale() {
+		item.DesiredCount = m.DesiredCount
+		if m.Stats != nil {
+			item.Stats = &statusScaleStatsJSON{
+				Available:  m.Stats.Available,
+				Assigned:   m.Stats.Assigned,
+				Running:    m.Stats.Running,
+				Busy:       m.Stats.Busy,
+				Idle:       m.Stats.Idle,
+				Registered: m.Stats.Registered,
+			}
+		}
+	} else {
+		item.Repository = m.Repository
+		item.JobDisplayName = m.JobDisplayName
+		item.RunnerName = m.RunnerName
+		item.Result = m.Result
+		item.JobURL = messageJobURL(m)
+	}
+	return item
+}
+
+// statusDataJSON is everything the status page shows that changes at runtime.
+// MoreMessages tells whether messages past the requested page match the
+// filter.
+type statusDataJSON struct {
+	Now          string               `json:"now"`
+	Trays        []statusTrayJSON     `json:"trays"`
+	Messages     []statusMessageJSON  `json:"messages"`
+	MoreMessages bool                 `json:"moreMessages"`
+	TrayTypes    []statusTrayTypeJSON `json:"trayTypes"`
+}
+
+func (h *Handlers) statusData(ctx context.Context, filter messageRepo.MessageFilter) (*statusDataJSON, error) {
+	trayList, err := h.TrayManager.ListTrays(ctx)
 	if err != nil {
-		log.Errorf("StatusData: failed to list trays: %v", err)
-		http.Error(w, "failed to list trays", http.StatusInternalServerError)
-		return
+		return nil, fmt.Errorf("failed to list trays: %w", err)
 	}
 
 	trayItems := make([]statusTrayJSON, len(trayList))
 	for i, t := range trayList {
-		trayItems[i] = statusTrayJSON{
-			Id:            t.Id,
-			TrayTypeName:  t.TrayTypeName,
-			GitHubOrgName: t.GitHubOrgName,
-			Status:        t.Status.String(),
-			Repository:    t.Repository,
-			WorkflowName:  t.WorkflowName,
-			JobName:       t.JobName,
-			JobURL:        jobURL(t),
-			Since:         formatAge(t.StatusChanged),
-		}
+		trayItems[i] = newStatusTrayJSON(t)
 	}
 
-	msgs := h.ScaleSetManager.MessageHistory()
+	// Ask for one message more than the page to learn whether there are more.
+	pageLimit := filter.Limit
+	filter.Limit++
+	msgs, err := h.ScaleSetManager.MessageHistory(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list messages: %w", err)
+	}
+	more := len(msgs) > pageLimit
+	if more {
+		msgs = msgs[:pageLimit]
+	}
 	msgItems := make([]statusMessageJSON, len(msgs))
 	for i, m := range msgs {
-		item := statusMessageJSON{
-			Time:     m.Time.UTC().Format("15:04:05"),
-			TimeFull: m.Time.UTC().Format("2006-01-02 15:04:05 UTC"),
-			TrayType: m.TrayType,
-			Kind:     string(m.Kind),
+		msgItems[i] = newStatusMessageJSON(m)
+	}
+
+	trayTypes := newStatusTrayTypes(config.Get().TrayTypes, time.Now(), h.TrayManager.BreakerStatus, h.Elector.Leadership())
+	trayTypeItems := make([]statusTrayTypeJSON, len(trayTypes))
+	for i, tt := range trayTypes {
+		trayTypeItems[i] = statusTrayTypeJSON{
+			Name:     tt.Name,
+			Profile:  tt.Profile,
+			MaxTrays: tt.MaxTrays,
+			Breaker: statusBreakerJSON{
+				State:     tt.Breaker.State.String(),
+				Failures:  tt.Breaker.Failures,
+				LastError: tt.Breaker.LastError,
+			},
+		}
+		if !tt.Breaker.RetryAt.IsZero() {
+			trayTypeItems[i].Breaker.RetryIn = formatUntil(tt.Breaker.RetryAt)
 		}
-		if m.IsScale() {
-			item.DesiredCount = m.DesiredCount
-			if m.Stats != nil {
-				item.Stats = &statusScaleStatsJSON{
-					Available:  m.Stats.Available,
-					Assigned:   m.Stats.Assigned,
-					Running:    m.Stats.Running,
-					Busy:       m.Stats.Busy,
-					Idle:       m.Stats.Idle,
-					Registered: m.Stats.Registered,
-				}
+		if tt.Leader != nil {
+			trayTypeItems[i].Leader = &statusLeaderJSON{Holder: tt.Leader.Holder, Leader: tt.Leader.Leader, Terms: tt.Leader.Terms}
+			if tt.Leader.Leader {
+				trayTypeItems[i].Leader.Since = formatAge(tt.Leader.AcquiredAt)
 			}
-		} else {
-			item.Repository = m.Repository
-			item.JobDisplayName = m.JobDisplayName
-			item.RunnerName = m.RunnerName
-			item.Result = m.Result
-			item.JobURL = messageJobURL(m)
 		}
-		msgItems[i] = item
+	}
+
+	return &statusDataJSON{
+		Now:          formatNow(),
+		Trays:        trayItems,
+		Messages:     msgItems,
+		MoreMessages: more,
+		TrayTypes:    trayTypeItems,
+	}, nil
+}
+
+// StatusData returns the status page's data as JSON. The scale set messages
+// are paged with the limit and offset query parameters, newest first; type,
+// kind and repo filter them on exact matches.
+func (h *Handlers) StatusData(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseMessageFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data, err := h.statusData(r.Context(), filter)
+	if err != nil {
+		statusLogger.Errorf("StatusData: %v", err)
+		http.Error(w, "failed to load status data", http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(struct {
-		Now      string              `json:"now"`
-		Trays    []statusTrayJSON    `json:"trays"`
-		Messages []statusMessageJSON `json:"messages"`
-	}{
-		Now:      time.Now().UTC().Format("2006-01-02 15:04:05 UTC"),
-		Trays:    trayItems,
-		Messages: msgItems,
-	})
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+// parseMessageFilter reads the message filter and page of a /status/data
+// request. limit defaults to statusMessagesLimit.
+func parseMessageFilter(r *http.Request) (messageRepo.MessageFilter, error) {
+	query := r.URL.Query()
+	filter := messageRepo.MessageFilter{
+		TrayType:   query.Get("type"),
+		Kind:       messageRepo.MessageKind(query.Get("kind")),
+		Repository: query.Get("repo"),
+		Limit:      statusMessagesLimit,
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > statusMessagesMaxLimit {
+			return filter, fmt.Errorf("invalid limit %q: must be between 1 and %d", value, statusMessagesMaxLimit)
+		}
+		filter.Limit = limit
+	}
+	if value := query.Get("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return filter, fmt.Errorf("invalid offset %q", value)
+		}
+		filter.Offset = offset
+	}
+	return filter, nil
 }
 
 // buildJobURL returns the GitHub Actions workflow run URL, or "" if any part
 // is missing. The scale set messages carry only a GUID job id (not the numeric
 // one GitHub's /job/{id} URLs need), so link to the run page instead.
-// Format: https://github.com/{owner}/{repo}/actions/runs/{workflowRunId}
-func buildJobURL(repo string, workflowRunID int64) string {
+// Format: {githubUrl}/{owner}/{repo}/actions/runs/{workflowRunId}
+func buildJobURL(githubURL string, repo string, workflowRunID int64) string {
 	if repo == "" || workflowRunID == 0 {
 		return ""
 	}
-	return fmt.Sprintf("https://github.com/%s/actions/runs/%d", repo, workflowRunID)
+	return fmt.Sprintf("%s/%s/actions/runs/%d", githubURL, repo, workflowRunID)
+}
+
+// githubURL returns the web URL of the GitHub instance an org lives on,
+// github.com for orgs no longer in the config.
+func githubURL(orgName string) string {
+	org := config.Get().GetGitHubOrg(orgName)
+	if org == nil {
+		return config.DefaultGitHubURL
+	}
+	return org.WebURL()
 }
 
 func jobURL(t *trays.Tray) string {
-	return buildJobURL(t.Repository, t.WorkflowRunId)
+	return buildJobURL(githubURL(t.GitHubOrgName), t.Repository, t.WorkflowRunId)
 }
 
-func messageJobURL(m *scaleSetPoller.Message) string {
-	return buildJobURL(m.Repository, m.WorkflowRunID)
+func messageJobURL(m *messageRepo.Message) string {
+	orgName := ""
+	if trayType := config.Get().GetTrayType(m.TrayType); trayType != nil {
+		orgName = trayType.GitHubOrg
+	}
+	return buildJobURL(githubURL(orgName), m.Repository, m.WorkflowRunID)
 }
 
 func formatAge(t time.Time) string {
@@ -211,3 +399,128 @@ func formatAge(t time.Time) string {
 		return d.Round(time.Hour).String()
 	}
 }
+
+// formatNow is the time shown in the status page header.
+func formatNow() string {
+	return time.Now().UTC().Format("2006-01-02 15:04:05 UTC")
+}
+
+// formatUntil returns the time left until t, at least 0s.
+func formatUntil(t time.Time) string {
+	return max(time.Until(t), 0).Round(time.Second).String()
+}
+
+// statusTrayEvent is a tray event with the time since the previous one, ""
+// for the first.
+type statusTrayEvent struct {
+	trays.TrayEvent
+	Elapsed string
+}
+
+func newStatusTrayEvents(events []trays.TrayEvent) []statusTrayEvent {
+	result := make([]statusTrayEvent, len(events))
+	for i, event := range events {
+		result[i] = statusTrayEvent{TrayEvent: event}
+		if i > 0 {
+			result[i].Elapsed = "+" + event.Time.Sub(events[i-1].Time).Round(time.Second).String()
+		}
+	}
+	return result
+}
+
+// trayDetails loads the tray (nil once deleted) and the lifecycle events of
+// the tray in the request path. It writes the error response itself and
+// returns ok=false if either lookup fails or neither exists.
+func (h *Handlers) trayDetails(w http.ResponseWriter, r *http.Request, caller string) (*trays.Tray, []statusTrayEvent, bool) {
+	trayId := r.PathValue("id")
+
+	tray, err := h.TrayManager.GetTrayById(r.Context(), trayId)
+	if err != nil {
+		statusLogger.WithField(logging.TrayIdKey, trayId).Errorf("%s: failed to get tray %s: %v", caller, trayId, err)
+		http.Error(w, "failed to get tray", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+
+	events, err := h.TrayManager.ListTrayEvents(r.Context(), trayId)
+	if err != nil {
+		statusLogger.WithField(logging.TrayIdKey, trayId).Errorf("%s: failed to list events of tray %s: %v", caller, trayId, err)
+		http.Error(w, "failed to list tray events", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+
+	if tray == nil && len(events) == 0 {
+		http.Error(w, "tray not found", http.StatusNotFound)
+		return nil, nil, false
+	}
+	return tray, newStatusTrayEvents(events), true
+}
+
+// StatusTray renders a tray's detail page: its row while it exists and its
+// lifecycle events, which are kept after it is deleted.
+func (h *Handlers) StatusTray(w http.ResponseWriter, r *http.Request) {
+	tray, events, ok := h.trayDetails(w, r, "StatusTray")
+	if !ok {
+		return
+	}
+
+	data := struct {
+		Now     time.Time
+		Version string
+		TrayId  string
+		Tray    *trays.Tray
+		Events  []statusTrayEvent
+	}{
+		Now:     time.Now().UTC(),
+		Version: version.Get(),
+		TrayId:  r.PathValue("id"),
+		Tray:    tray,
+		Events:  events,
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := trayTmpl.Execute(w, data); err != nil {
+		statusLogger.Errorf("StatusTray: template error: %v", err)
+	}
+}
+
+type statusTrayEventJSON struct {
+	Time    time.Time `json:"time"`
+	Kind    string    `json:"kind"`
+	Message string    `json:"message,omitempty"`
+	Elapsed string    `json:"elapsed,omitempty"`
+}
+
+// StatusTrayEvents returns a tray's lifecycle events, oldest first, and its
+// current status, "" once the tray is deleted.
+func (h *Handlers) StatusTrayEvents(w http.ResponseWriter, r *http.Request) {
+	tray, events, ok := h.trayDetails(w, r, "StatusTrayEvents")
+	if !ok {
+		return
+	}
+
+	items := make([]statusTrayEventJSON, len(events))
+	for i, event := range events {
+		items[i] = statusTrayEventJSON{
+			Time:    event.Time,
+			Kind:    string(event.Kind),
+			Message: event.Message,
+			Elapsed: event.Elapsed,
+		}
+	}
+
+	status := ""
+	if tray != nil {
+		status = tray.Status.String()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(struct {
+		Id     string                `json:"id"`
+		Status string                `json:"status"`
+		Events []statusTrayEventJSON `json:"events"`
+	}{
+		Id:     r.PathValue("id"),
+		Status: status,
+		Events: items,
+	})
+}
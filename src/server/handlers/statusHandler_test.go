@@ -2,12 +2,19 @@ package handlers
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 	"time"
 
 	"cattery/lib/config"
-	"cattery/lib/scaleSetPoller"
+	"cattery/lib/election"
+	messageRepo "cattery/lib/scaleSetPoller/repositories"
+	"cattery/lib/testutil"
+	"cattery/lib/trayManager"
 	"cattery/lib/trays"
 
 	"github.com/stretchr/testify/assert"
@@ -30,10 +37,10 @@ func TestStatusTemplateRenders(t *testing.T) {
 		Now       time.Time
 		Version   string
 		Trays     []*trays.Tray
-		Messages  []*scaleSetPoller.Message
+		Messages  []*messageRepo.Message
 		Orgs      []*config.GitHubOrganization
 		Providers []*config.ProviderConfig
-		TrayTypes []*config.TrayType
+		TrayTypes []statusTrayType
 	}{
 		Now:     now,
 		Version: "v0.0.0-test",
@@ -86,17 +93,17 @@ func TestStatusTemplateRenders(t *testing.T) {
 				StatusChanged: now.Add(-time.Minute),
 			},
 		},
-		Messages: []*scaleSetPoller.Message{
+		Messages: []*messageRepo.Message{
 			{
 				Time:         now.Add(-time.Minute),
-				Kind:         scaleSetPoller.MessageKindScale,
+				Kind:         messageRepo.MessageKindScale,
 				TrayType:     "gce-large",
 				DesiredCount: 2,
-				Stats:        &scaleSetPoller.ScaleStats{Available: 1, Assigned: 1, Running: 1, Busy: 1, Idle: 1, Registered: 2},
+				Stats:        &messageRepo.ScaleStats{Available: 1, Assigned: 1, Running: 1, Busy: 1, Idle: 1, Registered: 2},
 			},
 			{
 				Time:           now.Add(-2 * time.Minute),
-				Kind:           scaleSetPoller.MessageKindJobStarted,
+				Kind:           messageRepo.MessageKindJobStarted,
 				TrayType:       "gce-large",
 				Repository:     "test-org/repo",
 				WorkflowRunID:  42,
@@ -106,7 +113,7 @@ func TestStatusTemplateRenders(t *testing.T) {
 			},
 			{
 				Time:           now.Add(-3 * time.Minute),
-				Kind:           scaleSetPoller.MessageKindJobCompleted,
+				Kind:           messageRepo.MessageKindJobCompleted,
 				TrayType:       "gce-large",
 				Repository:     "test-org/repo",
 				WorkflowRunID:  41,
@@ -118,12 +125,22 @@ func TestStatusTemplateRenders(t *testing.T) {
 		},
 		Orgs:      []*config.GitHubOrganization{{Name: "test-org", AppId: 123456, InstallationId: 654321}},
 		Providers: []*config.ProviderConfig{{"name": "gce", "type": "gce"}},
-		TrayTypes: []*config.TrayType{
+		TrayTypes: newStatusTrayTypes([]*config.TrayType{
 			{Name: "gce-large", Provider: "gce", GitHubOrg: "test-org", RunnerGroupId: 1, MaxTrays: 5,
 				Description: "GCE e2-standard-8 spot VM for heavy builds"},
 			{Name: "docker-small", Provider: "docker", GitHubOrg: "test-org", RunnerGroupId: 1},
-			{Name: "nomad-spot", Provider: "nomad", GitHubOrg: "test-org", RunnerGroupId: 1, MaxTrays: 2},
-		},
+			{Name: "nomad-spot", Provider: "nomad", GitHubOrg: "test-org", RunnerGroupId: 1, MaxTrays: 2,
+				Schedule: allDaySchedule(t, "office-hours", 8)},
+		}, now, func(tt *config.TrayType) trayManager.BreakerStatus {
+			if tt.Name == "docker-small" {
+				return trayManager.BreakerStatus{State: trayManager.BreakerOpen, Failures: 5,
+					RetryAt: now.Add(time.Minute), LastError: "image not found"}
+			}
+			return trayManager.BreakerStatus{}
+		}, []election.Leadership{
+			{Key: "gce-large", Holder: "replica-a", Leader: true, AcquiredAt: now.Add(-2 * time.Minute), Terms: 3},
+			{Key: "docker-small", Holder: "replica-b"},
+		}),
 	}
 
 	var buf bytes.Buffer
@@ -132,6 +149,15 @@ func TestStatusTemplateRenders(t *testing.T) {
 
 	// Tray type limits reach the client via data attributes on the tray types table.
 	assert.Contains(t, out, `data-max="5"`)
+	assert.Contains(t, out, `data-max="8"`) // nomad-spot's scheduled maxTrays
+	assert.Contains(t, out, `<td class="profile">office-hours</td>`)
+	assert.Contains(t, out, `<td class="profile"><span class="dim">default</span></td>`)
+	assert.Contains(t, out, `<td class="breaker"><span class="dim">closed</span></td>`)
+	assert.Contains(t, out, `<span class="breaker-open" title="image not found">open</span>`)
+	assert.Contains(t, out, "5 failures, retry in 1m0s")
+	assert.Contains(t, out, `<span class="leader-here" title="replica-a">this replica</span> <span class="dim">for 2m0s, term 3</span>`)
+	assert.Contains(t, out, `<td class="leader">replica-b</td>`)
+	assert.Contains(t, out, `<td class="leader"><span class="dim">&mdash;</span></td>`)
 	assert.Contains(t, out, "gce-large")
 	assert.Contains(t, out, "GCE e2-standard-8 spot VM for heavy builds")
 	assert.Contains(t, out, "(google)") // provider type next to provider name
@@ -145,3 +171,136 @@ func TestStatusTemplateRenders(t *testing.T) {
 		require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
 	}
 }
+
+// allDaySchedule returns an initialized schedule whose single window is
+// always active and overrides maxTrays.
+func allDaySchedule(t *testing.T, name string, maxTrays int) *config.TrayTypeSchedule {
+	t.Helper()
+	schedule := &config.TrayTypeSchedule{
+		Windows: []config.ScheduleWindow{{Name: name, Start: "00:00", End: "00:00", MaxTrays: &maxTrays}},
+	}
+	require.NoError(t, schedule.Init())
+	return schedule
+}
+
+func TestJobURL_GitHubEnterprise(t *testing.T) {
+	config.SetForTest(t, &config.CatteryConfig{
+		Github: []*config.GitHubOrganization{
+			{Name: "cloud-org"},
+			{Name: "ghes-org", BaseUrl: "https://ghes.example.com"},
+		},
+		TrayTypes: []*config.TrayType{
+			{Name: "ghes-linux", GitHubOrg: "ghes-org"},
+		},
+	})
+
+	assert.Equal(t, "https://github.com/cloud-org/repo/actions/runs/1",
+		jobURL(&trays.Tray{GitHubOrgName: "cloud-org", Repository: "cloud-org/repo", WorkflowRunId: 1}))
+	assert.Equal(t, "https://ghes.example.com/ghes-org/repo/actions/runs/2",
+		jobURL(&trays.Tray{GitHubOrgName: "ghes-org", Repository: "ghes-org/repo", WorkflowRunId: 2}))
+	assert.Equal(t, "https://ghes.example.com/ghes-org/repo/actions/runs/3",
+		messageJobURL(&messageRepo.Message{TrayType: "ghes-linux", Repository: "ghes-org/repo", WorkflowRunID: 3}))
+	assert.Equal(t, "https://github.com/other/repo/actions/runs/4",
+		messageJobURL(&messageRepo.Message{TrayType: "removed", Repository: "other/repo", WorkflowRunID: 4}))
+	assert.Empty(t, jobURL(&trays.Tray{GitHubOrgName: "ghes-org"}))
+}
+
+func TestStatusTrayEvents(t *testing.T) {
+	config.SetForTest(t, &config.CatteryConfig{})
+	repo := testutil.NewMockTrayRepository()
+	repo.Trays["linux-1"] = &trays.Tray{
+		Id: "linux-1", TrayTypeName: "linux", GitHubOrgName: "org-a", Status: trays.TrayStatusRegistered,
+		StatusChanged: time.Now(), ProviderData: map[string]string{},
+	}
+	h := setupHandlersWithProvider(repo, &mockProvider{})
+	ctx := context.Background()
+	h.TrayManager.RecordEvent(ctx, "linux-1", "linux", trays.TrayEventCreated, "provider gce")
+	h.TrayManager.RecordEvent(ctx, "linux-1", "linux", trays.TrayEventRegistered, "runner id 42")
+	h.TrayManager.RecordEvent(ctx, "linux-0", "linux", trays.TrayEventDeleted, "")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status/trays/{id}", h.StatusTray)
+	mux.HandleFunc("GET /status/trays/{id}/events", h.StatusTrayEvents)
+	get := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		return w
+	}
+
+	w := get("/status/trays/linux-1/events")
+	require.Equal(t, http.StatusOK, w.Code)
+	var got struct {
+		Id     string                `json:"id"`
+		Status string                `json:"status"`
+		Events []statusTrayEventJSON `json:"events"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	assert.Equal(t, "linux-1", got.Id)
+	assert.Equal(t, "registered", got.Status)
+	require.Len(t, got.Events, 2)
+	assert.Equal(t, "created", got.Events[0].Kind)
+	assert.Empty(t, got.Events[0].Elapsed)
+	assert.Equal(t, "runner id 42", got.Events[1].Message)
+	assert.NotEmpty(t, got.Events[1].Elapsed)
+
+	w = get("/status/trays/linux-1")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "runner id 42")
+
+	// A deleted tray's events are still served.
+	w = get("/status/trays/linux-0")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "has been deleted")
+
+	assert.Equal(t, http.StatusNotFound, get("/status/trays/missing/events").Code)
+}
+
+func TestStatusData_Messages(t *testing.T) {
+	config.SetForTest(t, &config.CatteryConfig{})
+	h := setupHandlers(testutil.NewMockTrayRepository())
+	ctx := context.Background()
+	base := time.Now().UTC()
+	for i, trayType := range []string{"linux", "windows", "linux", "linux"} {
+		h.ScaleSetManager.RecordMessage(ctx, &messageRepo.Message{
+			Time: base.Add(time.Duration(i) * time.Second), Kind: messageRepo.MessageKindScale, TrayType: trayType, DesiredCount: i,
+		})
+	}
+	h.ScaleSetManager.RecordMessage(ctx, &messageRepo.Message{
+		Time: base.Add(5 * time.Second), Kind: messageRepo.MessageKindJobStarted, TrayType: "linux", Repository: "org/repo",
+	})
+
+	get := func(target string) (*httptest.ResponseRecorder, statusDataJSON) {
+		w := httptest.NewRecorder()
+		h.StatusData(w, httptest.NewRequest("GET", target, nil))
+		var data statusDataJSON
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&data))
+		}
+		return w, data
+	}
+
+	_, data := get("/status/data")
+	require.Len(t, data.Messages, 5)
+	assert.Equal(t, "job_started", data.Messages[0].Kind)
+	assert.False(t, data.MoreMessages)
+
+	_, data = get("/status/data?type=linux&kind=scale&limit=2")
+	require.Len(t, data.Messages, 2)
+	assert.Equal(t, 3, data.Messages[0].DesiredCount)
+	assert.Equal(t, 2, data.Messages[1].DesiredCount)
+	assert.True(t, data.MoreMessages)
+
+	_, data = get("/status/data?type=linux&kind=scale&limit=2&offset=2")
+	require.Len(t, data.Messages, 1)
+	assert.Equal(t, 0, data.Messages[0].DesiredCount)
+	assert.False(t, data.MoreMessages)
+
+	_, data = get("/status/data?repo=org/repo")
+	require.Len(t, data.Messages, 1)
+	assert.Equal(t, "org/repo", data.Messages[0].Repository)
+
+	for _, query := range []string{"limit=0", "limit=abc", "limit=1001", "offset=-1"} {
+		w, _ := get("/status/data?" + query)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
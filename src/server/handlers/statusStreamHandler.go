@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"cattery/lib/logging"
+	messageRepo "cattery/lib/scaleSetPoller/repositories"
+	"cattery/lib/trayManager"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// statusStreamBuffer is how many tray changes or messages a status stream may
+// fall behind before it is dropped. The page then reconnects and starts over
+// from a fresh snapshot.
+const statusStreamBuffer = 256
+
+// statusStreamResync is how often a status stream resends the full snapshot.
+// Tray changes and messages of other replicas reach the page this way.
+var statusStreamResync = 15 * time.Second
+
+// statusTrayChangeJSON is a tray event and the tray's state after it. Tray is
+// null when the tray was deleted or could not be looked up.
+type statusTrayChangeJSON struct {
+	Id      string              `json:"id"`
+	Tray    *statusTrayJSON     `json:"tray"`
+	Deleted bool                `json:"deleted"`
+	Event   statusTrayEventJSON `json:"event"`
+}
+
+// statusStreams ends the open status streams on shutdown. The zero value is
+// ready to use.
+type statusStreams struct {
+	init      sync.Once
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// done is closed by closeAll.
+func (s *statusStreams) done() <-chan struct{} {
+	s.init.Do(func() { s.closed = make(chan struct{}) })
+	return s.closed
+}
+
+func (s *statusStreams) closeAll() {
+	s.done()
+	s.closeOnce.Do(func() { close(s.closed) })
+}
+
+// CloseStatusStreams ends every open status stream, and any opened later.
+// http.Server.Shutdown neither cancels nor waits out such long-lived
+// responses, so register it with http.Server.RegisterOnShutdown.
+func (h *Handlers) CloseStatusStreams() {
+	h.streams.closeAll()
+}
+
+// StatusStream streams the status page's data as server-sent events: a
+// "snapshot" event with the /status/data document on connect and every
+// statusStreamResync, a "tray" event for every tray event recorded on this
+// replica and a "message" event for every scale set message of its pollers.
+func (h *Handlers) StatusStream(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Subscribe before taking the snapshot, so nothing falls in between.
+	changes, stopChanges := h.TrayManager.SubscribeTrayChanges(statusStreamBuffer)
+	defer stopChanges()
+	messages, stopMessages := h.ScaleSetManager.SubscribeMessages(statusStreamBuffer)
+	defer stopMessages()
+
+	snapshot, err := h.statusData(ctx, messageRepo.MessageFilter{Limit: statusMessagesLimit})
+	if err != nil {
+		statusLogger.Errorf("StatusStream: %v", err)
+		http.Error(w, "failed to load status data", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Keep reverse proxies such as nginx from buffering the stream.
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	rc := http.NewResponseController(w)
+	send := func(event string, data any) bool {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			statusLogger.Errorf("StatusStream: failed to encode %s event: %v", event, err)
+			return false
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+
+	if !send("snapshot", snapshot) {
+		return
+	}
+
+	resync := time.NewTicker(statusStreamResync)
+	defer resync.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.streams.done():
+			return
+		case change, ok := <-changes:
+			if !ok {
+				return
+			}
+			if !send("tray", h.newStatusTrayChangeJSON(ctx, change)) {
+				return
+			}
+		case m, ok := <-messages:
+			if !ok {
+				return
+			}
+			if !send("message", newStatusMessageJSON(m)) {
+				return
+			}
+		case <-resync.C:
+			snapshot, err := h.statusData(ctx, messageRepo.MessageFilter{Limit: statusMessagesLimit})
+			if err != nil {
+				statusLogger.Errorf("StatusStream: %v", err)
+				continue
+			}
+			if !send("snapshot", snapshot) {
+				return
+			}
+		}
+	}
+}
+
+func (h *Handlers) newStatusTrayChangeJSON(ctx context.Context, change trayManager.TrayChange) statusTrayChangeJSON {
+	item := statusTrayChangeJSON{
+		Id:      change.Event.TrayId,
+		Deleted: change.Deleted,
+		Event: statusTrayEventJSON{
+			Time:    change.Event.Time,
+			Kind:    string(change.Event.Kind),
+			Message: change.Event.Message,
+		},
+	}
+
+	tray := change.Tray
+	if tray == nil && !change.Deleted {
+		// Events recorded by tray id carry no tray; send its current state.
+		var err error
+		tray, err = h.TrayManager.GetTrayById(ctx, item.Id)
+		if err != nil {
+			statusLogger.WithField(logging.TrayIdKey, item.Id).Warnf("StatusStream: failed to get tray %s: %v", item.Id, err)
+		}
+	}
+	if tray != nil {
+		trayItem := newStatusTrayJSON(tray)
+		item.Tray = &trayItem
+	}
+	return item
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cattery/lib/config"
+	messageRepo "cattery/lib/scaleSetPoller/repositories"
+	"cattery/lib/testutil"
+	"cattery/lib/trays"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readStatusEvent reads the next server-sent event and decodes its data into v.
+func readStatusEvent(t *testing.T, r *bufio.Reader, v any) string {
+	t.Helper()
+	var event, data string
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimRight(line, "\n")
+		if line == "" {
+			break
+		}
+		if name, ok := strings.CutPrefix(line, "event: "); ok {
+			event = name
+		}
+		if payload, ok := strings.CutPrefix(line, "data: "); ok {
+			data = payload
+		}
+	}
+	require.NoError(t, json.Unmarshal([]byte(data), v))
+	return event
+}
+
+func TestStatusStream(t *testing.T) {
+	trayType := &config.TrayType{Name: "linux", GitHubOrg: "org-a", Provider: "docker"}
+	config.SetForTest(t, &config.CatteryConfig{TrayTypes: []*config.TrayType{trayType}})
+	repo := testutil.NewMockTrayRepository()
+	repo.Trays["linux-1"] = &trays.Tray{
+		Id: "linux-1", TrayTypeName: "linux", GitHubOrgName: "org-a", ProviderName: "docker",
+		Status: trays.TrayStatusRegistered, StatusChanged: time.Now(), ProviderData: map[string]string{},
+	}
+	h := setupHandlersWithProvider(repo, &mockProvider{})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status/stream", h.StatusStream)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", srv.URL+"/status/stream", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	events := bufio.NewReader(resp.Body)
+
+	var snapshot statusDataJSON
+	require.Equal(t, "snapshot", readStatusEvent(t, events, &snapshot))
+	require.Len(t, snapshot.Trays, 1)
+	assert.Equal(t, "docker", snapshot.Trays[0].ProviderName)
+
+	// An event recorded by tray id carries the tray's current state.
+	h.TrayManager.RecordEvent(ctx, "linux-1", "linux", trays.TrayEventUnregistered, "done")
+	var change statusTrayChangeJSON
+	require.Equal(t, "tray", readStatusEvent(t, events, &change))
+	assert.Equal(t, "linux-1", change.Id)
+	assert.Equal(t, "unregistered", change.Event.Kind)
+	require.NotNil(t, change.Tray)
+	assert.Equal(t, "registered", change.Tray.Status)
+
+	_, err = h.TrayManager.DeleteTray(ctx, "linux-1")
+	require.NoError(t, err)
+	change = statusTrayChangeJSON{}
+	require.Equal(t, "tray", readStatusEvent(t, events, &change))
+	assert.Equal(t, "deleting", change.Tray.Status)
+	change = statusTrayChangeJSON{}
+	require.Equal(t, "tray", readStatusEvent(t, events, &change))
+	assert.Equal(t, "deleted", change.Event.Kind)
+	assert.True(t, change.Deleted)
+	assert.Nil(t, change.Tray)
+
+	h.ScaleSetManager.RecordMessage(ctx, &messageRepo.Message{Time: time.Now(), Kind: messageRepo.MessageKindScale, TrayType: "linux", DesiredCount: 2})
+	var message statusMessageJSON
+	require.Equal(t, "message", readStatusEvent(t, events, &message))
+	assert.Equal(t, "scale", message.Kind)
+	assert.Equal(t, 2, message.DesiredCount)
+}
+
+func TestStatusStream_EndsOnShutdown(t *testing.T) {
+	config.SetForTest(t, &config.CatteryConfig{})
+	h := setupHandlers(testutil.NewMockTrayRepository())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status/stream", h.StatusStream)
+	srv := httptest.NewUnstartedServer(mux)
+	srv.Config.RegisterOnShutdown(h.CloseStatusStreams)
+	srv.Start()
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/status/stream")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var snapshot statusDataJSON
+	require.Equal(t, "snapshot", readStatusEvent(t, bufio.NewReader(resp.Body), &snapshot))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	require.NoError(t, srv.Config.Shutdown(ctx), "the open stream does not hold up shutdown")
+	assert.Less(t, time.Since(start), time.Second)
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"cattery/lib/config"
+	"cattery/lib/election"
+	"cattery/lib/logging"
+	"cattery/lib/scaleSetClient"
+	"cattery/lib/scaleSetPoller"
+	"cattery/lib/trayManager"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// pollerSet runs one leader-elected scale set poller per configured tray type
+// and keeps the set in line with the config across reloads. Settings a poller
+// reads on every message (maxTrays, schedule, warm pool, provider config) apply
+// without a restart; a poller is only restarted when its scale set identity —
+// GitHub org, scope, runner group, labels or org credentials — changes.
+type pollerSet struct {
+	ctx         context.Context
+	tm          *trayManager.TrayManager
+	ssm         *scaleSetPoller.Manager
+	jitRegistry *scaleSetClient.JitRegistry
+	elector     election.Elector
+	logger      *log.Entry
+
+	mu      sync.Mutex
+	running map[string]*runningPoller
+}
+
+type runningPoller struct {
+	trayType *config.TrayType
+	org      config.GitHubOrganization
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+func newPollerSet(ctx context.Context, tm *trayManager.TrayManager, ssm *scaleSetPoller.Manager, jitRegistry *scaleSetClient.JitRegistry, elector election.Elector, logger *log.Entry) *pollerSet {
+	return &pollerSet{
+		ctx:         ctx,
+		tm:          tm,
+		ssm:         ssm,
+		jitRegistry: jitRegistry,
+		elector:     elector,
+		logger:      logger,
+		running:     make(map[string]*runningPoller),
+	}
+}
+
+// Apply starts pollers for tray types added in cfg, stops those of removed
+// tray types and restarts those whose scale set identity changed. It returns
+// the first error starting a poller; the other tray types are still applied.
+func (s *pollerSet) Apply(cfg *config.CatteryConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name, rp := range s.running {
+		trayType := cfg.GetTrayType(name)
+		if trayType == nil {
+			s.logger.WithField(logging.TrayTypeKey, name).Infof("Tray type '%s' removed from config; stopping its poller", name)
+			s.stop(name, rp)
+			continue
+		}
+		org := cfg.GetGitHubOrg(trayType.GitHubOrg)
+		if org == nil || *org != rp.org || scaleSetChanged(rp.trayType, trayType) {
+			s.logger.WithField(logging.TrayTypeKey, name).Infof("Scale set settings of tray type '%s' changed; restarting its poller", name)
+			s.stop(name, rp)
+		}
+	}
+
+	var firstErr error
+	for _, trayType := range cfg.TrayTypes {
+		if _, ok := s.running[trayType.Name]; ok {
+			continue
+		}
+		if err := s.start(cfg, trayType); err != nil {
+			s.logger.WithField(logging.TrayTypeKey, trayType.Name).Errorf("Failed to start poller for tray type '%s': %v", trayType.Name, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+func (s *pollerSet) start(cfg *config.CatteryConfig, trayType *config.TrayType) error {
+	org := cfg.GetGitHubOrg(trayType.GitHubOrg)
+	if org == nil {
+		return fmt.Errorf("GitHub organization '%s' not found for tray type '%s'", trayType.GitHubOrg, trayType.Name)
+	}
+
+	ssClient, err := scaleSetClient.NewScaleSetClient(org, trayType)
+	if err != nil {
+		return fmt.Errorf("failed to create scale set client for tray type '%s': %w", trayType.Name, err)
+	}
+	s.jitRegistry.Register(trayType.Name, ssClient)
+
+	poller := scaleSetPoller.NewPoller(ssClient, trayType, s.tm)
+	s.ssm.Register(trayType.Name, poller)
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	rp := &runningPoller{
+		trayType: trayType,
+		org:      *org,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	s.running[trayType.Name] = rp
+
+	logger := s.logger.WithField(logging.TrayTypeKey, trayType.Name)
+	s.ssm.Add(1)
+	go func(name string) {
+		defer s.ssm.Done()
+		defer close(rp.done)
+		// Run the poller only while this replica holds the lease for this
+		// tray type; leaderCtx is cancelled the moment leadership is lost.
+		err := s.elector.Run(ctx, name, func(leaderCtx context.Context) {
+			runPoller(leaderCtx, poller, name, logger)
+		})
+		if err != nil && ctx.Err() == nil {
+			logger.Errorf("Leader election for '%s' exited: %v", name, err)
+		}
+	}(trayType.Name)
+
+	return nil
+}
+
+// stop cancels a poller and waits for it to close its session and give up
+// its lease, so a restarted poller does not race its predecessor.
+func (s *pollerSet) stop(name string, rp *runningPoller) {
+	rp.cancel()
+	<-rp.done
+	delete(s.running, name)
+	s.ssm.Unregister(name)
+	s.jitRegistry.Unregister(name)
+}
+
+// scaleSetChanged reports whether a tray type now maps to a different scale
+// set (another runner group, repository or enterprise) or to different labels.
+func scaleSetChanged(old *config.TrayType, trayType *config.TrayType) bool {
+	return old.RunnerGroupId != trayType.RunnerGroupId ||
+		old.RunnerGroup != trayType.RunnerGroup ||
+		old.Repository != trayType.Repository ||
+		old.Enterprise != trayType.Enterprise ||
+		!slices.Equal(old.ScaleSetLabels(), trayType.ScaleSetLabels())
+}
@@ -3,14 +3,14 @@ package server
 import (
 	"cattery/lib/config"
 	"cattery/lib/election"
+	"cattery/lib/logging"
 	"cattery/lib/metrics"
 	"cattery/lib/restarter"
-	restarterRepo "cattery/lib/restarter/repositories"
 	"cattery/lib/scaleSetClient"
 	"cattery/lib/scaleSetPoller"
+	"cattery/lib/tracing"
 	"cattery/lib/trayManager"
 	"cattery/lib/trays/providers"
-	"cattery/lib/trays/repositories"
 	"cattery/server/handlers"
 	"context"
 	"errors"
@@ -22,12 +22,11 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
-	"go.mongodb.org/mongo-driver/v2/mongo"
-	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 func Start() {
-	var logger = log.New()
+	logger := logging.Logger("server")
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -35,86 +34,56 @@ func Start() {
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
 
-	// Db connection
-	serverAPI := options.ServerAPI(options.ServerAPIVersion1)
-	opts := options.Client().
-		ApplyURI(config.Get().Database.Uri).
-		SetServerAPIOptions(serverAPI)
-
-	client, err := mongo.Connect(opts)
+	shutdownTracing, err := tracing.Setup(ctx, config.Get().Tracing)
 	if err != nil {
-		logger.Fatal(err)
+		logger.Fatalf("Failed to set up tracing: %v", err)
 	}
 
-	{
-		timeoutCtx, cf := context.WithTimeout(context.Background(), 3*time.Second)
-		defer cf()
-
-		err = client.Ping(timeoutCtx, nil)
-		if err != nil {
-			logger.Errorf("Failed to connect to MongoDB: %v", err)
-			os.Exit(1)
-		}
+	// Db connection
+	store, err := openStorage(ctx, config.Get().Database.WithDefaults(), logger)
+	if err != nil {
+		logger.Fatalf("Failed to open database: %v", err)
 	}
 
-	var database = client.Database(config.Get().Database.Database)
-
 	// Initialize tray manager and repository
-	var trayRepository = repositories.NewMongodbTrayRepository()
-	trayRepository.Connect(database.Collection("trays"))
-	tm := trayManager.NewTrayManager(trayRepository, providers.DefaultFactory{})
+	tm := trayManager.NewTrayManager(store.trays, store.pauses, store.trayEvents, providers.DefaultFactory{})
 
 	// Register DB-backed metrics collector
 	metrics.RegisterTrayCollector(tm)
 
 	// Initialize restarter
-	var restartManagerRepository = restarterRepo.NewMongodbRestarterRepository()
-	restartManagerRepository.Connect(database.Collection("restarters"))
-	rm := restarter.NewWorkflowRestarter(restartManagerRepository)
-
-	// Initialize scale set pollers — one per TrayType.
-	// The JIT registry is populated alongside, so the agent register handler can
-	// generate JIT configs on any replica without depending on the (leader-only)
-	// poller for that tray type.
-	ssm := scaleSetPoller.NewManager()
+	rm := restarter.NewWorkflowRestarter(store.restarters)
+
+	// Initialize scale set pollers — one per TrayType, kept in line with the
+	// config across reloads. The JIT registry is populated alongside, so the
+	// agent register handler can generate JIT configs on any replica without
+	// depending on the (leader-only) poller for that tray type.
+	ssm := scaleSetPoller.NewManager(store.messages)
 	jitRegistry := scaleSetClient.NewJitRegistry()
 
 	// Leader election decides which replica runs each tray type's poller. The
 	// tray HTTP plane (served via jitRegistry above) runs on every replica
 	// regardless, so trays stay served during rollouts and failovers.
-	elector, err := election.NewFromConfig(config.Get().Coordination.WithDefaults(), database.Collection("leases"))
+	elector, err := election.NewFromConfig(config.Get().Coordination.WithDefaults(), store.leases)
 	if err != nil {
 		logger.Fatalf("Failed to initialize leader election: %v", err)
 	}
+	metrics.RegisterLeaderCollector(elector)
 
-	for _, trayType := range config.Get().TrayTypes {
-		org := config.Get().GetGitHubOrg(trayType.GitHubOrg)
-		if org == nil {
-			logger.Fatalf("GitHub organization '%s' not found for tray type '%s'", trayType.GitHubOrg, trayType.Name)
-		}
+	pollers := newPollerSet(ctx, tm, ssm, jitRegistry, elector, logger)
+	if err := pollers.Apply(config.Get()); err != nil {
+		logger.Fatalf("Failed to start scale set pollers: %v", err)
+	}
 
-		ssClient, err := scaleSetClient.NewScaleSetClient(org, trayType)
-		if err != nil {
-			logger.Fatalf("Failed to create scale set client for tray type '%s': %v", trayType.Name, err)
+	// Apply config file changes (and SIGHUP) without a restart
+	watchConfig(ctx, logger, func(cfg *config.CatteryConfig) {
+		if err := logging.Configure(cfg.Logging); err != nil {
+			logger.Errorf("Failed to apply reloaded logging config: %v", err)
 		}
-		jitRegistry.Register(trayType.Name, ssClient)
-
-		poller := scaleSetPoller.NewPoller(ssClient, trayType, tm)
-		ssm.Register(trayType.Name, poller)
-
-		ssm.Add(1)
-		go func(p *scaleSetPoller.Poller, name string) {
-			defer ssm.Done()
-			// Run the poller only while this replica holds the lease for this
-			// tray type; leaderCtx is cancelled the moment leadership is lost.
-			err := elector.Run(ctx, name, func(leaderCtx context.Context) {
-				runPoller(leaderCtx, p, name, logger)
-			})
-			if err != nil && ctx.Err() == nil {
-				logger.Errorf("Leader election for '%s' exited: %v", name, err)
-			}
-		}(poller, trayType.Name)
-	}
+		if err := pollers.Apply(cfg); err != nil {
+			logger.Errorf("Failed to apply reloaded config: %v", err)
+		}
+	})
 
 	// Start restart poller (replaces workflow_run webhook)
 	rm.StartPoller(ctx)
@@ -122,11 +91,24 @@ func Start() {
 	// Start stale tray cleanup
 	tm.HandleStale(ctx)
 
+	// Apply schedule windows as they open and close
+	tm.HandleSchedules(ctx)
+
+	// Start orphaned resource cleanup
+	tm.HandleOrphans(ctx)
+
+	// Prune tray lifecycle events past their retention
+	tm.HandleTrayEvents(ctx)
+
+	// Prune stored scale set messages past their retention
+	ssm.HandleMessages(ctx)
+
 	h := &handlers.Handlers{
 		TrayManager:     tm,
 		RestartManager:  rm,
 		ScaleSetManager: ssm,
 		JitRegistry:     jitRegistry,
+		Elector:         elector,
 	}
 
 	servers := startServers(logger, cancel, h)
@@ -153,16 +135,17 @@ func Start() {
 
 	disconnectCtx, disconnectCancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer disconnectCancel()
-	if err := client.Disconnect(disconnectCtx); err != nil {
-		logger.Errorf("Failed to disconnect from MongoDB: %v", err)
+	store.close(disconnectCtx)
+
+	if err := shutdownTracing(disconnectCtx); err != nil {
+		logger.Errorf("Failed to flush traces: %v", err)
 	}
-	logger.Info("MongoDB connection closed")
 }
 
 // runPoller runs a tray type's scale set listener until ctx is cancelled —
 // either leadership was lost (leaderCtx) or the process is shutting down —
 // restarting the listener after transient errors.
-func runPoller(ctx context.Context, p *scaleSetPoller.Poller, name string, logger *log.Logger) {
+func runPoller(ctx context.Context, p *scaleSetPoller.Poller, name string, logger *log.Entry) {
 	for {
 		if err := p.Run(ctx); err != nil {
 			if ctx.Err() != nil {
@@ -196,11 +179,54 @@ func agentMux(h *handlers.Handlers) *http.ServeMux {
 func registerStatusRoutes(mux *http.ServeMux, h *handlers.Handlers) {
 	mux.HandleFunc("/status", h.Status)
 	mux.HandleFunc("GET /status/data", h.StatusData)
+	mux.HandleFunc("GET /status/stream", h.StatusStream)
+	mux.HandleFunc("GET /status/trays/{id}", h.StatusTray)
+	mux.HandleFunc("GET /status/trays/{id}/events", h.StatusTrayEvents)
 	mux.Handle("/metrics", promhttp.Handler())
+
+	mux.HandleFunc("GET /api/v1/trays", handlers.AdminAuth(h.AdminListTrays))
+	mux.HandleFunc("GET /api/v1/trays/{id}", handlers.AdminAuth(h.AdminGetTray))
+	mux.HandleFunc("DELETE /api/v1/trays/{id}", handlers.AdminAuth(h.AdminDeleteTray))
+	mux.HandleFunc("POST /api/v1/trays/{id}/drain", handlers.AdminAuth(h.AdminDrainTray))
+
+	mux.HandleFunc("GET /api/v1/pauses", handlers.AdminAuth(h.AdminListPauses))
+	mux.HandleFunc("GET /api/v1/trayTypes/{name}/pause", handlers.AdminAuth(h.AdminGetPause))
+	mux.HandleFunc("POST /api/v1/trayTypes/{name}/pause", handlers.AdminAuth(h.AdminPauseTrayType))
+	mux.HandleFunc("DELETE /api/v1/trayTypes/{name}/pause", handlers.AdminAuth(h.AdminResumeTrayType))
+
+	mux.HandleFunc("GET /api/v1/leadership", handlers.AdminAuth(h.AdminListLeadership))
+	mux.HandleFunc("POST /api/v1/trayTypes/{name}/stepDown", handlers.AdminAuth(h.AdminStepDown))
+}
+
+// untracedPaths are polled too often for their spans to be worth keeping, or,
+// like the status stream, stay open for as long as the page does.
+var untracedPaths = map[string]bool{
+	"/healthcheck":   true,
+	"/metrics":       true,
+	"/status/data":   true,
+	"/status/stream": true,
+}
+
+// traced wraps mux with a span per request, named after the route pattern
+// that handles it and continuing the trace context the caller sent.
+func traced(mux *http.ServeMux) http.Handler {
+	return otelhttp.NewHandler(mux, "http",
+		otelhttp.WithPropagators(tracing.Propagator),
+		otelhttp.WithFilter(func(r *http.Request) bool { return !untracedPaths[r.URL.Path] }),
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			if _, pattern := mux.Handler(r); pattern != "" {
+				return pattern
+			}
+			return r.Method
+		}),
+	)
 }
 
-func listenAndServe(logger *log.Logger, cancel context.CancelFunc, addr string, handler http.Handler) *http.Server {
+func listenAndServe(logger *log.Entry, cancel context.CancelFunc, addr string, handler http.Handler, h *handlers.Handlers) *http.Server {
 	srv := &http.Server{Addr: addr, Handler: handler}
+	// Status streams only end with their request; without this one open
+	// status page holds Shutdown until its deadline.
+	srv.RegisterOnShutdown(h.CloseStatusStreams)
 	go func() {
 		logger.Infof("Starting server on %s", addr)
 		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
@@ -211,10 +237,10 @@ func listenAndServe(logger *log.Logger, cancel context.CancelFunc, addr string,
 	return srv
 }
 
-// startServers starts the agent server and the status+metrics server.
-// If statusListenAddress is unset or matches the agent address, status and
-// metrics are served on the same port as the agent endpoints.
-func startServers(logger *log.Logger, cancel context.CancelFunc, h *handlers.Handlers) []*http.Server {
+// startServers starts the agent server and the status+metrics server, which
+// also carries the admin API. If statusListenAddress is unset or matches the
+// agent address, these are served on the same port as the agent endpoints.
+func startServers(logger *log.Entry, cancel context.CancelFunc, h *handlers.Handlers) []*http.Server {
 	mainAddr := config.Get().Server.ListenAddress
 	statusAddr := config.Get().Server.StatusListenAddress
 
@@ -222,7 +248,7 @@ func startServers(logger *log.Logger, cancel context.CancelFunc, h *handlers.Han
 
 	if statusAddr == "" || statusAddr == mainAddr {
 		registerStatusRoutes(aMux, h)
-		return []*http.Server{listenAndServe(logger, cancel, mainAddr, aMux)}
+		return []*http.Server{listenAndServe(logger, cancel, mainAddr, traced(aMux), h)}
 	}
 
 	sMux := http.NewServeMux()
@@ -230,7 +256,7 @@ func startServers(logger *log.Logger, cancel context.CancelFunc, h *handlers.Han
 	sMux.HandleFunc("GET /healthcheck", h.Healthcheck)
 	registerStatusRoutes(sMux, h)
 	return []*http.Server{
-		listenAndServe(logger, cancel, mainAddr, aMux),
-		listenAndServe(logger, cancel, statusAddr, sMux),
+		listenAndServe(logger, cancel, mainAddr, traced(aMux), h),
+		listenAndServe(logger, cancel, statusAddr, traced(sMux), h),
 	}
 }
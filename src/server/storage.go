@@ -0,0 +1,191 @@
+package server
+
+import (
+	"cattery/lib/bolt"
+	"cattery/lib/config"
+	"cattery/lib/election"
+	"cattery/lib/postgres"
+	restarterRepo "cattery/lib/restarter/repositories"
+	messageRepo "cattery/lib/scaleSetPoller/repositories"
+	"cattery/lib/trays/repositories"
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// storage is everything persistent, built on the configured database driver.
+// leases is nil for drivers without a lease store.
+type storage struct {
+	trays      repositories.TrayRepository
+	pauses     repositories.PauseRepository
+	trayEvents repositories.TrayEventRepository
+	restarters restarterRepo.RestarterRepository
+	messages   messageRepo.MessageRepository
+	leases     election.LeaseStore
+	close      func(ctx context.Context)
+}
+
+// openStorage opens the storage of cfg's driver, with the tray, pause, tray
+// event and poller message repositories traced.
+func openStorage(ctx context.Context, cfg config.DatabaseConfig, logger *log.Entry) (*storage, error) {
+	s, err := openDriverStorage(ctx, cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	s.trays = repositories.NewTracedTrayRepository(s.trays, cfg.Driver)
+	s.pauses = repositories.NewTracedPauseRepository(s.pauses, cfg.Driver)
+	s.trayEvents = repositories.NewTracedTrayEventRepository(s.trayEvents, cfg.Driver)
+	s.messages = messageRepo.NewTracedMessageRepository(s.messages, cfg.Driver)
+	return s, nil
+}
+
+func openDriverStorage(ctx context.Context, cfg config.DatabaseConfig, logger *log.Entry) (*storage, error) {
+	switch cfg.Driver {
+	case config.DatabaseDriverMongo:
+		return openMongoStorage(cfg, logger)
+	case config.DatabaseDriverPostgres:
+		return openPostgresStorage(ctx, cfg, logger)
+	case config.DatabaseDriverBolt:
+		return openBoltStorage(cfg, logger)
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", cfg.Driver)
+	}
+}
+
+func openMongoStorage(cfg config.DatabaseConfig, logger *log.Entry) (*storage, error) {
+	serverAPI := options.ServerAPI(options.ServerAPIVersion1)
+	opts := options.Client().
+		ApplyURI(cfg.Uri).
+		SetServerAPIOptions(serverAPI)
+
+	client, err := mongo.Connect(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	timeoutCtx, cf := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cf()
+	if err := client.Ping(timeoutCtx, nil); err != nil {
+		return nil, fmt.Errorf("failed to connect to MongoDB: %w", err)
+	}
+
+	database := client.Database(cfg.Database)
+
+	trayRepository := repositories.NewMongodbTrayRepository()
+	trayRepository.Connect(database.Collection("trays"))
+
+	pauseRepository := repositories.NewMongodbPauseRepository()
+	pauseRepository.Connect(database.Collection("pauses"))
+
+	trayEventRepository := repositories.NewMongodbTrayEventRepository()
+	trayEventRepository.Connect(database.Collection("trayEvents"))
+
+	restarterRepository := restarterRepo.NewMongodbRestarterRepository()
+	restarterRepository.Connect(database.Collection("restarters"))
+
+	messageRepository := messageRepo.NewMongodbMessageRepository()
+	messageRepository.Connect(database.Collection("pollerMessages"))
+
+	leaseStore := election.NewMongoLeaseStore()
+	leaseStore.Connect(database.Collection("leases"))
+
+	return &storage{
+		trays:      trayRepository,
+		pauses:     pauseRepository,
+		trayEvents: trayEventRepository,
+		restarters: restarterRepository,
+		messages:   messageRepository,
+		leases:     leaseStore,
+		close: func(ctx context.Context) {
+			if err := client.Disconnect(ctx); err != nil {
+				logger.Errorf("Failed to disconnect from MongoDB: %v", err)
+				return
+			}
+			logger.Info("MongoDB connection closed")
+		},
+	}, nil
+}
+
+func openPostgresStorage(ctx context.Context, cfg config.DatabaseConfig, logger *log.Entry) (*storage, error) {
+	pool, err := postgres.Connect(ctx, cfg.Uri)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := postgres.Migrate(ctx, pool); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to migrate postgres schema: %w", err)
+	}
+
+	trayRepository := repositories.NewPostgresTrayRepository()
+	trayRepository.Connect(pool)
+
+	pauseRepository := repositories.NewPostgresPauseRepository()
+	pauseRepository.Connect(pool)
+
+	trayEventRepository := repositories.NewPostgresTrayEventRepository()
+	trayEventRepository.Connect(pool)
+
+	restarterRepository := restarterRepo.NewPostgresRestarterRepository()
+	restarterRepository.Connect(pool)
+
+	messageRepository := messageRepo.NewPostgresMessageRepository()
+	messageRepository.Connect(pool)
+
+	leaseStore := election.NewPostgresLeaseStore()
+	leaseStore.Connect(pool)
+
+	return &storage{
+		trays:      trayRepository,
+		pauses:     pauseRepository,
+		trayEvents: trayEventRepository,
+		restarters: restarterRepository,
+		messages:   messageRepository,
+		leases:     leaseStore,
+		close: func(ctx context.Context) {
+			pool.Close()
+			logger.Info("Postgres connection closed")
+		},
+	}, nil
+}
+
+func openBoltStorage(cfg config.DatabaseConfig, logger *log.Entry) (*storage, error) {
+	db, err := bolt.Open(cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	trayRepository := repositories.NewBoltTrayRepository()
+	trayRepository.Connect(db)
+
+	pauseRepository := repositories.NewBoltPauseRepository()
+	pauseRepository.Connect(db)
+
+	trayEventRepository := repositories.NewBoltTrayEventRepository()
+	trayEventRepository.Connect(db)
+
+	restarterRepository := restarterRepo.NewBoltRestarterRepository()
+	restarterRepository.Connect(db)
+
+	messageRepository := messageRepo.NewBoltMessageRepository()
+	messageRepository.Connect(db)
+
+	return &storage{
+		trays:      trayRepository,
+		pauses:     pauseRepository,
+		trayEvents: trayEventRepository,
+		restarters: restarterRepository,
+		messages:   messageRepository,
+		close: func(ctx context.Context) {
+			if err := db.Close(); err != nil {
+				logger.Errorf("Failed to close bolt database: %v", err)
+				return
+			}
+			logger.Info("Bolt database closed")
+		},
+	}, nil
+}
@@ -2,5 +2,5 @@ package ui
 
 import "embed"
 
-//go:embed status.html
+//go:embed status.html tray.html
 var Templates embed.FS